@@ -0,0 +1,15 @@
+package register
+
+import (
+	cm "kubecloud/backend/controllermanager"
+	"kubecloud/backend/controllers/acmecertificate"
+)
+
+func startAcmeCertificateController(ctx cm.ControllerContext) error {
+	go acmecertificate.NewAcmeCertificateController(ctx.Cluster).Run(ctx.Stop)
+	return nil
+}
+
+func init() {
+	cm.RegisterController("acmecertificate", startAcmeCertificateController)
+}
@@ -4,14 +4,30 @@ import (
 	"fmt"
 	cm "kubecloud/backend/controllermanager"
 	"kubecloud/backend/controllers/application"
+	"kubecloud/backend/util/kubeutil"
+
+	"k8s.io/client-go/tools/cache"
 )
 
 func startApplicationController(ctx cm.ControllerContext) error {
+	versions := kubeutil.GetAPIVersions(ctx.Cluster, ctx.Client.Discovery())
+	informers := make(map[string]cache.SharedIndexInformer)
+	for _, res := range []string{"deployments", "statefulsets", "daemonsets", "cronjobs"} {
+		informer, err := versions.Informer(ctx.InformerFactory, res)
+		if err != nil {
+			return fmt.Errorf("error creating %s informer of application controller: %v", res, err)
+		}
+		informers[res] = informer
+	}
 	ac, err := application.NewApplicationController(
 		ctx.Cluster, ctx.Client,
-		ctx.InformerFactory.Apps().V1beta1().Deployments())
+		informers["deployments"],
+		informers["statefulsets"],
+		informers["daemonsets"],
+		ctx.InformerFactory.Batch().V1().Jobs(),
+		informers["cronjobs"])
 	if err != nil {
-		return fmt.Errorf("error creating deployment controller: %v", err)
+		return fmt.Errorf("error creating application controller: %v", err)
 	}
 	go ac.Run(ctx.Option.NormalConcurrentSyncs, ctx.Stop)
 	return nil
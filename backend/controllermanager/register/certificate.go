@@ -0,0 +1,15 @@
+package register
+
+import (
+	cm "kubecloud/backend/controllermanager"
+	"kubecloud/backend/controllers/certificate"
+)
+
+func startCertificateController(ctx cm.ControllerContext) error {
+	go certificate.NewCertificateController(ctx.Cluster).Run(ctx.Stop)
+	return nil
+}
+
+func init() {
+	cm.RegisterController("certificate", startCertificateController)
+}
@@ -4,12 +4,16 @@ import (
 	"fmt"
 	cm "kubecloud/backend/controllermanager"
 	"kubecloud/backend/controllers/ingress"
+	"kubecloud/backend/util/kubeutil"
 )
 
 func startIngressController(ctx cm.ControllerContext) error {
-	ic, err := ingress.NewIngressController(
-		ctx.Cluster, ctx.Client,
-		ctx.InformerFactory.Extensions().V1beta1().Ingresses())
+	versions := kubeutil.GetAPIVersions(ctx.Cluster, ctx.Client.Discovery())
+	informer, err := versions.Informer(ctx.InformerFactory, "ingresses")
+	if err != nil {
+		return fmt.Errorf("error creating ingress informer: %v", err)
+	}
+	ic, err := ingress.NewIngressController(ctx.Cluster, ctx.Client, informer)
 	if err != nil {
 		return fmt.Errorf("error creating ingress controller: %v", err)
 	}
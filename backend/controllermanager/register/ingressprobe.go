@@ -0,0 +1,15 @@
+package register
+
+import (
+	cm "kubecloud/backend/controllermanager"
+	"kubecloud/backend/controllers/ingressprobe"
+)
+
+func startIngressProbeController(ctx cm.ControllerContext) error {
+	go ingressprobe.NewIngressProbeController(ctx.Cluster).Run(ctx.Stop)
+	return nil
+}
+
+func init() {
+	cm.RegisterController("ingressprobe", startIngressProbeController)
+}
@@ -0,0 +1,15 @@
+package register
+
+import (
+	cm "kubecloud/backend/controllermanager"
+	"kubecloud/backend/controllers/scaleschedule"
+)
+
+func startScaleScheduleController(ctx cm.ControllerContext) error {
+	go scaleschedule.NewScaleScheduleController(ctx.Cluster).Run(ctx.Stop)
+	return nil
+}
+
+func init() {
+	cm.RegisterController("scaleschedule", startScaleScheduleController)
+}
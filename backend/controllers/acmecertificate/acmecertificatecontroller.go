@@ -0,0 +1,50 @@
+package acmecertificate
+
+import (
+	"fmt"
+	"time"
+
+	"kubecloud/backend/resource"
+
+	"github.com/astaxie/beego"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// AcmeCertificateController issues and renews the acme certificates of the ingress hosts of the cluster,
+// it is run by the leader of the controller manager only
+type AcmeCertificateController struct {
+	cluster string
+	handler func() error
+}
+
+// NewAcmeCertificateController creates a new AcmeCertificateController.
+func NewAcmeCertificateController(cluster string) *AcmeCertificateController {
+	ac := &AcmeCertificateController{cluster: cluster}
+	ac.handler = ac.syncAcmeCertificates
+	return ac
+}
+
+// Run begins checking the certificates.
+func (ac *AcmeCertificateController) Run(stopCh <-chan struct{}) {
+	checkTime, err := beego.AppConfig.Int64("acme::checkTime")
+	if checkTime == 0 || err != nil {
+		checkTime = 300
+	}
+	go wait.Until(ac.worker, time.Duration(checkTime)*time.Second, stopCh)
+	<-stopCh
+}
+
+func (ac *AcmeCertificateController) worker() {
+	if err := ac.handler(); err != nil {
+		beego.Warn(fmt.Sprintf("renew acme certificates of cluster %s failed: %v", ac.cluster, err))
+	}
+}
+
+func (ac *AcmeCertificateController) syncAcmeCertificates() error {
+	startTime := time.Now()
+	defer func() {
+		beego.Debug(fmt.Sprintf("Finished renewing acme certificates of cluster %s (%v)", ac.cluster, time.Now().Sub(startTime)))
+	}()
+
+	return resource.RenewAcmeCertificates(ac.cluster)
+}
@@ -5,16 +5,21 @@ import (
 	"time"
 
 	"kubecloud/backend/controllers/util"
+	"kubecloud/backend/resource"
+	"kubecloud/backend/util/kubeutil"
 
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/orm"
 
-	"k8s.io/api/apps/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
-	informers "k8s.io/client-go/informers/apps/v1beta1"
+	batchinformers "k8s.io/client-go/informers/batch/v1"
 	"k8s.io/client-go/kubernetes"
-	listers "k8s.io/client-go/listers/apps/v1beta1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
@@ -29,7 +34,14 @@ const (
 )
 
 // controllerKind contains the schema.GroupVersionKind for this controller type.
-var controllerKind = v1beta1.SchemeGroupVersion.WithKind("Deployment")
+var controllerKind = appsv1.SchemeGroupVersion.WithKind("Deployment")
+
+// appKey is the item of the workqueue, the same key of different workload kinds
+// must be synced separately
+type appKey struct {
+	kind string
+	key  string
+}
 
 // DeploymentController is responsible for synchronizing Deployment objects stored
 // in the system with actual running replica sets and pods.
@@ -38,17 +50,27 @@ type ApplicationController struct {
 	client  kubernetes.Interface
 
 	// To allow injection of syncDeployment for testing.
-	syncHandler func(dKey string) error
+	syncHandler func(key appKey) error
 	// used for unit testing
-	enqueueDeployment func(deployment *v1beta1.Deployment)
+	enqueueDeployment func(deployment *appsv1.Deployment)
 
-	// dLister can list/get deployments from the shared informer's store
-	dLister listers.DeploymentLister
+	// dIndexer can get deployments of the negotiated version from the shared informer's store
+	dIndexer cache.Indexer
 
 	// dListerSynced returns true if the Deployment store has been synced at least once.
 	// Added as a member to the struct to allow injection for testing.
 	dListerSynced cache.InformerSynced
 
+	// stores of the other application workloads, the job is always batch/v1
+	stsIndexer      cache.Indexer
+	stsListerSynced cache.InformerSynced
+	dsIndexer       cache.Indexer
+	dsListerSynced  cache.InformerSynced
+	jobLister       batchlisters.JobLister
+	jobListerSynced cache.InformerSynced
+	cjIndexer       cache.Indexer
+	cjListerSynced  cache.InformerSynced
+
 	// Deployments that need to be synced
 	queue workqueue.RateLimitingInterface
 	// application dbhandler
@@ -56,25 +78,47 @@ type ApplicationController struct {
 }
 
 // NewDeploymentController creates a new DeploymentController.
+// The informers of deployment, statefulset, daemonset and cronjob are of the versions negotiated
+// with the cluster, their objects are converted to the GA versions before syncing.
 func NewApplicationController(cluster string,
 	client kubernetes.Interface,
-	dInformer informers.DeploymentInformer) (*ApplicationController, error) {
+	dInformer cache.SharedIndexInformer,
+	stsInformer cache.SharedIndexInformer,
+	dsInformer cache.SharedIndexInformer,
+	jobInformer batchinformers.JobInformer,
+	cjInformer cache.SharedIndexInformer) (*ApplicationController, error) {
 	ac := &ApplicationController{
 		cluster: cluster,
 		client:  client,
 		queue:   workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "deployment"),
 	}
 
-	dInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+	dInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    ac.addDeployment,
 		UpdateFunc: ac.updateDeployment,
 		DeleteFunc: ac.deleteDeployment,
 	})
-	ac.syncHandler = ac.syncDeployment
+	for kind, informer := range map[string]cache.SharedIndexInformer{
+		resource.AppKindStatefulSet: stsInformer,
+		resource.AppKindDaemonSet:   dsInformer,
+		resource.AppKindJob:         jobInformer.Informer(),
+		resource.AppKindCronJob:     cjInformer,
+	} {
+		informer.AddEventHandler(ac.workloadEventHandler(kind))
+	}
+	ac.syncHandler = ac.syncApp
 	ac.enqueueDeployment = ac.enqueue
 
-	ac.dLister = dInformer.Lister()
-	ac.dListerSynced = dInformer.Informer().HasSynced
+	ac.dIndexer = dInformer.GetIndexer()
+	ac.dListerSynced = dInformer.HasSynced
+	ac.stsIndexer = stsInformer.GetIndexer()
+	ac.stsListerSynced = stsInformer.HasSynced
+	ac.dsIndexer = dsInformer.GetIndexer()
+	ac.dsListerSynced = dsInformer.HasSynced
+	ac.jobLister = jobInformer.Lister()
+	ac.jobListerSynced = jobInformer.Informer().HasSynced
+	ac.cjIndexer = cjInformer.GetIndexer()
+	ac.cjListerSynced = cjInformer.HasSynced
 
 	ac.syncAppHandler = newSyncApplication(cluster)
 
@@ -85,7 +129,7 @@ func NewApplicationController(cluster string,
 func (ac *ApplicationController) Run(workers int, stopCh <-chan struct{}) {
 	defer ac.queue.ShutDown()
 
-	if !cache.WaitForCacheSync(stopCh, ac.dListerSynced) {
+	if !cache.WaitForCacheSync(stopCh, ac.dListerSynced, ac.stsListerSynced, ac.dsListerSynced, ac.jobListerSynced, ac.cjListerSynced) {
 		beego.Error("application controller cache sync failed!")
 		return
 	}
@@ -98,26 +142,33 @@ func (ac *ApplicationController) Run(workers int, stopCh <-chan struct{}) {
 }
 
 func (ac *ApplicationController) addDeployment(obj interface{}) {
-	d := obj.(*v1beta1.Deployment)
+	d, err := kubeutil.ToDeployment(obj)
+	if err != nil {
+		beego.Error(err)
+		return
+	}
 	ac.enqueueDeployment(d)
 }
 
 func (ac *ApplicationController) updateDeployment(old, cur interface{}) {
-	//oldD := old.(*v1beta1.Deployment)
-	curD := cur.(*v1beta1.Deployment)
+	curD, err := kubeutil.ToDeployment(cur)
+	if err != nil {
+		beego.Error(err)
+		return
+	}
 	ac.enqueueDeployment(curD)
 }
 
 func (ac *ApplicationController) deleteDeployment(obj interface{}) {
-	d, ok := obj.(*v1beta1.Deployment)
-	if !ok {
+	d, err := kubeutil.ToDeployment(obj)
+	if err != nil {
 		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
 		if !ok {
 			beego.Error(fmt.Errorf("Couldn't get object from tombstone %#v", obj))
 			return
 		}
-		d, ok = tombstone.Obj.(*v1beta1.Deployment)
-		if !ok {
+		d, err = kubeutil.ToDeployment(tombstone.Obj)
+		if err != nil {
 			beego.Error(fmt.Errorf("Tombstone contained object that is not a Deployment %#v", obj))
 			return
 		}
@@ -125,24 +176,44 @@ func (ac *ApplicationController) deleteDeployment(obj interface{}) {
 	ac.enqueueDeployment(d)
 }
 
-func (ac *ApplicationController) enqueue(deployment *v1beta1.Deployment) {
+func (ac *ApplicationController) enqueue(deployment *appsv1.Deployment) {
+	ac.enqueueWorkload(resource.AppKindDeployment, deployment)
+}
+
+func (ac *ApplicationController) enqueueRateLimited(deployment *appsv1.Deployment) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(deployment)
 	if err != nil {
 		beego.Error(fmt.Errorf("Couldn't get key for object %#v: %v", deployment, err))
 		return
 	}
 
-	ac.queue.Add(key)
+	ac.queue.AddRateLimited(appKey{kind: resource.AppKindDeployment, key: key})
 }
 
-func (ac *ApplicationController) enqueueRateLimited(deployment *v1beta1.Deployment) {
-	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(deployment)
+func (ac *ApplicationController) enqueueWorkload(kind string, obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
-		beego.Error(fmt.Errorf("Couldn't get key for object %#v: %v", deployment, err))
+		beego.Error(fmt.Errorf("Couldn't get key for object %#v: %v", obj, err))
 		return
 	}
 
-	ac.queue.AddRateLimited(key)
+	ac.queue.Add(appKey{kind: kind, key: key})
+}
+
+// workloadEventHandler enqueues the statefulset, daemonset, job or cronjob by its kind,
+// tombstones are handled by DeletionHandlingMetaNamespaceKeyFunc
+func (ac *ApplicationController) workloadEventHandler(kind string) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ac.enqueueWorkload(kind, obj)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			ac.enqueueWorkload(kind, cur)
+		},
+		DeleteFunc: func(obj interface{}) {
+			ac.enqueueWorkload(kind, obj)
+		},
+	}
 }
 
 // worker runs a worker thread that just dequeues items, processes them, and marks them done.
@@ -160,7 +231,7 @@ func (ac *ApplicationController) processNextWorkItem() bool {
 	}
 	defer ac.queue.Done(key)
 
-	err := ac.syncHandler(key.(string))
+	err := ac.syncHandler(key.(appKey))
 	ac.handleErr(err, key)
 
 	return true
@@ -177,10 +248,20 @@ func (ac *ApplicationController) handleErr(err error, key interface{}) {
 		return
 	}
 
-	beego.Warn(fmt.Sprintf("Dropping deployment %q out of the queue: %v, cluster: %s", key, err, ac.cluster))
+	beego.Warn(fmt.Sprintf("Dropping %v out of the queue: %v, cluster: %s", key, err, ac.cluster))
 	ac.queue.Forget(key)
 }
 
+// syncApp dispatches the key to the sync function of its workload kind.
+func (ac *ApplicationController) syncApp(key appKey) error {
+	switch key.kind {
+	case resource.AppKindDeployment:
+		return ac.syncDeployment(key.key)
+	default:
+		return ac.syncWorkload(key.kind, key.key)
+	}
+}
+
 // syncDeployment will sync the deployment with the given key.
 // This function is not meant to be invoked concurrently with the same key.
 func (ac *ApplicationController) syncDeployment(key string) error {
@@ -193,7 +274,7 @@ func (ac *ApplicationController) syncDeployment(key string) error {
 		//beego.Warn("Skip this syncing of cluster "+ac.cluster, namespace)
 		return nil
 	}
-	deployment, err := ac.dLister.Deployments(namespace).Get(name)
+	deployment, err := getDeployment(ac.dIndexer, key)
 	if errors.IsNotFound(err) {
 		beego.Info(fmt.Sprintf("Deployment %v has been deleted, cluster: %s", key, ac.cluster))
 		_, err := ac.syncAppHandler.appHandler.GetAppByName(ac.cluster, namespace, name)
@@ -211,3 +292,102 @@ func (ac *ApplicationController) syncDeployment(key string) error {
 
 	return ac.syncAppHandler.syncDeployApplication(*deployment)
 }
+
+// syncWorkload will sync the statefulset, daemonset, job or cronjob with the given key.
+func (ac *ApplicationController) syncWorkload(kind, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	if util.FilterNamespace(ac.cluster, namespace) {
+		return nil
+	}
+	var (
+		obj      metav1.Object
+		replicas *int32
+		status   *resource.AppStatus
+	)
+	switch kind {
+	case resource.AppKindStatefulSet:
+		var sts *appsv1.StatefulSet
+		if sts, err = getStatefulSet(ac.stsIndexer, key); err == nil {
+			obj, replicas, status = sts, sts.Spec.Replicas, resource.GetStatefulSetStatus(sts)
+		}
+	case resource.AppKindDaemonSet:
+		var ds *appsv1.DaemonSet
+		if ds, err = getDaemonSet(ac.dsIndexer, key); err == nil {
+			obj, status = ds, resource.GetDaemonSetStatus(ds)
+		}
+	case resource.AppKindJob:
+		var job *batchv1.Job
+		if job, err = ac.jobLister.Jobs(namespace).Get(name); err == nil {
+			// the jobs spawned by cronjob carry the labels of the cronjob application
+			if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == "CronJob" {
+				return nil
+			}
+			obj, status = job, resource.GetJobStatus(job)
+		}
+	case resource.AppKindCronJob:
+		var cj *batchv1.CronJob
+		if cj, err = getCronJob(ac.cjIndexer, key); err == nil {
+			obj, status = cj, resource.GetCronJobStatus(cj)
+		}
+	default:
+		return fmt.Errorf("unknown application kind %s of %s", kind, key)
+	}
+	if errors.IsNotFound(err) {
+		beego.Info(fmt.Sprintf("%s %v has been deleted, cluster: %s", kind, key, ac.cluster))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return ac.syncAppHandler.syncWorkloadApplication(kind, obj, replicas, status)
+}
+
+// getObject gets the object of the negotiated version from the store, the NotFound error is
+// returned if it does not exist just like the listers
+func getObject(indexer cache.Indexer, resource, key string) (interface{}, error) {
+	obj, exists, err := indexer.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		_, name, _ := cache.SplitMetaNamespaceKey(key)
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: resource}, name)
+	}
+	return obj, nil
+}
+
+func getDeployment(indexer cache.Indexer, key string) (*appsv1.Deployment, error) {
+	obj, err := getObject(indexer, "deployments", key)
+	if err != nil {
+		return nil, err
+	}
+	return kubeutil.ToDeployment(obj)
+}
+
+func getStatefulSet(indexer cache.Indexer, key string) (*appsv1.StatefulSet, error) {
+	obj, err := getObject(indexer, "statefulsets", key)
+	if err != nil {
+		return nil, err
+	}
+	return kubeutil.ToStatefulSet(obj)
+}
+
+func getDaemonSet(indexer cache.Indexer, key string) (*appsv1.DaemonSet, error) {
+	obj, err := getObject(indexer, "daemonsets", key)
+	if err != nil {
+		return nil, err
+	}
+	return kubeutil.ToDaemonSet(obj)
+}
+
+func getCronJob(indexer cache.Indexer, key string) (*batchv1.CronJob, error) {
+	obj, err := getObject(indexer, "cronjobs", key)
+	if err != nil {
+		return nil, err
+	}
+	return kubeutil.ToCronJob(obj)
+}
@@ -3,9 +3,10 @@ package application
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/astaxie/beego"
-	v1beta1 "k8s.io/api/apps/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"kubecloud/backend/controllers/util"
@@ -34,7 +35,7 @@ func newSyncApplication(cluster string) *syncApplication {
 }
 
 // update if the app is existed, or add it
-func (sa *syncApplication) syncDeployApplication(deployment v1beta1.Deployment) error {
+func (sa *syncApplication) syncDeployApplication(deployment appsv1.Deployment) error {
 	appname := getAppNameByDeploy(deployment)
 	if !sa.appHandler.AppExist(sa.cluster, deployment.Namespace, appname) {
 		return fmt.Errorf("application(%s/%s/%s) is not existed in db, the deployment is %s!", sa.cluster, deployment.Namespace, appname, deployment.Name)
@@ -43,7 +44,7 @@ func (sa *syncApplication) syncDeployApplication(deployment v1beta1.Deployment)
 	}
 }
 
-func (sa *syncApplication) updateDeployStatus(appname string, deployment v1beta1.Deployment) error {
+func (sa *syncApplication) updateDeployStatus(appname string, deployment appsv1.Deployment) error {
 	app, err := sa.appHandler.GetAppByName(sa.cluster, deployment.Namespace, appname)
 	if err != nil {
 		return err
@@ -62,7 +63,7 @@ func (sa *syncApplication) updateDeployStatus(appname string, deployment v1beta1
 	if deployment.Labels["heritage"] == "Tiller" {
 		deployment.TypeMeta = metav1.TypeMeta{
 			Kind:       "Deployment",
-			APIVersion: "apps/v1beta1",
+			APIVersion: "apps/v1",
 		}
 		deployment.ObjectMeta.ResourceVersion = ""
 		deployment.ObjectMeta.SelfLink = ""
@@ -128,7 +129,7 @@ func (sa *syncApplication) updateDeployStatus(appname string, deployment v1beta1
 		needUpdate = true
 	}
 	for _, condition := range deployment.Status.Conditions {
-		if condition.Type == v1beta1.DeploymentAvailable {
+		if condition.Type == appsv1.DeploymentAvailable {
 			if string(condition.Status) != app.AvailableStatus {
 				app.AvailableStatus = string(condition.Status)
 				needUpdate = true
@@ -156,7 +157,83 @@ func (sa *syncApplication) updateDeployStatus(appname string, deployment v1beta1
 	return nil
 }
 
-func getAppNameByDeploy(deploy v1beta1.Deployment) string {
+// update the status of statefulset, daemonset, job or cronjob application of the kind,
+// replicas is nil if the replicas of workload is not set by user
+func (sa *syncApplication) syncWorkloadApplication(kind string, obj metav1.Object, replicas *int32, status *resource.AppStatus) error {
+	appname := obj.GetName()
+	if v, ok := obj.GetLabels()[keyword.LABEL_APPNAME_KEY]; ok {
+		appname = v
+	}
+	if !sa.appHandler.AppExist(sa.cluster, obj.GetNamespace(), appname) {
+		return fmt.Errorf("application(%s/%s/%s) is not existed in db, the workload is %s!", sa.cluster, obj.GetNamespace(), appname, obj.GetName())
+	}
+	app, err := sa.appHandler.GetAppByName(sa.cluster, obj.GetNamespace(), appname)
+	if err != nil {
+		return err
+	}
+	// the workload of other kind may carry the labels of the application, such as the jobs of cronjob
+	if !strings.EqualFold(app.Kind, kind) {
+		beego.Debug(fmt.Sprintf("the %s %s is not the workload of %s application(%s/%s/%s)", kind, obj.GetName(), app.Kind, app.Cluster, app.Namespace, app.Name))
+		return nil
+	}
+	version := resource.GetResourceVersion(obj, resource.ResTypeDeploy, app.Image)
+	if resource.GetResourceVersion(app, resource.ResTypeApp, "") != version {
+		beego.Warn(fmt.Sprintf("application(%s/%s/%s) dont need update for versions(%s/%s) are not equal!", app.Cluster, app.Namespace, app.Name, version, resource.GetResourceVersion(app, resource.ResTypeApp, "")))
+		return nil
+	}
+
+	needUpdate := false
+	if replicas != nil && app.Replicas != int(*replicas) {
+		app.Replicas = int(*replicas)
+		template, err := resource.CreateAppTemplateByApp(*app)
+		if err != nil {
+			beego.Error("create app template by app failed:", err)
+		} else {
+			if tstr, err := template.Replicas(app.Replicas).String(); err == nil {
+				app.Template = tstr
+				needUpdate = true
+			} else {
+				beego.Error("template switch to string failed:", err)
+			}
+		}
+	}
+	if app.StatusReplicas != status.StatusReplicas {
+		app.StatusReplicas = status.StatusReplicas
+		needUpdate = true
+	}
+	if app.ReadyReplicas != status.ReadyReplicas {
+		app.ReadyReplicas = status.ReadyReplicas
+		needUpdate = true
+	}
+	if app.AvailableReplicas != status.AvailableReplicas {
+		app.AvailableReplicas = status.AvailableReplicas
+		needUpdate = true
+	}
+	if app.UpdatedReplicas != status.UpdatedReplicas {
+		app.UpdatedReplicas = status.UpdatedReplicas
+		needUpdate = true
+	}
+	if app.AvailableStatus != status.AvailableStatus {
+		app.AvailableStatus = status.AvailableStatus
+		needUpdate = true
+	}
+	if app.Message != status.Message {
+		app.Message = status.Message
+		needUpdate = true
+	}
+	if needUpdate {
+		err = sa.appHandler.UpdateApp(app, false)
+		if err != nil {
+			beego.Error("Update application", sa.cluster, app.Namespace, app.Name, "failed for", err)
+		} else {
+			beego.Info("Update application", sa.cluster, app.Namespace, app.Name, "successfully")
+		}
+		return err
+	}
+	return nil
+}
+
+func getAppNameByDeploy(deploy appsv1.Deployment) string {
 	appname := deploy.Name
 	if deploy.Labels["heritage"] != "Tiller" {
 		if v, ok := deploy.Labels[keyword.LABEL_APPNAME_KEY]; ok {
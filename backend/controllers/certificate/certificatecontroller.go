@@ -0,0 +1,50 @@
+package certificate
+
+import (
+	"fmt"
+	"time"
+
+	"kubecloud/backend/resource"
+
+	"github.com/astaxie/beego"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// CertificateController alerts the expiring certificates of the tls secrets and nodes of the cluster by events,
+// it is run by the leader of the controller manager only
+type CertificateController struct {
+	cluster string
+	handler func() error
+}
+
+// NewCertificateController creates a new CertificateController.
+func NewCertificateController(cluster string) *CertificateController {
+	cc := &CertificateController{cluster: cluster}
+	cc.handler = cc.checkCertificates
+	return cc
+}
+
+// Run begins checking the certificates.
+func (cc *CertificateController) Run(stopCh <-chan struct{}) {
+	checkTime, err := beego.AppConfig.Int64("certificate::checkTime")
+	if checkTime == 0 || err != nil {
+		checkTime = 3600
+	}
+	go wait.Until(cc.worker, time.Duration(checkTime)*time.Second, stopCh)
+	<-stopCh
+}
+
+func (cc *CertificateController) worker() {
+	if err := cc.handler(); err != nil {
+		beego.Warn(fmt.Sprintf("check certificates of cluster %s failed: %v", cc.cluster, err))
+	}
+}
+
+func (cc *CertificateController) checkCertificates() error {
+	startTime := time.Now()
+	defer func() {
+		beego.Debug(fmt.Sprintf("Finished checking certificates of cluster %s (%v)", cc.cluster, time.Now().Sub(startTime)))
+	}()
+
+	return resource.CheckCertificateExpiry(cc.cluster)
+}
@@ -6,15 +6,16 @@ import (
 
 	"kubecloud/backend/controllers/util"
 	dao "kubecloud/backend/dao"
+	"kubecloud/backend/resource"
+	"kubecloud/backend/util/kubeutil"
 
 	"github.com/astaxie/beego"
 
-	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
-	extensionsinformers "k8s.io/client-go/informers/extensions/v1beta1"
 	"k8s.io/client-go/kubernetes"
-	extensionslisters "k8s.io/client-go/listers/extensions/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
@@ -36,10 +37,10 @@ type IngressController struct {
 	// To allow injection of syncIngress for testing.
 	syncHandler func(dKey string) error
 	// used for unit testing
-	enqueueIngress func(ing *extensions.Ingress)
+	enqueueIngress func(ing *networkingv1.Ingress)
 
-	// ingLister can list/get ingresses from the shared informer's store
-	ingLister extensionslisters.IngressLister
+	// ingIndexer can get ingresses of the negotiated version from the shared informer's store
+	ingIndexer cache.Indexer
 
 	// ingListerSynced returns true if the ingress store has been synced at least once.
 	// Added as a member to the struct to allow injection for testing.
@@ -52,16 +53,17 @@ type IngressController struct {
 }
 
 // NewIngressController creates a new IngressController.
+// The informer is of the ingress version negotiated with the cluster.
 func NewIngressController(cluster string,
 	client kubernetes.Interface,
-	ingInformer extensionsinformers.IngressInformer) (*IngressController, error) {
+	ingInformer cache.SharedIndexInformer) (*IngressController, error) {
 	ic := &IngressController{
 		cluster: cluster,
 		client:  client,
 		queue:   workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ingress"),
 	}
 
-	ingInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+	ingInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    ic.addIngress,
 		UpdateFunc: ic.updateIngress,
 		DeleteFunc: ic.deleteIngress,
@@ -69,8 +71,8 @@ func NewIngressController(cluster string,
 	ic.syncHandler = ic.syncIngress
 	ic.enqueueIngress = ic.enqueue
 
-	ic.ingLister = ingInformer.Lister()
-	ic.ingListerSynced = ingInformer.Informer().HasSynced
+	ic.ingIndexer = ingInformer.GetIndexer()
+	ic.ingListerSynced = ingInformer.HasSynced
 
 	ic.kubeIngHandler = dao.NewK8sIngressModel()
 
@@ -94,26 +96,33 @@ func (ic *IngressController) Run(workers int, stopCh <-chan struct{}) {
 }
 
 func (ic *IngressController) addIngress(obj interface{}) {
-	ing := obj.(*extensions.Ingress)
+	ing, err := kubeutil.ToIngress(obj)
+	if err != nil {
+		beego.Error(err)
+		return
+	}
 	ic.enqueueIngress(ing)
 }
 
 func (ic *IngressController) updateIngress(old, cur interface{}) {
-	//olding := old.(*extensions.Ingress)
-	curing := cur.(*extensions.Ingress)
+	curing, err := kubeutil.ToIngress(cur)
+	if err != nil {
+		beego.Error(err)
+		return
+	}
 	ic.enqueueIngress(curing)
 }
 
 func (ic *IngressController) deleteIngress(obj interface{}) {
-	ing, ok := obj.(*extensions.Ingress)
-	if !ok {
+	ing, err := kubeutil.ToIngress(obj)
+	if err != nil {
 		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
 		if !ok {
 			beego.Error(fmt.Errorf("Couldn't get object from tombstone %#v", obj))
 			return
 		}
-		ing, ok = tombstone.Obj.(*extensions.Ingress)
-		if !ok {
+		ing, err = kubeutil.ToIngress(tombstone.Obj)
+		if err != nil {
 			beego.Error(fmt.Errorf("Tombstone contained object that is not a Ingress %#v", obj))
 			return
 		}
@@ -121,7 +130,7 @@ func (ic *IngressController) deleteIngress(obj interface{}) {
 	ic.enqueueIngress(ing)
 }
 
-func (ic *IngressController) enqueue(ing *extensions.Ingress) {
+func (ic *IngressController) enqueue(ing *networkingv1.Ingress) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(ing)
 	if err != nil {
 		beego.Error(fmt.Errorf("Couldn't get key for object %#v: %v", ing, err))
@@ -179,17 +188,38 @@ func (ic *IngressController) syncIngress(key string) error {
 		//beego.Warn("Skip this syncing of cluster "+ic.cluster, namespace)
 		return nil
 	}
-	ing, err := ic.ingLister.Ingresses(namespace).Get(name)
+	ing, err := ic.getIngress(key)
 	if errors.IsNotFound(err) {
 		err = ic.deleteIngressRecord(namespace, name)
 		if err != nil {
 			beego.Error("Delete ingress from database failed: ", err)
+			return err
 		}
-		return err
+		return resource.DeleteIngressRoutes(ic.cluster, namespace, name)
 	}
 	if err != nil {
 		return err
 	}
+	// the temporary ingress of acme challenge and the canary ingresses of match rules are not the rules of applications
+	if ing.Labels[kubeutil.AcmeSolverLabelKey] != "" || ing.Labels[resource.IngressOwnerLabelKey] != "" {
+		return nil
+	}
 
-	return ic.syncIngressRecord(*ing)
+	if err = ic.syncIngressRecord(*ing); err != nil {
+		return err
+	}
+	return resource.SyncIngressRoutes(ic.cluster, ing)
+}
+
+// getIngress gets the ingress from the store and converts it to networking.k8s.io/v1
+func (ic *IngressController) getIngress(key string) (*networkingv1.Ingress, error) {
+	obj, exists, err := ic.ingIndexer.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		_, name, _ := cache.SplitMetaNamespaceKey(key)
+		return nil, errors.NewNotFound(schema.GroupResource{Group: "networking.k8s.io", Resource: "ingresses"}, name)
+	}
+	return kubeutil.ToIngress(obj)
 }
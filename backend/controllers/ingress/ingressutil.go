@@ -2,12 +2,13 @@ package ingress
 
 import (
 	"kubecloud/backend/models"
+	"kubecloud/backend/util/kubeutil"
 	commutil "kubecloud/common/utils"
 
-	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 )
 
-func genIngressRecord(cluster string, ing extensions.Ingress) models.K8sIngress {
+func genIngressRecord(cluster string, ing networkingv1.Ingress) models.K8sIngress {
 	record := models.K8sIngress{}
 	record.Name = ing.Name
 	record.Namespace = ing.Namespace
@@ -23,7 +24,7 @@ func genIngressRecord(cluster string, ing extensions.Ingress) models.K8sIngress
 	return record
 }
 
-func genIngressRuleRecords(cluster string, ing extensions.Ingress, rule extensions.IngressRule) []*models.K8sIngressRule {
+func genIngressRuleRecords(cluster string, ing networkingv1.Ingress, rule networkingv1.IngressRule) []*models.K8sIngressRule {
 	recordList := []*models.K8sIngressRule{}
 	record := models.K8sIngressRule{}
 	record.Namespace = ing.Namespace
@@ -49,8 +50,7 @@ func genIngressRuleRecords(cluster string, ing extensions.Ingress, rule extensio
 			item := models.K8sIngressRule{}
 			item = record
 			item.Path = path.Path
-			item.ServiceName = path.Backend.ServiceName
-			item.ServicePort = path.Backend.ServicePort.IntValue()
+			item.ServiceName, item.ServicePort = kubeutil.IngressBackendService(path.Backend)
 			recordList = append(recordList, &item)
 		}
 	}
@@ -5,11 +5,11 @@ import (
 
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/orm"
-	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 )
 
 // update if the app is existed, or add it
-func (ic *IngressController) syncIngressRecord(ing extensions.Ingress) error {
+func (ic *IngressController) syncIngressRecord(ing networkingv1.Ingress) error {
 	old, err := ic.kubeIngHandler.Get(ic.cluster, ing.Namespace, ing.Name)
 	if err != nil {
 		if err != orm.ErrNoRows {
@@ -32,7 +32,7 @@ func (ic *IngressController) deleteIngressRecord(namespace, name string) error {
 	return err
 }
 
-func (ic *IngressController) createIngressRecord(ing extensions.Ingress) error {
+func (ic *IngressController) createIngressRecord(ing networkingv1.Ingress) error {
 	record := genIngressRecord(ic.cluster, ing)
 	if len(record.Rules) == 0 {
 		beego.Warn("no rules of ingress", ic.cluster, record.Namespace, record.Name)
@@ -46,7 +46,7 @@ func (ic *IngressController) createIngressRecord(ing extensions.Ingress) error {
 	return nil
 }
 
-func (ic *IngressController) updateIngressRecord(ing extensions.Ingress, old models.K8sIngress) error {
+func (ic *IngressController) updateIngressRecord(ing networkingv1.Ingress, old models.K8sIngress) error {
 	record := genIngressRecord(ic.cluster, ing)
 	err := ic.kubeIngHandler.Update(old, record)
 	if err != nil {
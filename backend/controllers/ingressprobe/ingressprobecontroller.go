@@ -0,0 +1,50 @@
+package ingressprobe
+
+import (
+	"fmt"
+	"time"
+
+	"kubecloud/backend/resource"
+
+	"github.com/astaxie/beego"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// IngressProbeController requests the hosts and paths of the ingress rules of the cluster through its load balancer
+// periodically and records the results, it is run by the leader of the controller manager only
+type IngressProbeController struct {
+	cluster string
+	handler func() error
+}
+
+// NewIngressProbeController creates a new IngressProbeController.
+func NewIngressProbeController(cluster string) *IngressProbeController {
+	pc := &IngressProbeController{cluster: cluster}
+	pc.handler = pc.probeIngressRules
+	return pc
+}
+
+// Run begins probing the ingress rules.
+func (pc *IngressProbeController) Run(stopCh <-chan struct{}) {
+	checkTime, err := beego.AppConfig.Int64("ingressprobe::checkTime")
+	if checkTime == 0 || err != nil {
+		checkTime = 60
+	}
+	go wait.Until(pc.worker, time.Duration(checkTime)*time.Second, stopCh)
+	<-stopCh
+}
+
+func (pc *IngressProbeController) worker() {
+	if err := pc.handler(); err != nil {
+		beego.Warn(fmt.Sprintf("probe ingress rules of cluster %s failed: %v", pc.cluster, err))
+	}
+}
+
+func (pc *IngressProbeController) probeIngressRules() error {
+	startTime := time.Now()
+	defer func() {
+		beego.Debug(fmt.Sprintf("Finished probing ingress rules of cluster %s (%v)", pc.cluster, time.Now().Sub(startTime)))
+	}()
+
+	return resource.ProbeIngressRules(pc.cluster)
+}
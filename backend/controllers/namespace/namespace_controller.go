@@ -1,6 +1,7 @@
 package namespace
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -231,7 +232,7 @@ func (nc *NamespaceController) syncNamespaceFromKey(key string) error {
 		}
 		row.CreatedAt = namespace.CreationTimestamp.Unix()
 		// fetch quota from k8s
-		if quotaList, err := nc.client.CoreV1().ResourceQuotas(key).List(metav1.ListOptions{}); err == nil {
+		if quotaList, err := nc.client.CoreV1().ResourceQuotas(key).List(context.TODO(), metav1.ListOptions{}); err == nil {
 			quotaName := resource.GenResourceQuotaName(key)
 			for _, item := range quotaList.Items {
 				if item.Name == quotaName {
@@ -17,6 +17,7 @@ limitations under the License.
 package node
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -258,7 +259,7 @@ func (nc *NodeController) syncNodeRecord(node *v1.Node) (bool, error) {
 			beego.Error(err.Error())
 			return false, err
 		}
-		pods, err := nc.kubeClient.CoreV1().Pods(v1.NamespaceAll).List(metav1.ListOptions{
+		pods, err := nc.kubeClient.CoreV1().Pods(v1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
 			FieldSelector: fieldSelector.String(),
 		})
 		if err != nil {
@@ -268,7 +269,7 @@ func (nc *NodeController) syncNodeRecord(node *v1.Node) (bool, error) {
 		for _, ipod := range pods.Items {
 			var gracePeriod int64
 			gracePeriod = 0
-			err := nc.kubeClient.CoreV1().Pods(ipod.Namespace).Delete(ipod.Name, &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+			err := nc.kubeClient.CoreV1().Pods(ipod.Namespace).Delete(context.TODO(), ipod.Name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
 			if err != nil {
 				beego.Error(err.Error())
 				return false, err
@@ -309,7 +310,7 @@ func (nc *NodeController) syncNodeInfo(node models.ZcloudNode, kubeNode *v1.Node
 					if oldNode.Department != "" {
 						kubeNode.ObjectMeta.Labels[labelPreffix+resource.DepartmentLabel] = oldNode.Department
 					}
-					if _, err := nc.kubeClient.CoreV1().Nodes().Update(kubeNode); err != nil {
+					if _, err := nc.kubeClient.CoreV1().Nodes().Update(context.TODO(), kubeNode, metav1.UpdateOptions{}); err != nil {
 						return fmt.Errorf("set bizcluster or department label on node in k8s has error: %v", err)
 					}
 				}
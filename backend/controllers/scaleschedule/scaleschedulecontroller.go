@@ -0,0 +1,50 @@
+package scaleschedule
+
+import (
+	"fmt"
+	"time"
+
+	"kubecloud/backend/resource"
+
+	"github.com/astaxie/beego"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// ScaleScheduleController scales the applications by the schedules of the cluster,
+// it is run by the leader of the controller manager only
+type ScaleScheduleController struct {
+	cluster string
+	handler func() error
+}
+
+// NewScaleScheduleController creates a new ScaleScheduleController.
+func NewScaleScheduleController(cluster string) *ScaleScheduleController {
+	sc := &ScaleScheduleController{cluster: cluster}
+	sc.handler = sc.syncScaleSchedule
+	return sc
+}
+
+// Run begins checking the schedules.
+func (sc *ScaleScheduleController) Run(stopCh <-chan struct{}) {
+	checkTime, err := beego.AppConfig.Int64("scaleschedule::checkTime")
+	if checkTime == 0 || err != nil {
+		checkTime = 30
+	}
+	go wait.Until(sc.worker, time.Duration(checkTime)*time.Second, stopCh)
+	<-stopCh
+}
+
+func (sc *ScaleScheduleController) worker() {
+	if err := sc.handler(); err != nil {
+		beego.Warn(fmt.Sprintf("run scale schedules of cluster %s failed: %v", sc.cluster, err))
+	}
+}
+
+func (sc *ScaleScheduleController) syncScaleSchedule() error {
+	startTime := time.Now()
+	defer func() {
+		beego.Debug(fmt.Sprintf("Finished running scale schedules of cluster %s (%v)", sc.cluster, time.Now().Sub(startTime)))
+	}()
+
+	return resource.RunScaleSchedules(sc.cluster)
+}
@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type AcmeCertificateModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewAcmeCertificateModel() *AcmeCertificateModel {
+	return &AcmeCertificateModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudAcmeCertificate{}).TableName(),
+	}
+}
+
+func (am *AcmeCertificateModel) GetCertificateList(cluster string) ([]models.ZcloudAcmeCertificate, error) {
+	list := []models.ZcloudAcmeCertificate{}
+	_, err := am.tOrmer.QueryTable(am.TableName).
+		Filter("cluster", cluster).
+		Filter("deleted", 0).OrderBy("id").All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+func (am *AcmeCertificateModel) GetCertificate(cluster, namespace, host string) (*models.ZcloudAcmeCertificate, error) {
+	cert := models.ZcloudAcmeCertificate{}
+	err := am.tOrmer.QueryTable(am.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("host", host).
+		Filter("deleted", 0).One(&cert)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// GetChallengeResponse returns the key authorization of the http-01 challenge token
+func (am *AcmeCertificateModel) GetChallengeResponse(token string) (string, error) {
+	cert := models.ZcloudAcmeCertificate{}
+	err := am.tOrmer.QueryTable(am.TableName).
+		Filter("challenge_token", token).
+		Filter("deleted", 0).One(&cert, "challenge_response")
+	if err != nil {
+		return "", err
+	}
+	return cert.ChallengeResponse, nil
+}
+
+func (am *AcmeCertificateModel) CreateCertificate(cert *models.ZcloudAcmeCertificate) error {
+	cert.Addons = models.NewAddons()
+	_, err := am.tOrmer.Insert(cert)
+	return err
+}
+
+func (am *AcmeCertificateModel) UpdateCertificate(cert *models.ZcloudAcmeCertificate, cols ...string) error {
+	cert.Addons = cert.Addons.UpdateAddons()
+	if len(cols) != 0 {
+		cols = append(cols, "update_at")
+	}
+	_, err := am.tOrmer.Update(cert, cols...)
+	return err
+}
+
+func (am *AcmeCertificateModel) DeleteCertificate(cluster, namespace, host string) error {
+	_, err := am.tOrmer.Raw("UPDATE "+am.TableName+" SET deleted=1, delete_at=now() WHERE cluster=? AND namespace=? AND host=? AND deleted=0",
+		cluster, namespace, host).Exec()
+	return err
+}
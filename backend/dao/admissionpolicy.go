@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type AdmissionPolicyModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewAdmissionPolicyModel() *AdmissionPolicyModel {
+	return &AdmissionPolicyModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudAdmissionPolicy{}).TableName(),
+	}
+}
+
+// GetPolicyList returns the policies of the cluster, the ones of namespace and the cluster wide ones are
+// returned if namespace is not empty
+func (pm *AdmissionPolicyModel) GetPolicyList(cluster, namespace string) ([]models.ZcloudAdmissionPolicy, error) {
+	list := []models.ZcloudAdmissionPolicy{}
+	query := pm.tOrmer.QueryTable(pm.TableName).
+		Filter("cluster", cluster).
+		Filter("deleted", 0)
+	if namespace != "" {
+		query = query.Filter("namespace__in", "", namespace)
+	}
+	_, err := query.OrderBy("id").All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+func (pm *AdmissionPolicyModel) GetPolicy(cluster string, id int64) (*models.ZcloudAdmissionPolicy, error) {
+	policy := models.ZcloudAdmissionPolicy{}
+	err := pm.tOrmer.QueryTable(pm.TableName).
+		Filter("cluster", cluster).
+		Filter("id", id).
+		Filter("deleted", 0).One(&policy)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (pm *AdmissionPolicyModel) PolicyExists(cluster, namespace, name string) bool {
+	return pm.tOrmer.QueryTable(pm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("name", name).
+		Filter("deleted", 0).Exist()
+}
+
+func (pm *AdmissionPolicyModel) CreatePolicy(policy *models.ZcloudAdmissionPolicy) error {
+	policy.Addons = models.NewAddons()
+	_, err := pm.tOrmer.Insert(policy)
+	return err
+}
+
+func (pm *AdmissionPolicyModel) UpdatePolicy(policy *models.ZcloudAdmissionPolicy) error {
+	policy.Addons = policy.Addons.UpdateAddons()
+	_, err := pm.tOrmer.Update(policy)
+	return err
+}
+
+func (pm *AdmissionPolicyModel) DeletePolicy(cluster string, id int64) error {
+	_, err := pm.tOrmer.Raw("UPDATE "+pm.TableName+" SET deleted=1, delete_at=now() WHERE cluster=? AND id=? AND deleted=0", cluster, id).Exec()
+	return err
+}
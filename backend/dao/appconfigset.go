@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type AppConfigSetModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewAppConfigSetModel() *AppConfigSetModel {
+	return &AppConfigSetModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudAppConfigSet{}).TableName(),
+	}
+}
+
+func (cm *AppConfigSetModel) GetConfigSetList(cluster, namespace string) ([]models.ZcloudAppConfigSet, error) {
+	list := []models.ZcloudAppConfigSet{}
+	_, err := cm.tOrmer.QueryTable(cm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("deleted", 0).OrderBy("name").All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+func (cm *AppConfigSetModel) GetConfigSet(cluster, namespace, name string) (*models.ZcloudAppConfigSet, error) {
+	set := models.ZcloudAppConfigSet{}
+	err := cm.tOrmer.QueryTable(cm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("name", name).
+		Filter("deleted", 0).One(&set)
+	if err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func (cm *AppConfigSetModel) ConfigSetExists(cluster, namespace, name string) bool {
+	return cm.tOrmer.QueryTable(cm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("name", name).
+		Filter("deleted", 0).Exist()
+}
+
+func (cm *AppConfigSetModel) CreateConfigSet(set *models.ZcloudAppConfigSet) error {
+	set.Addons = models.NewAddons()
+	_, err := cm.tOrmer.Insert(set)
+	return err
+}
+
+func (cm *AppConfigSetModel) UpdateConfigSet(set *models.ZcloudAppConfigSet) error {
+	set.Addons = set.Addons.UpdateAddons()
+	_, err := cm.tOrmer.Update(set)
+	return err
+}
+
+func (cm *AppConfigSetModel) DeleteConfigSet(cluster, namespace, name string) error {
+	_, err := cm.tOrmer.Raw("UPDATE "+cm.TableName+" SET deleted=1, delete_at=now() WHERE cluster=? AND namespace=? AND name=? AND deleted=0", cluster, namespace, name).Exec()
+	return err
+}
@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"fmt"
+
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type AutoscalerModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewAutoscalerModel() *AutoscalerModel {
+	return &AutoscalerModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudAutoscaler{}).TableName(),
+	}
+}
+
+func (am *AutoscalerModel) GetAutoscaler(cluster, namespace, appname string) (*models.ZcloudAutoscaler, error) {
+	autoscaler := models.ZcloudAutoscaler{}
+
+	err := am.tOrmer.QueryTable(am.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("name", appname).
+		Filter("deleted", 0).One(&autoscaler)
+	if err != nil {
+		return nil, err
+	}
+	return &autoscaler, nil
+}
+
+func (am *AutoscalerModel) SetAutoscaler(autoscaler *models.ZcloudAutoscaler) error {
+	if autoscaler == nil {
+		return fmt.Errorf("autoscaler must be given!")
+	}
+	old, err := am.GetAutoscaler(autoscaler.Cluster, autoscaler.Namespace, autoscaler.Name)
+	if err == orm.ErrNoRows {
+		// insert
+		autoscaler.Addons = models.NewAddons()
+		_, err = am.tOrmer.Insert(autoscaler)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	autoscaler.Id = old.Id
+	autoscaler.Addons = old.Addons.UpdateAddons()
+	_, err = am.tOrmer.Update(autoscaler)
+	return err
+}
+
+func (am *AutoscalerModel) DeleteAutoscaler(cluster, namespace, appname string) error {
+	_, err := am.tOrmer.Raw("UPDATE "+am.TableName+" SET deleted=1, delete_at=now() WHERE cluster=? AND namespace=? AND name=? AND deleted=0",
+		cluster, namespace, appname).Exec()
+	return err
+}
@@ -4,8 +4,12 @@ import (
 	"sync"
 
 	"github.com/astaxie/beego/orm"
+	"github.com/go-sql-driver/mysql"
 )
 
+// the error number of mysql if the unique index is violated
+const mysqlErrDuplicateEntry = 1062
+
 var globalOrm orm.Ormer
 var once sync.Once
 
@@ -16,3 +20,9 @@ func GetOrmer() orm.Ormer {
 	})
 	return globalOrm
 }
+
+// IsDuplicateError checks the error is caused by the unique index
+func IsDuplicateError(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == mysqlErrDuplicateEntry
+}
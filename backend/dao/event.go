@@ -114,3 +114,19 @@ func GetNodeEventsByFilter(cluster, host string, filterQuery *utils.FilterQuery)
 		},
 		List: events}, err
 }
+
+// GetLatestEvent returns the latest event of the object with the reason
+func GetLatestEvent(cluster, namespace, objectKind, objectName, reason string) (*models.ZcloudEvent, error) {
+	var event models.ZcloudEvent
+	err := GetOrmer().QueryTable("zcloud_event").
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("object_kind", objectKind).
+		Filter("object_name", objectName).
+		Filter("reason", reason).
+		OrderBy("-last_time").Limit(1).One(&event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,151 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type HelmReleaseModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewHelmReleaseModel() *HelmReleaseModel {
+	return &HelmReleaseModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudHelmRelease{}).TableName(),
+	}
+}
+
+// GetReleaseList returns all revisions of the releases in the namespace, ordered by name and revision desc
+func (hm *HelmReleaseModel) GetReleaseList(cluster, namespace string) ([]models.ZcloudHelmRelease, error) {
+	list := []models.ZcloudHelmRelease{}
+	_, err := hm.tOrmer.QueryTable(hm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("deleted", 0).
+		OrderBy("name", "-revision").All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+// GetReleaseHistory returns the revisions of the release, the latest one is the first
+func (hm *HelmReleaseModel) GetReleaseHistory(cluster, namespace, name string) ([]models.ZcloudHelmRelease, error) {
+	list := []models.ZcloudHelmRelease{}
+	_, err := hm.tOrmer.QueryTable(hm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("name", name).
+		Filter("deleted", 0).
+		OrderBy("-revision").All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+func (hm *HelmReleaseModel) GetRelease(cluster, namespace, name string, revision int) (*models.ZcloudHelmRelease, error) {
+	release := models.ZcloudHelmRelease{}
+	err := hm.tOrmer.QueryTable(hm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("name", name).
+		Filter("revision", revision).
+		Filter("deleted", 0).One(&release)
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// the times of recording the release again if the revision is recorded by a concurrent deployment
+const helmReleaseCreateRetries = 3
+
+// CreateRelease records the release as the next revision, the previous deployed revisions are superseded
+// if it is deployed. The revision is allocated again if it is recorded by a concurrent deployment
+func (hm *HelmReleaseModel) CreateRelease(release *models.ZcloudHelmRelease) error {
+	var err error
+	for i := 0; i < helmReleaseCreateRetries; i++ {
+		if err = hm.createRelease(release); !IsDuplicateError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (hm *HelmReleaseModel) createRelease(release *models.ZcloudHelmRelease) error {
+	// the global ormer is shared, so the transaction uses its own ormer
+	o := orm.NewOrm()
+	if err := o.Begin(); err != nil {
+		return err
+	}
+	latest := models.ZcloudHelmRelease{}
+	err := o.QueryTable(hm.TableName).
+		Filter("cluster", release.Cluster).
+		Filter("namespace", release.Namespace).
+		Filter("name", release.Name).
+		Filter("deleted", 0).
+		OrderBy("-revision").Limit(1).One(&latest, "revision")
+	if err != nil && err != orm.ErrNoRows {
+		o.Rollback()
+		return err
+	}
+	release.Id = 0
+	release.Revision = latest.Revision + 1
+	release.Addons = models.NewAddons()
+	// the unique index of revision avoids recording the concurrent deployments as the same revision
+	if _, err := o.Insert(release); err != nil {
+		o.Rollback()
+		return err
+	}
+	if err := hm.supersedeReleases(o, release); err != nil {
+		o.Rollback()
+		return err
+	}
+	return o.Commit()
+}
+
+// UpdateRelease updates the manifest, status and description of the recorded revision,
+// the previous deployed revisions are superseded if it is deployed
+func (hm *HelmReleaseModel) UpdateRelease(release *models.ZcloudHelmRelease) error {
+	o := orm.NewOrm()
+	if err := o.Begin(); err != nil {
+		return err
+	}
+	release.UpdateAt = models.NewAddons().UpdateAt
+	if _, err := o.Update(release, "manifest", "status", "description", "update_at"); err != nil {
+		o.Rollback()
+		return err
+	}
+	if err := hm.supersedeReleases(o, release); err != nil {
+		o.Rollback()
+		return err
+	}
+	return o.Commit()
+}
+
+func (hm *HelmReleaseModel) supersedeReleases(o orm.Ormer, release *models.ZcloudHelmRelease) error {
+	if release.Status != models.HELM_RELEASE_STATUS_DEPLOYED {
+		return nil
+	}
+	_, err := o.QueryTable(hm.TableName).
+		Filter("cluster", release.Cluster).
+		Filter("namespace", release.Namespace).
+		Filter("name", release.Name).
+		Filter("status", models.HELM_RELEASE_STATUS_DEPLOYED).
+		Filter("deleted", 0).
+		Exclude("revision", release.Revision).
+		Update(orm.Params{
+			"status":    models.HELM_RELEASE_STATUS_SUPERSEDED,
+			"update_at": release.UpdateAt,
+		})
+	return err
+}
+
+func (hm *HelmReleaseModel) DeleteRelease(cluster, namespace, name string) error {
+	_, err := hm.tOrmer.Raw("UPDATE "+hm.TableName+" SET deleted=1, delete_at=now() WHERE cluster=? AND namespace=? AND name=? AND deleted=0",
+		cluster, namespace, name).Exec()
+	return err
+}
@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type IngressProbeModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewIngressProbeModel() *IngressProbeModel {
+	return &IngressProbeModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudIngressProbe{}).TableName(),
+	}
+}
+
+func (pm *IngressProbeModel) CreateProbe(probe *models.ZcloudIngressProbe) error {
+	_, err := pm.tOrmer.Insert(probe)
+	return err
+}
+
+func ingressRuleProbesCond(cluster, namespace, host, path string) *orm.Condition {
+	return orm.NewCondition().
+		And("cluster", cluster).
+		And("namespace", namespace).
+		And("host", host).
+		And("path", path)
+}
+
+// GetLatestProbe returns the latest probe result of the host and path
+func (pm *IngressProbeModel) GetLatestProbe(cluster, namespace, host, path string) (*models.ZcloudIngressProbe, error) {
+	probe := models.ZcloudIngressProbe{}
+	err := pm.tOrmer.QueryTable(pm.TableName).
+		SetCond(ingressRuleProbesCond(cluster, namespace, host, path)).
+		OrderBy("-probe_at", "-id").Limit(1).One(&probe)
+	if err != nil {
+		return nil, err
+	}
+	return &probe, nil
+}
+
+// CountProbes returns the count of all and the successful probe results of the host and path since the time.
+// The probe is successful if the route answers the request, the requests rejected by the access policies
+// of ingress are successful too, because the probes have no credentials
+func (pm *IngressProbeModel) CountProbes(cluster, namespace, host, path string, since time.Time) (int64, int64, error) {
+	cond := ingressRuleProbesCond(cluster, namespace, host, path).And("probe_at__gte", since)
+	total, err := pm.tOrmer.QueryTable(pm.TableName).SetCond(cond).Count()
+	if err != nil {
+		return 0, 0, err
+	}
+	successCond := orm.NewCondition().
+		AndCond(orm.NewCondition().And("status_code__gte", http.StatusOK).And("status_code__lt", http.StatusBadRequest)).
+		Or("status_code__in", http.StatusUnauthorized, http.StatusForbidden)
+	success, err := pm.tOrmer.QueryTable(pm.TableName).SetCond(cond.AndCond(successCond)).Count()
+	if err != nil {
+		return 0, 0, err
+	}
+	return total, success, nil
+}
+
+// DeleteProbesBefore deletes the probe results of the cluster before the time
+func (pm *IngressProbeModel) DeleteProbesBefore(cluster string, before time.Time) error {
+	_, err := pm.tOrmer.QueryTable(pm.TableName).
+		Filter("cluster", cluster).
+		Filter("probe_at__lt", before).Delete()
+	return err
+}
@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type IngressRouteModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewIngressRouteModel() *IngressRouteModel {
+	return &IngressRouteModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudIngressRoute{}).TableName(),
+	}
+}
+
+// ListRoutes returns the routes of the host on the load balancer, all routes of the load balancer are returned
+// if host is empty, and the routes of all load balancers are returned if loadBalancer is empty
+func (rm *IngressRouteModel) ListRoutes(loadBalancer, host string) ([]models.ZcloudIngressRoute, error) {
+	list := []models.ZcloudIngressRoute{}
+	query := rm.tOrmer.QueryTable(rm.TableName).Filter("deleted", 0)
+	if loadBalancer != "" {
+		query = query.Filter("load_balancer", loadBalancer)
+	}
+	if host != "" {
+		query = query.Filter("host", host)
+	}
+	_, err := query.OrderBy("id").Limit(-1).All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+// ListIngressRoutes returns the routes registered by the ingress
+func (rm *IngressRouteModel) ListIngressRoutes(cluster, namespace, ingressName string) ([]models.ZcloudIngressRoute, error) {
+	list := []models.ZcloudIngressRoute{}
+	_, err := rm.tOrmer.QueryTable(rm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("ingress_name", ingressName).
+		Filter("deleted", 0).OrderBy("id").All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+func (rm *IngressRouteModel) CreateRoute(route *models.ZcloudIngressRoute) error {
+	route.Addons = models.NewAddons()
+	_, err := rm.tOrmer.Insert(route)
+	return err
+}
+
+func (rm *IngressRouteModel) DeleteRoute(id int64) error {
+	_, err := rm.tOrmer.Raw("UPDATE "+rm.TableName+" SET deleted=1, delete_at=now() WHERE id=? AND deleted=0", id).Exec()
+	return err
+}
+
+func (rm *IngressRouteModel) DeleteIngressRoutes(cluster, namespace, ingressName string) error {
+	_, err := rm.tOrmer.Raw("UPDATE "+rm.TableName+" SET deleted=1, delete_at=now() WHERE cluster=? AND namespace=? AND ingress_name=? AND deleted=0",
+		cluster, namespace, ingressName).Exec()
+	return err
+}
+
+// UpdateLoadBalancer moves the routes of cluster to its new load balancer
+func (rm *IngressRouteModel) UpdateLoadBalancer(cluster, loadBalancer string) error {
+	_, err := rm.tOrmer.Raw("UPDATE "+rm.TableName+" SET load_balancer=? WHERE cluster=?",
+		loadBalancer, cluster).Exec()
+	return err
+}
@@ -159,3 +159,51 @@ func (im *IngressRuleModel) GetIngressNameByHost(cluster, namespace, host string
 	}
 	return rule.IngressName
 }
+
+// ListIngressNamesByHost returns the names of the ingresses having the rules of host in the order of creation
+func (im *IngressRuleModel) ListIngressNamesByHost(cluster, namespace, host string) ([]string, error) {
+	rulelist := []models.K8sIngressRule{}
+	if _, err := im.tOrmer.QueryTable(im.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("host", host).
+		Filter("deleted", 0).OrderBy("id").Limit(-1).All(&rulelist, "ingress_name"); err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	names := []string{}
+	for _, rule := range rulelist {
+		if !utils.ContainsString(names, rule.IngressName) {
+			names = append(names, rule.IngressName)
+		}
+	}
+	return names, nil
+}
+
+// ListTLSRules returns the https rules, the rules of all clusters are returned if cluster is empty
+func (im *IngressRuleModel) ListTLSRules(cluster string, nslist []string) ([]models.K8sIngressRule, error) {
+	rulelist := []models.K8sIngressRule{}
+	query := im.tOrmer.QueryTable(im.TableName).
+		Filter("is_tls", true).
+		Filter("deleted", 0)
+	if cluster != "" {
+		query = query.Filter("cluster", cluster)
+	}
+	if len(nslist) != 0 {
+		query = query.Filter("namespace__in", nslist)
+	}
+	if _, err := query.Limit(-1).All(&rulelist); err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	return rulelist, nil
+}
+
+// ListClusterRules returns all rules of the cluster
+func (im *IngressRuleModel) ListClusterRules(cluster string) ([]models.K8sIngressRule, error) {
+	rulelist := []models.K8sIngressRule{}
+	if _, err := im.tOrmer.QueryTable(im.TableName).
+		Filter("cluster", cluster).
+		Filter("deleted", 0).OrderBy("id").Limit(-1).All(&rulelist); err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	return rulelist, nil
+}
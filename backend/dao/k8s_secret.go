@@ -117,3 +117,21 @@ func (sm *SecretModel) GetSecretList(cluster string, nslist []string, filterQuer
 		},
 		List: secretList}, err
 }
+
+// GetSecretsByType returns the secrets of the type, the secrets of all clusters are returned if cluster is empty
+func (sm *SecretModel) GetSecretsByType(cluster string, nslist []string, secretType string) ([]models.K8sSecret, error) {
+	secretList := []models.K8sSecret{}
+	query := sm.ormer.QueryTable(sm.TableName).
+		Filter("type", secretType).
+		Filter("deleted", 0)
+	if cluster != "" {
+		query = query.Filter("cluster", cluster)
+	}
+	if len(nslist) != 0 {
+		query = query.Filter("namespace__in", nslist)
+	}
+	if _, err := query.Limit(-1).All(&secretList); err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	return secretList, nil
+}
@@ -121,6 +121,32 @@ func (km *K8sServiceModel) List(cluster, namespace string, nodePort int) ([]mode
 	return svcs, nil
 }
 
+// ListServices returns the services of namespace with their ports
+func (km *K8sServiceModel) ListServices(cluster, namespace string) ([]models.K8sService, error) {
+	var svcs []models.K8sService
+
+	if _, err := km.tOrmer.QueryTable(km.svcTable).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("deleted", 0).OrderBy("name").All(&svcs); err != nil {
+		return nil, err
+	}
+	for i := range svcs {
+		if _, err := km.tOrmer.LoadRelated(&svcs[i], "ports"); err != nil {
+			return nil, err
+		}
+		var ports []*models.K8sServicePort
+		for _, port := range svcs[i].Ports {
+			if port.Deleted == 0 {
+				ports = append(ports, port)
+			}
+		}
+		svcs[i].Ports = ports
+	}
+
+	return svcs, nil
+}
+
 func (km *K8sServiceModel) Get(cluster, namespace, oname, name string) (*models.K8sService, error) {
 	var svc models.K8sService
 	if oname == "" && name == "" {
@@ -137,6 +163,9 @@ func (km *K8sServiceModel) Get(cluster, namespace, oname, name string) (*models.
 	}
 	if name != "" {
 		query = query.Filter("name", name)
+	} else {
+		// the headless service of statefulset has the same owner
+		query = query.Exclude("cluster_ip", "None")
 	}
 	if err := query.One(&svc); err != nil {
 		return nil, err
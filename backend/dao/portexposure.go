@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type PortExposureModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewPortExposureModel() *PortExposureModel {
+	return &PortExposureModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudPortExposure{}).TableName(),
+	}
+}
+
+// ListExposures returns the exposures of the application, all exposures of the namespace are returned
+// if appname is empty, and all exposures of the cluster are returned if namespace is empty too
+func (pm *PortExposureModel) ListExposures(cluster, namespace, appname string) ([]models.ZcloudPortExposure, error) {
+	list := []models.ZcloudPortExposure{}
+	query := pm.tOrmer.QueryTable(pm.TableName).
+		Filter("cluster", cluster).
+		Filter("deleted", 0)
+	if namespace != "" {
+		query = query.Filter("namespace", namespace)
+	}
+	if appname != "" {
+		query = query.Filter("app_name", appname)
+	}
+	_, err := query.OrderBy("id").Limit(-1).All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+// ListExposuresByType returns the exposures of the type in the cluster, the exposures of all clusters
+// are returned if cluster is empty
+func (pm *PortExposureModel) ListExposuresByType(cluster, exposureType string) ([]models.ZcloudPortExposure, error) {
+	list := []models.ZcloudPortExposure{}
+	query := pm.tOrmer.QueryTable(pm.TableName).
+		Filter("type", exposureType).
+		Filter("deleted", 0)
+	if cluster != "" {
+		query = query.Filter("cluster", cluster)
+	}
+	_, err := query.OrderBy("id").Limit(-1).All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+func (pm *PortExposureModel) GetExposure(cluster, namespace string, id int64) (*models.ZcloudPortExposure, error) {
+	exposure := models.ZcloudPortExposure{}
+	err := pm.tOrmer.QueryTable(pm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("id", id).
+		Filter("deleted", 0).One(&exposure)
+	if err != nil {
+		return nil, err
+	}
+	return &exposure, nil
+}
+
+func (pm *PortExposureModel) CreateExposure(exposure *models.ZcloudPortExposure) error {
+	exposure.Addons = models.NewAddons()
+	_, err := pm.tOrmer.Insert(exposure)
+	return err
+}
+
+func (pm *PortExposureModel) DeleteExposure(id int64) error {
+	_, err := pm.tOrmer.Raw("UPDATE "+pm.TableName+" SET deleted=1, delete_at=now() WHERE id=? AND deleted=0", id).Exec()
+	return err
+}
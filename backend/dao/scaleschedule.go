@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+	"kubecloud/backend/models"
+)
+
+type ScaleScheduleModel struct {
+	tOrmer    orm.Ormer
+	TableName string
+}
+
+func NewScaleScheduleModel() *ScaleScheduleModel {
+	return &ScaleScheduleModel{
+		tOrmer:    GetOrmer(),
+		TableName: (&models.ZcloudScaleSchedule{}).TableName(),
+	}
+}
+
+// GetScheduleList returns the schedules of the namespace, or all schedules of the cluster if namespace is empty
+func (sm *ScaleScheduleModel) GetScheduleList(cluster, namespace string) ([]models.ZcloudScaleSchedule, error) {
+	list := []models.ZcloudScaleSchedule{}
+	query := sm.tOrmer.QueryTable(sm.TableName).
+		Filter("cluster", cluster).
+		Filter("deleted", 0)
+	if namespace != "" {
+		query = query.Filter("namespace", namespace)
+	}
+	_, err := query.OrderBy("id").All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
+
+func (sm *ScaleScheduleModel) GetSchedule(cluster, namespace string, id int64) (*models.ZcloudScaleSchedule, error) {
+	schedule := models.ZcloudScaleSchedule{}
+	err := sm.tOrmer.QueryTable(sm.TableName).
+		Filter("cluster", cluster).
+		Filter("namespace", namespace).
+		Filter("id", id).
+		Filter("deleted", 0).One(&schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (sm *ScaleScheduleModel) CreateSchedule(schedule *models.ZcloudScaleSchedule) error {
+	schedule.Addons = models.NewAddons()
+	_, err := sm.tOrmer.Insert(schedule)
+	return err
+}
+
+func (sm *ScaleScheduleModel) UpdateSchedule(schedule *models.ZcloudScaleSchedule, cols ...string) error {
+	schedule.Addons = schedule.Addons.UpdateAddons()
+	if len(cols) != 0 {
+		cols = append(cols, "update_at")
+	}
+	_, err := sm.tOrmer.Update(schedule, cols...)
+	return err
+}
+
+func (sm *ScaleScheduleModel) DeleteSchedule(cluster, namespace string, id int64) error {
+	_, err := sm.tOrmer.Raw("UPDATE "+sm.TableName+" SET deleted=1, delete_at=now() WHERE cluster=? AND namespace=? AND id=? AND deleted=0",
+		cluster, namespace, id).Exec()
+	return err
+}
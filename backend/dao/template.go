@@ -115,3 +115,72 @@ func (tm *TemplateModel) GetTemplateList(nslist []string, filterQuery *utils.Fil
 		},
 		List: tList}, err
 }
+
+// CreateTemplateVersion inserts the new version and makes it the latest one of the template in a transaction
+func (tm *TemplateModel) CreateTemplateVersion(template *models.ZcloudTemplate, version *models.ZcloudTemplateVersion) error {
+	// the global ormer is shared, so the transaction uses its own ormer
+	o := orm.NewOrm()
+	if err := o.Begin(); err != nil {
+		return err
+	}
+	version.TemplateId = template.Id
+	version.Name = template.Name
+	version.Namespace = template.Namespace
+	version.Version = template.LatestVersion + 1
+	version.Addons = models.NewAddons()
+	if _, err := o.Insert(version); err != nil {
+		o.Rollback()
+		return err
+	}
+	template.LatestVersion = version.Version
+	template.Spec = version.Spec
+	template.Kind = version.Kind
+	template.Parameters = version.Parameters
+	template.Description = version.Description
+	template.Addons = template.Addons.UpdateAddons()
+	// the latest version is checked to avoid losing the concurrent updating
+	num, err := o.QueryTable(tm.TableName).
+		Filter("id", template.Id).
+		Filter("latest_version", version.Version-1).
+		Update(orm.Params{
+			"latest_version": template.LatestVersion,
+			"spec":           template.Spec,
+			"kind":           template.Kind,
+			"parameters":     template.Parameters,
+			"description":    template.Description,
+			"update_at":      template.UpdateAt,
+		})
+	if err == nil && num == 0 {
+		err = fmt.Errorf("template %s/%s has been updated by others!", template.Namespace, template.Name)
+	}
+	if err != nil {
+		o.Rollback()
+		return err
+	}
+	return o.Commit()
+}
+
+func (tm *TemplateModel) GetTemplateVersion(templateId int64, version int) (*models.ZcloudTemplateVersion, error) {
+	var tv models.ZcloudTemplateVersion
+
+	if err := tm.tOrmer.QueryTable((&models.ZcloudTemplateVersion{}).TableName()).
+		Filter("template_id", templateId).
+		Filter("version", version).
+		Filter("deleted", 0).One(&tv); err != nil {
+		return nil, err
+	}
+
+	return &tv, nil
+}
+
+func (tm *TemplateModel) GetTemplateVersionList(templateId int64) ([]models.ZcloudTemplateVersion, error) {
+	list := []models.ZcloudTemplateVersion{}
+
+	_, err := tm.tOrmer.QueryTable((&models.ZcloudTemplateVersion{}).TableName()).
+		Filter("template_id", templateId).
+		Filter("deleted", 0).OrderBy("-version").All(&list)
+	if err == orm.ErrNoRows {
+		return list, nil
+	}
+	return list, err
+}
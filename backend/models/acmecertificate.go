@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// ZcloudAcmeCertificate is the certificate of ingress host issued by the built-in acme client,
+// it is issued into the tls secret of the host and renewed before it expires
+type ZcloudAcmeCertificate struct {
+	Id         int64  `orm:"pk;column(id);auto" json:"id"`
+	Cluster    string `orm:"column(cluster)" json:"cluster"`
+	Namespace  string `orm:"column(namespace)" json:"namespace"`
+	Host       string `orm:"column(host)" json:"host"`
+	SecretName string `orm:"column(secret_name)" json:"secret_name"`
+	Status     string `orm:"column(status)" json:"status"`
+	Message    string `orm:"column(message);type(text)" json:"message,omitempty"`
+	// the http-01 challenge being validated, it is served by /.well-known/acme-challenge/{token}
+	ChallengeToken    string    `orm:"column(challenge_token)" json:"-"`
+	ChallengeResponse string    `orm:"column(challenge_response);type(text)" json:"-"`
+	NotAfter          time.Time `orm:"column(not_after);null" json:"not_after"`
+	Addons
+}
+
+const (
+	ACME_CERTIFICATE_STATUS_PENDING = "pending"
+	ACME_CERTIFICATE_STATUS_READY   = "ready"
+	ACME_CERTIFICATE_STATUS_FAILED  = "failed"
+)
+
+func (t *ZcloudAcmeCertificate) TableName() string {
+	return "zcloud_acme_certificate"
+}
+
+// the deleted certificates are kept, so the deleted flag and time are a part of the unique key
+func (u *ZcloudAcmeCertificate) TableUnique() [][]string {
+	return [][]string{
+		[]string{"Cluster", "Namespace", "Host", "Deleted", "DeleteAt"},
+	}
+}
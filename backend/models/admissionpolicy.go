@@ -0,0 +1,18 @@
+package models
+
+type ZcloudAdmissionPolicy struct {
+	Id      int64  `orm:"pk;column(id);auto" json:"id"`
+	Cluster string `orm:"column(cluster)" json:"cluster"`
+	// the policy applies to all namespaces of the cluster if namespace is empty
+	Namespace   string `orm:"column(namespace)" json:"namespace"`
+	Name        string `orm:"column(name)" json:"name"`
+	Description string `orm:"column(description)" json:"description"`
+	Enabled     bool   `orm:"column(enabled)" json:"enabled"`
+	// json of the rules of the policy
+	Rules string `orm:"column(rules);type(text)" json:"-"`
+	Addons
+}
+
+func (t *ZcloudAdmissionPolicy) TableName() string {
+	return "zcloud_admission_policy"
+}
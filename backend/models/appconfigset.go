@@ -0,0 +1,16 @@
+package models
+
+type ZcloudAppConfigSet struct {
+	Id          int64  `orm:"pk;column(id);auto" json:"id"`
+	Cluster     string `orm:"column(cluster)" json:"cluster"`
+	Namespace   string `orm:"column(namespace)" json:"namespace"`
+	Name        string `orm:"column(name)" json:"name"`
+	Description string `orm:"column(description)" json:"description"`
+	// json of the items of the config set
+	Items string `orm:"column(items);type(text)" json:"-"`
+	Addons
+}
+
+func (t *ZcloudAppConfigSet) TableName() string {
+	return "zcloud_app_config_set"
+}
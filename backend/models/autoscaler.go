@@ -0,0 +1,22 @@
+package models
+
+type ZcloudAutoscaler struct {
+	Id        int64  `orm:"pk;column(id);auto" json:"id"`
+	Cluster   string `orm:"column(cluster)" json:"cluster"`
+	Namespace string `orm:"column(namespace)" json:"namespace"`
+	Name      string `orm:"column(name)" json:"name"` // name is application name
+	Kind      string `orm:"column(kind)" json:"kind"`
+	// target is the name of the workload scaled by the autoscaler
+	Target                  string `orm:"column(target)" json:"target"`
+	MinReplicas             int    `orm:"column(min_replicas)" json:"min_replicas"`
+	MaxReplicas             int    `orm:"column(max_replicas)" json:"max_replicas"`
+	TargetCPUUtilization    int    `orm:"column(target_cpu_utilization)" json:"target_cpu_utilization"`
+	TargetMemoryUtilization int    `orm:"column(target_memory_utilization)" json:"target_memory_utilization"`
+	// metrics is the json of the custom metrics
+	Metrics string `orm:"column(metrics);type(text)" json:"metrics,omitempty"`
+	Addons
+}
+
+func (t *ZcloudAutoscaler) TableName() string {
+	return "zcloud_autoscaler"
+}
@@ -34,11 +34,15 @@ type ZcloudCluster struct {
 	TillerHost             string `orm:"column(tiller_host)" json:"tiller_host"`
 	PromRuleIndex          int64  `orm:"column(prom_rule_index);default(0)" json:"prom_rule_index"`
 	IngressSLB             string `orm:"column(ingress_slb)" json:"ingress_slb"`
+	IngressProvider        string `orm:"column(ingress_provider)" json:"ingress_provider"`
 	LabelPrefix            string `orm:"column(label_prefix)" json:"label_prefix"`
 	ConfigRepo             string `orm:"column(config_repo)" json:"config_repo"`
 	ConfigRepoBranch       string `orm:"column(config_repo_branch)" json:"config_repo_branch"`
 	ConfigRepoToken        string `orm:"column(config_repo_token)" json:"config_repo_token"`
 	LastCommitId           string `orm:"column(last_commit_id)" json:"last_commit_id"`
+	// the ports allocated to the tcp/udp exposures of applications, such as "30000-30999,31080"
+	NodePortPool         string `orm:"column(node_port_pool)" json:"node_port_pool"`
+	LoadbalancerPortPool string `orm:"column(lb_port_pool)" json:"loadbalancer_port_pool"`
 	Addons
 }
 
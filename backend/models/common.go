@@ -144,10 +144,20 @@ func initOrm() {
 		new(K8sSecret),
 		new(ZcloudEvent),
 		new(ZcloudTemplate),
+		new(ZcloudTemplateVersion),
 		new(ZcloudApplication),
 		new(ZcloudVersion),
+		new(ZcloudAutoscaler),
+		new(ZcloudScaleSchedule),
+		new(ZcloudAcmeCertificate),
+		new(ZcloudAdmissionPolicy),
+		new(ZcloudAppConfigSet),
+		new(ZcloudHelmRelease),
 		new(K8sIngress),
 		new(K8sIngressRule),
+		new(ZcloudIngressRoute),
+		new(ZcloudIngressProbe),
+		new(ZcloudPortExposure),
 		new(K8sService),
 		new(K8sServicePort),
 		new(K8sEndpoint),
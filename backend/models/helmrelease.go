@@ -0,0 +1,42 @@
+package models
+
+type ZcloudHelmRelease struct {
+	Id        int64  `orm:"pk;column(id);auto" json:"id"`
+	Cluster   string `orm:"column(cluster)" json:"cluster"`
+	Namespace string `orm:"column(namespace)" json:"namespace"`
+	Name      string `orm:"column(name)" json:"name"`
+	// revision starts from 1 and increases on each install, upgrade or rollback of the release
+	Revision     int    `orm:"column(revision)" json:"revision"`
+	Chart        string `orm:"column(chart)" json:"chart"`
+	ChartVersion string `orm:"column(chart_version)" json:"chart_version"`
+	// json of the chart source, it is reloaded by upgrade
+	Source string `orm:"column(source);type(text)" json:"source,omitempty"`
+	// yaml of the values given by user
+	Values string `orm:"column(chart_values);type(text)" json:"values"`
+	// manifest rendered from the chart, it is redeployed by rollback
+	Manifest     string `orm:"column(manifest);type(text)" json:"manifest,omitempty"`
+	Config       string `orm:"column(config);type(text)" json:"config,omitempty"`
+	TemplateName string `orm:"column(template_name)" json:"template_name"`
+	Status       string `orm:"column(status)" json:"status"`
+	Description  string `orm:"column(description)" json:"description"`
+	Addons
+}
+
+const (
+	// the revision is allocated before the manifest is rendered and deployed
+	HELM_RELEASE_STATUS_PENDING    = "pending"
+	HELM_RELEASE_STATUS_DEPLOYED   = "deployed"
+	HELM_RELEASE_STATUS_SUPERSEDED = "superseded"
+	HELM_RELEASE_STATUS_FAILED     = "failed"
+)
+
+func (t *ZcloudHelmRelease) TableName() string {
+	return "zcloud_helm_release"
+}
+
+// the revisions restart from 1 after the release is deleted, so the deleted flag and time are a part of the unique key
+func (t *ZcloudHelmRelease) TableUnique() [][]string {
+	return [][]string{
+		[]string{"Cluster", "Namespace", "Name", "Revision", "Deleted", "DeleteAt"},
+	}
+}
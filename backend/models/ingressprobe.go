@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// ZcloudIngressProbe is the result of requesting the host and path of an ingress rule through
+// the load balancer of its cluster, the results are kept for the retention days as the availability history
+type ZcloudIngressProbe struct {
+	Id          int64  `orm:"pk;column(id);auto" json:"id"`
+	Cluster     string `orm:"column(cluster)" json:"cluster"`
+	Namespace   string `orm:"column(namespace)" json:"namespace"`
+	IngressName string `orm:"column(ingress_name)" json:"ingress_name"`
+	Host        string `orm:"column(host)" json:"host"`
+	Path        string `orm:"column(path)" json:"path"`
+	// StatusCode is 0 if the request failed
+	StatusCode int   `orm:"column(status_code)" json:"status_code"`
+	LatencyMs  int64 `orm:"column(latency_ms)" json:"latency_ms"`
+	IsTls      bool  `orm:"column(is_tls)" json:"is_tls"`
+	TlsValid   bool  `orm:"column(tls_valid)" json:"tls_valid"`
+	// Error is the error of request or the reason why the certificate is not valid
+	Error   string    `orm:"column(error);type(text)" json:"error,omitempty"`
+	ProbeAt time.Time `orm:"column(probe_at);index" json:"probe_at"`
+}
+
+func (t *ZcloudIngressProbe) TableName() string {
+	return "zcloud_ingress_probe"
+}
+
+func (t *ZcloudIngressProbe) TableIndex() [][]string {
+	return [][]string{
+		[]string{"Cluster", "Namespace", "Host", "Path", "ProbeAt"},
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+// ZcloudIngressRoute is the host and path of ingress registered on the load balancer of its cluster,
+// the clusters sharing a load balancer serve the routes of each other, so a route must be owned by
+// only one ingress among them
+type ZcloudIngressRoute struct {
+	Id           int64  `orm:"pk;column(id);auto" json:"id"`
+	LoadBalancer string `orm:"column(load_balancer);index" json:"load_balancer"`
+	Host         string `orm:"column(host)" json:"host"`
+	Path         string `orm:"column(path)" json:"path"`
+	Cluster      string `orm:"column(cluster)" json:"cluster"`
+	Namespace    string `orm:"column(namespace)" json:"namespace"`
+	IngressName  string `orm:"column(ingress_name)" json:"ingress_name"`
+	Addons
+}
+
+func (t *ZcloudIngressRoute) TableName() string {
+	return "zcloud_ingress_route"
+}
@@ -0,0 +1,31 @@
+package models
+
+// ZcloudPortExposure is the tcp/udp port of the service of an application exposed outside the cluster, the port is
+// allocated from the node port pool of cluster or the load balancer port pool shared by the clusters of load balancer
+type ZcloudPortExposure struct {
+	Id          int64  `orm:"pk;column(id);auto" json:"id"`
+	Cluster     string `orm:"column(cluster)" json:"cluster"`
+	Namespace   string `orm:"column(namespace)" json:"namespace"`
+	AppName     string `orm:"column(app_name)" json:"app_name"`
+	ServiceName string `orm:"column(service_name)" json:"service_name"`
+	ServicePort int    `orm:"column(service_port)" json:"service_port"`
+	Protocol    string `orm:"column(protocol)" json:"protocol"`
+	// Type is NodePort or LoadBalancer, Port is the node port or the port of load balancer
+	Type        string `orm:"column(type)" json:"type"`
+	Port        int    `orm:"column(port)" json:"port"`
+	Description string `orm:"column(description)" json:"description"`
+	// PoolOwner is the owner of the port pool which the port is allocated from, the cluster of the node port
+	// or the load balancer shared by the clusters, the port is unique in it
+	PoolOwner string `orm:"column(pool_owner)" json:"-"`
+	Addons
+}
+
+func (t *ZcloudPortExposure) TableName() string {
+	return "zcloud_port_exposure"
+}
+
+func (t *ZcloudPortExposure) TableUnique() [][]string {
+	return [][]string{
+		[]string{"PoolOwner", "Type", "Protocol", "Port", "Deleted", "DeleteAt"},
+	}
+}
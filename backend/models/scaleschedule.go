@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+type ZcloudScaleSchedule struct {
+	Id        int64  `orm:"pk;column(id);auto" json:"id"`
+	Cluster   string `orm:"column(cluster)" json:"cluster"`
+	Namespace string `orm:"column(namespace)" json:"namespace"`
+	// name is application name, all applications of the namespace are scaled if it is empty
+	Name string `orm:"column(name)" json:"name"`
+	// cron spec of minute, hour, day of month, month and day of week, such as "0 20 * * 1-5"
+	ScaleSchedule   string `orm:"column(scale_schedule)" json:"scale_schedule"`
+	Replicas        int    `orm:"column(replicas)" json:"replicas"`
+	RestoreSchedule string `orm:"column(restore_schedule)" json:"restore_schedule"`
+	Description     string `orm:"column(description)" json:"description"`
+	Status          string `orm:"column(status)" json:"status"`
+	// json of the replicas of the applications before scaling, they are restored by it
+	OriginalReplicas string    `orm:"column(original_replicas);type(text)" json:"original_replicas,omitempty"`
+	CheckAt          time.Time `orm:"column(check_at);null" json:"check_at"`
+	Addons
+}
+
+const (
+	SCALE_SCHEDULE_STATUS_NORMAL = "normal"
+	SCALE_SCHEDULE_STATUS_SCALED = "scaled"
+)
+
+func (t *ZcloudScaleSchedule) TableName() string {
+	return "zcloud_scale_schedule"
+}
@@ -7,9 +7,30 @@ type ZcloudTemplate struct {
 	Description string `orm:"column(description)" json:"description,omitempty"`
 	Spec        string `orm:"column(spec);type(text)" json:"spec"` //TemplateSpec
 	Kind        string `orm:"column(kind)" json:"kind"`
+	// Parameters is json of the typed parameters of the latest version
+	Parameters    string `orm:"column(parameters);type(text)" json:"parameters,omitempty"`
+	LatestVersion int    `orm:"column(latest_version)" json:"latest_version"`
 	Addons
 }
 
 func (t *ZcloudTemplate) TableName() string {
 	return "zcloud_template"
 }
+
+// ZcloudTemplateVersion is immutable, a new version is created by each updating of the template
+type ZcloudTemplateVersion struct {
+	Id          int64  `orm:"pk;column(id);auto" json:"id"`
+	TemplateId  int64  `orm:"column(template_id);index" json:"template_id"`
+	Name        string `orm:"column(name)" json:"name"`
+	Namespace   string `orm:"column(namespace)" json:"namespace"`
+	Version     int    `orm:"column(version)" json:"version"`
+	Description string `orm:"column(description)" json:"description,omitempty"`
+	Spec        string `orm:"column(spec);type(text)" json:"spec"`
+	Kind        string `orm:"column(kind)" json:"kind"`
+	Parameters  string `orm:"column(parameters);type(text)" json:"parameters,omitempty"`
+	Addons
+}
+
+func (t *ZcloudTemplateVersion) TableName() string {
+	return "zcloud_template_version"
+}
@@ -0,0 +1,411 @@
+package resource
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/backend/service"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	"golang.org/x/crypto/acme"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// AcmeChallengePath is the path of the http-01 challenges, they are routed to kubecloud by the solver ingress
+	AcmeChallengePath = "/.well-known/acme-challenge/"
+
+	acmeSolverName        = "kubecloud-acme-solver"
+	acmeAccountSecretName = "kubecloud-acme-account"
+	acmeAccountKey        = "account.key"
+
+	// the certificates are renewed before they expire in 30 days, and the failed ones are retried hourly
+	acmeRenewBefore   = 30 * 24 * time.Hour
+	acmeRetryInterval = time.Hour
+	acmeIssueTimeout  = 5 * time.Minute
+)
+
+// acmeIssuer issues the certificates of the ingress hosts of cluster by the http-01 challenges,
+// the challenges are served by kubecloud through the solver ingress of the host
+type acmeIssuer struct {
+	cluster  string
+	client   kubernetes.Interface
+	versions kubeutil.APIVersions
+	provider IngressProvider
+	acme     *acme.Client
+	model    acmeCertificateStore
+}
+
+// acmeCertificateStore records the progress of issuing the certificates
+type acmeCertificateStore interface {
+	UpdateCertificate(cert *models.ZcloudAcmeCertificate, cols ...string) error
+}
+
+// RenewAcmeCertificates issues the pending acme certificates of cluster, renews the ones which will expire soon,
+// and retries the failed ones
+func RenewAcmeCertificates(cluster string) error {
+	model := dao.NewAcmeCertificateModel()
+	certs, err := model.GetCertificateList(cluster)
+	if err != nil {
+		return err
+	}
+	var issuer *acmeIssuer
+	for i := range certs {
+		cert := &certs[i]
+		if !acmeCertificateDue(cert, time.Now()) {
+			continue
+		}
+		if issuer == nil {
+			if issuer, err = newAcmeIssuer(cluster); err != nil {
+				return err
+			}
+		}
+		if err := issuer.issue(cert); err != nil {
+			beego.Warn(fmt.Sprintf("issue the acme certificate of host %s of cluster %s failed: %v", cert.Host, cluster, err))
+			cert.Status = models.ACME_CERTIFICATE_STATUS_FAILED
+			cert.Message = err.Error()
+			cert.ChallengeToken = ""
+			cert.ChallengeResponse = ""
+			if err := model.UpdateCertificate(cert, "status", "message", "challenge_token", "challenge_response"); err != nil {
+				beego.Error(fmt.Sprintf("update the acme certificate of host %s failed: %v", cert.Host, err))
+			}
+		}
+	}
+	return nil
+}
+
+// acmeCertificateDue checks the certificate should be issued now, the ready ones are renewed before they expire,
+// the failed ones are retried after the retry interval, and the pending ones are issued at once
+func acmeCertificateDue(cert *models.ZcloudAcmeCertificate, now time.Time) bool {
+	switch cert.Status {
+	case models.ACME_CERTIFICATE_STATUS_READY:
+		return cert.NotAfter.Sub(now) <= acmeRenewBefore
+	case models.ACME_CERTIFICATE_STATUS_FAILED:
+		return now.Sub(cert.UpdateAt) >= acmeRetryInterval
+	}
+	return true
+}
+
+// GetAcmeChallengeResponse returns the key authorization of the http-01 challenge token
+func GetAcmeChallengeResponse(token string) (string, error) {
+	if token == "" {
+		return "", fmt.Errorf("the challenge token is empty")
+	}
+	return dao.NewAcmeCertificateModel().GetChallengeResponse(token)
+}
+
+func newAcmeIssuer(cluster string) (*acmeIssuer, error) {
+	client, err := service.GetClientset(cluster)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := GetIngressProvider(cluster)
+	if err != nil {
+		return nil, err
+	}
+	issuer := &acmeIssuer{
+		cluster:  cluster,
+		client:   client,
+		versions: kubeutil.GetAPIVersions(cluster, client.Discovery()),
+		provider: provider,
+		model:    dao.NewAcmeCertificateModel(),
+	}
+	key, err := issuer.accountKey()
+	if err != nil {
+		return nil, fmt.Errorf("get the acme account key failed: %v", err)
+	}
+	issuer.acme = &acme.Client{
+		Key:          key,
+		DirectoryURL: beego.AppConfig.DefaultString("acme::directory", acme.LetsEncryptURL),
+	}
+	// the test servers such as pebble are served by the self-signed certificates
+	if beego.AppConfig.DefaultBool("acme::insecureSkipVerify", false) {
+		issuer.acme.HTTPClient = utils.HttpClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), acmeIssueTimeout)
+	defer cancel()
+	account := &acme.Account{}
+	if email := beego.AppConfig.String("acme::email"); email != "" {
+		account.Contact = []string{"mailto:" + email}
+	}
+	if _, err := issuer.acme.Register(ctx, account, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, fmt.Errorf("register the acme account failed: %v", err)
+	}
+	return issuer, nil
+}
+
+// accountKey returns the key of acme account of the cluster, it is generated and saved in the secret at the first time
+func (ai *acmeIssuer) accountKey() (crypto.Signer, error) {
+	namespace := beego.AppConfig.DefaultString("acme::namespace", "kube-system")
+	secret, err := ai.client.CoreV1().Secrets(namespace).Get(context.TODO(), acmeAccountSecretName, metav1.GetOptions{})
+	if err == nil {
+		block, _ := pem.Decode(secret.Data[acmeAccountKey])
+		if block == nil {
+			return nil, fmt.Errorf("the key of secret %s/%s is not pem encoded", namespace, acmeAccountSecretName)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	key, data, err := newECKey()
+	if err != nil {
+		return nil, err
+	}
+	secret = &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: acmeAccountSecretName, Namespace: namespace},
+		Data:       map[string][]byte{acmeAccountKey: data},
+	}
+	if _, err := ai.client.CoreV1().Secrets(namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// issue orders the certificate of host, validates the authorizations by http-01 challenges,
+// and saves the certificate into the tls secret
+func (ai *acmeIssuer) issue(cert *models.ZcloudAcmeCertificate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), acmeIssueTimeout)
+	defer cancel()
+	order, err := ai.acme.AuthorizeOrder(ctx, acme.DomainIDs(cert.Host))
+	if err != nil {
+		return err
+	}
+	for _, url := range order.AuthzURLs {
+		if err := ai.authorize(ctx, cert, url); err != nil {
+			return err
+		}
+	}
+	if order, err = ai.acme.WaitOrder(ctx, order.URI); err != nil {
+		return err
+	}
+	key, keyData, err := newECKey()
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{cert.Host}}, key)
+	if err != nil {
+		return err
+	}
+	chain, _, err := ai.acme.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return err
+	}
+	certData := []byte{}
+	for _, der := range chain {
+		certData = append(certData, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	notAfter, err := utils.CertificateNotAfter(certData)
+	if err != nil {
+		return err
+	}
+	if err := ai.saveSecret(cert.Namespace, cert.SecretName, certData, keyData); err != nil {
+		return err
+	}
+	cert.Status = models.ACME_CERTIFICATE_STATUS_READY
+	cert.Message = ""
+	cert.NotAfter = notAfter
+	cert.ChallengeToken = ""
+	cert.ChallengeResponse = ""
+	return ai.model.UpdateCertificate(cert, "status", "message", "not_after", "challenge_token", "challenge_response")
+}
+
+// authorize validates the authorization of host by the http-01 challenge,
+// the challenge is served until the authorization is finished
+func (ai *acmeIssuer) authorize(ctx context.Context, cert *models.ZcloudAcmeCertificate, url string) error {
+	authz, err := ai.acme.GetAuthorization(ctx, url)
+	if err != nil {
+		return err
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	var chal *acme.Challenge
+	for _, item := range authz.Challenges {
+		if item.Type == "http-01" {
+			chal = item
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("the http-01 challenge of host %s is not offered by the acme server", cert.Host)
+	}
+	response, err := ai.acme.HTTP01ChallengeResponse(chal.Token)
+	if err != nil {
+		return err
+	}
+	cert.ChallengeToken = chal.Token
+	cert.ChallengeResponse = response
+	if err := ai.model.UpdateCertificate(cert, "challenge_token", "challenge_response"); err != nil {
+		return err
+	}
+	cleanup, err := ai.createSolver(cert.Namespace, cert.Host)
+	if err != nil {
+		return fmt.Errorf("create the acme challenge solver failed: %v", err)
+	}
+	defer cleanup()
+	if _, err := ai.acme.Accept(ctx, chal); err != nil {
+		return err
+	}
+	_, err = ai.acme.WaitAuthorization(ctx, authz.URI)
+	return err
+}
+
+// createSolver routes the challenge path of host to kubecloud by the solver service and ingress,
+// the returned function deletes the ingress
+func (ai *acmeIssuer) createSolver(namespace, host string) (func(), error) {
+	addr := beego.AppConfig.String("acme::solverService")
+	solverHost, solverPort, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("the acme solver service %q must be host:port of kubecloud reachable from the cluster: %v", addr, err)
+	}
+	port, err := strconv.Atoi(solverPort)
+	if err != nil {
+		return nil, fmt.Errorf("the port of acme solver service %q is invalid: %v", addr, err)
+	}
+	if err := ai.ensureSolverService(namespace, solverHost, port); err != nil {
+		return nil, err
+	}
+	obj := &networkingv1.Ingress{}
+	obj.Name = providerObjectName(acmeSolverName, host)
+	obj.Namespace = namespace
+	obj.Labels = map[string]string{kubeutil.AcmeSolverLabelKey: "true"}
+	obj.Annotations = map[string]string{}
+	obj.Spec.Rules = []networkingv1.IngressRule{{
+		Host: host,
+		IngressRuleValue: networkingv1.IngressRuleValue{
+			HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{kubeutil.NewIngressPath(AcmeChallengePath, acmeSolverName, port)},
+			},
+		},
+	}}
+	if err := ai.provider.SetIngress(obj, &IngressConfig{}, []string{AcmeChallengePath}, host); err != nil {
+		return nil, err
+	}
+	if err := kubeutil.DeleteIngress(ai.client, ai.versions, namespace, obj.Name); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if _, err := kubeutil.CreateIngress(ai.client, ai.versions, obj); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := kubeutil.DeleteIngress(ai.client, ai.versions, namespace, obj.Name); err != nil && !errors.IsNotFound(err) {
+			beego.Warn(fmt.Sprintf("delete the acme solver ingress %s/%s failed: %v", namespace, obj.Name, err))
+		}
+	}, nil
+}
+
+// ensureSolverService creates the service of kubecloud in the namespace of ingress, it is the external name of kubecloud,
+// or the endpoint of kubecloud if it is addressed by ip
+func (ai *acmeIssuer) ensureSolverService(namespace, host string, port int) error {
+	svc := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: acmeSolverName, Namespace: namespace},
+		Spec: apiv1.ServiceSpec{
+			Ports: []apiv1.ServicePort{{Name: "http", Port: int32(port), TargetPort: intstr.FromInt(port)}},
+		},
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		svc.Spec.Type = apiv1.ServiceTypeExternalName
+		svc.Spec.ExternalName = host
+	}
+	old, err := ai.client.CoreV1().Services(namespace).Get(context.TODO(), acmeSolverName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && (old.Spec.Type == apiv1.ServiceTypeExternalName) != (ip == nil) {
+		// the type of service is changed, so it is recreated
+		if err := ai.client.CoreV1().Services(namespace).Delete(context.TODO(), acmeSolverName, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+		old = nil
+	}
+	if err == nil && old != nil {
+		old.Spec.Ports = svc.Spec.Ports
+		old.Spec.ExternalName = svc.Spec.ExternalName
+		if _, err := ai.client.CoreV1().Services(namespace).Update(context.TODO(), old, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	} else if _, err := ai.client.CoreV1().Services(namespace).Create(context.TODO(), svc, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	if ip == nil {
+		return nil
+	}
+	ep := &apiv1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: acmeSolverName, Namespace: namespace},
+		Subsets: []apiv1.EndpointSubset{{
+			Addresses: []apiv1.EndpointAddress{{IP: host}},
+			Ports:     []apiv1.EndpointPort{{Name: "http", Port: int32(port)}},
+		}},
+	}
+	oldEp, err := ai.client.CoreV1().Endpoints(namespace).Get(context.TODO(), acmeSolverName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = ai.client.CoreV1().Endpoints(namespace).Create(context.TODO(), ep, metav1.CreateOptions{})
+		return err
+	}
+	oldEp.Subsets = ep.Subsets
+	_, err = ai.client.CoreV1().Endpoints(namespace).Update(context.TODO(), oldEp, metav1.UpdateOptions{})
+	return err
+}
+
+// saveSecret saves the certificate and key into the tls secret
+func (ai *acmeIssuer) saveSecret(namespace, name string, certData, keyData []byte) error {
+	data := map[string][]byte{
+		apiv1.TLSCertKey:       certData,
+		apiv1.TLSPrivateKeyKey: keyData,
+	}
+	old, err := ai.client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		secret := &apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Type:       apiv1.SecretTypeTLS,
+			Data:       data,
+		}
+		_, err = ai.client.CoreV1().Secrets(namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+		return err
+	}
+	if old.Type != apiv1.SecretTypeTLS {
+		return fmt.Errorf("the secret %s/%s is not the tls secret, its type is %s", namespace, name, old.Type)
+	}
+	old.Data = data
+	_, err = ai.client.CoreV1().Secrets(namespace).Update(context.TODO(), old, metav1.UpdateOptions{})
+	return err
+}
+
+// newECKey generates the ecdsa key and returns it with the pem encoded data
+func newECKey() (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
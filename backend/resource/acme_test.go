@@ -0,0 +1,322 @@
+package resource
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"kubecloud/backend/models"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/acme"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testAcmeHost      = "www.example.com"
+	testAcmeNamespace = "default"
+	testAcmeToken     = "token-1"
+)
+
+type fakeAcmeStore struct {
+	mu      sync.Mutex
+	updates [][]string
+	token   string
+}
+
+func (s *fakeAcmeStore) UpdateCertificate(cert *models.ZcloudAcmeCertificate, cols ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updates = append(s.updates, cols)
+	s.token = cert.ChallengeToken
+	return nil
+}
+
+// fakeAcmeServer serves the directory, order, authorization and challenge of one host like the acme server,
+// the authorization is valid or invalid after the challenge is accepted, and the order is ready after it is polled twice
+type fakeAcmeServer struct {
+	t          *testing.T
+	server     *httptest.Server
+	client     *fake.Clientset
+	store      *fakeAcmeStore
+	caKey      *ecdsa.PrivateKey
+	caCert     *x509.Certificate
+	validity   time.Duration
+	invalid    bool
+	mu         sync.Mutex
+	accepted   bool
+	orderPolls int
+	finalized  bool
+	nonce      int
+	// the certificate chain issued on finalizing the order
+	issuedChain []byte
+}
+
+func newFakeAcmeServer(t *testing.T, client *fake.Clientset, store *fakeAcmeStore) *fakeAcmeServer {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake acme ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	assert.Nil(t, err)
+	caCert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	s := &fakeAcmeServer{t: t, client: client, store: store, caKey: caKey, caCert: caCert, validity: 90 * 24 * time.Hour}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *fakeAcmeServer) url(path string) string {
+	return s.server.URL + path
+}
+
+func (s *fakeAcmeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/directory" {
+		fmt.Fprintf(w, `{"newNonce": %q, "newAccount": %q, "newOrder": %q}`,
+			s.url("/new-nonce"), s.url("/new-account"), s.url("/new-order"))
+		return
+	}
+	s.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", s.nonce))
+	switch r.URL.Path {
+	case "/new-nonce":
+	case "/new-order":
+		w.Header().Set("Location", s.url("/order"))
+		w.WriteHeader(http.StatusCreated)
+		s.writeOrder(w, "pending")
+	case "/authz":
+		status, chalStatus, chalError := "pending", "pending", ""
+		if s.accepted {
+			status, chalStatus = acme.StatusValid, acme.StatusValid
+			if s.invalid {
+				status, chalStatus = acme.StatusInvalid, acme.StatusInvalid
+				chalError = `, "error": {"type": "urn:ietf:params:acme:error:unauthorized", "detail": "invalid response from the challenge path: 404"}`
+			}
+		}
+		fmt.Fprintf(w, `{"status": %q, "identifier": {"type": "dns", "value": %q}, "challenges": [
+			{"type": "dns-01", "url": %q, "token": "dns-token", "status": "pending"},
+			{"type": "http-01", "url": %q, "token": %q, "status": %q%s}]}`,
+			status, testAcmeHost, s.url("/challenge-dns"), s.url("/challenge"), testAcmeToken, chalStatus, chalError)
+	case "/challenge":
+		// the challenge is validated through the solver ingress which routes the challenge path to kubecloud
+		ing, err := s.client.NetworkingV1().Ingresses(testAcmeNamespace).Get(context.TODO(), providerObjectName(acmeSolverName, testAcmeHost), metav1.GetOptions{})
+		if assert.Nil(s.t, err, "the solver ingress must exist when the challenge is accepted") {
+			assert.Equal(s.t, testAcmeHost, ing.Spec.Rules[0].Host)
+			assert.Equal(s.t, AcmeChallengePath, ing.Spec.Rules[0].HTTP.Paths[0].Path)
+		}
+		assert.Equal(s.t, testAcmeToken, s.store.token, "the challenge response must be recorded before accepting")
+		s.accepted = true
+		fmt.Fprintf(w, `{"type": "http-01", "url": %q, "token": %q, "status": "processing"}`, s.url("/challenge"), testAcmeToken)
+	case "/order":
+		s.orderPolls++
+		switch {
+		case s.finalized:
+			s.writeOrder(w, acme.StatusValid)
+		case s.orderPolls < 2:
+			w.Header().Set("Retry-After", "1")
+			s.writeOrder(w, acme.StatusProcessing)
+		default:
+			s.writeOrder(w, acme.StatusReady)
+		}
+	case "/finalize":
+		csr, err := s.decodeCSR(r)
+		if !assert.Nil(s.t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(s.t, []string{testAcmeHost}, csr.DNSNames)
+		s.finalized = true
+		s.writeOrder(w, acme.StatusValid)
+	case "/cert":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(s.issuedChain)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *fakeAcmeServer) writeOrder(w http.ResponseWriter, status string) {
+	fmt.Fprintf(w, `{"status": %q, "identifiers": [{"type": "dns", "value": %q}], "authorizations": [%q], "finalize": %q, "certificate": %q}`,
+		status, testAcmeHost, s.url("/authz"), s.url("/finalize"), s.url("/cert"))
+}
+
+// decodeCSR decodes the csr from the payload of the jws request, and issues the certificate of it
+func (s *fakeAcmeServer) decodeCSR(r *http.Request) (*x509.CertificateRequest, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	jws := struct {
+		Payload string `json:"payload"`
+	}{}
+	if err := json.Unmarshal(body, &jws); err != nil {
+		return nil, err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		return nil, err
+	}
+	req := struct {
+		CSR string `json:"csr"`
+	}{}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, err
+	}
+	der, err := base64.RawURLEncoding.DecodeString(req.CSR)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: csr.DNSNames[0]},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(s.validity),
+	}
+	leaf, err := x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+	if err != nil {
+		return nil, err
+	}
+	s.issuedChain = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})...)
+	return csr, nil
+}
+
+func newTestAcmeIssuer(t *testing.T, invalid bool) (*acmeIssuer, *fakeAcmeServer) {
+	beego.AppConfig.Set("acme::solverService", "10.0.0.10:8080")
+	client := fake.NewSimpleClientset()
+	store := &fakeAcmeStore{}
+	server := newFakeAcmeServer(t, client, store)
+	server.invalid = invalid
+	key, _, err := newECKey()
+	assert.Nil(t, err)
+	return &acmeIssuer{
+		cluster:  "test",
+		client:   client,
+		versions: kubeutil.GAAPIVersions,
+		provider: &traefikProvider{},
+		model:    store,
+		acme: &acme.Client{
+			Key:          key,
+			DirectoryURL: server.url("/directory"),
+			KID:          acme.KeyID(server.url("/account")),
+		},
+	}, server
+}
+
+func newTestAcmeCertificate() *models.ZcloudAcmeCertificate {
+	return &models.ZcloudAcmeCertificate{
+		Cluster:    "test",
+		Namespace:  testAcmeNamespace,
+		Host:       testAcmeHost,
+		SecretName: "www-tls",
+		Status:     models.ACME_CERTIFICATE_STATUS_PENDING,
+	}
+}
+
+func TestAcmeIssue(t *testing.T) {
+	issuer, server := newTestAcmeIssuer(t, false)
+	defer server.server.Close()
+	cert := newTestAcmeCertificate()
+
+	assert.Nil(t, issuer.issue(cert))
+	assert.Equal(t, models.ACME_CERTIFICATE_STATUS_READY, cert.Status)
+	assert.Equal(t, "", cert.ChallengeToken)
+	assert.WithinDuration(t, time.Now().Add(90*24*time.Hour), cert.NotAfter, time.Minute)
+	// the order is polled until it is ready, then it is finalized
+	assert.Equal(t, 2, server.orderPolls)
+	assert.Equal(t, [][]string{
+		{"challenge_token", "challenge_response"},
+		{"status", "message", "not_after", "challenge_token", "challenge_response"},
+	}, issuer.model.(*fakeAcmeStore).updates)
+
+	secret, err := issuer.client.CoreV1().Secrets(testAcmeNamespace).Get(context.TODO(), "www-tls", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, apiv1.SecretTypeTLS, secret.Type)
+	notAfter, err := utils.CertificateNotAfter(secret.Data[apiv1.TLSCertKey])
+	assert.Nil(t, err)
+	assert.Equal(t, cert.NotAfter, notAfter)
+	assert.NotEmpty(t, secret.Data[apiv1.TLSPrivateKeyKey])
+
+	// the solver service addresses kubecloud by its endpoint, and the solver ingress is deleted
+	svc, err := issuer.client.CoreV1().Services(testAcmeNamespace).Get(context.TODO(), acmeSolverName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(8080), svc.Spec.Ports[0].Port)
+	ep, err := issuer.client.CoreV1().Endpoints(testAcmeNamespace).Get(context.TODO(), acmeSolverName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.10", ep.Subsets[0].Addresses[0].IP)
+	_, err = issuer.client.NetworkingV1().Ingresses(testAcmeNamespace).Get(context.TODO(), providerObjectName(acmeSolverName, testAcmeHost), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestAcmeIssueInvalidChallenge(t *testing.T) {
+	issuer, server := newTestAcmeIssuer(t, true)
+	defer server.server.Close()
+	cert := newTestAcmeCertificate()
+
+	err := issuer.issue(cert)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid response from the challenge path: 404")
+	assert.False(t, server.finalized)
+	assert.Equal(t, models.ACME_CERTIFICATE_STATUS_PENDING, cert.Status)
+
+	// the solver ingress is deleted, and the secret is not created
+	_, err = issuer.client.NetworkingV1().Ingresses(testAcmeNamespace).Get(context.TODO(), providerObjectName(acmeSolverName, testAcmeHost), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = issuer.client.CoreV1().Secrets(testAcmeNamespace).Get(context.TODO(), "www-tls", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestAcmeCertificateDue(t *testing.T) {
+	now := time.Now()
+	for _, item := range []struct {
+		status   string
+		notAfter time.Time
+		updateAt time.Time
+		due      bool
+	}{
+		{models.ACME_CERTIFICATE_STATUS_PENDING, time.Time{}, now, true},
+		{models.ACME_CERTIFICATE_STATUS_READY, now.Add(acmeRenewBefore + time.Hour), now, false},
+		{models.ACME_CERTIFICATE_STATUS_READY, now.Add(acmeRenewBefore), now, true},
+		{models.ACME_CERTIFICATE_STATUS_READY, now.Add(-time.Hour), now, true},
+		{models.ACME_CERTIFICATE_STATUS_FAILED, time.Time{}, now.Add(-acmeRetryInterval + time.Minute), false},
+		{models.ACME_CERTIFICATE_STATUS_FAILED, time.Time{}, now.Add(-acmeRetryInterval), true},
+	} {
+		cert := &models.ZcloudAcmeCertificate{Status: item.status, NotAfter: item.notAfter}
+		cert.UpdateAt = item.updateAt
+		assert.Equal(t, item.due, acmeCertificateDue(cert, now), fmt.Sprintf("%s %v %v", item.status, item.notAfter, item.updateAt))
+	}
+}
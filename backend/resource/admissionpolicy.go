@@ -0,0 +1,415 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/common"
+
+	"github.com/astaxie/beego/orm"
+	apiv1 "k8s.io/api/core/v1"
+	kuberesource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	PolicyRuleAllowedRegistries     = "allowed_registries"
+	PolicyRuleMaxCPU                = "max_cpu"
+	PolicyRuleMaxMemory             = "max_memory"
+	PolicyRuleMaxReplicas           = "max_replicas"
+	PolicyRuleRequiredLabels        = "required_labels"
+	PolicyRuleRequiredAnnotations   = "required_annotations"
+	PolicyRuleDenyHostPath          = "deny_host_path"
+	PolicyRuleDenyPrivileged        = "deny_privileged"
+	PolicyRuleRequireLivenessProbe  = "require_liveness_probe"
+	PolicyRuleRequireReadinessProbe = "require_readiness_probe"
+	PolicyRuleImagePullPolicy       = "image_pull_policy"
+
+	policyViolationCode = "PolicyViolation"
+)
+
+// AdmissionRules are the rules which the applications must comply with, the empty rule is not checked
+type AdmissionRules struct {
+	// the registries or repositories which the images must be pulled from, such as "harbor.example.com/library"
+	AllowedRegistries []string `json:"allowed_registries,omitempty"`
+	// the max cpu and memory limits of each container, such as "2" and "4Gi"
+	MaxCPU    string `json:"max_cpu,omitempty"`
+	MaxMemory string `json:"max_memory,omitempty"`
+	// the max replicas of deployment and statefulset, or the max completions of job
+	MaxReplicas int32 `json:"max_replicas,omitempty"`
+	// the keys of the labels and annotations which the application object must have
+	RequiredLabels      []string `json:"required_labels,omitempty"`
+	RequiredAnnotations []string `json:"required_annotations,omitempty"`
+	DenyHostPath        bool     `json:"deny_host_path"`
+	DenyPrivileged      bool     `json:"deny_privileged"`
+	// the probes are required by the long running applications only
+	RequireLivenessProbe  bool `json:"require_liveness_probe"`
+	RequireReadinessProbe bool `json:"require_readiness_probe"`
+	// the image pull policy which all containers must set explicitly: Always, IfNotPresent or Never
+	ImagePullPolicy string `json:"image_pull_policy,omitempty"`
+}
+
+type AdmissionPolicyParam struct {
+	// the policy applies to all namespaces of the cluster if namespace is empty
+	Namespace   string         `json:"namespace"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Enabled     bool           `json:"enabled"`
+	Rules       AdmissionRules `json:"rules"`
+}
+
+type AdmissionPolicyDetail struct {
+	models.ZcloudAdmissionPolicy
+	Rules AdmissionRules `json:"rules"`
+}
+
+// PolicyViolation is a violation of a rule of policy by the application object or its container
+type PolicyViolation struct {
+	Policy    string `json:"policy"`
+	Rule      string `json:"rule"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Container string `json:"container,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (param AdmissionPolicyParam) Validate() error {
+	if strings.TrimSpace(param.Name) == "" {
+		return fmt.Errorf("policy name must be given!")
+	}
+	if param.Rules.MaxCPU != "" {
+		if _, err := kuberesource.ParseQuantity(param.Rules.MaxCPU); err != nil {
+			return fmt.Errorf("max cpu %q is invalid: %v!", param.Rules.MaxCPU, err)
+		}
+	}
+	if param.Rules.MaxMemory != "" {
+		if _, err := kuberesource.ParseQuantity(param.Rules.MaxMemory); err != nil {
+			return fmt.Errorf("max memory %q is invalid: %v!", param.Rules.MaxMemory, err)
+		}
+	}
+	if param.Rules.MaxReplicas < 0 {
+		return fmt.Errorf("max replicas can not be negative!")
+	}
+	switch apiv1.PullPolicy(param.Rules.ImagePullPolicy) {
+	case "", apiv1.PullAlways, apiv1.PullIfNotPresent, apiv1.PullNever:
+	default:
+		return fmt.Errorf("image pull policy must be one of Always, IfNotPresent and Never!")
+	}
+	for _, registry := range param.Rules.AllowedRegistries {
+		if strings.TrimSpace(registry) == "" {
+			return fmt.Errorf("allowed registry can not be empty!")
+		}
+	}
+	return nil
+}
+
+// AdmissionPolicyList returns the policies of the cluster, or the ones which apply to namespace if it is not empty
+func AdmissionPolicyList(cluster, namespace string) ([]AdmissionPolicyDetail, error) {
+	list, err := dao.NewAdmissionPolicyModel().GetPolicyList(cluster, namespace)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	details := []AdmissionPolicyDetail{}
+	for _, policy := range list {
+		detail, err := admissionPolicyToDetail(policy)
+		if err != nil {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+		details = append(details, *detail)
+	}
+	return details, nil
+}
+
+func AdmissionPolicyInspect(cluster string, id int64) (*AdmissionPolicyDetail, error) {
+	policy, err := getAdmissionPolicy(cluster, id)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := admissionPolicyToDetail(*policy)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return detail, nil
+}
+
+func AdmissionPolicyCreate(cluster string, param AdmissionPolicyParam) (*AdmissionPolicyDetail, error) {
+	if err := param.Validate(); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	model := dao.NewAdmissionPolicyModel()
+	if model.PolicyExists(cluster, param.Namespace, param.Name) {
+		return nil, common.NewConflict().SetCause(fmt.Errorf("policy %s is existed!", param.Name))
+	}
+	rules, err := json.Marshal(param.Rules)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	policy := &models.ZcloudAdmissionPolicy{
+		Cluster:     cluster,
+		Namespace:   param.Namespace,
+		Name:        param.Name,
+		Description: param.Description,
+		Enabled:     param.Enabled,
+		Rules:       string(rules),
+	}
+	if err := model.CreatePolicy(policy); err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return &AdmissionPolicyDetail{ZcloudAdmissionPolicy: *policy, Rules: param.Rules}, nil
+}
+
+func AdmissionPolicyUpdate(cluster string, id int64, param AdmissionPolicyParam) (*AdmissionPolicyDetail, error) {
+	policy, err := getAdmissionPolicy(cluster, id)
+	if err != nil {
+		return nil, err
+	}
+	if param.Namespace != policy.Namespace || param.Name != policy.Name {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("the namespace and name of policy can not be changed!"))
+	}
+	if err := param.Validate(); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	rules, err := json.Marshal(param.Rules)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	policy.Description = param.Description
+	policy.Enabled = param.Enabled
+	policy.Rules = string(rules)
+	if err := dao.NewAdmissionPolicyModel().UpdatePolicy(policy); err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return &AdmissionPolicyDetail{ZcloudAdmissionPolicy: *policy, Rules: param.Rules}, nil
+}
+
+func AdmissionPolicyDelete(cluster string, id int64) error {
+	if _, err := getAdmissionPolicy(cluster, id); err != nil {
+		return err
+	}
+	if err := dao.NewAdmissionPolicyModel().DeletePolicy(cluster, id); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return nil
+}
+
+// CheckAdmissionPolicies checks the application template against the enabled policies of the cluster and namespace,
+// the violations are the details of the returned error
+func CheckAdmissionPolicies(cluster, namespace string, template AppTemplate) error {
+	tpl, ok := template.(*NativeAppTemplate)
+	if !ok {
+		return nil
+	}
+	list, err := dao.NewAdmissionPolicyModel().GetPolicyList(cluster, namespace)
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	violations, err := admissionPolicyViolations(list, namespace, tpl)
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return violationsError(tpl.GetAppName(), violations)
+}
+
+// CheckAdmissionReplicas checks the replicas which the application is scaled to against the enabled policies,
+// the scaling and autoscaling do not go through the template check of deploying
+func CheckAdmissionReplicas(cluster, namespace, kind, appname string, replicas int) error {
+	list, err := dao.NewAdmissionPolicyModel().GetPolicyList(cluster, namespace)
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	violations, err := replicasViolations(list, namespace, kind, appname, int32(replicas))
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return violationsError(appname, violations)
+}
+
+func violationsError(appname string, violations []PolicyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	messages := []string{}
+	for _, v := range violations {
+		messages = append(messages, v.Message)
+	}
+	return common.NewBadRequest().
+		SetCode(policyViolationCode).
+		SetMessage("application %s violates %v rules of the admission policies", appname, len(violations)).
+		SetCause(fmt.Errorf("%s", strings.Join(messages, "; "))).
+		SetDetails(violations)
+}
+
+// admissionPolicyViolations returns the violations of the enabled policies which apply to the namespace
+func admissionPolicyViolations(list []models.ZcloudAdmissionPolicy, namespace string, tpl *NativeAppTemplate) ([]PolicyViolation, error) {
+	violations := []PolicyViolation{}
+	for _, policy := range list {
+		if !policy.Enabled || (policy.Namespace != "" && policy.Namespace != namespace) {
+			continue
+		}
+		detail, err := admissionPolicyToDetail(policy)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, detail.Rules.check(policy.Name, tpl)...)
+	}
+	return violations, nil
+}
+
+// replicasViolations returns the max replicas violations of the enabled policies which apply to the namespace
+func replicasViolations(list []models.ZcloudAdmissionPolicy, namespace, kind, name string, replicas int32) ([]PolicyViolation, error) {
+	violations := []PolicyViolation{}
+	for _, policy := range list {
+		if !policy.Enabled || (policy.Namespace != "" && policy.Namespace != namespace) {
+			continue
+		}
+		detail, err := admissionPolicyToDetail(policy)
+		if err != nil {
+			return nil, err
+		}
+		if v := detail.Rules.checkReplicas(policy.Name, kind, name, replicas); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	return violations, nil
+}
+
+// checkReplicas returns the violation of max replicas, the daemonset and cronjob have no replicas
+func (rules AdmissionRules) checkReplicas(policy, kind, name string, replicas int32) *PolicyViolation {
+	if rules.MaxReplicas == 0 || replicas <= rules.MaxReplicas {
+		return nil
+	}
+	if kind != AppKindDeployment && kind != AppKindStatefulSet && kind != AppKindJob {
+		return nil
+	}
+	return &PolicyViolation{
+		Policy:  policy,
+		Rule:    PolicyRuleMaxReplicas,
+		Kind:    kind,
+		Name:    name,
+		Message: fmt.Sprintf("the replicas %v of %s %s is above the max %v", replicas, kind, name, rules.MaxReplicas),
+	}
+}
+
+// check returns the violations of the rules by the application object of template
+func (rules AdmissionRules) check(policy string, tpl *NativeAppTemplate) []PolicyViolation {
+	violations := []PolicyViolation{}
+	obj, ok := tpl.appObject().(metav1.Object)
+	podTemplate := tpl.podTemplate()
+	if !ok || podTemplate == nil {
+		return violations
+	}
+	kind := tpl.GetAppKind()
+	addViolation := func(rule, container, format string, args ...interface{}) {
+		violations = append(violations, PolicyViolation{
+			Policy:    policy,
+			Rule:      rule,
+			Kind:      kind,
+			Name:      obj.GetName(),
+			Container: container,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+	for _, key := range rules.RequiredLabels {
+		if _, existed := obj.GetLabels()[key]; !existed {
+			addViolation(PolicyRuleRequiredLabels, "", "the %s %s has no label %s", kind, obj.GetName(), key)
+		}
+	}
+	for _, key := range rules.RequiredAnnotations {
+		if _, existed := obj.GetAnnotations()[key]; !existed {
+			addViolation(PolicyRuleRequiredAnnotations, "", "the %s %s has no annotation %s", kind, obj.GetName(), key)
+		}
+	}
+	if v := rules.checkReplicas(policy, kind, obj.GetName(), tpl.getReplicas()); v != nil {
+		violations = append(violations, *v)
+	}
+	spec := podTemplate.Spec
+	if rules.DenyHostPath {
+		for _, vol := range spec.Volumes {
+			if vol.HostPath != nil {
+				addViolation(PolicyRuleDenyHostPath, "", "the volume %s of %s %s is hostPath", vol.Name, kind, obj.GetName())
+			}
+		}
+	}
+	longRunning := kind == AppKindDeployment || kind == AppKindStatefulSet || kind == AppKindDaemonSet
+	containers := append([]apiv1.Container{}, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for i, c := range containers {
+		isInit := i < len(spec.InitContainers)
+		if len(rules.AllowedRegistries) != 0 && !imageInRegistries(c.Image, rules.AllowedRegistries) {
+			addViolation(PolicyRuleAllowedRegistries, c.Name, "the image %s of container %s is not from the allowed registries %v", c.Image, c.Name, rules.AllowedRegistries)
+		}
+		if rules.MaxCPU != "" {
+			max := kuberesource.MustParse(rules.MaxCPU)
+			if limit, existed := c.Resources.Limits[apiv1.ResourceCPU]; !existed {
+				addViolation(PolicyRuleMaxCPU, c.Name, "the container %s has no cpu limit, the max is %s", c.Name, rules.MaxCPU)
+			} else if limit.Cmp(max) > 0 {
+				addViolation(PolicyRuleMaxCPU, c.Name, "the cpu limit %s of container %s is above the max %s", limit.String(), c.Name, rules.MaxCPU)
+			}
+		}
+		if rules.MaxMemory != "" {
+			max := kuberesource.MustParse(rules.MaxMemory)
+			if limit, existed := c.Resources.Limits[apiv1.ResourceMemory]; !existed {
+				addViolation(PolicyRuleMaxMemory, c.Name, "the container %s has no memory limit, the max is %s", c.Name, rules.MaxMemory)
+			} else if limit.Cmp(max) > 0 {
+				addViolation(PolicyRuleMaxMemory, c.Name, "the memory limit %s of container %s is above the max %s", limit.String(), c.Name, rules.MaxMemory)
+			}
+		}
+		if rules.DenyPrivileged && c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+			addViolation(PolicyRuleDenyPrivileged, c.Name, "the container %s is privileged", c.Name)
+		}
+		if rules.ImagePullPolicy != "" && string(c.ImagePullPolicy) != rules.ImagePullPolicy {
+			addViolation(PolicyRuleImagePullPolicy, c.Name, "the image pull policy of container %s must be %s", c.Name, rules.ImagePullPolicy)
+		}
+		if isInit || !longRunning {
+			continue
+		}
+		if rules.RequireLivenessProbe && c.LivenessProbe == nil {
+			addViolation(PolicyRuleRequireLivenessProbe, c.Name, "the container %s has no liveness probe", c.Name)
+		}
+		if rules.RequireReadinessProbe && c.ReadinessProbe == nil {
+			addViolation(PolicyRuleRequireReadinessProbe, c.Name, "the container %s has no readiness probe", c.Name)
+		}
+	}
+	return violations
+}
+
+// imageInRegistries checks whether the image is pulled from one of the registries or repositories,
+// the image without registry is pulled from docker hub
+func imageInRegistries(image string, registries []string) bool {
+	name := imageName(image)
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 1 || !(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		name = "docker.io/" + name
+	}
+	for _, registry := range registries {
+		registry = strings.TrimSuffix(strings.TrimSpace(registry), "/")
+		if strings.HasPrefix(name, registry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func getAdmissionPolicy(cluster string, id int64) (*models.ZcloudAdmissionPolicy, error) {
+	policy, err := dao.NewAdmissionPolicyModel().GetPolicy(cluster, id)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(fmt.Errorf("admission policy %v is not found!", id))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return policy, nil
+}
+
+func admissionPolicyToDetail(policy models.ZcloudAdmissionPolicy) (*AdmissionPolicyDetail, error) {
+	detail := &AdmissionPolicyDetail{ZcloudAdmissionPolicy: policy}
+	if policy.Rules != "" {
+		if err := json.Unmarshal([]byte(policy.Rules), &detail.Rules); err != nil {
+			return nil, fmt.Errorf("rules of admission policy %s is invalid: %v", policy.Name, err)
+		}
+	}
+	return detail, nil
+}
@@ -0,0 +1,217 @@
+package resource
+
+import (
+	"encoding/json"
+	"testing"
+
+	"kubecloud/backend/models"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	kuberesource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImageInRegistries(t *testing.T) {
+	cases := []struct {
+		image      string
+		registries []string
+		allowed    bool
+	}{
+		{"harbor.example.com/library/nginx:1.21", []string{"harbor.example.com"}, true},
+		{"harbor.example.com/library/nginx:1.21", []string{"harbor.example.com/library"}, true},
+		{"harbor.example.com/library/nginx:1.21", []string{" harbor.example.com/library/ "}, true},
+		{"harbor.example.com/other/nginx", []string{"harbor.example.com/library"}, false},
+		// the registry is matched by the whole path segment
+		{"harbor.example.com.evil.io/library/nginx", []string{"harbor.example.com"}, false},
+		{"harbor.example.com/library-evil/nginx", []string{"harbor.example.com/library"}, false},
+		// the port is a part of the registry
+		{"harbor.example.com:5000/library/nginx:1.21", []string{"harbor.example.com:5000"}, true},
+		{"harbor.example.com:5000/library/nginx:1.21", []string{"harbor.example.com"}, false},
+		{"harbor.example.com/library/nginx:1.21", []string{"harbor.example.com:5000"}, false},
+		{"localhost:5000/nginx", []string{"localhost:5000"}, true},
+		{"localhost/nginx", []string{"localhost"}, true},
+		// the image without registry is pulled from docker hub
+		{"nginx:1.21", []string{"docker.io"}, true},
+		{"nginx:1.21", []string{"docker.io/library"}, false},
+		{"library/nginx", []string{"docker.io/library"}, true},
+		{"library/nginx", []string{"library"}, false},
+		{"harbor.example.com/library/nginx@sha256:0123456789abcdef", []string{"harbor.example.com/library"}, true},
+		{"harbor.example.com/library/nginx", []string{"quay.io", "harbor.example.com/library"}, true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.allowed, imageInRegistries(c.image, c.registries), "image %s in %v", c.image, c.registries)
+	}
+}
+
+func newTestPolicyTemplate(kind string, replicas int32, containers ...apiv1.Container) *NativeAppTemplate {
+	tpl := &NativeAppTemplate{}
+	tpl.Kind = kind
+	meta := metav1.ObjectMeta{Name: "demo", Labels: map[string]string{"team": "infra"}}
+	podTemplate := apiv1.PodTemplateSpec{Spec: apiv1.PodSpec{Containers: containers}}
+	switch kind {
+	case AppKindDeployment:
+		tpl.Deployment = &appsv1.Deployment{ObjectMeta: meta, Spec: appsv1.DeploymentSpec{Replicas: &replicas, Template: podTemplate}}
+	case AppKindDaemonSet:
+		tpl.DaemonSet = &appsv1.DaemonSet{ObjectMeta: meta, Spec: appsv1.DaemonSetSpec{Template: podTemplate}}
+	case AppKindJob:
+		tpl.Job = &batchv1.Job{ObjectMeta: meta, Spec: batchv1.JobSpec{Completions: &replicas, Template: podTemplate}}
+	}
+	return tpl
+}
+
+func newTestPolicyContainer(cpu, memory string) apiv1.Container {
+	c := apiv1.Container{
+		Name:            "app",
+		Image:           "harbor.example.com/library/demo:v1",
+		ImagePullPolicy: apiv1.PullIfNotPresent,
+		LivenessProbe:   &apiv1.Probe{},
+		ReadinessProbe:  &apiv1.Probe{},
+		Resources:       apiv1.ResourceRequirements{Limits: apiv1.ResourceList{}},
+	}
+	if cpu != "" {
+		c.Resources.Limits[apiv1.ResourceCPU] = kuberesource.MustParse(cpu)
+	}
+	if memory != "" {
+		c.Resources.Limits[apiv1.ResourceMemory] = kuberesource.MustParse(memory)
+	}
+	return c
+}
+
+func violatedRules(violations []PolicyViolation) []string {
+	rules := []string{}
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestAdmissionRulesCheck(t *testing.T) {
+	privileged := true
+	privilegedContainer := newTestPolicyContainer("1", "1Gi")
+	privilegedContainer.SecurityContext = &apiv1.SecurityContext{Privileged: &privileged}
+	noProbeContainer := newTestPolicyContainer("1", "1Gi")
+	noProbeContainer.LivenessProbe, noProbeContainer.ReadinessProbe = nil, nil
+	dockerHubContainer := newTestPolicyContainer("1", "1Gi")
+	dockerHubContainer.Image = "nginx:1.21"
+	cases := []struct {
+		name     string
+		rules    AdmissionRules
+		tpl      *NativeAppTemplate
+		violated []string
+	}{
+		{"no rules", AdmissionRules{}, newTestPolicyTemplate(AppKindDeployment, 3, newTestPolicyContainer("", "")), []string{}},
+		{"cpu limit equal to max", AdmissionRules{MaxCPU: "2"},
+			newTestPolicyTemplate(AppKindDeployment, 1, newTestPolicyContainer("2000m", "")), []string{}},
+		{"cpu limit above max", AdmissionRules{MaxCPU: "2"},
+			newTestPolicyTemplate(AppKindDeployment, 1, newTestPolicyContainer("2001m", "")), []string{PolicyRuleMaxCPU}},
+		{"no cpu limit", AdmissionRules{MaxCPU: "2"},
+			newTestPolicyTemplate(AppKindDeployment, 1, newTestPolicyContainer("", "1Gi")), []string{PolicyRuleMaxCPU}},
+		{"memory limit in other unit", AdmissionRules{MaxMemory: "4Gi"},
+			newTestPolicyTemplate(AppKindDeployment, 1, newTestPolicyContainer("", "4096Mi")), []string{}},
+		{"memory limit above max", AdmissionRules{MaxMemory: "4Gi"},
+			newTestPolicyTemplate(AppKindDeployment, 1, newTestPolicyContainer("", "4G"), newTestPolicyContainer("", "5Gi")),
+			[]string{PolicyRuleMaxMemory}},
+		{"replicas equal to max", AdmissionRules{MaxReplicas: 3},
+			newTestPolicyTemplate(AppKindDeployment, 3, newTestPolicyContainer("", "")), []string{}},
+		{"replicas above max", AdmissionRules{MaxReplicas: 3},
+			newTestPolicyTemplate(AppKindDeployment, 4, newTestPolicyContainer("", "")), []string{PolicyRuleMaxReplicas}},
+		{"completions above max", AdmissionRules{MaxReplicas: 3},
+			newTestPolicyTemplate(AppKindJob, 4, newTestPolicyContainer("", "")), []string{PolicyRuleMaxReplicas}},
+		{"daemonset has no replicas", AdmissionRules{MaxReplicas: 3},
+			newTestPolicyTemplate(AppKindDaemonSet, 0, newTestPolicyContainer("", "")), []string{}},
+		{"registry", AdmissionRules{AllowedRegistries: []string{"harbor.example.com/library"}},
+			newTestPolicyTemplate(AppKindDeployment, 1, newTestPolicyContainer("", ""), dockerHubContainer), []string{PolicyRuleAllowedRegistries}},
+		{"labels and annotations", AdmissionRules{RequiredLabels: []string{"team", "owner"}, RequiredAnnotations: []string{"owner"}},
+			newTestPolicyTemplate(AppKindDeployment, 1, newTestPolicyContainer("", "")),
+			[]string{PolicyRuleRequiredLabels, PolicyRuleRequiredAnnotations}},
+		{"privileged", AdmissionRules{DenyPrivileged: true},
+			newTestPolicyTemplate(AppKindDeployment, 1, privilegedContainer), []string{PolicyRuleDenyPrivileged}},
+		{"image pull policy", AdmissionRules{ImagePullPolicy: string(apiv1.PullAlways)},
+			newTestPolicyTemplate(AppKindDeployment, 1, newTestPolicyContainer("", "")), []string{PolicyRuleImagePullPolicy}},
+		{"probes of long running application", AdmissionRules{RequireLivenessProbe: true, RequireReadinessProbe: true},
+			newTestPolicyTemplate(AppKindDeployment, 1, noProbeContainer),
+			[]string{PolicyRuleRequireLivenessProbe, PolicyRuleRequireReadinessProbe}},
+		{"probes of job", AdmissionRules{RequireLivenessProbe: true, RequireReadinessProbe: true},
+			newTestPolicyTemplate(AppKindJob, 1, noProbeContainer), []string{}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.violated, violatedRules(c.rules.check("test", c.tpl)), c.name)
+	}
+}
+
+func TestAdmissionPolicyViolations(t *testing.T) {
+	policy := func(namespace, name string, enabled bool, rules AdmissionRules) models.ZcloudAdmissionPolicy {
+		data, _ := json.Marshal(rules)
+		return models.ZcloudAdmissionPolicy{Cluster: "test", Namespace: namespace, Name: name, Enabled: enabled, Rules: string(data)}
+	}
+	list := []models.ZcloudAdmissionPolicy{
+		policy("", "cluster-policy", true, AdmissionRules{MaxReplicas: 5}),
+		policy("prod", "prod-policy", true, AdmissionRules{MaxReplicas: 2}),
+		policy("dev", "dev-policy", true, AdmissionRules{MaxReplicas: 1}),
+		policy("prod", "disabled-policy", false, AdmissionRules{MaxReplicas: 1}),
+	}
+	tpl := newTestPolicyTemplate(AppKindDeployment, 3, newTestPolicyContainer("", ""))
+	cases := []struct {
+		namespace string
+		policies  []string
+	}{
+		// the policies of the cluster apply to all namespaces, the disabled ones are skipped
+		{"prod", []string{"prod-policy"}},
+		{"dev", []string{"dev-policy"}},
+		{"test", []string{}},
+	}
+	for _, c := range cases {
+		violations, err := admissionPolicyViolations(list, c.namespace, tpl)
+		assert.Nil(t, err)
+		policies := []string{}
+		for _, v := range violations {
+			policies = append(policies, v.Policy)
+		}
+		assert.Equal(t, c.policies, policies, c.namespace)
+	}
+
+	violations, err := admissionPolicyViolations(list, "prod", newTestPolicyTemplate(AppKindDeployment, 6, newTestPolicyContainer("", "")))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(violations))
+	assert.Equal(t, "cluster-policy", violations[0].Policy)
+
+	_, err = admissionPolicyViolations([]models.ZcloudAdmissionPolicy{{Name: "broken", Enabled: true, Rules: "{"}}, "prod", tpl)
+	assert.NotNil(t, err)
+}
+
+func TestReplicasViolations(t *testing.T) {
+	policy := func(namespace, name string, rules AdmissionRules) models.ZcloudAdmissionPolicy {
+		data, _ := json.Marshal(rules)
+		return models.ZcloudAdmissionPolicy{Cluster: "test", Namespace: namespace, Name: name, Enabled: true, Rules: string(data)}
+	}
+	list := []models.ZcloudAdmissionPolicy{
+		policy("", "cluster-policy", AdmissionRules{MaxReplicas: 5}),
+		policy("prod", "prod-policy", AdmissionRules{MaxReplicas: 2, DenyPrivileged: true}),
+	}
+	cases := []struct {
+		namespace string
+		kind      string
+		replicas  int32
+		policies  []string
+	}{
+		{"prod", AppKindDeployment, 2, []string{}},
+		{"prod", AppKindDeployment, 3, []string{"prod-policy"}},
+		{"prod", AppKindStatefulSet, 6, []string{"cluster-policy", "prod-policy"}},
+		{"dev", AppKindDeployment, 3, []string{}},
+		{"dev", AppKindDeployment, 6, []string{"cluster-policy"}},
+		{"prod", AppKindDaemonSet, 6, []string{}},
+	}
+	for _, c := range cases {
+		violations, err := replicasViolations(list, c.namespace, c.kind, "demo", c.replicas)
+		assert.Nil(t, err)
+		policies := []string{}
+		for _, v := range violations {
+			assert.Equal(t, PolicyRuleMaxReplicas, v.Rule)
+			policies = append(policies, v.Policy)
+		}
+		assert.Equal(t, c.policies, policies, "%s %s %v", c.namespace, c.kind, c.replicas)
+	}
+}
@@ -0,0 +1,361 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/common"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const configSetVolumePrefix = "configset-"
+
+// AppConfigItem is a key of the config set, its value is a literal, or a key of configmap or secret
+type AppConfigItem struct {
+	// Name is the name of the env var, or the name of the file if the item is mounted
+	Name      string `json:"name"`
+	Value     string `json:"value,omitempty"`
+	ConfigMap string `json:"configmap,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+	// Key is the key of configmap or secret, it is the same as name if it is empty
+	Key string `json:"key,omitempty"`
+	// MountPath is the file path which the key of configmap or secret is mounted to,
+	// the item is injected as env var if it is empty
+	MountPath string `json:"mount_path,omitempty"`
+}
+
+// AppConfigSetParam is the config set of namespace, which is injected to the applications
+// whose deploy config refers to it
+type AppConfigSetParam struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Items       []AppConfigItem `json:"items"`
+}
+
+type AppConfigSetDetail struct {
+	models.ZcloudAppConfigSet
+	Items []AppConfigItem `json:"items"`
+	// Applications are the applications which use the config set
+	Applications []string `json:"applications,omitempty"`
+}
+
+func (param AppConfigSetParam) Validate() error {
+	if errs := validation.IsDNS1123Label(param.Name); len(errs) != 0 {
+		return fmt.Errorf("config set name %q is invalid: %s!", param.Name, strings.Join(errs, ","))
+	}
+	names := make(map[string]bool)
+	for _, item := range param.Items {
+		if item.Name == "" {
+			return fmt.Errorf("the name of config item can not be empty!")
+		}
+		if names[item.Name] {
+			return fmt.Errorf("config item %s is duplicated!", item.Name)
+		}
+		names[item.Name] = true
+		if item.ConfigMap != "" && item.Secret != "" {
+			return fmt.Errorf("config item %s can not refer to both configmap and secret!", item.Name)
+		}
+		if item.Value != "" && (item.ConfigMap != "" || item.Secret != "") {
+			return fmt.Errorf("config item %s can not have both value and configmap or secret!", item.Name)
+		}
+		if item.MountPath == "" {
+			if errs := validation.IsEnvVarName(item.Name); len(errs) != 0 {
+				return fmt.Errorf("config item %s is not a valid env name: %s!", item.Name, strings.Join(errs, ","))
+			}
+			continue
+		}
+		if item.ConfigMap == "" && item.Secret == "" {
+			return fmt.Errorf("only the key of configmap or secret can be mounted, config item %s has none of them!", item.Name)
+		}
+		if !path.IsAbs(item.MountPath) || path.Base(item.MountPath) == "/" {
+			return fmt.Errorf("the mount path of config item %s must be an absolute file path!", item.Name)
+		}
+	}
+	return nil
+}
+
+func AppConfigSetList(cluster, namespace string) ([]AppConfigSetDetail, error) {
+	list, err := dao.NewAppConfigSetModel().GetConfigSetList(cluster, namespace)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	details := []AppConfigSetDetail{}
+	for _, set := range list {
+		detail, err := appConfigSetToDetail(set)
+		if err != nil {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+		details = append(details, *detail)
+	}
+	return details, nil
+}
+
+func AppConfigSetInspect(cluster, namespace, name string) (*AppConfigSetDetail, error) {
+	set, err := getAppConfigSet(cluster, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := appConfigSetToDetail(*set)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	apps, err := appsUsingConfigSet(cluster, namespace, name)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	for _, app := range apps {
+		detail.Applications = append(detail.Applications, app.Name)
+	}
+	return detail, nil
+}
+
+func AppConfigSetCreate(cluster, namespace string, param AppConfigSetParam) (*AppConfigSetDetail, error) {
+	if err := param.Validate(); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	model := dao.NewAppConfigSetModel()
+	if model.ConfigSetExists(cluster, namespace, param.Name) {
+		return nil, common.NewConflict().SetCause(fmt.Errorf("config set %s is existed!", param.Name))
+	}
+	items, err := json.Marshal(param.Items)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	set := &models.ZcloudAppConfigSet{
+		Cluster:     cluster,
+		Namespace:   namespace,
+		Name:        param.Name,
+		Description: param.Description,
+		Items:       string(items),
+	}
+	if err := model.CreateConfigSet(set); err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return &AppConfigSetDetail{ZcloudAppConfigSet: *set, Items: param.Items}, nil
+}
+
+// AppConfigSetUpdate updates the items of config set, and restarts the applications which use it,
+// the new items are injected by the restart
+func AppConfigSetUpdate(cluster, namespace, name string, param AppConfigSetParam) (*AppConfigSetDetail, error) {
+	set, err := getAppConfigSet(cluster, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if param.Name != name {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("the name of config set can not be changed!"))
+	}
+	if err := param.Validate(); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	items, err := json.Marshal(param.Items)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	set.Description = param.Description
+	set.Items = string(items)
+	if err := dao.NewAppConfigSetModel().UpdateConfigSet(set); err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	detail := &AppConfigSetDetail{ZcloudAppConfigSet: *set, Items: param.Items}
+	apps, err := appsUsingConfigSet(cluster, namespace, name)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if len(apps) == 0 {
+		return detail, nil
+	}
+	ar, err := NewAppRes(cluster, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, app := range apps {
+		if err := ar.Restart(namespace, app.Name); err != nil {
+			beego.Error(fmt.Sprintf("restart application %s/%s for the change of config set %s failed: %v", namespace, app.Name, name, err))
+			continue
+		}
+		detail.Applications = append(detail.Applications, app.Name)
+	}
+	return detail, nil
+}
+
+func AppConfigSetDelete(cluster, namespace, name string) error {
+	if _, err := getAppConfigSet(cluster, namespace, name); err != nil {
+		return err
+	}
+	apps, err := appsUsingConfigSet(cluster, namespace, name)
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if len(apps) != 0 {
+		names := []string{}
+		for _, app := range apps {
+			names = append(names, app.Name)
+		}
+		return common.NewConflict().SetCause(fmt.Errorf("config set %s is used by applications %v!", name, names))
+	}
+	if err := dao.NewAppConfigSetModel().DeleteConfigSet(cluster, namespace, name); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return nil
+}
+
+func getAppConfigSet(cluster, namespace, name string) (*models.ZcloudAppConfigSet, error) {
+	set, err := dao.NewAppConfigSetModel().GetConfigSet(cluster, namespace, name)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(fmt.Errorf("config set %s is not existed!", name))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return set, nil
+}
+
+func appConfigSetToDetail(set models.ZcloudAppConfigSet) (*AppConfigSetDetail, error) {
+	detail := &AppConfigSetDetail{ZcloudAppConfigSet: set, Items: []AppConfigItem{}}
+	if set.Items != "" {
+		if err := json.Unmarshal([]byte(set.Items), &detail.Items); err != nil {
+			return nil, err
+		}
+	}
+	return detail, nil
+}
+
+// appsUsingConfigSet returns the applications of namespace whose deploy config refers to the config set
+func appsUsingConfigSet(cluster, namespace, name string) ([]models.ZcloudApplication, error) {
+	list, err := dao.GetAllAppsByCluster(cluster, []string{namespace}, nil)
+	if err != nil {
+		return nil, err
+	}
+	apps := []models.ZcloudApplication{}
+	for _, app := range list {
+		template, err := CreateAppTemplateByApp(app)
+		if err != nil {
+			beego.Warn(fmt.Sprintf("decode template of application %s/%s failed: %v", namespace, app.Name, err))
+			continue
+		}
+		tpl, ok := template.(*NativeAppTemplate)
+		if !ok {
+			continue
+		}
+		for _, item := range tpl.Config.ConfigSets {
+			if item == name {
+				apps = append(apps, app)
+				break
+			}
+		}
+	}
+	return apps, nil
+}
+
+func kubeObjectPodSpec(obj interface{}) *apiv1.PodSpec {
+	switch t := obj.(type) {
+	case *appsv1.Deployment:
+		return &t.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &t.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		return &t.Spec.Template.Spec
+	case *batchv1.Job:
+		return &t.Spec.Template.Spec
+	case *batchv1.CronJob:
+		return &t.Spec.JobTemplate.Spec.Template.Spec
+	}
+	return nil
+}
+
+// injectConfigSets injects the items of config sets to the containers of podSpec, the env vars of containers
+// and the former config sets are not overridden, the containers and volumes are copied because they are shared with the template
+func injectConfigSets(cluster, namespace string, names []string, podSpec *apiv1.PodSpec) error {
+	if podSpec == nil || len(names) == 0 {
+		return nil
+	}
+	envs := []apiv1.EnvVar{}
+	mounts := []apiv1.VolumeMount{}
+	volumes := append([]apiv1.Volume{}, podSpec.Volumes...)
+	for _, name := range names {
+		set, err := dao.NewAppConfigSetModel().GetConfigSet(cluster, namespace, name)
+		if err != nil {
+			if err == orm.ErrNoRows {
+				return fmt.Errorf("config set %s is not existed!", name)
+			}
+			return err
+		}
+		detail, err := appConfigSetToDetail(*set)
+		if err != nil {
+			return err
+		}
+		for i, item := range detail.Items {
+			key := item.Key
+			if key == "" {
+				key = item.Name
+			}
+			if item.MountPath == "" {
+				env := apiv1.EnvVar{Name: item.Name, Value: item.Value}
+				if item.ConfigMap != "" {
+					env.Value = ""
+					env.ValueFrom = &apiv1.EnvVarSource{ConfigMapKeyRef: &apiv1.ConfigMapKeySelector{
+						LocalObjectReference: apiv1.LocalObjectReference{Name: item.ConfigMap},
+						Key:                  key,
+					}}
+				} else if item.Secret != "" {
+					env.Value = ""
+					env.ValueFrom = &apiv1.EnvVarSource{SecretKeyRef: &apiv1.SecretKeySelector{
+						LocalObjectReference: apiv1.LocalObjectReference{Name: item.Secret},
+						Key:                  key,
+					}}
+				}
+				envs = append(envs, env)
+				continue
+			}
+			// the key is mounted as a single file, the other files of the directory are kept
+			fileName := path.Base(item.MountPath)
+			volume := apiv1.Volume{Name: fmt.Sprintf("%s%s-%d", configSetVolumePrefix, name, i)}
+			keys := []apiv1.KeyToPath{{Key: key, Path: fileName}}
+			if item.ConfigMap != "" {
+				volume.ConfigMap = &apiv1.ConfigMapVolumeSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: item.ConfigMap},
+					Items:                keys,
+				}
+			} else {
+				volume.Secret = &apiv1.SecretVolumeSource{SecretName: item.Secret, Items: keys}
+			}
+			volumes = append(volumes, volume)
+			mounts = append(mounts, apiv1.VolumeMount{
+				Name:      volume.Name,
+				MountPath: item.MountPath,
+				SubPath:   fileName,
+				ReadOnly:  true,
+			})
+		}
+	}
+	containers := make([]apiv1.Container, len(podSpec.Containers))
+	copy(containers, podSpec.Containers)
+	for i := range containers {
+		existed := make(map[string]bool)
+		for _, env := range containers[i].Env {
+			existed[env.Name] = true
+		}
+		containerEnvs := append([]apiv1.EnvVar{}, containers[i].Env...)
+		for _, env := range envs {
+			if !existed[env.Name] {
+				containerEnvs = append(containerEnvs, env)
+				existed[env.Name] = true
+			}
+		}
+		containers[i].Env = containerEnvs
+		containers[i].VolumeMounts = append(append([]apiv1.VolumeMount{}, containers[i].VolumeMounts...), mounts...)
+	}
+	podSpec.Containers = containers
+	podSpec.Volumes = volumes
+	return nil
+}
@@ -0,0 +1,222 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DependsOnAnnotationKey is set on the application object of template, its value is the comma separated
+	// names of the applications of the same template which must be ready before the application is deployed
+	DependsOnAnnotationKey = "kubecloud/depends-on"
+
+	defaultDependencyTimeout = 10 * time.Minute
+	dependencyPollInterval   = 5 * time.Second
+
+	dependencyGateAvailable = "wait until available"
+	dependencyGateCompleted = "wait until completed"
+
+	appDependencyComponent = "kubecloud-deployer"
+	// the reasons of the events which record the progress of deploying the applications with dependencies
+	AppDependencyWaitingReason  = "WaitingDependencies"
+	AppDependencyDeployedReason = "DependenciesReady"
+	AppDependencyFailedReason   = "DependenciesFailed"
+)
+
+// GetAppDependencies returns the applications which template depends on
+func GetAppDependencies(template AppTemplate) []string {
+	tpl, ok := template.(*NativeAppTemplate)
+	if !ok {
+		return nil
+	}
+	accessor, ok := tpl.appObject().(metav1.Object)
+	if !ok {
+		return nil
+	}
+	deps := []string{}
+	existed := make(map[string]bool)
+	for _, name := range strings.Split(accessor.GetAnnotations()[DependsOnAnnotationKey], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || existed[name] {
+			continue
+		}
+		existed[name] = true
+		deps = append(deps, name)
+	}
+	return deps
+}
+
+// SortAppTemplates sorts the templates in topological order of their dependencies, the templates of a batch
+// depend on the ones of the former batches only, so they can be deployed in parallel
+func SortAppTemplates(tplList []AppTemplate) ([][]AppTemplate, error) {
+	tplMap := make(map[string]AppTemplate)
+	for _, tpl := range tplList {
+		tplMap[tpl.GetAppName()] = tpl
+	}
+	inDegree := make(map[string]int)
+	dependents := make(map[string][]string)
+	for _, tpl := range tplList {
+		name := tpl.GetAppName()
+		for _, dep := range GetAppDependencies(tpl) {
+			if dep == name {
+				return nil, fmt.Errorf("application %s can not depend on itself!", name)
+			}
+			if _, ok := tplMap[dep]; !ok {
+				return nil, fmt.Errorf("application %s depends on %s, which is not in the template!", name, dep)
+			}
+			inDegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+	batches := [][]AppTemplate{}
+	current := []string{}
+	for _, tpl := range tplList {
+		if inDegree[tpl.GetAppName()] == 0 {
+			current = append(current, tpl.GetAppName())
+		}
+	}
+	sorted := 0
+	for len(current) != 0 {
+		batch := []AppTemplate{}
+		next := []string{}
+		for _, name := range current {
+			batch = append(batch, tplMap[name])
+			for _, dependent := range dependents[name] {
+				inDegree[dependent]--
+				if inDegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		sorted += len(batch)
+		batches = append(batches, batch)
+		sort.Strings(next)
+		current = next
+	}
+	if sorted != len(tplList) {
+		cycle := []string{}
+		for name, degree := range inDegree {
+			if degree > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("the dependencies of applications %v are circular!", cycle)
+	}
+	return batches, nil
+}
+
+// AppDependencyGraph returns the dependency graph of the templates, the edge is from the dependency
+// to the application which depends on it, and its label is the readiness gate of the dependency
+func AppDependencyGraph(tplList []AppTemplate) (*utils.VisGraph, error) {
+	if _, err := SortAppTemplates(tplList); err != nil {
+		return nil, err
+	}
+	graph := utils.NewVisGraph()
+	for _, tpl := range tplList {
+		graph.AddNode(tpl.GetAppName())
+	}
+	kinds := make(map[string]string)
+	for _, tpl := range tplList {
+		kinds[tpl.GetAppName()] = tpl.GetAppKind()
+	}
+	for _, tpl := range tplList {
+		for _, dep := range GetAppDependencies(tpl) {
+			gate := dependencyGateAvailable
+			if kinds[dep] == AppKindJob {
+				gate = dependencyGateCompleted
+			}
+			graph.AddEdge(dep, tpl.GetAppName(), map[string]string{"gate": gate})
+		}
+	}
+	graph.MakeEdgeLabel()
+	return graph, nil
+}
+
+// appIsReady checks the readiness gate of the application, the job must be completed,
+// and all replicas of the other kinds must be updated and available. The error is returned
+// if the application can not be ready any more, such as the failed job
+func appIsReady(kind string, status *AppStatus) (bool, error) {
+	if status.Failed {
+		return false, fmt.Errorf("the %s failed: %s", strings.ToLower(kind), status.Message)
+	}
+	if status.AvailableStatus != string(apiv1.ConditionTrue) {
+		return false, nil
+	}
+	if kind == AppKindJob {
+		return true, nil
+	}
+	return status.UpdatedReplicas >= status.StatusReplicas && status.AvailableReplicas >= status.StatusReplicas, nil
+}
+
+// waitAppReady waits until the deployed application of template passes its readiness gate
+func (ar *AppRes) waitAppReady(namespace string, template AppTemplate) error {
+	timeout := defaultDependencyTimeout
+	if tpl, ok := template.(*NativeAppTemplate); ok && tpl.Config.DependencyTimeout > 0 {
+		timeout = time.Duration(tpl.Config.DependencyTimeout) * time.Second
+	}
+	app, err := ar.Appmodel.GetAppByName(ar.Cluster, namespace, template.GetAppName())
+	if err != nil {
+		return err
+	}
+	handle := NewKubeAppHandle(ar.Client, ar.Cluster, namespace, app.Kind)
+	if handle == nil {
+		return fmt.Errorf("cant support this application kind: %s", app.Kind)
+	}
+	message := "the application is not created"
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := handle.Status(app.Name, app.PodVersion)
+		if err == nil {
+			ready, err := appIsReady(app.Kind, status)
+			if err != nil {
+				return fmt.Errorf("application %s can not be ready: %v", app.Name, err)
+			}
+			if ready {
+				return nil
+			}
+			message = status.Message
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("application %s is not ready in %v: %s", app.Name, timeout, message)
+		}
+		time.Sleep(dependencyPollInterval)
+	}
+}
+
+func recordAppDeployEvents(cluster, namespace string, batch []AppTemplate, eventType, reason, message string) {
+	now, _ := time.Parse("2006-01-02 15:04:05", time.Now().Local().Format("2006-01-02 15:04:05"))
+	for _, tpl := range batch {
+		event := models.ZcloudEvent{
+			EventUid:        utils.NewUUID(),
+			EventType:       eventType,
+			Cluster:         cluster,
+			Namespace:       namespace,
+			SourceComponent: appDependencyComponent,
+			ObjectKind:      tpl.GetAppKind(),
+			ObjectName:      tpl.GetAppName(),
+			Reason:          reason,
+			Message:         message,
+			Count:           1,
+			FirstTimestamp:  now,
+			LastTimestamp:   now,
+		}
+		if err := dao.CreateEvent(event); err != nil {
+			beego.Error(fmt.Sprintf("record event of application %s failed: %v", tpl.GetAppName(), err))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+func TestAppIsReady(t *testing.T) {
+	ready, err := appIsReady(AppKindDeployment, &AppStatus{StatusReplicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1, AvailableStatus: "True"})
+	assert.Nil(t, err)
+	assert.False(t, ready)
+	ready, err = appIsReady(AppKindDeployment, &AppStatus{StatusReplicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2, AvailableStatus: "True"})
+	assert.Nil(t, err)
+	assert.True(t, ready)
+
+	job := &batchv1.Job{}
+	ready, err = appIsReady(AppKindJob, GetJobStatus(job))
+	assert.Nil(t, err)
+	assert.False(t, ready)
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: apiv1.ConditionTrue}}
+	ready, err = appIsReady(AppKindJob, GetJobStatus(job))
+	assert.Nil(t, err)
+	assert.True(t, ready)
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: apiv1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"}}
+	ready, err = appIsReady(AppKindJob, GetJobStatus(job))
+	assert.False(t, ready)
+	assert.EqualError(t, err, "the job failed: BackoffLimitExceeded: Job has reached the specified backoff limit")
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -66,17 +67,21 @@ type AppDetail struct {
 	Ingress []SimpleIngressDetail `json:"ingress,omitempty"`
 	Service ServiceDetail         `json:"service,omitempty"`
 	Pods    []*AppPod             `json:"pods,omitempty"`
+	// only statefulset has the headless service
+	HeadlessService *ServiceDetail    `json:"headless_service,omitempty"`
+	Autoscaler      *AutoscalerDetail `json:"autoscaler,omitempty"`
 }
 
 type AppRes struct {
-	Cluster      string
-	DomainSuffix string
-	Client       kubernetes.Interface
-	Appmodel     *dao.AppModel
-	SvcRes       *ServiceRes
-	IngRes       *IngressRes
-	versionModel *dao.VersionModel
-	listNSFunc   NamespaceListFunction
+	Cluster         string
+	DomainSuffix    string
+	Client          kubernetes.Interface
+	Appmodel        *dao.AppModel
+	SvcRes          *ServiceRes
+	IngRes          *IngressRes
+	versionModel    *dao.VersionModel
+	autoscalerModel *dao.AutoscalerModel
+	listNSFunc      NamespaceListFunction
 }
 
 type AppPodBasicParam struct {
@@ -93,10 +98,11 @@ type AppPodDetail struct {
 func NewAppRes(cluster string, get NamespaceListFunction) (*AppRes, error) {
 	if cluster == "" {
 		return &AppRes{
-			Cluster:      cluster,
-			Appmodel:     dao.NewAppModel(),
-			versionModel: dao.NewVersionModel(),
-			listNSFunc:   get,
+			Cluster:         cluster,
+			Appmodel:        dao.NewAppModel(),
+			versionModel:    dao.NewVersionModel(),
+			autoscalerModel: dao.NewAutoscalerModel(),
+			listNSFunc:      get,
 		}, nil
 	}
 	clusterInfo, err := dao.GetCluster(cluster)
@@ -113,14 +119,15 @@ func NewAppRes(cluster string, get NamespaceListFunction) (*AppRes, error) {
 	svc := NewServiceRes(cluster, nil)
 	ing, _ := NewIngressRes(cluster, client, nil)
 	return &AppRes{
-		Cluster:      cluster,
-		DomainSuffix: clusterInfo.DomainSuffix,
-		Appmodel:     dao.NewAppModel(),
-		versionModel: dao.NewVersionModel(),
-		listNSFunc:   get,
-		Client:       client,
-		SvcRes:       svc,
-		IngRes:       ing,
+		Cluster:         cluster,
+		DomainSuffix:    clusterInfo.DomainSuffix,
+		Appmodel:        dao.NewAppModel(),
+		versionModel:    dao.NewVersionModel(),
+		autoscalerModel: dao.NewAutoscalerModel(),
+		listNSFunc:      get,
+		Client:          client,
+		SvcRes:          svc,
+		IngRes:          ing,
 	}, nil
 }
 
@@ -216,6 +223,16 @@ func (ar *AppRes) GetAppDetail(namespace, name string) (*AppDetail, error) {
 	} else {
 		detail.Service = svc
 	}
+	if app.Kind == AppKindStatefulSet {
+		if svc, err := ar.SvcRes.GetHeadlessSvcDetail(namespace, name); err == nil && svc.Name != "" {
+			detail.HeadlessService = &svc
+		}
+	}
+	if autoscaler, existed, err := autoscalerExisted(ar.autoscalerModel, ar.Cluster, namespace, name); err != nil {
+		beego.Warn("get autoscaler failed:", err)
+	} else if existed {
+		detail.Autoscaler = ar.getAutoscalerDetail(autoscaler)
+	}
 	return &detail, nil
 }
 
@@ -224,7 +241,7 @@ func (ar *AppRes) GetAppPodStatus(namespace, appName, podName string) (interface
 	if err != nil {
 		return "", err
 	}
-	pod, err := ar.Client.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
+	pod, err := ar.Client.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
 	if err != nil {
 		return "", err
 	}
@@ -241,7 +258,7 @@ func (ar *AppRes) GetAppPodLog(namespace, appName, podName, containerName string
 	body, err := ar.Client.CoreV1().Pods(namespace).GetLogs(podName, &apiv1.PodLogOptions{
 		Container: containerName,
 		TailLines: &tailLines,
-	}).Do().Raw()
+	}).Do(context.TODO()).Raw()
 
 	if err != nil {
 		return "", err
@@ -262,6 +279,9 @@ func (ar *AppRes) InstallApp(projectid int64,
 		return common.NewBadRequest().SetCause(err)
 	}
 	if err := template.Default(ar.Cluster).Deploy(projectid, ar.Cluster, namespace, tname, eparam); err != nil {
+		if e, ok := err.(*common.Error); ok {
+			return e
+		}
 		return common.NewInternalServerError().SetCause(err)
 	}
 	return nil
@@ -277,7 +297,7 @@ func (ar *AppRes) UninstallApp(app models.ZcloudApplication) error {
 	}
 	kr := NewKubeAppRes(ar.Client, ar.Cluster, app.Namespace, ar.DomainSuffix, app.Kind)
 
-	return kr.DeleteAppResource(template, app.PodVersion)
+	return kr.DeleteAppResource(template, app.PodVersion, true)
 }
 
 func (ar *AppRes) DeleteApp(namespace, appname string) error {
@@ -293,6 +313,14 @@ func (ar *AppRes) DeleteApp(namespace, appname string) error {
 	if err != nil {
 		return err
 	}
+	if err = ar.DeleteAutoscaler(namespace, appname); err != nil {
+		beego.Warn("delete application autoscaler failed: "+err.Error(),
+			"namespace: "+namespace, "appname: "+appname)
+	}
+	if err = ReleasePortExposures(ar.Cluster, namespace, appname); err != nil {
+		beego.Warn("release application port exposures failed: "+err.Error(),
+			"namespace: "+namespace, "appname: "+appname)
+	}
 	err = ar.Appmodel.DeleteApp(*app)
 	if err == nil {
 		// delete version info
@@ -321,6 +349,13 @@ func (ar *AppRes) Restart(namespace, appname string) error {
 }
 
 func (ar *AppRes) ReconfigureApp(app models.ZcloudApplication, template AppTemplate) (*AppDetail, error) {
+	if err := CheckAdmissionPolicies(ar.Cluster, app.Namespace, template); err != nil {
+		return nil, err
+	}
+	template, err := ar.keepAutoscaledReplicas(&app, template)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
 	kr := NewKubeAppRes(ar.Client, ar.Cluster, app.Namespace, ar.DomainSuffix, app.Kind)
 	exist, err := kr.CheckAppIsExisted(app.Name, app.PodVersion)
 	if err != nil {
@@ -353,6 +388,10 @@ func (ar *AppRes) ReconfigureApp(app models.ZcloudApplication, template AppTempl
 	if err != nil {
 		return nil, common.NewInternalServerError().SetCause(err)
 	}
+	if err := ar.retargetAutoscaler(&app); err != nil {
+		beego.Warn("retarget application autoscaler failed: "+err.Error(),
+			"namespace: "+app.Namespace, "appname: "+app.Name)
+	}
 	appDetail, err := ar.GetAppDetail(app.Namespace, app.Name)
 	if err != nil {
 		return nil, common.NewInternalServerError().SetCause(err)
@@ -376,14 +415,25 @@ func (ar *AppRes) RollingUpdateApp(namespace, appname string, param []ContainerP
 	if err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
+	template = template.Image(param)
+	if err := CheckAdmissionPolicies(ar.Cluster, namespace, template); err != nil {
+		return err
+	}
+	if template, err = ar.keepAutoscaledReplicas(app, template); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
 	kr := NewKubeAppRes(ar.Client, ar.Cluster, namespace, ar.DomainSuffix, app.Kind)
-	if err = kr.UpdateAppResource(app, template.Image(param), nil, false); err != nil {
+	if err = kr.UpdateAppResource(app, template, nil, false); err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
 	beego.Debug(fmt.Sprintf("new image for %s/%s/%s is %s!", ar.Cluster, namespace, appname, app.Image))
 	if err = ar.Appmodel.UpdateApp(app, true); err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
+	if err = ar.retargetAutoscaler(app); err != nil {
+		beego.Warn("retarget application autoscaler failed: "+err.Error(),
+			"namespace: "+namespace, "appname: "+appname)
+	}
 
 	return nil
 }
@@ -447,11 +497,27 @@ func (ar *AppRes) ScaleApp(namespace, appname string, replicas int) error {
 	if err != nil {
 		return err
 	}
+	// the replicas is owned by the autoscaler
+	autoscaler, existed, err := autoscalerExisted(ar.autoscalerModel, ar.Cluster, namespace, appname)
+	if err != nil {
+		return err
+	}
+	if existed {
+		return common.NewConflict().SetCause(fmt.Errorf("the replicas of application %s is managed by its autoscaler(%v~%v), please update the autoscaler instead!",
+			appname, autoscaler.MinReplicas, autoscaler.MaxReplicas))
+	}
+	return ar.scaleApp(item, replicas)
+}
+
+func (ar *AppRes) scaleApp(item *models.ZcloudApplication, replicas int) error {
+	if err := CheckAdmissionReplicas(ar.Cluster, item.Namespace, item.Kind, item.Name, replicas); err != nil {
+		return err
+	}
 	template, err := CreateAppTemplateByApp(*item)
 	if err != nil {
 		return err
 	}
-	kr := NewKubeAppRes(ar.Client, ar.Cluster, namespace, ar.DomainSuffix, item.Kind)
+	kr := NewKubeAppRes(ar.Client, ar.Cluster, item.Namespace, ar.DomainSuffix, item.Kind)
 	if err := kr.Scale(item, template, replicas); err != nil {
 		return err
 	}
@@ -612,12 +678,12 @@ func CreateHarborSecret(cluster, namespace string) error {
 		}
 	}
 	auth, _ := json.Marshal(harborInfo)
-	harborSec, err := client.CoreV1().Secrets(namespace).Get(harborSecretName, metav1.GetOptions{})
+	harborSec, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), harborSecretName, metav1.GetOptions{})
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			return err
 		}
-		_, err = client.CoreV1().Secrets(namespace).Create(&apiv1.Secret{
+		_, err = client.CoreV1().Secrets(namespace).Create(context.TODO(), &apiv1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: namespace,
 				Name:      harborSecretName,
@@ -626,13 +692,13 @@ func CreateHarborSecret(cluster, namespace string) error {
 			Data: map[string][]byte{
 				".dockercfg": auth,
 			},
-		})
+		}, metav1.CreateOptions{})
 	} else {
 		if string(harborSec.Data[".dockercfg"]) == string(auth) {
 			return nil
 		}
 		harborSec.Data = map[string][]byte{".dockercfg": auth}
-		_, err = client.CoreV1().Secrets(namespace).Update(harborSec)
+		_, err = client.CoreV1().Secrets(namespace).Update(context.TODO(), harborSec, metav1.UpdateOptions{})
 	}
 	if err != nil {
 		beego.Warning(fmt.Sprintf("set harbor secret failed: %v", err.Error()))
@@ -10,6 +10,7 @@ import (
 
 	"github.com/astaxie/beego"
 	yamlencoder "github.com/ghodss/yaml"
+	apiv1 "k8s.io/api/core/v1"
 )
 
 const TemplateMaxSize = 1024000 //1000KB
@@ -41,16 +42,90 @@ func CreateAppTemplateByApp(app models.ZcloudApplication) (AppTemplate, error) {
 	return CreateNativeAppTemplate(app, "", nil)
 }
 
-func DeployAppTemplates(appTplList []AppTemplate, projectid int64, cluster, namespace, tname string, eparam *ExtensionParam) error {
+// DeployAppTemplates deploys the templates in the topological order of their dependencies,
+// the applications which others depend on must be ready before the next batch is deployed.
+// The first batch is deployed before returning, and the later batches are deployed in background,
+// their progress is recorded as the events of the applications. So nil is returned before the
+// deployment is finished, the callers which record its result should wait for the Finished of eparam
+func DeployAppTemplates(appTplList []AppTemplate, projectid int64, cluster, namespace, tname string, eparam *ExtensionParam) (err error) {
+	background := false
+	defer func() {
+		if !background {
+			eparam.finish(err)
+		}
+	}()
 	if len(appTplList) == 0 {
 		return nil
 	}
-	errInfoList := []string{}
-	var workers []*DeployWorker
+	batches, err := SortAppTemplates(appTplList)
+	if err != nil {
+		return err
+	}
 	ar, err := NewAppRes(cluster, nil)
 	if err != nil {
 		return err
 	}
+	dependencies := make(map[string]bool)
+	for _, tpl := range appTplList {
+		for _, dep := range GetAppDependencies(tpl) {
+			dependencies[dep] = true
+		}
+	}
+	if err := deployAppTemplateBatch(ar, batches[0], namespace, tname, eparam); err != nil {
+		return skipAppTemplates(err, batches[1:])
+	}
+	if len(batches) > 1 {
+		for _, batch := range batches[1:] {
+			recordAppDeployEvents(cluster, namespace, batch, apiv1.EventTypeNormal, AppDependencyWaitingReason,
+				"the application is waiting for its dependencies to be ready")
+		}
+		background = true
+		go func() {
+			eparam.finish(deployDependentAppTemplates(ar, batches, namespace, tname, eparam, dependencies))
+		}()
+	}
+	return nil
+}
+
+// deployDependentAppTemplates deploys the batches after the first one, each batch is deployed after
+// the applications of the former batch which others depend on are ready
+func deployDependentAppTemplates(ar *AppRes, batches [][]AppTemplate, namespace, tname string, eparam *ExtensionParam, dependencies map[string]bool) error {
+	for i := 1; i < len(batches); i++ {
+		errInfoList := []string{}
+		for _, res := range waitAppTemplatesReady(ar, batches[i-1], namespace, dependencies) {
+			if res.Result != nil {
+				errInfoList = append(errInfoList, res.AppName+":"+res.Result.Error())
+				beego.Error(res.Result)
+			}
+		}
+		if len(errInfoList) != 0 {
+			return skipDependentAppTemplates(ar.Cluster, namespace, fmt.Errorf("%s", strings.Join(errInfoList, ";")), batches[i:])
+		}
+		if err := deployAppTemplateBatch(ar, batches[i], namespace, tname, eparam); err != nil {
+			recordAppDeployEvents(ar.Cluster, namespace, batches[i], apiv1.EventTypeWarning, AppDependencyFailedReason,
+				"deploy the application failed: "+err.Error())
+			return skipDependentAppTemplates(ar.Cluster, namespace, err, batches[i+1:])
+		}
+		recordAppDeployEvents(ar.Cluster, namespace, batches[i], apiv1.EventTypeNormal, AppDependencyDeployedReason,
+			"the dependencies of the application are ready, and it is deployed")
+	}
+	return nil
+}
+
+func skipDependentAppTemplates(cluster, namespace string, err error, batches [][]AppTemplate) error {
+	skipErr := skipAppTemplates(err, batches)
+	beego.Error(skipErr)
+	for _, batch := range batches {
+		recordAppDeployEvents(cluster, namespace, batch, apiv1.EventTypeWarning, AppDependencyFailedReason,
+			"the application is not deployed because the applications which are deployed before it failed: "+err.Error())
+	}
+	return skipErr
+}
+
+// deployAppTemplateBatch deploys the templates in parallel, they do not depend on each other
+func deployAppTemplateBatch(ar *AppRes, appTplList []AppTemplate, namespace, tname string, eparam *ExtensionParam) error {
+	errInfoList := []string{}
+	var workers []*DeployWorker
 	workerResult := make(chan WorkerResult)
 	var wg sync.WaitGroup
 	for _, tpl := range appTplList {
@@ -88,6 +163,50 @@ func DeployAppTemplates(appTplList []AppTemplate, projectid int64, cluster, name
 	return nil
 }
 
+// waitAppTemplatesReady waits for the applications of batch which others depend on in parallel
+func waitAppTemplatesReady(ar *AppRes, batch []AppTemplate, namespace string, dependencies map[string]bool) []WorkerResult {
+	results := []WorkerResult{}
+	workerResult := make(chan WorkerResult)
+	var wg sync.WaitGroup
+	for _, tpl := range batch {
+		if !dependencies[tpl.GetAppName()] {
+			continue
+		}
+		wg.Add(1)
+		go func(app AppTemplate) {
+			defer wg.Done()
+			beego.Info("waiting for application " + app.GetAppName() + " to be ready...")
+			workerResult <- WorkerResult{
+				AppName:    app.GetAppName(),
+				AppVersion: app.GetAppVersion(),
+				AppKind:    app.GetAppKind(),
+				Result:     ar.waitAppReady(namespace, app),
+			}
+		}(tpl)
+	}
+	go func() {
+		wg.Wait()
+		close(workerResult)
+	}()
+	for res := range workerResult {
+		results = append(results, res)
+	}
+	return results
+}
+
+func skipAppTemplates(err error, batches [][]AppTemplate) error {
+	skipped := []string{}
+	for _, batch := range batches {
+		for _, tpl := range batch {
+			skipped = append(skipped, tpl.GetAppName())
+		}
+	}
+	if len(skipped) == 0 {
+		return err
+	}
+	return fmt.Errorf("%v; applications %v are not deployed because the applications which are deployed before them failed", err, skipped)
+}
+
 func AppTemplateToYamlString(tpl AppTemplate, cluster, namespace, podVersion, domainSuffix string) (string, error) {
 	objs, err := tpl.GenerateKubeObject(cluster, namespace, podVersion, domainSuffix)
 	if err != nil && objs == nil {
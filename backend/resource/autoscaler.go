@@ -0,0 +1,307 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/backend/util/labels"
+	"kubecloud/common"
+	"kubecloud/common/keyword"
+	"kubecloud/gitops"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type AutoscalerParam struct {
+	MinReplicas int `json:"min_replicas"`
+	MaxReplicas int `json:"max_replicas"`
+	// the average utilization of the requests, 0 means not used
+	TargetCPUUtilization    int `json:"target_cpu_utilization,omitempty"`
+	TargetMemoryUtilization int `json:"target_memory_utilization,omitempty"`
+	// custom metrics, such as pods or external metrics
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
+}
+
+type AutoscalerDetail struct {
+	AutoscalerParam
+	Name            string                       `json:"name"`
+	CurrentReplicas int32                        `json:"current_replicas"`
+	DesiredReplicas int32                        `json:"desired_replicas"`
+	LastScaleTime   string                       `json:"last_scale_time,omitempty"`
+	CurrentMetrics  []autoscalingv2.MetricStatus `json:"current_metrics,omitempty"`
+	Message         string                       `json:"message,omitempty"`
+}
+
+func (param AutoscalerParam) Validate() error {
+	if param.MinReplicas < 1 || param.MaxReplicas > common.ReplicasMax {
+		return fmt.Errorf("replicas of autoscaler must be in the range of %v to %v!", 1, common.ReplicasMax)
+	}
+	if param.MinReplicas > param.MaxReplicas {
+		return fmt.Errorf("min replicas %v must not be greater than max replicas %v!", param.MinReplicas, param.MaxReplicas)
+	}
+	if param.TargetCPUUtilization < 0 || param.TargetMemoryUtilization < 0 {
+		return fmt.Errorf("target utilization must not be negative!")
+	}
+	if param.TargetCPUUtilization == 0 && param.TargetMemoryUtilization == 0 && len(param.Metrics) == 0 {
+		return fmt.Errorf("at least one of cpu, memory or custom metrics must be given!")
+	}
+	for _, metric := range param.Metrics {
+		switch metric.Type {
+		case autoscalingv2.ObjectMetricSourceType, autoscalingv2.PodsMetricSourceType,
+			autoscalingv2.ExternalMetricSourceType, autoscalingv2.ContainerResourceMetricSourceType:
+		case autoscalingv2.ResourceMetricSourceType:
+			return fmt.Errorf("resource metric should be set by target_cpu_utilization or target_memory_utilization!")
+		default:
+			return fmt.Errorf("metric type %s is not supported!", metric.Type)
+		}
+	}
+	return nil
+}
+
+// metricSpecs returns the metrics of the autoscaler, cpu and memory are the resource metrics
+func (param AutoscalerParam) metricSpecs() []autoscalingv2.MetricSpec {
+	metrics := []autoscalingv2.MetricSpec{}
+	utilization := func(name apiv1.ResourceName, value int) autoscalingv2.MetricSpec {
+		target := int32(value)
+		return autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: name,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: &target,
+				},
+			},
+		}
+	}
+	if param.TargetCPUUtilization > 0 {
+		metrics = append(metrics, utilization(apiv1.ResourceCPU, param.TargetCPUUtilization))
+	}
+	if param.TargetMemoryUtilization > 0 {
+		metrics = append(metrics, utilization(apiv1.ResourceMemory, param.TargetMemoryUtilization))
+	}
+	return append(metrics, param.Metrics...)
+}
+
+func autoscalerToParam(autoscaler *models.ZcloudAutoscaler) AutoscalerParam {
+	param := AutoscalerParam{
+		MinReplicas:             autoscaler.MinReplicas,
+		MaxReplicas:             autoscaler.MaxReplicas,
+		TargetCPUUtilization:    autoscaler.TargetCPUUtilization,
+		TargetMemoryUtilization: autoscaler.TargetMemoryUtilization,
+	}
+	if autoscaler.Metrics != "" {
+		if err := json.Unmarshal([]byte(autoscaler.Metrics), &param.Metrics); err != nil {
+			beego.Warn(fmt.Sprintf("decode metrics of autoscaler %s/%s failed: %v", autoscaler.Namespace, autoscaler.Name, err))
+		}
+	}
+	return param
+}
+
+// GenAutoscalerObject generates the autoscaling/v2 object which scales the workload of the application
+func GenAutoscalerObject(autoscaler *models.ZcloudAutoscaler) *autoscalingv2.HorizontalPodAutoscaler {
+	param := autoscalerToParam(autoscaler)
+	minReplicas := int32(param.MinReplicas)
+	kind := "Deployment"
+	if autoscaler.Kind == AppKindStatefulSet {
+		kind = "StatefulSet"
+	}
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      autoscaler.Target,
+			Namespace: autoscaler.Namespace,
+			Labels: map[string]string{
+				keyword.LABEL_APPNAME_KEY: autoscaler.Name,
+			},
+			Annotations: map[string]string{
+				OwnerNameAnnotationKey: autoscaler.Name,
+			},
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: AppAPIVersion,
+				Kind:       kind,
+				Name:       autoscaler.Target,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: int32(param.MaxReplicas),
+			Metrics:     param.metricSpecs(),
+		},
+	}
+}
+
+func (ar *AppRes) GetAutoscaler(namespace, appname string) (*AutoscalerDetail, error) {
+	autoscaler, err := ar.autoscalerModel.GetAutoscaler(ar.Cluster, namespace, appname)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(fmt.Errorf("application %s has no autoscaler!", appname))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return ar.getAutoscalerDetail(autoscaler), nil
+}
+
+func (ar *AppRes) SetAutoscaler(namespace, appname string, param AutoscalerParam) (*AutoscalerDetail, error) {
+	app, err := ar.Appmodel.GetAppByName(ar.Cluster, namespace, appname)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(err)
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if app.Kind != AppKindDeployment && app.Kind != AppKindStatefulSet {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("%s application can not be autoscaled!", app.Kind))
+	}
+	if kubeutil.GetAPIVersions(ar.Cluster, ar.Client.Discovery()).HorizontalPodAutoscaler == "" {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("cluster %s does not support autoscaling/v2!", ar.Cluster))
+	}
+	if err := param.Validate(); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	if err := CheckAdmissionReplicas(ar.Cluster, namespace, app.Kind, appname, param.MaxReplicas); err != nil {
+		return nil, err
+	}
+	autoscaler := &models.ZcloudAutoscaler{
+		Cluster:                 ar.Cluster,
+		Namespace:               namespace,
+		Name:                    appname,
+		Kind:                    app.Kind,
+		Target:                  GenerateDeployName(app.Name, app.PodVersion),
+		MinReplicas:             param.MinReplicas,
+		MaxReplicas:             param.MaxReplicas,
+		TargetCPUUtilization:    param.TargetCPUUtilization,
+		TargetMemoryUtilization: param.TargetMemoryUtilization,
+	}
+	if len(param.Metrics) != 0 {
+		metrics, err := json.Marshal(param.Metrics)
+		if err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+		autoscaler.Metrics = string(metrics)
+	}
+	// the replicas of the template should be in the range, or it will be reset by each deploying
+	replicas := app.Replicas
+	if replicas < param.MinReplicas {
+		replicas = param.MinReplicas
+	} else if replicas > param.MaxReplicas {
+		replicas = param.MaxReplicas
+	}
+	if replicas != app.Replicas {
+		if err := ar.scaleApp(app, replicas); err != nil {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+	}
+	if err := ar.autoscalerModel.SetAutoscaler(autoscaler); err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	go gitops.CommitK8sResource(ar.Cluster, []interface{}{GenAutoscalerObject(autoscaler)})
+	return ar.getAutoscalerDetail(autoscaler), nil
+}
+
+func (ar *AppRes) DeleteAutoscaler(namespace, appname string) error {
+	autoscaler, err := ar.autoscalerModel.GetAutoscaler(ar.Cluster, namespace, appname)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	hpa := GenAutoscalerObject(autoscaler)
+	hpa.Annotations = labels.AddLabel(hpa.Annotations, keyword.DELETE_LABLE, keyword.DELETE_LABLE_VALUE)
+	go gitops.CommitK8sResource(ar.Cluster, []interface{}{hpa})
+	beego.Warn(fmt.Sprintf("delete autoscaler %s successfully!", hpa.Name))
+	return ar.autoscalerModel.DeleteAutoscaler(ar.Cluster, namespace, appname)
+}
+
+// keepAutoscaledReplicas sets the replicas of the template to the replicas desired by the autoscaler,
+// so that updating the application does not reset the replicas scaled by the autoscaler
+func (ar *AppRes) keepAutoscaledReplicas(app *models.ZcloudApplication, template AppTemplate) (AppTemplate, error) {
+	autoscaler, existed, err := autoscalerExisted(ar.autoscalerModel, ar.Cluster, app.Namespace, app.Name)
+	if err != nil || !existed || (app.Kind != AppKindDeployment && app.Kind != AppKindStatefulSet) {
+		return template, err
+	}
+	// the live replicas of the workload is used if the autoscaler is not working yet
+	replicas := app.Replicas
+	hpa, err := kubeutil.GetHorizontalPodAutoscaler(ar.Client, kubeutil.GetAPIVersions(ar.Cluster, ar.Client.Discovery()), autoscaler.Namespace, autoscaler.Target)
+	if err == nil && hpa.Status.DesiredReplicas > 0 {
+		replicas = int(hpa.Status.DesiredReplicas)
+	} else if status, err := NewKubeAppHandle(ar.Client, ar.Cluster, app.Namespace, app.Kind).Status(app.Name, app.PodVersion); err == nil && status.StatusReplicas > 0 {
+		replicas = int(status.StatusReplicas)
+	}
+	if replicas < autoscaler.MinReplicas {
+		replicas = autoscaler.MinReplicas
+	} else if replicas > autoscaler.MaxReplicas {
+		replicas = autoscaler.MaxReplicas
+	}
+	return template.Replicas(replicas), nil
+}
+
+// retargetAutoscaler moves the autoscaler to the workload of the current pod version of the application,
+// the autoscaler is named by its target, so the old one is deleted and the new one is created
+func (ar *AppRes) retargetAutoscaler(app *models.ZcloudApplication) error {
+	autoscaler, existed, err := autoscalerExisted(ar.autoscalerModel, ar.Cluster, app.Namespace, app.Name)
+	if err != nil || !existed {
+		return err
+	}
+	target := GenerateDeployName(app.Name, app.PodVersion)
+	if autoscaler.Target == target {
+		return nil
+	}
+	old := GenAutoscalerObject(autoscaler)
+	old.Annotations = labels.AddLabel(old.Annotations, keyword.DELETE_LABLE, keyword.DELETE_LABLE_VALUE)
+	autoscaler.Target = target
+	if err := ar.autoscalerModel.SetAutoscaler(autoscaler); err != nil {
+		return err
+	}
+	go gitops.CommitK8sResource(ar.Cluster, []interface{}{old, GenAutoscalerObject(autoscaler)})
+	beego.Info(fmt.Sprintf("autoscaler %s is retargeted to %s!", old.Name, target))
+	return nil
+}
+
+// getAutoscalerDetail returns the autoscaler with its status in the cluster
+func (ar *AppRes) getAutoscalerDetail(autoscaler *models.ZcloudAutoscaler) *AutoscalerDetail {
+	detail := &AutoscalerDetail{
+		AutoscalerParam: autoscalerToParam(autoscaler),
+		Name:            autoscaler.Target,
+	}
+	hpa, err := kubeutil.GetHorizontalPodAutoscaler(ar.Client, kubeutil.GetAPIVersions(ar.Cluster, ar.Client.Discovery()), autoscaler.Namespace, autoscaler.Target)
+	if err != nil {
+		detail.Message = err.Error()
+		return detail
+	}
+	detail.CurrentReplicas = hpa.Status.CurrentReplicas
+	detail.DesiredReplicas = hpa.Status.DesiredReplicas
+	detail.CurrentMetrics = hpa.Status.CurrentMetrics
+	if hpa.Status.LastScaleTime != nil {
+		detail.LastScaleTime = hpa.Status.LastScaleTime.Format("2006-01-02 15:04:05")
+	}
+	for _, condition := range hpa.Status.Conditions {
+		// the message of the abnormal condition is more useful
+		abnormal := condition.Status == apiv1.ConditionFalse
+		if condition.Type == autoscalingv2.ScalingLimited {
+			abnormal = condition.Status == apiv1.ConditionTrue
+		}
+		if abnormal {
+			detail.Message = condition.Message
+		}
+	}
+	return detail
+}
+
+func autoscalerExisted(model *dao.AutoscalerModel, cluster, namespace, appname string) (*models.ZcloudAutoscaler, bool, error) {
+	autoscaler, err := model.GetAutoscaler(cluster, namespace, appname)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return autoscaler, true, nil
+}
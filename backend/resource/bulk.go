@@ -0,0 +1,222 @@
+package resource
+
+import (
+	"fmt"
+
+	"kubecloud/common"
+	"kubecloud/common/keyword"
+	"kubecloud/common/utils"
+	"kubecloud/common/validate"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+const (
+	BulkActionRestart       = "restart"
+	BulkActionScale         = "scale"
+	BulkActionRollingUpdate = "rollingupdate"
+	BulkActionDelete        = "delete"
+	BulkActionSetLabels     = "setlabels"
+
+	bulkConcurrencyDefault = 10
+	bulkConcurrencyMax     = 50
+)
+
+type BulkOperation struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	App       string `json:"app"`
+	Action    string `json:"action"`
+	// Replicas is used by scale
+	Replicas *int `json:"replicas,omitempty"`
+	// Containers is used by rolling update
+	Containers []ContainerParam `json:"containers,omitempty"`
+	// Labels is used by set labels
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type BulkRequest struct {
+	// only the operations are validated if it is true
+	DryRun      bool            `json:"dry_run"`
+	Concurrency int             `json:"concurrency"`
+	Operations  []BulkOperation `json:"operations"`
+}
+
+type BulkResult struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	App       string `json:"app"`
+	Action    string `json:"action"`
+	Success   bool   `json:"success"`
+	// Status is the http status of the failed operation
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type bulkItem struct {
+	index int
+	op    BulkOperation
+	ar    *AppRes
+}
+
+// BulkOperate validates all of the operations and then runs the valid ones with bounded concurrency,
+// the failure of an operation does not stop the others, the results are in the order of the operations
+func BulkOperate(req BulkRequest) ([]BulkResult, error) {
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = bulkConcurrencyDefault
+	}
+	if concurrency > bulkConcurrencyMax {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("concurrency must not be greater than %v!", bulkConcurrencyMax))
+	}
+	results := make([]BulkResult, len(req.Operations))
+	// the handler of each cluster is shared by its operations
+	handlers := make(map[string]*AppRes)
+	handlerErrs := make(map[string]error)
+	items := []interface{}{}
+	for i, op := range req.Operations {
+		results[i] = BulkResult{Cluster: op.Cluster, Namespace: op.Namespace, App: op.App, Action: op.Action}
+		if op.Cluster == "" || op.Namespace == "" || op.App == "" {
+			setBulkResult(&results[i], common.NewBadRequest().SetCause(fmt.Errorf("cluster, namespace and app must be given!")))
+			continue
+		}
+		ar, ok := handlers[op.Cluster]
+		if !ok {
+			if _, failed := handlerErrs[op.Cluster]; !failed {
+				var err error
+				if ar, err = NewAppRes(op.Cluster, nil); err != nil {
+					handlerErrs[op.Cluster] = err
+				} else {
+					handlers[op.Cluster] = ar
+				}
+			}
+		}
+		if err, failed := handlerErrs[op.Cluster]; failed {
+			setBulkResult(&results[i], err)
+			continue
+		}
+		items = append(items, bulkItem{index: i, op: op, ar: ar})
+	}
+
+	valid := []interface{}{}
+	for i, ret := range utils.GoEach(items, func(data interface{}) interface{} {
+		item := data.(bulkItem)
+		return validateBulkOperation(item.ar, item.op)
+	}, concurrency) {
+		item := items[i].(bulkItem)
+		if ret != nil {
+			setBulkResult(&results[item.index], ret.(error))
+			continue
+		}
+		valid = append(valid, item)
+	}
+	if req.DryRun {
+		for _, data := range valid {
+			setBulkResult(&results[data.(bulkItem).index], nil)
+		}
+		return results, nil
+	}
+
+	for i, ret := range utils.GoEach(valid, func(data interface{}) interface{} {
+		item := data.(bulkItem)
+		if err := runBulkOperation(item.ar, item.op); err != nil {
+			beego.Error(fmt.Sprintf("%s application(%s/%s/%s) failed: %v", item.op.Action, item.op.Cluster, item.op.Namespace, item.op.App, err))
+			return err
+		}
+		beego.Info(fmt.Sprintf("%s application(%s/%s/%s) successfully!", item.op.Action, item.op.Cluster, item.op.Namespace, item.op.App))
+		return nil
+	}, concurrency) {
+		err, _ := ret.(error)
+		setBulkResult(&results[valid[i].(bulkItem).index], err)
+	}
+	return results, nil
+}
+
+func validateBulkOperation(ar *AppRes, op BulkOperation) error {
+	app, err := ar.Appmodel.GetAppByName(ar.Cluster, op.Namespace, op.App)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return common.NewNotFound().SetCause(fmt.Errorf(`application "%s" not found!`, op.App))
+		} else if err == orm.ErrMultiRows {
+			return common.NewConflict().SetCause(fmt.Errorf(`application "%s" not unique!`, op.App))
+		}
+		return common.NewInternalServerError().SetCause(err)
+	}
+	switch op.Action {
+	case BulkActionRestart:
+		if app.Kind == AppKindCronJob {
+			return common.NewBadRequest().SetCause(fmt.Errorf("%s application can not be restarted!", app.Kind))
+		}
+	case BulkActionScale:
+		if op.Replicas == nil || *op.Replicas < common.ReplicasMin || *op.Replicas > common.ReplicasMax {
+			return common.NewBadRequest().SetCause(fmt.Errorf("replicas must be in the range of %v to %v!", common.ReplicasMin, common.ReplicasMax))
+		}
+		if app.Kind != AppKindDeployment && app.Kind != AppKindStatefulSet {
+			return common.NewBadRequest().SetCause(fmt.Errorf("%s application can not be scaled!", app.Kind))
+		}
+		autoscaler, existed, err := autoscalerExisted(ar.autoscalerModel, ar.Cluster, op.Namespace, op.App)
+		if err != nil {
+			return common.NewInternalServerError().SetCause(err)
+		}
+		if existed {
+			return common.NewConflict().SetCause(fmt.Errorf("the replicas of application %s is managed by its autoscaler(%v~%v)!",
+				op.App, autoscaler.MinReplicas, autoscaler.MaxReplicas))
+		}
+	case BulkActionRollingUpdate:
+		if len(op.Containers) == 0 {
+			return common.NewBadRequest().SetCause(fmt.Errorf("containers must be given!"))
+		}
+		if err := CheckImageValidate(op.Containers); err != nil {
+			return err
+		}
+		for _, ctn := range op.Containers {
+			if err := HarborEnsureImageUrl(ctn.Image); err != nil {
+				return err
+			}
+		}
+	case BulkActionSetLabels:
+		if err := validate.ValidateLabels(keyword.K8S_RESOURCE_TYPE_APP, op.Labels); err != nil {
+			return common.NewBadRequest().SetCause(err)
+		}
+	case BulkActionDelete:
+	default:
+		return common.NewBadRequest().SetCause(fmt.Errorf("action %q is not supported!", op.Action))
+	}
+	return nil
+}
+
+func runBulkOperation(ar *AppRes, op BulkOperation) error {
+	switch op.Action {
+	case BulkActionRestart:
+		return ar.Restart(op.Namespace, op.App)
+	case BulkActionScale:
+		return ar.ScaleApp(op.Namespace, op.App, *op.Replicas)
+	case BulkActionRollingUpdate:
+		return ar.RollingUpdateApp(op.Namespace, op.App, op.Containers)
+	case BulkActionDelete:
+		return ar.DeleteApp(op.Namespace, op.App)
+	case BulkActionSetLabels:
+		return ar.SetLabels(op.Namespace, op.App, op.Labels)
+	}
+	return common.NewBadRequest().SetCause(fmt.Errorf("action %q is not supported!", op.Action))
+}
+
+func setBulkResult(result *BulkResult, err error) {
+	if err == nil {
+		result.Success = true
+		return
+	}
+	result.Success = false
+	if e, ok := err.(*common.Error); ok {
+		result.Status = e.Status()
+		if e.Cause() != nil {
+			result.Message = e.Cause().Error()
+		} else {
+			result.Message = e.Message()
+		}
+		return
+	}
+	result.Status = common.NewInternalServerError().Status()
+	result.Message = err.Error()
+}
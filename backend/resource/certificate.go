@@ -0,0 +1,207 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/backend/service"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	CertificateReasonExpiring = "CertificateExpiring"
+	CertificateReasonExpired  = "CertificateExpired"
+
+	certificateComponent = "certificate-monitor"
+	// the expiring certificates are alerted again after a day until they are renewed
+	certificateAlertInterval      = 24 * time.Hour
+	defaultCertificateWarningDays = 30
+)
+
+type CertificateIngressRule struct {
+	ID          int64  `json:"id"`
+	IngressName string `json:"ingress_name"`
+	Host        string `json:"host"`
+	Path        string `json:"path"`
+}
+
+// Certificate is the certificate of a tls secret and the ingress rules referencing it
+type Certificate struct {
+	Cluster     string   `json:"cluster"`
+	Namespace   string   `json:"namespace"`
+	SecretName  string   `json:"secret_name"`
+	Subject     string   `json:"subject,omitempty"`
+	DNSNames    []string `json:"dns_names,omitempty"`
+	IPAddresses []string `json:"ip_addresses,omitempty"`
+	Issuer      string   `json:"issuer,omitempty"`
+	NotBefore   string   `json:"not_before,omitempty"`
+	NotAfter    string   `json:"not_after,omitempty"`
+	// DaysLeft is the days before the certificate expires, it is negative if the certificate is expired
+	DaysLeft     int                      `json:"days_left"`
+	Error        string                   `json:"error,omitempty"`
+	IngressRules []CertificateIngressRule `json:"ingress_rules"`
+	notAfter     time.Time
+}
+
+// ListCertificates returns the certificates of the tls secrets of cluster sorted by expiry, the certificates
+// of all clusters are returned if cluster is empty. Only the certificates expiring within expireWithin days are
+// returned if it is positive, and the certificates which can not be parsed are the last ones otherwise
+func ListCertificates(cluster string, nslist []string, expireWithin int) ([]Certificate, error) {
+	secrets, err := dao.NewSecretModel().GetSecretsByType(cluster, nslist, string(apiv1.SecretTypeTLS))
+	if err != nil {
+		return nil, err
+	}
+	rules, err := dao.NewIngressRuleModel().ListTLSRules(cluster, nslist)
+	if err != nil {
+		return nil, err
+	}
+	ruleMap := make(map[string][]CertificateIngressRule)
+	for _, rule := range rules {
+		key := rule.Cluster + "/" + rule.Namespace + "/" + rule.SecretName
+		ruleMap[key] = append(ruleMap[key], CertificateIngressRule{
+			ID:          rule.Id,
+			IngressName: rule.IngressName,
+			Host:        rule.Host,
+			Path:        utils.GetRootPath(rule.Path),
+		})
+	}
+	certs := []Certificate{}
+	for _, secret := range secrets {
+		cert := parseSecretCertificate(secret)
+		cert.IngressRules = ruleMap[secret.Cluster+"/"+secret.Namespace+"/"+secret.Name]
+		if cert.IngressRules == nil {
+			cert.IngressRules = []CertificateIngressRule{}
+		}
+		if expireWithin > 0 && (cert.Error != "" || cert.DaysLeft > expireWithin) {
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	sort.SliceStable(certs, func(i, j int) bool {
+		if (certs[i].Error == "") != (certs[j].Error == "") {
+			return certs[i].Error == ""
+		}
+		return certs[i].notAfter.Before(certs[j].notAfter)
+	})
+	return certs, nil
+}
+
+func parseSecretCertificate(secret models.K8sSecret) Certificate {
+	cert := Certificate{
+		Cluster:    secret.Cluster,
+		Namespace:  secret.Namespace,
+		SecretName: secret.Name,
+	}
+	data := make(map[string][]byte)
+	if err := json.Unmarshal([]byte(secret.Data), &data); err != nil {
+		cert.Error = fmt.Sprintf("decode the data of secret failed: %v", err)
+		return cert
+	}
+	item, err := utils.ParseCertificate(data[apiv1.TLSCertKey])
+	if err != nil {
+		cert.Error = fmt.Sprintf("parse the certificate failed: %v", err)
+		return cert
+	}
+	cert.Subject = item.Subject.String()
+	cert.DNSNames = item.DNSNames
+	for _, ip := range item.IPAddresses {
+		cert.IPAddresses = append(cert.IPAddresses, ip.String())
+	}
+	cert.Issuer = item.Issuer.String()
+	cert.NotBefore = item.NotBefore.Local().Format("2006-01-02 15:04:05")
+	cert.NotAfter = item.NotAfter.Local().Format("2006-01-02 15:04:05")
+	cert.DaysLeft = certificateDaysLeft(item.NotAfter)
+	cert.notAfter = item.NotAfter
+	return cert
+}
+
+func certificateDaysLeft(notAfter time.Time) int {
+	return int(math.Floor(time.Until(notAfter).Hours() / 24))
+}
+
+// CheckCertificateExpiry records the warning events of the certificates of the tls secrets and nodes of cluster,
+// which expire within the warning days
+func CheckCertificateExpiry(cluster string) error {
+	warningDays := beego.AppConfig.DefaultInt("certificate::warningDays", defaultCertificateWarningDays)
+	certs, err := ListCertificates(cluster, nil, warningDays)
+	if err != nil {
+		return err
+	}
+	for _, cert := range certs {
+		recordCertificateEvent(cluster, cert.Namespace, "Secret", cert.SecretName, cert.notAfter)
+	}
+	client, err := service.GetClientset(cluster)
+	if err != nil {
+		return err
+	}
+	nodes, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes.Items {
+		if _, ok := node.Annotations[NodeCertificateEndAt]; !ok {
+			continue
+		}
+		// the time of annotation is the local time
+		deadline, err := time.ParseInLocation("2006-01-02 15:04:05", node.Annotations[NodeCertificateEndAt], time.Local)
+		if err != nil {
+			beego.Warn(fmt.Sprintf("parse the certificate time of node %s failed: %v", node.Name, err))
+			continue
+		}
+		if certificateDaysLeft(deadline) <= warningDays {
+			recordCertificateEvent(cluster, "", "Node", node.Name, deadline)
+		}
+	}
+	return nil
+}
+
+// recordCertificateEvent records the event of the expiring certificate, the event is updated
+// instead of recorded again if it has been recorded in the alert interval
+func recordCertificateEvent(cluster, namespace, kind, name string, notAfter time.Time) {
+	reason := CertificateReasonExpiring
+	message := fmt.Sprintf("the certificate of %s %s expires at %s, %d days left", kind, name,
+		notAfter.Local().Format("2006-01-02 15:04:05"), certificateDaysLeft(notAfter))
+	if time.Now().After(notAfter) {
+		reason = CertificateReasonExpired
+		message = fmt.Sprintf("the certificate of %s %s has expired at %s", kind, name, notAfter.Local().Format("2006-01-02 15:04:05"))
+	}
+	now, _ := time.Parse("2006-01-02 15:04:05", time.Now().Local().Format("2006-01-02 15:04:05"))
+	event, err := dao.GetLatestEvent(cluster, namespace, kind, name, reason)
+	if err == nil {
+		if now.Sub(event.LastTimestamp) < certificateAlertInterval {
+			return
+		}
+		event.Count++
+		event.Message = message
+		event.LastTimestamp = now
+	} else {
+		event = &models.ZcloudEvent{
+			EventUid:        utils.NewUUID(),
+			EventType:       "Warning",
+			Cluster:         cluster,
+			Namespace:       namespace,
+			SourceComponent: certificateComponent,
+			ObjectKind:      kind,
+			ObjectName:      name,
+			Reason:          reason,
+			Message:         message,
+			Count:           1,
+			FirstTimestamp:  now,
+			LastTimestamp:   now,
+		}
+	}
+	beego.Warn(fmt.Sprintf("cluster %s: %s", cluster, message))
+	if err := dao.CreateEvent(*event); err != nil {
+		beego.Error(fmt.Sprintf("record event of certificate of %s %s failed: %v", kind, name, err))
+	}
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -56,6 +57,15 @@ func (cluster Cluster) Verify() error {
 	if !dao.HarborIsExist(cluster.Tenant, cluster.Registry) {
 		return fmt.Errorf("default registry(%s) is not existed!", cluster.Registry)
 	}
+	if err := ValidateIngressProvider(cluster.IngressProvider); err != nil {
+		return err
+	}
+	if _, err := utils.ParsePortPool(cluster.NodePortPool); err != nil {
+		return fmt.Errorf("node port pool is not right: %v", err)
+	}
+	if _, err := utils.ParsePortPool(cluster.LoadbalancerPortPool); err != nil {
+		return fmt.Errorf("load balancer port pool is not right: %v", err)
+	}
 	return nil
 }
 
@@ -178,6 +188,9 @@ func CreateCluster(cluster Cluster) (*Cluster, error) {
 		KubeServiceAddress:     cluster.KubeServiceAddress,
 		KubePodSubnet:          cluster.KubePodSubnet,
 		IngressSLB:             cluster.IngressSLB,
+		IngressProvider:        cluster.IngressProvider,
+		NodePortPool:           cluster.NodePortPool,
+		LoadbalancerPortPool:   cluster.LoadbalancerPortPool,
 		Env:                    cluster.Env,
 		Usage:                  cluster.Usage,
 		LabelPrefix:            cluster.LabelPrefix,
@@ -251,6 +264,15 @@ func UpdateCluster(cluster Cluster) (*Cluster, error) {
 	if cluster.IngressSLB != "" {
 		item.IngressSLB = cluster.IngressSLB
 	}
+	if cluster.IngressProvider != "" {
+		item.IngressProvider = cluster.IngressProvider
+	}
+	if cluster.NodePortPool != "" {
+		item.NodePortPool = cluster.NodePortPool
+	}
+	if cluster.LoadbalancerPortPool != "" {
+		item.LoadbalancerPortPool = cluster.LoadbalancerPortPool
+	}
 	if cluster.Status != "" {
 		item.Status = cluster.Status
 	}
@@ -299,6 +321,10 @@ func UpdateCluster(cluster Cluster) (*Cluster, error) {
 	if err := dao.UpdateCluster(*item); err != nil {
 		return nil, err
 	}
+	// the routes of ingresses are moved to the new load balancer if the ingress slb is changed
+	if err := dao.NewIngressRouteModel().UpdateLoadBalancer(item.ClusterId, IngressLoadBalancer(item)); err != nil {
+		beego.Warn(fmt.Sprintf("update the ingress routes of cluster %s failed: %v", item.ClusterId, err))
+	}
 
 	return GetClusterDetail(cluster.ClusterId)
 }
@@ -371,7 +397,7 @@ func CheckClusterApi() {
 				for i := 0; i < 4; i++ {
 					client, err := service.GetClientset(item.ClusterId)
 					if err == nil {
-						_, err = client.CoreV1().Namespaces().Get("default", meta_v1.GetOptions{})
+						_, err = client.CoreV1().Namespaces().Get(context.TODO(), "default", meta_v1.GetOptions{})
 					}
 					if err != nil {
 						beego.Error("CheckClusterApi failed:", item.Name, err.Error())
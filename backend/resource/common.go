@@ -18,7 +18,6 @@ import (
 	"github.com/astaxie/beego"
 	"github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
-	app "k8s.io/api/apps/v1beta1"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -34,10 +33,14 @@ const (
 const (
 	AppKindDaemonSet              = "daemonset"
 	AppKindDeployment             = "deployment"
+	AppKindStatefulSet            = "statefulset"
+	AppKindJob                    = "job"
+	AppKindCronJob                = "cronjob"
 	ServiceKind                   = "service"
 	IngressKind                   = "ingress"
 	ConfigMapKind                 = "configmap"
 	SecretKind                    = "secret"
+	UnstructuredKind              = "unstructured"
 	BasenameAnnotationKey         = "basename"
 	DomainNameAnnotationKey       = "domain_name"
 	TemplateNameAnnotationKey     = "template_name"
@@ -49,9 +52,9 @@ const (
 	ENABLED_KUBE_MONKEY           = "kube-monkey/enabled"
 	DEFAULT_PROJECT_ID            = 0
 	YamlSeparator                 = "---\n"
-	AppAPIVersion                 = "apps/v1beta1"
+	AppAPIVersion                 = "apps/v1"
 	SvcApiVersion                 = "v1"
-	IngApiVersion                 = "extensions/v1beta1"
+	IngApiVersion                 = "networking.k8s.io/v1"
 
 	AnnotationKubernetesIngressClass = "kubernetes.io/ingress.class"
 	DefaultIngressClass              = "traefik"
@@ -172,13 +175,14 @@ func GetResourceVersion(res interface{}, resType ResType, exparam string) string
 			}
 		}
 	case ResTypeDeploy:
-		if deploy, ok := res.(*app.Deployment); ok {
+		// deploy is any application workload object, such as deployment or statefulset
+		if deploy, ok := res.(metav1.Object); ok {
 			typeIsRight = true
-			if v, ok := deploy.Labels[keyword.LABEL_APPVERSION_KEY]; ok {
+			if v, ok := deploy.GetLabels()[keyword.LABEL_APPVERSION_KEY]; ok {
 				// firstly get app version
 				version = v
 			} else {
-				if v, ok := deploy.Labels[keyword.LABEL_PODVERSION_KEY]; ok {
+				if v, ok := deploy.GetLabels()[keyword.LABEL_PODVERSION_KEY]; ok {
 					// secondly get pod version
 					version = v
 				} else {
@@ -201,6 +205,9 @@ func GenServiceName(templateKind, svcname string) string {
 }
 
 func GenHeadlessSvcName(templateKind, svcname string) string {
+	if templateKind == AppKindStatefulSet {
+		return fmt.Sprintf("hlsvc-%s", svcname)
+	}
 	return svcname
 }
 
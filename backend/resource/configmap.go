@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"github.com/astaxie/beego"
 	corev1 "k8s.io/api/core/v1"
@@ -61,7 +62,7 @@ func ConfigMapList(cluster string, namespace string) ([]corev1.ConfigMap, error)
 		namespace = corev1.NamespaceAll
 	}
 	configMaps := ConfigMaps{}
-	configMapList, err := client.CoreV1().ConfigMaps(namespace).List(metav1.ListOptions{})
+	configMapList, err := client.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		beego.Error(fmt.Sprintf("Get ConfigMap list error: %v", err.Error()))
 		return nil, common.NewInternalServerError().SetCause(err)
@@ -77,7 +78,7 @@ func ConfigMapInspect(cluster, namespace, name string) (*corev1.ConfigMap, error
 		beego.Error(fmt.Sprintf("Get ConfigMap inspect error: %v", err.Error()))
 		return nil, err
 	}
-	configMap, err := client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	configMap, err := client.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 	if err != nil {
 		beego.Error(fmt.Sprintf("Get ConfigMap inspect error: %v", err.Error()))
 		return nil, err
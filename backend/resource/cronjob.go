@@ -0,0 +1,116 @@
+package resource
+
+import (
+	"fmt"
+	"kubecloud/common/keyword"
+
+	"github.com/astaxie/beego"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/backend/util/labels"
+)
+
+type CronJobRes struct {
+	Cluster   string
+	Namespace string
+	client    kubernetes.Interface
+}
+
+func NewCronJobRes(client kubernetes.Interface, cluster, namespace string) KubeAppInterface {
+	return &CronJobRes{
+		Cluster:   cluster,
+		Namespace: namespace,
+		client:    client,
+	}
+}
+
+func (kr *CronJobRes) apiVersions() kubeutil.APIVersions {
+	return kubeutil.GetAPIVersions(kr.Cluster, kr.client.Discovery())
+}
+
+func (kr *CronJobRes) CreateOrUpdate(obj interface{}) (interface{}, error) {
+	cj, ok := obj.(*batchv1.CronJob)
+	if !ok {
+		return nil, fmt.Errorf("can not generate cronjob object!")
+	}
+	beego.Info("creating or updating cronjob, " + cj.Name)
+	return cj, nil
+}
+
+func (kr *CronJobRes) Status(appname, suffix string) (*AppStatus, error) {
+	cj, err := kubeutil.GetCronJob(kr.client, kr.apiVersions(), kr.Namespace, GenerateDeployName(appname, suffix))
+	if err != nil {
+		return nil, err
+	}
+	return GetCronJobStatus(cj), nil
+}
+
+func (kr *CronJobRes) Delete(obj interface{}) (interface{}, error) {
+	cj, ok := obj.(*batchv1.CronJob)
+	if !ok {
+		return nil, fmt.Errorf("can not generate cronjob object!")
+	}
+	cj.ObjectMeta.Annotations = labels.AddLabel(cj.ObjectMeta.Annotations, keyword.DELETE_LABLE, keyword.DELETE_LABLE_VALUE)
+	beego.Warn(fmt.Sprintf("delete cronjob %s successfully!", cj.Name))
+	return cj, nil
+}
+
+func (kr *CronJobRes) AppIsExisted(appname, suffix string) (bool, error) {
+	name := GenerateDeployName(appname, suffix)
+	_, err := kubeutil.GetCronJob(kr.client, kr.apiVersions(), kr.Namespace, name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		} else {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (kr *CronJobRes) Scale(obj interface{}, replicas int) error {
+	return fmt.Errorf("cronjob can not be scaled, please reconfigure its job template!")
+}
+
+func (kr *CronJobRes) Restart(obj interface{}) error {
+	return fmt.Errorf("cronjob can not be restarted, it runs jobs by its schedule!")
+}
+
+func (kr *CronJobRes) GetOwnerForPod(pod apiv1.Pod, ref *metav1.OwnerReference) interface{} {
+	job, ok := NewJobRes(kr.client, kr.Cluster, pod.Namespace).GetOwnerForPod(pod, ref).(*batchv1.Job)
+	if !ok {
+		return nil
+	}
+	// Now find the CronJob that owns that Job.
+	cjRef := metav1.GetControllerOf(job)
+	if cjRef == nil || cjRef.Kind != batchv1.SchemeGroupVersion.WithKind("CronJob").Kind {
+		return nil
+	}
+	cj, err := kubeutil.GetCronJob(kr.client, kr.apiVersions(), pod.Namespace, cjRef.Name)
+	if err != nil || cj.UID != cjRef.UID {
+		return nil
+	}
+	return cj
+}
+
+func GetCronJobStatus(cj *batchv1.CronJob) *AppStatus {
+	active := int32(len(cj.Status.Active))
+	status := &AppStatus{
+		StatusReplicas:    active,
+		ReadyReplicas:     active,
+		AvailableReplicas: active,
+		UpdatedReplicas:   active,
+		AvailableStatus:   string(apiv1.ConditionTrue),
+	}
+	if cj.Spec.Suspend != nil && *cj.Spec.Suspend {
+		status.AvailableStatus = string(apiv1.ConditionFalse)
+		status.Message = "cronjob is suspended"
+	} else if cj.Status.LastScheduleTime != nil {
+		status.Message = "last scheduled at " + cj.Status.LastScheduleTime.Format("2006-01-02 15:04:05")
+	}
+	return status
+}
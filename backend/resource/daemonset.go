@@ -0,0 +1,118 @@
+package resource
+
+import (
+	"fmt"
+	"kubecloud/common/keyword"
+	"kubecloud/gitops"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/backend/util/labels"
+)
+
+type DaemonSetRes struct {
+	Cluster   string
+	Namespace string
+	client    kubernetes.Interface
+}
+
+func NewDaemonSetRes(client kubernetes.Interface, cluster, namespace string) KubeAppInterface {
+	return &DaemonSetRes{
+		Cluster:   cluster,
+		Namespace: namespace,
+		client:    client,
+	}
+}
+
+func (kr *DaemonSetRes) apiVersions() kubeutil.APIVersions {
+	return kubeutil.GetAPIVersions(kr.Cluster, kr.client.Discovery())
+}
+
+func (kr *DaemonSetRes) CreateOrUpdate(obj interface{}) (interface{}, error) {
+	ds, ok := obj.(*appsv1.DaemonSet)
+	if !ok {
+		return nil, fmt.Errorf("can not generate daemonset object!")
+	}
+	beego.Info("creating or updating daemonset, " + ds.Name)
+	return ds, nil
+}
+
+func (kr *DaemonSetRes) Status(appname, suffix string) (*AppStatus, error) {
+	ds, err := kubeutil.GetDaemonSet(kr.client, kr.apiVersions(), kr.Namespace, GenerateDeployName(appname, suffix))
+	if err != nil {
+		return nil, err
+	}
+	return GetDaemonSetStatus(ds), nil
+}
+
+func (kr *DaemonSetRes) Delete(obj interface{}) (interface{}, error) {
+	ds, ok := obj.(*appsv1.DaemonSet)
+	if !ok {
+		return nil, fmt.Errorf("can not generate daemonset object!")
+	}
+	ds.ObjectMeta.Annotations = labels.AddLabel(ds.ObjectMeta.Annotations, keyword.DELETE_LABLE, keyword.DELETE_LABLE_VALUE)
+	beego.Warn(fmt.Sprintf("delete daemonset %s successfully!", ds.Name))
+	return ds, nil
+}
+
+func (kr *DaemonSetRes) AppIsExisted(appname, suffix string) (bool, error) {
+	name := GenerateDeployName(appname, suffix)
+	_, err := kubeutil.GetDaemonSet(kr.client, kr.apiVersions(), kr.Namespace, name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		} else {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (kr *DaemonSetRes) Scale(obj interface{}, replicas int) error {
+	return fmt.Errorf("daemonset runs one pod on every selected node, it can not be scaled!")
+}
+
+func (kr *DaemonSetRes) Restart(obj interface{}) error {
+	ds, ok := obj.(*appsv1.DaemonSet)
+	if !ok {
+		return fmt.Errorf("can not generate daemonset object!")
+	}
+	ds.Spec.Template.ObjectMeta.Annotations = labels.AddLabel(ds.Spec.Template.ObjectMeta.Annotations, keyword.RESTART_LABLE, strconv.FormatInt(time.Now().Unix(), 10))
+	go gitops.CommitK8sResource(kr.Cluster, []interface{}{ds})
+	return nil
+}
+
+func (kr *DaemonSetRes) GetOwnerForPod(pod apiv1.Pod, ref *metav1.OwnerReference) interface{} {
+	if ref == nil || ref.Kind != appsv1.SchemeGroupVersion.WithKind("DaemonSet").Kind {
+		return nil
+	}
+	ds, err := kubeutil.GetDaemonSet(kr.client, kr.apiVersions(), pod.Namespace, ref.Name)
+	if err != nil || ds.UID != ref.UID {
+		beego.Warn(fmt.Sprintf("Cannot get daemonset %s for pod %s: %v", ref.Name, pod.Name, err))
+		return nil
+	}
+	return ds
+}
+
+func GetDaemonSetStatus(ds *appsv1.DaemonSet) *AppStatus {
+	status := &AppStatus{
+		StatusReplicas:    ds.Status.DesiredNumberScheduled,
+		ReadyReplicas:     ds.Status.NumberReady,
+		AvailableReplicas: ds.Status.NumberAvailable,
+		UpdatedReplicas:   ds.Status.UpdatedNumberScheduled,
+		AvailableStatus:   string(apiv1.ConditionFalse),
+	}
+	if ds.Status.NumberAvailable >= ds.Status.DesiredNumberScheduled {
+		status.AvailableStatus = string(apiv1.ConditionTrue)
+	} else {
+		status.Message = fmt.Sprintf("%v of %v pods are available", ds.Status.NumberAvailable, ds.Status.DesiredNumberScheduled)
+	}
+	return status
+}
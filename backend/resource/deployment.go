@@ -8,11 +8,12 @@ import (
 	"time"
 
 	"github.com/astaxie/beego"
-	"k8s.io/api/apps/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"kubecloud/backend/util/kubeutil"
 	"kubecloud/backend/util/labels"
 )
 
@@ -27,11 +28,14 @@ type KubeAppInterface interface {
 }
 
 type AppStatus struct {
+	StatusReplicas    int32
 	ReadyReplicas     int32
 	UpdatedReplicas   int32
 	AvailableReplicas int32
 	AvailableStatus   string
 	Message           string
+	// Failed is set if the application can not be ready any more, such as the failed job
+	Failed bool
 }
 
 type DeploymentRes struct {
@@ -48,8 +52,12 @@ func NewDeploymentRes(client kubernetes.Interface, cluster, namespace string) Ku
 	}
 }
 
+func (kr *DeploymentRes) apiVersions() kubeutil.APIVersions {
+	return kubeutil.GetAPIVersions(kr.Cluster, kr.client.Discovery())
+}
+
 func (kr *DeploymentRes) CreateOrUpdate(obj interface{}) (interface{}, error) {
-	dp, ok := obj.(*v1beta1.Deployment)
+	dp, ok := obj.(*appsv1.Deployment)
 	if !ok {
 		return nil, fmt.Errorf("can not generate deployment object!")
 	}
@@ -59,17 +67,18 @@ func (kr *DeploymentRes) CreateOrUpdate(obj interface{}) (interface{}, error) {
 }
 
 func (kr *DeploymentRes) Status(appname, suffix string) (*AppStatus, error) {
-	deployment, err := kr.client.AppsV1beta1().Deployments(kr.Namespace).Get(GenerateDeployName(appname, suffix), metav1.GetOptions{})
+	deployment, err := kubeutil.GetDeployment(kr.client, kr.apiVersions(), kr.Namespace, GenerateDeployName(appname, suffix))
 	if err != nil {
 		return nil, err
 	}
 	status := &AppStatus{
+		StatusReplicas:    deployment.Status.Replicas,
 		ReadyReplicas:     deployment.Status.ReadyReplicas,
 		AvailableReplicas: deployment.Status.AvailableReplicas,
 		UpdatedReplicas:   deployment.Status.UpdatedReplicas,
 	}
 	for _, condition := range deployment.Status.Conditions {
-		if condition.Type == v1beta1.DeploymentAvailable {
+		if condition.Type == appsv1.DeploymentAvailable {
 			status.AvailableStatus = string(condition.Status)
 			status.Message = condition.Message
 			break
@@ -79,7 +88,7 @@ func (kr *DeploymentRes) Status(appname, suffix string) (*AppStatus, error) {
 }
 
 func (kr *DeploymentRes) Delete(obj interface{}) (interface{}, error) {
-	dp, ok := obj.(*v1beta1.Deployment)
+	dp, ok := obj.(*appsv1.Deployment)
 	if !ok {
 		return nil, fmt.Errorf("can not generate deployment object!")
 	}
@@ -91,7 +100,7 @@ func (kr *DeploymentRes) Delete(obj interface{}) (interface{}, error) {
 
 func (kr *DeploymentRes) AppIsExisted(appname, suffix string) (bool, error) {
 	name := GenerateDeployName(appname, suffix)
-	_, err := kr.client.AppsV1beta1().Deployments(kr.Namespace).Get(name, metav1.GetOptions{})
+	_, err := kubeutil.GetDeployment(kr.client, kr.apiVersions(), kr.Namespace, name)
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			return false, err
@@ -103,7 +112,7 @@ func (kr *DeploymentRes) AppIsExisted(appname, suffix string) (bool, error) {
 }
 
 func (kr *DeploymentRes) Scale(obj interface{}, replicas int) error {
-	dp, ok := obj.(*v1beta1.Deployment)
+	dp, ok := obj.(*appsv1.Deployment)
 	if !ok {
 		return fmt.Errorf("can not generate deployment object!")
 	}
@@ -117,7 +126,7 @@ func (kr *DeploymentRes) Scale(obj interface{}, replicas int) error {
 }
 
 func (kr *DeploymentRes) Restart(obj interface{}) error {
-	dp, ok := obj.(*v1beta1.Deployment)
+	dp, ok := obj.(*appsv1.Deployment)
 	if !ok {
 		return fmt.Errorf("can not generate deployment object!")
 	}
@@ -130,7 +139,7 @@ func (kr *DeploymentRes) GetOwnerForPod(pod apiv1.Pod, ref *metav1.OwnerReferenc
 	if ref == nil {
 		return nil
 	}
-	rs, err := kr.client.ExtensionsV1beta1().ReplicaSets(pod.Namespace).Get(ref.Name, metav1.GetOptions{})
+	rs, err := kubeutil.GetReplicaSet(kr.client, kr.apiVersions(), pod.Namespace, ref.Name)
 	if err != nil || rs.UID != ref.UID {
 		beego.Warn(fmt.Sprintf("Cannot get replicaset %s for pod %s: %v", ref.Name, pod.Name, err))
 		return nil
@@ -142,10 +151,10 @@ func (kr *DeploymentRes) GetOwnerForPod(pod apiv1.Pod, ref *metav1.OwnerReferenc
 	}
 	// We can't look up by UID, so look up by Name and then verify UID.
 	// Don't even try to look up by Name if it's the wrong Kind.
-	if depRef.Kind != v1beta1.SchemeGroupVersion.WithKind("Deployment").Kind {
+	if depRef.Kind != appsv1.SchemeGroupVersion.WithKind("Deployment").Kind {
 		return nil
 	}
-	d, err := kr.client.AppsV1beta1().Deployments(pod.Namespace).Get(depRef.Name, metav1.GetOptions{})
+	d, err := kubeutil.GetDeployment(kr.client, kr.apiVersions(), pod.Namespace, depRef.Name)
 	if err != nil {
 		return nil
 	}
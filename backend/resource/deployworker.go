@@ -14,6 +14,15 @@ import (
 type ExtensionParam struct {
 	Force   bool //when user deploy its app and the app is existed in other namespace, the old app will be deleted
 	Patcher PatcherFunction
+	// Finished is called with the result after all the applications of the templates are deployed,
+	// it may be called after returning since the applications depending on others are deployed in background
+	Finished func(err error)
+}
+
+func (ep *ExtensionParam) finish(err error) {
+	if ep != nil && ep.Finished != nil {
+		ep.Finished(err)
+	}
 }
 
 type DeployWorker struct {
@@ -42,6 +51,10 @@ func (wk *DeployWorker) Start(templateName string, param AppParam) error {
 	}
 	app, err := wk.arHandle.Appmodel.GetAppByName(wk.arHandle.Cluster, wk.kubeRes.Namespace, param.Name)
 	if err == nil {
+		// record the template version which the application is redeployed from
+		if templateName != "" {
+			app.TemplateName = templateName
+		}
 		return wk.updateAppRes(*app)
 	} else {
 		if err != orm.ErrNoRows {
@@ -109,7 +122,7 @@ func (wk *DeployWorker) createAppRes(templateName string, param AppParam) error
 	}
 	err = wk.arHandle.Appmodel.CreateApp(*app)
 	if err != nil {
-		wk.kubeRes.DeleteAppResource(wk.template, app.PodVersion)
+		wk.kubeRes.DeleteAppResource(wk.template, app.PodVersion, false)
 		wk.arHandle.Appmodel.DeleteApp(*app)
 		return err
 	}
@@ -0,0 +1,374 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"kubecloud/backend/models"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common"
+	"kubecloud/gitops"
+
+	"github.com/astaxie/beego/orm"
+	yamlencoder "github.com/ghodss/yaml"
+	"github.com/pmezard/go-difflib/difflib"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	DryRunActionCreate = "create"
+	DryRunActionUpdate = "update"
+)
+
+// ObjectDiff is the preview of an object which would be applied, nothing is changed by the preview
+type ObjectDiff struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Action is create or update, it depends on whether the live object exists
+	Action string `json:"action"`
+	// ServerDryRun is true if the object is admitted by the server-side dry run of the api server,
+	// the live diff is against the object returned by the api server in this case
+	ServerDryRun bool `json:"server_dry_run"`
+	// File is the file of the object in the gitops repo of the cluster
+	File     string `json:"file,omitempty"`
+	LiveDiff string `json:"live_diff"`
+	RepoDiff string `json:"repo_diff"`
+	// Errors are the failures of the dry run, the validation error of the api server is included
+	Errors []string `json:"errors,omitempty"`
+}
+
+// DryRunInstallApp previews the objects of the native template which would be deployed to namespace
+func (ar *AppRes) DryRunInstallApp(namespace string, template Template) ([]ObjectDiff, error) {
+	if err := template.Validate(); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	native, ok := template.Default(ar.Cluster).(*NativeTemplate)
+	if !ok {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("dry run is only supported by native template!"))
+	}
+	tplList, otherObjList, err := native.GenNativeAppTemplate(namespace, INIT_APPNAME)
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	result := []ObjectDiff{}
+	for _, tpl := range tplList {
+		if err := CheckAdmissionPolicies(ar.Cluster, namespace, tpl); err != nil {
+			return nil, err
+		}
+		// the existing application is reconfigured by the deployment
+		app, err := ar.Appmodel.GetAppByName(ar.Cluster, namespace, tpl.GetAppName())
+		if err != nil {
+			if err != orm.ErrNoRows {
+				return nil, common.NewInternalServerError().SetCause(err)
+			}
+			if app, err = tpl.GenerateAppObject(ar.Cluster, namespace, "", ar.DomainSuffix); err != nil {
+				return nil, common.NewBadRequest().SetCause(err)
+			}
+		} else if err := tpl.UpdateAppObject(app, ar.DomainSuffix); err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+		kr := NewKubeAppRes(ar.Client, ar.Cluster, namespace, ar.DomainSuffix, app.Kind)
+		diffs, err := kr.DryRunAppResource(tpl, app.PodVersion, true)
+		if err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+		result = append(result, diffs...)
+	}
+	kr := NewKubeAppRes(ar.Client, ar.Cluster, namespace, ar.DomainSuffix, "")
+	for _, obj := range otherObjList {
+		robj, err := decodeNoAppObject(obj)
+		if err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+		if robj == nil {
+			continue
+		}
+		if accessor, ok := robj.(metav1.Object); ok {
+			accessor.SetNamespace(namespace)
+		}
+		result = append(result, kr.dryRunObject(robj))
+	}
+	return result, nil
+}
+
+// DryRunReconfigureApp previews the objects of the application which would be reconfigured by template
+func (ar *AppRes) DryRunReconfigureApp(app models.ZcloudApplication, template AppTemplate) ([]ObjectDiff, error) {
+	if err := CheckAdmissionPolicies(ar.Cluster, app.Namespace, template); err != nil {
+		return nil, err
+	}
+	// the replicas scaled by the autoscaler are kept like reconfiguring, or the diff shows a false scaling
+	template, err := ar.keepAutoscaledReplicas(&app, template)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if err := template.UpdateAppObject(&app, ar.DomainSuffix); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	kr := NewKubeAppRes(ar.Client, ar.Cluster, app.Namespace, ar.DomainSuffix, app.Kind)
+	diffs, err := kr.DryRunAppResource(template, app.PodVersion, true)
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	return diffs, nil
+}
+
+// DryRunRollingUpdateApp previews the application object whose images would be updated by param
+func (ar *AppRes) DryRunRollingUpdateApp(namespace, appname string, param []ContainerParam) ([]ObjectDiff, error) {
+	app, err := ar.Appmodel.GetAppByName(ar.Cluster, namespace, appname)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(err)
+		} else if err == orm.ErrMultiRows {
+			return nil, common.NewConflict().SetCause(err)
+		} else {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+	}
+	template, err := CreateAppTemplateByApp(*app)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	template = template.Image(param)
+	if err := CheckAdmissionPolicies(ar.Cluster, namespace, template); err != nil {
+		return nil, err
+	}
+	if err := template.UpdateAppObject(app, ar.DomainSuffix); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	kr := NewKubeAppRes(ar.Client, ar.Cluster, namespace, ar.DomainSuffix, app.Kind)
+	diffs, err := kr.DryRunAppResource(template, app.PodVersion, false)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return diffs, nil
+}
+
+// DryRunScaleApp previews the application object whose replicas would be scaled to replicas
+func (ar *AppRes) DryRunScaleApp(namespace, appname string, replicas int) ([]ObjectDiff, error) {
+	app, err := ar.Appmodel.GetAppByName(ar.Cluster, namespace, appname)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(err)
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	autoscaler, existed, err := autoscalerExisted(ar.autoscalerModel, ar.Cluster, namespace, appname)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if existed {
+		return nil, common.NewConflict().SetCause(fmt.Errorf("the replicas of application %s is managed by its autoscaler(%v~%v), please update the autoscaler instead!",
+			appname, autoscaler.MinReplicas, autoscaler.MaxReplicas))
+	}
+	template, err := CreateAppTemplateByApp(*app)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	kr := NewKubeAppRes(ar.Client, ar.Cluster, namespace, ar.DomainSuffix, app.Kind)
+	diffs, err := kr.DryRunAppResource(template.Replicas(replicas), app.PodVersion, false)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return diffs, nil
+}
+
+// DryRunAppResource previews the objects generated by template, the services and ingresses are
+// previewed only if all is true, as UpdateAppResource does
+func (kr *KubeAppRes) DryRunAppResource(template AppTemplate, podVersion string, all bool) ([]ObjectDiff, error) {
+	objMap, err := template.GenerateKubeObject(kr.cluster, kr.Namespace, podVersion, kr.DomainSuffix)
+	if err != nil {
+		return nil, err
+	}
+	objs := []runtime.Object{}
+	if obj, existed := objMap[template.GetAppKind()]; existed {
+		if err := NewKubeAppValidator(kr.client, kr.Namespace).Validator(obj); err != nil {
+			return nil, err
+		}
+		if robj, ok := obj.(runtime.Object); ok {
+			objs = append(objs, robj)
+		}
+	}
+	if all {
+		if svcList, ok := objMap[ServiceKind].([]*apiv1.Service); ok {
+			for _, svc := range svcList {
+				objs = append(objs, svc)
+			}
+		}
+		if ings, ok := objMap[IngressKind].([]*networkingv1.Ingress); ok {
+			for _, ing := range ings {
+				objs = append(objs, ing)
+			}
+		}
+		if objList, ok := objMap[UnstructuredKind].([]*unstructured.Unstructured); ok {
+			for _, item := range objList {
+				objs = append(objs, item)
+			}
+		}
+	}
+	result := []ObjectDiff{}
+	for _, obj := range objs {
+		result = append(result, kr.dryRunObject(obj))
+	}
+	return result, nil
+}
+
+// dryRunObject diffs obj against the live object and the copy in the gitops repo,
+// the generated object is not changed
+func (kr *KubeAppRes) dryRunObject(obj runtime.Object) ObjectDiff {
+	obj = obj.DeepCopyObject()
+	accessor, _ := obj.(metav1.Object)
+	diff := ObjectDiff{
+		Kind:      reflect.TypeOf(obj).Elem().Name(),
+		Namespace: accessor.GetNamespace(),
+		Name:      accessor.GetName(),
+		Action:    DryRunActionCreate,
+	}
+	u, isUnstructured := obj.(*unstructured.Unstructured)
+	if isUnstructured {
+		diff.Kind = u.GetKind()
+	}
+	// the file in the repo is written without the fields of the api server
+	file, data, err := gitops.K8sResourceFile(kr.cluster, obj.DeepCopyObject())
+	if err == nil {
+		diff.File = file
+		var repoData []byte
+		if repoData, err = gitops.ReadK8sResourceFile(kr.cluster, file); err == nil {
+			diff.RepoDiff = unifiedDiff(repoData, data, "repo", "dry-run")
+		}
+	}
+	if err != nil {
+		diff.Errors = append(diff.Errors, fmt.Sprintf("diff against gitops repo failed: %v", err))
+	}
+
+	versions := kubeutil.GetAPIVersions(kr.cluster, kr.client.Discovery())
+	var live runtime.Object
+	if isUnstructured {
+		live, err = kr.getUnstructured(u)
+	} else {
+		live, err = kubeutil.GetObject(kr.client, versions, obj)
+	}
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			diff.Errors = append(diff.Errors, fmt.Sprintf("get live object failed: %v", err))
+		}
+		live = nil
+	}
+	if live != nil {
+		diff.Action = DryRunActionUpdate
+		liveAccessor := live.(metav1.Object)
+		accessor.SetResourceVersion(liveAccessor.GetResourceVersion())
+		// the cluster ip is immutable and allocated by the api server
+		if svc, ok := obj.(*apiv1.Service); ok && svc.Spec.ClusterIP == "" {
+			liveSvc := live.(*apiv1.Service)
+			svc.Spec.ClusterIP = liveSvc.Spec.ClusterIP
+			svc.Spec.ClusterIPs = liveSvc.Spec.ClusterIPs
+		}
+	}
+	target := obj
+	var dryRun runtime.Object
+	if isUnstructured {
+		dryRun, err = kr.dryRunUnstructured(u)
+	} else {
+		dryRun, err = kubeutil.DryRunObject(kr.client, versions, obj, live != nil)
+	}
+	if err == nil {
+		diff.ServerDryRun = true
+		target = dryRun
+	} else {
+		diff.Errors = append(diff.Errors, fmt.Sprintf("server-side dry run failed: %v", err))
+	}
+
+	liveData, err := objectYaml(live)
+	if err == nil {
+		var targetData []byte
+		if targetData, err = objectYaml(target); err == nil {
+			diff.LiveDiff = unifiedDiff(liveData, targetData, "live", "dry-run")
+		}
+	}
+	if err != nil {
+		diff.Errors = append(diff.Errors, fmt.Sprintf("diff against live object failed: %v", err))
+	}
+	return diff
+}
+
+// decodeNoAppObject decodes the object which is deployed without application, it is nil if the kind is not deployed
+func decodeNoAppObject(obj ResObject) (runtime.Object, error) {
+	kind, err := metaAccessor.Kind(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	var robj runtime.Object
+	switch strings.ToLower(kind) {
+	case ServiceKind:
+		robj = &apiv1.Service{}
+	case ConfigMapKind:
+		robj = &apiv1.ConfigMap{}
+	case SecretKind:
+		robj = &apiv1.Secret{}
+	case IngressKind:
+		return kubeutil.DecodeIngress(obj.RawData)
+	default:
+		return nil, nil
+	}
+	if err := yamlencoder.Unmarshal(obj.RawData, robj); err != nil {
+		return nil, err
+	}
+	return robj, nil
+}
+
+// objectYaml returns the yaml of obj without the status and the fields maintained by the api server
+func objectYaml(obj runtime.Object) ([]byte, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(content, "apiVersion")
+	delete(content, "kind")
+	delete(content, "status")
+	// only the changes of the secret data are shown
+	for _, field := range []string{"data", "stringData"} {
+		if data, ok := content[field].(map[string]interface{}); ok && reflect.TypeOf(obj) == reflect.TypeOf(&apiv1.Secret{}) {
+			for key, value := range data {
+				data[key] = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(fmt.Sprint(value))))
+			}
+		}
+	}
+	if meta, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"managedFields", "resourceVersion", "uid", "selfLink", "generation", "creationTimestamp"} {
+			delete(meta, field)
+		}
+	}
+	return yamlencoder.Marshal(content)
+}
+
+func unifiedDiff(from, to []byte, fromFile, toFile string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(from),
+		B:        splitLines(to),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return difflib.SplitLines(string(data))
+}
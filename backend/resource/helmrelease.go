@@ -0,0 +1,280 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/common"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+type HelmReleaseInfo struct {
+	models.ZcloudHelmRelease
+	CreateAt string `json:"create_at"`
+	UpdateAt string `json:"update_at"`
+}
+
+type HelmReleaseRes struct {
+	cluster     string
+	modelHandle *dao.HelmReleaseModel
+}
+
+func NewHelmReleaseRes(cluster string) *HelmReleaseRes {
+	return &HelmReleaseRes{
+		cluster:     cluster,
+		modelHandle: dao.NewHelmReleaseModel(),
+	}
+}
+
+// HelmReleaseTemplateName is recorded as the template name of the applications of release, such as "helm:nginx"
+func HelmReleaseTemplateName(name string) string {
+	return "helm:" + name
+}
+
+// ReleaseList returns the latest revision of each release in the namespace
+func (hr *HelmReleaseRes) ReleaseList(namespace string) ([]HelmReleaseInfo, error) {
+	releases, err := hr.modelHandle.GetReleaseList(hr.cluster, namespace)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	list := []HelmReleaseInfo{}
+	for _, item := range releases {
+		if len(list) != 0 && list[len(list)-1].Name == item.Name {
+			continue
+		}
+		item.Manifest = ""
+		list = append(list, *helmReleaseToInfo(item))
+	}
+	return list, nil
+}
+
+// ReleaseHistory returns all revisions of the release, the latest one is the first
+func (hr *HelmReleaseRes) ReleaseHistory(namespace, name string) ([]HelmReleaseInfo, error) {
+	history, err := hr.getReleaseHistory(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	list := []HelmReleaseInfo{}
+	for _, item := range history {
+		list = append(list, *helmReleaseToInfo(item))
+	}
+	return list, nil
+}
+
+// InstallRelease installs the chart as a new release, or upgrades the release if it is existed
+func (hr *HelmReleaseRes) InstallRelease(projectid int64, namespace string, tpl *HelmTemplate, eparam *ExtensionParam) error {
+	ar, err := NewAppRes(hr.cluster, nil)
+	if err != nil {
+		return err
+	}
+	return ar.InstallApp(projectid, namespace, HelmReleaseTemplateName(tpl.ReleaseName()), tpl, eparam)
+}
+
+// RollbackRelease redeploys the manifest of the revision as the next revision of release,
+// the latest successfully deployed revision before the latest one is used if revision is 0
+func (hr *HelmReleaseRes) RollbackRelease(projectid int64, namespace, name string, revision int, eparam *ExtensionParam) error {
+	history, err := hr.getReleaseHistory(namespace, name)
+	if err != nil {
+		return err
+	}
+	target, err := rollbackTarget(history, revision)
+	if err != nil {
+		return err
+	}
+	release := *target
+	release.Id = 0
+	if err := hr.deployRelease(&release, history, fmt.Sprintf("Rollback to %v", target.Revision), projectid, eparam, nil); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return nil
+}
+
+// rollbackTarget returns the revision of history to roll back to, the revisions which are failed
+// or still pending can not be rolled back to
+func rollbackTarget(history []models.ZcloudHelmRelease, revision int) (*models.ZcloudHelmRelease, error) {
+	name := history[0].Name
+	if revision == 0 {
+		for i := 1; i < len(history); i++ {
+			if history[i].Status == models.HELM_RELEASE_STATUS_DEPLOYED || history[i].Status == models.HELM_RELEASE_STATUS_SUPERSEDED {
+				return &history[i], nil
+			}
+		}
+		return nil, common.NewNotFound().SetCause(fmt.Errorf("release %s has no previous revision deployed successfully!", name))
+	}
+	for i := range history {
+		if history[i].Revision != revision {
+			continue
+		}
+		if history[i].Status == models.HELM_RELEASE_STATUS_FAILED || history[i].Status == models.HELM_RELEASE_STATUS_PENDING {
+			return nil, common.NewBadRequest().SetCause(fmt.Errorf("revision %v of release %s is %s, it can not be rolled back to!", revision, name, history[i].Status))
+		}
+		return &history[i], nil
+	}
+	return nil, common.NewNotFound().SetCause(fmt.Errorf("revision %v of release %s is not found!", revision, name))
+}
+
+// UninstallRelease deletes the applications of the deployed revision and the records of release,
+// the applications which are redeployed by others are kept
+func (hr *HelmReleaseRes) UninstallRelease(namespace, name string) error {
+	history, err := hr.getReleaseHistory(namespace, name)
+	if err != nil {
+		return err
+	}
+	ar, err := NewAppRes(hr.cluster, nil)
+	if err != nil {
+		return err
+	}
+	for _, release := range history {
+		if release.Status != models.HELM_RELEASE_STATUS_DEPLOYED {
+			continue
+		}
+		for _, appname := range releaseAppNames(release.Manifest, namespace) {
+			if err := deleteReleaseApp(ar, namespace, appname, release.TemplateName); err != nil {
+				return common.NewInternalServerError().SetCause(err)
+			}
+		}
+	}
+	if err := hr.modelHandle.DeleteRelease(hr.cluster, namespace, name); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return nil
+}
+
+func (hr *HelmReleaseRes) getReleaseHistory(namespace, name string) ([]models.ZcloudHelmRelease, error) {
+	history, err := hr.modelHandle.GetReleaseHistory(hr.cluster, namespace, name)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if len(history) == 0 {
+		return nil, common.NewNotFound().SetCause(fmt.Errorf("release %s is not found!", name))
+	}
+	return history, nil
+}
+
+// deployRelease records the release as the next revision in pending, the manifest is rendered by render with
+// the allocated revision if it is given, then it is deployed. The status of the revision is updated after
+// all the applications are deployed, it is still pending on returning if some of them are deployed in background
+func (hr *HelmReleaseRes) deployRelease(release *models.ZcloudHelmRelease, history []models.ZcloudHelmRelease,
+	action string, projectid int64, eparam *ExtensionParam, render func(revision int) (string, error)) error {
+	config := DeployConfig{}
+	if release.Config != "" {
+		if err := json.Unmarshal([]byte(release.Config), &config); err != nil {
+			return err
+		}
+	}
+	release.Status = models.HELM_RELEASE_STATUS_PENDING
+	release.Description = action + " in progress"
+	if err := hr.modelHandle.CreateRelease(release); err != nil {
+		return err
+	}
+	var previous *models.ZcloudHelmRelease
+	for i := range history {
+		if history[i].Status == models.HELM_RELEASE_STATUS_DEPLOYED {
+			previous = &history[i]
+			break
+		}
+	}
+	// the revision is pending until the applications depending on others are deployed in background
+	var once sync.Once
+	finish := func(err error) {
+		once.Do(func() { hr.finishRelease(release, previous, action, err) })
+	}
+	ep := ExtensionParam{}
+	if eparam != nil {
+		ep = *eparam
+	}
+	ep.Finished = finish
+	var deployErr error
+	if render != nil {
+		release.Manifest, deployErr = render(release.Revision)
+	}
+	if deployErr == nil {
+		native := &NativeTemplate{Template: release.Manifest, Config: config}
+		deployErr = native.Deploy(projectid, release.Cluster, release.Namespace, release.TemplateName, &ep)
+	}
+	if deployErr != nil {
+		finish(deployErr)
+	}
+	return deployErr
+}
+
+// finishRelease records the result of the deployment of revision, the applications which are removed
+// from the previous deployed revision are deleted if it is deployed
+func (hr *HelmReleaseRes) finishRelease(release, previous *models.ZcloudHelmRelease, action string, deployErr error) {
+	if deployErr != nil {
+		release.Status = models.HELM_RELEASE_STATUS_FAILED
+		release.Description = fmt.Sprintf("%s failed: %v", action, deployErr)
+	} else {
+		release.Status = models.HELM_RELEASE_STATUS_DEPLOYED
+		release.Description = action + " complete"
+	}
+	if err := hr.modelHandle.UpdateRelease(release); err != nil {
+		beego.Error(fmt.Sprintf("record release(%s/%s/%s) failed: %v", release.Cluster, release.Namespace, release.Name, err))
+		return
+	}
+	if deployErr != nil || previous == nil {
+		return
+	}
+	current := make(map[string]bool)
+	for _, appname := range releaseAppNames(release.Manifest, release.Namespace) {
+		current[appname] = true
+	}
+	ar, err := NewAppRes(release.Cluster, nil)
+	if err != nil {
+		beego.Error(fmt.Sprintf("delete applications removed from release(%s/%s/%s) failed: %v", release.Cluster, release.Namespace, release.Name, err))
+		return
+	}
+	for _, appname := range releaseAppNames(previous.Manifest, release.Namespace) {
+		if current[appname] {
+			continue
+		}
+		if err := deleteReleaseApp(ar, release.Namespace, appname, previous.TemplateName); err != nil {
+			beego.Error(fmt.Sprintf("delete application(%s/%s/%s) removed from release %s failed: %v",
+				release.Cluster, release.Namespace, appname, release.Name, err))
+			continue
+		}
+		beego.Info(fmt.Sprintf("delete application(%s/%s/%s) removed from release %s successfully!",
+			release.Cluster, release.Namespace, appname, release.Name))
+	}
+}
+
+func releaseAppNames(manifest, namespace string) []string {
+	names := []string{}
+	tplList, _, err := (&NativeTemplate{Template: manifest}).GenNativeAppTemplate(namespace, INIT_APPNAME)
+	if err != nil {
+		beego.Error("parse manifest of release failed:", err)
+		return names
+	}
+	for _, tpl := range tplList {
+		names = append(names, tpl.GetAppName())
+	}
+	return names
+}
+
+// deleteReleaseApp deletes the application only if it is still deployed by the release
+func deleteReleaseApp(ar *AppRes, namespace, appname, tname string) error {
+	app, err := ar.Appmodel.GetAppByName(ar.Cluster, namespace, appname)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if app.TemplateName != tname {
+		return nil
+	}
+	return ar.DeleteApp(namespace, appname)
+}
+
+func helmReleaseToInfo(release models.ZcloudHelmRelease) *HelmReleaseInfo {
+	return &HelmReleaseInfo{
+		ZcloudHelmRelease: release,
+		CreateAt:          release.CreateAt.Format("2006-01-02 15:04:05"),
+		UpdateAt:          release.UpdateAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,52 @@
+package resource
+
+import (
+	"testing"
+
+	"kubecloud/backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestReleaseHistory(statuses ...string) []models.ZcloudHelmRelease {
+	history := []models.ZcloudHelmRelease{}
+	// the latest revision is the first
+	for i, status := range statuses {
+		history = append(history, models.ZcloudHelmRelease{Name: "nginx", Revision: len(statuses) - i, Status: status})
+	}
+	return history
+}
+
+func TestRollbackTarget(t *testing.T) {
+	const (
+		deployed   = models.HELM_RELEASE_STATUS_DEPLOYED
+		superseded = models.HELM_RELEASE_STATUS_SUPERSEDED
+		failed     = models.HELM_RELEASE_STATUS_FAILED
+		pending    = models.HELM_RELEASE_STATUS_PENDING
+	)
+	cases := []struct {
+		name     string
+		history  []models.ZcloudHelmRelease
+		revision int
+		target   int
+	}{
+		{"previous revision", newTestReleaseHistory(deployed, superseded, superseded), 0, 2},
+		{"the failed upgrade is rolled back to the deployed revision", newTestReleaseHistory(failed, deployed, superseded), 0, 2},
+		{"the failed revisions are skipped", newTestReleaseHistory(deployed, failed, pending, superseded), 0, 1},
+		{"no previous revision", newTestReleaseHistory(deployed), 0, 0},
+		{"no previous revision deployed", newTestReleaseHistory(deployed, failed), 0, 0},
+		{"given revision", newTestReleaseHistory(deployed, superseded, superseded), 1, 1},
+		{"given revision is failed", newTestReleaseHistory(deployed, failed, superseded), 2, 0},
+		{"given revision is not found", newTestReleaseHistory(deployed, superseded), 3, 0},
+	}
+	for _, c := range cases {
+		target, err := rollbackTarget(c.history, c.revision)
+		if c.target == 0 {
+			assert.NotNil(t, err, c.name)
+			continue
+		}
+		if assert.Nil(t, err, c.name) {
+			assert.Equal(t, c.target, target.Revision, c.name)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"kubecloud/backend/models"
+	"kubecloud/common/utils"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/astaxie/beego"
+	yamlencoder "github.com/ghodss/yaml"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// the length limit of release name in helm
+	helmReleaseNameMaxLength = 53
+	// the size limit of chart downloaded from repository
+	helmChartMaxSize = 20 << 20
+)
+
+// HelmChart is the source of chart, one of path, archive and repository must be given
+type HelmChart struct {
+	// Path is the chart directory or tarball relative to the chart directory(helm::chartDir)
+	Path string `json:"path,omitempty"`
+	// Archive is the chart tarball which is stored with the template, it is base64 encoded in json
+	Archive []byte `json:"archive,omitempty"`
+	// Repo is the url of chart repository, the chart is selected from its index by Name and Version,
+	// Version may be a constraint such as "~1.2", the latest one is selected if it is empty
+	Repo    string `json:"repo,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// helm template renders the chart into manifests in-process, the manifests are deployed as native template
+type HelmTemplate struct {
+	Chart HelmChart `json:"chart"`
+	// Values is the yaml which overrides the default values of chart
+	Values string `json:"values"`
+	// Release is the name of release, the chart name is used if it is empty
+	Release string       `json:"release"`
+	Config  DeployConfig `json:"config"`
+
+	// the loaded chart is cached, so the chart of repository is not downloaded again on deploying
+	chart *chart.Chart
+}
+
+func NewHelmTemplate() *HelmTemplate {
+	return &HelmTemplate{}
+}
+
+// set default value for template config
+func (t *HelmTemplate) Default(cluster string) Template {
+	native := &NativeTemplate{Config: t.Config}
+	native.Default(cluster)
+	t.Config = native.Config
+	return t
+}
+
+func (t *HelmTemplate) Validate() error {
+	sources := 0
+	for _, given := range []bool{t.Chart.Path != "", len(t.Chart.Archive) != 0, t.Chart.Repo != ""} {
+		if given {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("one of chart path, archive and repository must be given!")
+	}
+	if t.Chart.Repo != "" && t.Chart.Name == "" {
+		return fmt.Errorf("chart name must be given with the chart repository!")
+	}
+	if len(t.Chart.Archive) > TemplateMaxSize {
+		return fmt.Errorf("the size of chart archive can not be above %vKB!", TemplateMaxSize>>10)
+	}
+	if _, err := chartutil.ReadValues([]byte(t.Values)); err != nil {
+		return fmt.Errorf("the values is not valid yaml: %v", err)
+	}
+	if _, err := t.loadChart(); err != nil {
+		return err
+	}
+	name := t.ReleaseName()
+	if errs := validation.IsDNS1123Label(name); len(errs) != 0 {
+		return fmt.Errorf("release name %q is invalid: %s!", name, strings.Join(errs, ","))
+	}
+	if len(name) > helmReleaseNameMaxLength {
+		return fmt.Errorf("the length of release name can not be above %v!", helmReleaseNameMaxLength)
+	}
+	manifest, err := t.Render("", 1, nil)
+	if err != nil {
+		return err
+	}
+	native := &NativeTemplate{Template: manifest, Config: t.Config}
+	return native.Validate()
+}
+
+// Deploy renders the chart as the next revision of release, and deploys the manifests as native template
+func (t *HelmTemplate) Deploy(projectid int64, cluster, namespace, tname string, eparam *ExtensionParam) error {
+	ar, err := NewAppRes(cluster, nil)
+	if err != nil {
+		return err
+	}
+	caps, err := helmCapabilities(ar.Client)
+	if err != nil {
+		return err
+	}
+	hr := NewHelmReleaseRes(cluster)
+	history, err := hr.modelHandle.GetReleaseHistory(cluster, namespace, t.ReleaseName())
+	if err != nil {
+		return err
+	}
+	action := "Install"
+	if len(history) != 0 {
+		action = "Upgrade"
+	}
+	release, err := t.newRelease(cluster, namespace, tname, "")
+	if err != nil {
+		return err
+	}
+	// the revision is rendered into the manifest, so it is allocated before rendering
+	return hr.deployRelease(release, history, action, projectid, eparam, func(revision int) (string, error) {
+		return t.Render(namespace, revision, caps)
+	})
+}
+
+func (t *HelmTemplate) GetExample() []byte {
+	spec := "{\n" +
+		"  \"chart\": {\n" +
+		"    \"repo\": \"https://charts.example.com/stable\",\n" +
+		"    \"name\": \"helloworld\",\n" +
+		"    \"version\": \"~1.0\"\n" +
+		"  },\n" +
+		"  \"values\": \"replicaCount: 2\\nnodeSelector:\\n  com.zhonganinfo.bizcluster: zis\\n\",\n" +
+		"  \"release\": \"helloworld\"\n" +
+		"}"
+	return []byte(spec)
+}
+
+// ReleaseName returns the name of release, it is the chart name if the release is not given
+func (t *HelmTemplate) ReleaseName() string {
+	if t.Release != "" {
+		return t.Release
+	}
+	if t.chart != nil {
+		return t.chart.Name()
+	}
+	return t.Chart.Name
+}
+
+// Render renders the chart by the values into the manifests which are ordered by kind,
+// the hooks and notes of chart are not supported and skipped
+func (t *HelmTemplate) Render(namespace string, revision int, caps *chartutil.Capabilities) (string, error) {
+	chrt, err := t.loadChart()
+	if err != nil {
+		return "", err
+	}
+	// the template is validated without cluster if caps is nil, and the kube version is checked on deploying
+	checkKubeVersion := caps != nil
+	if caps == nil {
+		caps = chartutil.DefaultCapabilities
+	}
+	if checkKubeVersion && chrt.Metadata.KubeVersion != "" && !chartutil.IsCompatibleRange(chrt.Metadata.KubeVersion, caps.KubeVersion.String()) {
+		return "", fmt.Errorf("chart requires kubeVersion: %s which is incompatible with kubernetes %s!",
+			chrt.Metadata.KubeVersion, caps.KubeVersion.String())
+	}
+	vals, err := chartutil.ReadValues([]byte(t.Values))
+	if err != nil {
+		return "", fmt.Errorf("the values is not valid yaml: %v", err)
+	}
+	if err := chartutil.ProcessDependencies(chrt, vals); err != nil {
+		return "", fmt.Errorf("process dependencies of chart failed: %v", err)
+	}
+	options := chartutil.ReleaseOptions{
+		Name:      t.ReleaseName(),
+		Namespace: namespace,
+		Revision:  revision,
+		IsInstall: revision <= 1,
+		IsUpgrade: revision > 1,
+	}
+	renderVals, err := chartutil.ToRenderValues(chrt, vals, options, caps)
+	if err != nil {
+		return "", err
+	}
+	files, err := engine.Render(chrt, renderVals)
+	if err != nil {
+		return "", fmt.Errorf("render chart failed: %v", err)
+	}
+	for name := range files {
+		if strings.HasSuffix(name, "NOTES.txt") {
+			delete(files, name)
+		}
+	}
+	hooks, manifests, err := releaseutil.SortManifests(files, caps.APIVersions, releaseutil.InstallOrder)
+	if err != nil {
+		return "", fmt.Errorf("parse manifests of chart failed: %v", err)
+	}
+	if len(hooks) != 0 {
+		beego.Warn(fmt.Sprintf("the %v hooks of chart %s are skipped!", len(hooks), chrt.Name()))
+	}
+	buf := bytes.Buffer{}
+	for _, m := range manifests {
+		fmt.Fprintf(&buf, "---\n# Source: %s\n%s\n", m.Name, m.Content)
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("the chart %s has no manifests!", chrt.Name())
+	}
+	return buf.String(), nil
+}
+
+func (t *HelmTemplate) loadChart() (*chart.Chart, error) {
+	if t.chart != nil {
+		return t.chart, nil
+	}
+	var chrt *chart.Chart
+	var err error
+	switch {
+	case t.Chart.Path != "":
+		chrt, err = loadLocalChart(t.Chart.Path)
+	case len(t.Chart.Archive) != 0:
+		chrt, err = loader.LoadArchive(bytes.NewReader(t.Chart.Archive))
+	case t.Chart.Repo != "":
+		chrt, err = loadRepoChart(t.Chart.Repo, t.Chart.Name, t.Chart.Version)
+	default:
+		return nil, fmt.Errorf("the source of chart is not given!")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load chart failed: %v", err)
+	}
+	if err := chrt.Validate(); err != nil {
+		return nil, fmt.Errorf("the chart is invalid: %v", err)
+	}
+	if chrt.Metadata.Type != "" && chrt.Metadata.Type != "application" {
+		return nil, fmt.Errorf("the chart of type %q can not be deployed!", chrt.Metadata.Type)
+	}
+	t.chart = chrt
+	return chrt, nil
+}
+
+func loadLocalChart(path string) (*chart.Chart, error) {
+	path = filepath.Clean(path)
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("chart path %s must be relative to the chart directory!", path)
+	}
+	return loader.Load(filepath.Join(beego.AppConfig.DefaultString("helm::chartDir", "charts"), path))
+}
+
+type helmRepoIndex struct {
+	Entries map[string][]struct {
+		Version string   `json:"version"`
+		URLs    []string `json:"urls"`
+	} `json:"entries"`
+}
+
+// the chart repositories are configured by helm::repos, which is the comma separated urls of repositories,
+// only the charts of them can be downloaded, so that the server does not request any url given by users
+func helmRepos() []string {
+	repos := []string{}
+	for _, repo := range strings.Split(beego.AppConfig.DefaultString("helm::repos", ""), ",") {
+		if repo = strings.TrimSuffix(strings.TrimSpace(repo), "/"); repo != "" {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+// helmRepoURL returns the url of the configured repository
+func helmRepoURL(repo string) (*url.URL, error) {
+	repo = strings.TrimSuffix(strings.TrimSpace(repo), "/")
+	for _, item := range helmRepos() {
+		if item == repo {
+			return url.Parse(repo + "/")
+		}
+	}
+	return nil, fmt.Errorf("chart repository %s is not configured!", repo)
+}
+
+// helmURLAllowed checks the url is served by the host of a configured repository,
+// the urls of charts in the index of repository and the redirections are checked by it
+func helmURLAllowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	for _, repo := range helmRepos() {
+		if repoURL, err := url.Parse(repo); err == nil && strings.EqualFold(repoURL.Host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+var helmHttpClient = &http.Client{
+	Transport: utils.HttpClient.Transport,
+	Timeout:   utils.HttpClient.Timeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		if !helmURLAllowed(req.URL) {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+		return nil
+	},
+}
+
+// loadRepoChart downloads the chart from repository by the index of repository
+func loadRepoChart(repo, name, version string) (*chart.Chart, error) {
+	repoURL, err := helmRepoURL(repo)
+	if err != nil {
+		return nil, err
+	}
+	data, err := helmDownload(repoURL.ResolveReference(&url.URL{Path: "index.yaml"}))
+	if err != nil {
+		return nil, err
+	}
+	index := helmRepoIndex{}
+	if err := yamlencoder.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("decode index of repository %s failed: %v", repo, err)
+	}
+	constraint, err := semver.NewConstraint("*")
+	if version != "" {
+		constraint, err = semver.NewConstraint(version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("chart version %q is invalid: %v", version, err)
+	}
+	var selected *semver.Version
+	chartURL := ""
+	for _, entry := range index.Entries[name] {
+		v, err := semver.NewVersion(entry.Version)
+		if err != nil || len(entry.URLs) == 0 || !constraint.Check(v) {
+			continue
+		}
+		if selected == nil || v.GreaterThan(selected) {
+			selected = v
+			chartURL = entry.URLs[0]
+		}
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("chart %s(%s) is not found in repository %s!", name, version, repo)
+	}
+	ref, err := url.Parse(chartURL)
+	if err != nil {
+		return nil, err
+	}
+	// the url of chart may be relative to the repository
+	data, err = helmDownload(repoURL.ResolveReference(ref))
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadArchive(bytes.NewReader(data))
+}
+
+func helmDownload(u *url.URL) ([]byte, error) {
+	if !helmURLAllowed(u) {
+		return nil, fmt.Errorf("download %s is not allowed, it is not in the configured chart repositories!", u.String())
+	}
+	resp, err := helmHttpClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s failed: %s", u.String(), resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, helmChartMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download %s failed: %v", u.String(), err)
+	}
+	if len(data) > helmChartMaxSize {
+		return nil, fmt.Errorf("the size of %s is above %vMB!", u.String(), helmChartMaxSize>>20)
+	}
+	return data, nil
+}
+
+// helmCapabilities returns the capabilities of the cluster which the chart is rendered for,
+// the api versions are the served group versions and kinds like the ones of helm
+func helmCapabilities(client kubernetes.Interface) (*chartutil.Capabilities, error) {
+	info, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("get kubernetes version failed: %v", err)
+	}
+	// the versions of the groups which fail to be discovered are skipped
+	_, resourceLists, err := client.Discovery().ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("get kubernetes api versions failed: %v", err)
+	}
+	versions := chartutil.VersionSet{}
+	for _, list := range resourceLists {
+		versions = append(versions, list.GroupVersion)
+		for _, res := range list.APIResources {
+			versions = append(versions, list.GroupVersion+"/"+res.Kind)
+		}
+	}
+	caps := *chartutil.DefaultCapabilities
+	caps.KubeVersion = chartutil.KubeVersion{
+		Version: info.GitVersion,
+		Major:   info.Major,
+		Minor:   info.Minor,
+	}
+	caps.APIVersions = versions
+	return &caps, nil
+}
+
+func (t *HelmTemplate) newRelease(cluster, namespace, tname, manifest string) (*models.ZcloudHelmRelease, error) {
+	chrt, err := t.loadChart()
+	if err != nil {
+		return nil, err
+	}
+	// the archive is not recorded again, the release is rolled back by its manifest
+	source := t.Chart
+	source.Archive = nil
+	sourceData, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	configData, err := json.Marshal(t.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ZcloudHelmRelease{
+		Cluster:      cluster,
+		Namespace:    namespace,
+		Name:         t.ReleaseName(),
+		Chart:        chrt.Name(),
+		ChartVersion: chrt.Metadata.Version,
+		Source:       string(sourceData),
+		Values:       t.Values,
+		Manifest:     manifest,
+		Config:       string(configData),
+		TemplateName: tname,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package resource
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astaxie/beego"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadRepoChartRestrictsRepos(t *testing.T) {
+	requested := []string{}
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, "other"+r.URL.Path)
+	}))
+	defer other.Close()
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/stable/index.yaml":
+			fmt.Fprintf(w, "entries:\n  web:\n  - version: 1.0.0\n    urls: [%s/web-1.0.0.tgz]\n  - version: 0.9.0\n    urls: [web-0.9.0.tgz]\n", other.URL)
+		case "/stable/redirect/index.yaml":
+			http.Redirect(w, r, other.URL+"/index.yaml", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer repo.Close()
+
+	beego.AppConfig.Set("helm::repos", "")
+	_, err := loadRepoChart(repo.URL+"/stable", "web", "")
+	assert.EqualError(t, err, fmt.Sprintf("chart repository %s/stable is not configured!", repo.URL))
+
+	beego.AppConfig.Set("helm::repos", " "+repo.URL+"/stable/ ,"+repo.URL+"/stable/redirect")
+	defer beego.AppConfig.Set("helm::repos", "")
+	// the chart of the other host in the index is not downloaded
+	_, err = loadRepoChart(repo.URL+"/stable", "web", "")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is not allowed")
+	// the relative url of chart is resolved against the repository
+	_, err = loadRepoChart(repo.URL+"/stable/", "web", "~0.9")
+	assert.EqualError(t, err, fmt.Sprintf("download %s/stable/web-0.9.0.tgz failed: 404 Not Found", repo.URL))
+	// the redirection to the other host is not followed
+	_, err = loadRepoChart(repo.URL+"/stable/redirect", "web", "")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is not allowed")
+
+	assert.Equal(t, []string{"/stable/index.yaml", "/stable/index.yaml", "/stable/web-0.9.0.tgz", "/stable/redirect/index.yaml"}, requested)
+}
+
+func TestHelmCapabilities(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{GitVersion: "v1.21.3", Major: "1", Minor: "21"}
+	discovery.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment"}}},
+		{GroupVersion: "monitoring.coreos.com/v1", APIResources: []metav1.APIResource{{Name: "servicemonitors", Kind: "ServiceMonitor"}}},
+	}
+	caps, err := helmCapabilities(client)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.21.3", caps.KubeVersion.Version)
+	assert.True(t, caps.APIVersions.Has("monitoring.coreos.com/v1"))
+	assert.True(t, caps.APIVersions.Has("monitoring.coreos.com/v1/ServiceMonitor"))
+	assert.True(t, caps.APIVersions.Has("apps/v1/Deployment"))
+	assert.False(t, caps.APIVersions.Has("batch/v1"))
+}
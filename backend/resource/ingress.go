@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -17,10 +18,9 @@ import (
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/orm"
 	apiv1 "k8s.io/api/core/v1"
-	"k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 )
@@ -39,6 +39,8 @@ type BackendServer struct {
 type IngressDetail struct {
 	Backend []BackendServer `json:"backend"`
 	Config  IngressConfig   `json:"config,omitempty"`
+	// Probe is the availability of the host and path probed through the load balancer
+	Probe *IngressProbeStatus `json:"probe,omitempty"`
 }
 
 type IngressPath struct {
@@ -61,20 +63,22 @@ type Ingress struct {
 type IngressRule struct {
 	models.K8sIngressRule `json:",inline"`
 	Protocol              string `json:"protocol"`
-	CreateAt              string `json:"create_at"`
-	UpdateAt              string `json:"update_at"`
-	DeleteAt              string `json:"delete_at"`
+	// CertNotAfter is the expiry time of the certificate of https rule
+	CertNotAfter string `json:"cert_not_after,omitempty"`
+	CreateAt     string `json:"create_at"`
+	UpdateAt     string `json:"update_at"`
+	DeleteAt     string `json:"delete_at"`
 }
 
 type SimpleIngressRule struct {
-	Host  string                    `json:"host,omitempty"`
-	Paths []v1beta1.HTTPIngressPath `json:"paths,omitempty"`
+	Host  string                         `json:"host,omitempty"`
+	Paths []networkingv1.HTTPIngressPath `json:"paths,omitempty"`
 }
 
 type SimpleIngressDetail struct {
-	Name  string               `json:"name"`
-	TLS   []v1beta1.IngressTLS `json:"tls,omitempty"`
-	Rules []SimpleIngressRule  `json:"rules,omitempty"`
+	Name  string                    `json:"name"`
+	TLS   []networkingv1.IngressTLS `json:"tls,omitempty"`
+	Rules []SimpleIngressRule       `json:"rules,omitempty"`
 }
 
 type IngressRes struct {
@@ -84,6 +88,9 @@ type IngressRes struct {
 	modelEndpoint *dao.K8sEndpointModel
 	modelSvc      *dao.K8sServiceModel
 	kubeRule      *dao.IngressRuleModel
+	modelSecret   *dao.SecretModel
+	modelAcme     *dao.AcmeCertificateModel
+	provider      IngressProvider
 	listNSFunc    NamespaceListFunction
 }
 
@@ -112,20 +119,28 @@ func NewIngressRes(cluster string, client kubernetes.Interface, get NamespaceLis
 	ingHandle.modelEndpoint = dao.NewK8sEndpointModel()
 	ingHandle.modelSvc = dao.NewK8sServiceModel()
 	ingHandle.kubeRule = dao.NewIngressRuleModel()
+	ingHandle.modelSecret = dao.NewSecretModel()
+	ingHandle.modelAcme = dao.NewAcmeCertificateModel()
+	if ingHandle.provider, err = GetIngressProvider(cluster); err != nil {
+		return nil, fmt.Errorf("get ingress provider error %v", err)
+	}
 
 	return ingHandle, nil
 }
 
+func (ing *IngressRes) apiVersions() kubeutil.APIVersions {
+	return kubeutil.GetAPIVersions(ing.cluster, ing.client.Discovery())
+}
+
 func (ing *IngressRes) Validate(ingress *Ingress) error {
 	err := validate.ValidateString(ingress.Namespace)
 	if err != nil {
 		return err
 	}
 	if ingress.Protocol == PROTOCOL_HTTPS {
-		if ingress.SecretName == "" {
-			return fmt.Errorf("https must have certificate secret name!")
+		if err := ing.validateTLS(ingress); err != nil {
+			return err
 		}
-		// todo check secret is existed or not
 	} else if ingress.Protocol != PROTOCOL_HTTP {
 		return fmt.Errorf("communication protocol must be http or https!")
 	}
@@ -136,6 +151,7 @@ func (ing *IngressRes) Validate(ingress *Ingress) error {
 	if len(ingress.Paths) == 0 {
 		return fmt.Errorf("path and its backend server must be given!")
 	}
+	appnames := []string{}
 	for i, path := range ingress.Paths {
 		if len(path.Path) > validate.NormalMaxLen {
 			return fmt.Errorf("path length can not be above %v bytes!", validate.NormalMaxLen)
@@ -171,6 +187,9 @@ func (ing *IngressRes) Validate(ingress *Ingress) error {
 		}
 		// set real svc name
 		ingress.Paths[i].ServiceName = svc.Name
+		if !utils.ContainsString(appnames, svc.OwnerName) {
+			appnames = append(appnames, svc.OwnerName)
+		}
 		isExisted := false
 		for _, item := range svc.Ports {
 			if item.Port == path.ServicePort {
@@ -183,36 +202,33 @@ func (ing *IngressRes) Validate(ingress *Ingress) error {
 		}
 	}
 
-	return nil
+	if err := ing.validateAccess(ingress); err != nil {
+		return err
+	}
+	return ing.validateMatches(ingress, appnames)
 }
 
-func (ing *IngressRes) makeKubeIngress(ingress Ingress) *v1beta1.Ingress {
-	kubeIngress := &v1beta1.Ingress{}
+func (ing *IngressRes) makeKubeIngress(ingress Ingress) *networkingv1.Ingress {
+	kubeIngress := &networkingv1.Ingress{}
 	kubeIngress.Kind = "Ingress"
+	kubeIngress.APIVersion = IngApiVersion
 	kubeIngress.Name = ingress.Name
 	kubeIngress.Namespace = ingress.Namespace
 	kubeIngress.Labels = map[string]string{}
 	kubeIngress.Annotations = map[string]string{}
 	if ingress.Protocol == PROTOCOL_HTTPS {
-		tls := v1beta1.IngressTLS{
+		tls := networkingv1.IngressTLS{
 			Hosts:      []string{ingress.Host},
 			SecretName: ingress.SecretName,
 		}
 		kubeIngress.Spec.TLS = append(kubeIngress.Spec.TLS, tls)
 	}
 
-	http := v1beta1.HTTPIngressRuleValue{}
+	http := networkingv1.HTTPIngressRuleValue{}
 	for _, item := range ingress.Paths {
-		path := v1beta1.HTTPIngressPath{
-			Path: item.Path,
-			Backend: v1beta1.IngressBackend{
-				ServiceName: item.ServiceName,
-				ServicePort: intstr.FromInt(item.ServicePort),
-			},
-		}
-		http.Paths = append(http.Paths, path)
+		http.Paths = append(http.Paths, kubeutil.NewIngressPath(item.Path, item.ServiceName, item.ServicePort))
 	}
-	iRule := v1beta1.IngressRule{
+	iRule := networkingv1.IngressRule{
 		Host: ingress.Host,
 	}
 	iRule.HTTP = &http
@@ -245,12 +261,6 @@ func (ing *IngressRes) getBackend(rule *models.K8sIngressRule) ([]BackendServer,
 }
 
 func (ing *IngressRes) CreateIngress(ingress *Ingress) error {
-	if ingress.Name == "" {
-		ingress.Name = ing.kubeRule.GetIngressNameByHost(ing.cluster, ingress.Namespace, ingress.Host)
-		if ingress.Name == "" {
-			ingress.Name = genIngressName(ing.cluster, *ingress)
-		}
-	}
 	if err := ing.kubeRule.CheckHostUnique(ing.cluster, ingress.Namespace, ingress.Host); err != nil {
 		return common.NewConflict().SetCause(err)
 	}
@@ -261,9 +271,23 @@ func (ing *IngressRes) CreateIngress(ingress *Ingress) error {
 	if err := ing.kubeRule.CheckPathsUniqueInHost(ing.cluster, ingress.Namespace, ingress.Host, checkPaths, -1); err != nil {
 		return common.NewConflict().SetCause(err)
 	}
-	var obj *v1beta1.Ingress
+	if err := CheckIngressRoutes(ing.cluster, ingress.Namespace, ingress.Host, checkPaths); err != nil {
+		return common.NewConflict().SetCause(err)
+	}
+	// the paths are added to the ingress of host which has the same shared config
+	name, err := ing.selectIngress(ingress.Namespace, ingress.Host, ingress.Name, genIngressName(ing.cluster, *ingress), &ingress.IngressConfig)
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	ingress.Name = name
+	return ing.addPaths(ingress)
+}
+
+// addPaths adds the paths of host to the ingress, it is created if it is not existed
+func (ing *IngressRes) addPaths(ingress *Ingress) error {
+	var obj *networkingv1.Ingress
 	create := true
-	old, err := ing.client.ExtensionsV1beta1().Ingresses(ingress.Namespace).Get(ingress.Name, metav1.GetOptions{})
+	old, err := kubeutil.GetIngress(ing.client, ing.apiVersions(), ingress.Namespace, ingress.Name)
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			return common.NewInternalServerError().SetCause(err)
@@ -284,27 +308,47 @@ func (ing *IngressRes) CreateIngress(ingress *Ingress) error {
 		paths = append(paths, path.Path)
 		svcList[path.ServiceName] = nil
 	}
-	confer := NewIngressConfer(&ingress.IngressConfig, paths, ingress.Host)
-	if err = confer.Validate(); err != nil {
+	if err = ing.provider.Validate(ingress); err != nil {
 		return common.NewBadRequest().SetCause(err)
 	}
-	if err = confer.SetIngress(obj.Annotations); err != nil {
+	if obj.Annotations == nil {
+		obj.Annotations = make(map[string]string)
+	}
+	if err = ing.provider.SetIngress(obj, &ingress.IngressConfig, paths, ingress.Host); err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
+	if err = setIngressTLS(obj, ingress.Host, newTLSConfig(ingress)); err != nil {
+		return common.NewBadRequest().SetCause(err)
+	}
 	if create {
-		_, err = ing.client.ExtensionsV1beta1().Ingresses(obj.Namespace).Create(obj)
+		_, err = kubeutil.CreateIngress(ing.client, ing.apiVersions(), obj)
 	} else {
-		_, err = ing.client.ExtensionsV1beta1().Ingresses(obj.Namespace).Update(kubeutil.DeleteCreatedDefaultAnno(obj))
+		_, err = kubeutil.UpdateIngress(ing.client, ing.apiVersions(), kubeutil.DeleteCreatedDefaultAnno(obj))
 	}
 	if err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
+	if err = ing.syncHostTLS(ingress, ingress.Name); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
 	// set svc
 	for svc, _ := range svcList {
-		if newerr := ing.setService(ingress.Namespace, svc, confer); newerr != nil {
+		if newerr := ing.setService(ingress.Namespace, svc, &ingress.IngressConfig); newerr != nil {
 			beego.Warn("set service annotations failed: ", newerr)
 		}
 	}
+	if err = ing.applyProviderObjects(obj); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err = ing.applyVersionServices(ingress.Namespace); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err = ing.applyHtpasswdSecrets(ingress.Namespace); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err = ing.syncAcmeCertificate(obj, ingress.Host); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
 	check := func(param interface{}) error {
 		for _, path := range ingress.Paths {
 			record, err := ing.ModelHandle.Get(ing.cluster, ingress.Namespace, ingress.Name)
@@ -357,8 +401,11 @@ func (ing *IngressRes) UpdateIngress(id int64, ingress Ingress) error {
 	if ingress.Name == "" {
 		ingress.Name = oldRule.IngressName
 	}
+	if err := CheckIngressRoutes(ing.cluster, ingress.Namespace, ingress.Host, checkPaths); err != nil {
+		return common.NewConflict().SetCause(err)
+	}
 	//ingessObj := ing.makeKubeIngress(ingress)
-	old, err := ing.client.ExtensionsV1beta1().Ingresses(ingress.Namespace).Get(ingress.Name, metav1.GetOptions{})
+	old, err := kubeutil.GetIngress(ing.client, ing.apiVersions(), ingress.Namespace, ingress.Name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return common.NewNotFound().
@@ -366,6 +413,20 @@ func (ing *IngressRes) UpdateIngress(id int64, ingress Ingress) error {
 		}
 		return common.NewInternalServerError().SetCause(err)
 	}
+	// the paths are moved to another ingress of host if their shared config is changed,
+	// and the features which are not given are not changed
+	if ing.provider.SharedConfig(&ingress.IngressConfig) != nil {
+		fillIngressConfig(&ingress.IngressConfig, ing.GetConfig(oldRule))
+		if !ingressHasOnlyPaths(old, ingress.Host, append(checkPaths, oldRule.Path)) {
+			target, err := ing.selectIngress(ingress.Namespace, ingress.Host, ingress.Name, genIngressName(ing.cluster, ingress), &ingress.IngressConfig)
+			if err != nil {
+				return common.NewInternalServerError().SetCause(err)
+			}
+			if target != ingress.Name {
+				return ing.movePaths(*oldRule, ingress, target)
+			}
+		}
+	}
 	obj, err := ing.modifyRule(ingress, old, *oldRule)
 	if err != nil {
 		return common.NewBadRequest().SetCause(err)
@@ -380,24 +441,41 @@ func (ing *IngressRes) UpdateIngress(id int64, ingress Ingress) error {
 		paths = append(paths, path.Path)
 		svcList[path.ServiceName] = nil
 	}
-	confer := NewIngressConfer(&ingress.IngressConfig, paths, ingress.Host)
-	if err = confer.Validate(); err != nil {
+	if err = ing.provider.Validate(&ingress); err != nil {
 		return common.NewBadRequest().SetCause(err)
 	}
-	if err = confer.SetIngress(obj.Annotations); err != nil {
+	if err = ing.provider.SetIngress(obj, &ingress.IngressConfig, paths, ingress.Host); err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
+	if err = setIngressTLS(obj, ingress.Host, newTLSConfig(&ingress)); err != nil {
+		return common.NewBadRequest().SetCause(err)
+	}
 	if !utils.ObjectIsEqual(old, obj) {
-		if _, err = ing.client.ExtensionsV1beta1().Ingresses(obj.Namespace).Update(kubeutil.DeleteCreatedDefaultAnno(obj)); err != nil {
+		if _, err = kubeutil.UpdateIngress(ing.client, ing.apiVersions(), kubeutil.DeleteCreatedDefaultAnno(obj)); err != nil {
 			return common.NewInternalServerError().SetCause(err)
 		}
 	}
+	if err = ing.syncHostTLS(&ingress, ingress.Name); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
 	// set svc
 	for svc, _ := range svcList {
-		if newerr := ing.setService(ingress.Namespace, svc, confer); newerr != nil {
+		if newerr := ing.setService(ingress.Namespace, svc, &ingress.IngressConfig); newerr != nil {
 			beego.Warn("set service annotations failed: ", newerr)
 		}
 	}
+	if err = ing.applyProviderObjects(obj); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err = ing.applyVersionServices(ingress.Namespace); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err = ing.applyHtpasswdSecrets(ingress.Namespace); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err = ing.syncAcmeCertificate(obj, ingress.Host); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
 	return nil
 }
 
@@ -406,7 +484,7 @@ func (ing *IngressRes) DeleteIngress(namespace string, id int64) error {
 	if err != nil {
 		return err
 	}
-	old, err := ing.client.ExtensionsV1beta1().Ingresses(rule.Namespace).Get(rule.Ingress.Name, metav1.GetOptions{})
+	old, err := kubeutil.GetIngress(ing.client, ing.apiVersions(), rule.Namespace, rule.Ingress.Name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			beego.Warn(fmt.Sprintf("ingress(%s/%s/%s) is not found, so delete its info from db", ing.cluster, namespace, rule.Ingress.Name))
@@ -423,7 +501,7 @@ func (ing *IngressRes) DeleteIngress(namespace string, id int64) error {
 	}
 	if len(obj.Spec.Rules) == 0 {
 		// delete
-		err = ing.client.ExtensionsV1beta1().Ingresses(rule.Namespace).Delete(rule.Ingress.Name, &metav1.DeleteOptions{})
+		err = kubeutil.DeleteIngress(ing.client, ing.apiVersions(), rule.Namespace, rule.Ingress.Name)
 		if err != nil {
 			if errors.IsNotFound(err) {
 				return nil
@@ -431,11 +509,30 @@ func (ing *IngressRes) DeleteIngress(namespace string, id int64) error {
 		}
 	} else {
 		// update
-		_, err = ing.client.ExtensionsV1beta1().Ingresses(obj.Namespace).Update(kubeutil.DeleteCreatedDefaultAnno(obj))
+		_, err = kubeutil.UpdateIngress(ing.client, ing.apiVersions(), kubeutil.DeleteCreatedDefaultAnno(obj))
 	}
 	if err != nil {
 		return err
 	}
+	if err = ing.applyProviderObjects(obj); err != nil {
+		return err
+	}
+	if err = ing.applyVersionServices(rule.Namespace); err != nil {
+		return err
+	}
+	if err = ing.applyHtpasswdSecrets(rule.Namespace); err != nil {
+		return err
+	}
+	// the certificate of host is kept if the host is served by the other ingresses
+	others, err := ing.kubeRule.ListIngressNamesByHost(ing.cluster, rule.Namespace, rule.Host)
+	if err != nil {
+		return err
+	}
+	if len(others) == 0 || (len(others) == 1 && others[0] == rule.IngressName) {
+		if err = ing.syncAcmeCertificate(obj, rule.Host); err != nil {
+			return err
+		}
+	}
 	check := func(param interface{}) error {
 		_, err := ing.kubeRule.GetByID(ing.cluster, namespace, id)
 		if err == orm.ErrNoRows {
@@ -472,6 +569,7 @@ func (ing *IngressRes) ListIngresses(cluster, namespace, svcname string, filterQ
 	}
 	list := []IngressRule{}
 	svcHandler := dao.NewK8sServiceModel()
+	certNotAfter := make(map[string]string)
 	for _, item := range items {
 		svcName := svcname
 		svcPort := item.ServicePort
@@ -491,6 +589,17 @@ func (ing *IngressRes) ListIngresses(cluster, namespace, svcname string, filterQ
 		one.ServicePort = svcPort
 		if item.IsTls {
 			one.Protocol = PROTOCOL_HTTPS
+			key := item.Namespace + "/" + item.SecretName
+			if _, ok := certNotAfter[key]; !ok {
+				certNotAfter[key] = ""
+				notAfter, err := ing.getCertNotAfter(item.Namespace, item.SecretName)
+				if err != nil {
+					beego.Debug("get the certificate expiry of secret", key, "failed:", err)
+				} else {
+					certNotAfter[key] = notAfter.Local().Format("2006-01-02 15:04:05")
+				}
+			}
+			one.CertNotAfter = certNotAfter[key]
 		} else {
 			one.Protocol = PROTOCOL_HTTP
 		}
@@ -519,6 +628,9 @@ func (ing *IngressRes) GetIngressDetail(namespace string, id int64) (*IngressDet
 		Backend: be,
 		Config:  ing.GetConfig(rule),
 	}
+	if detail.Probe, err = GetIngressProbeStatus(rule); err != nil {
+		beego.Error(err)
+	}
 
 	return detail, nil
 }
@@ -526,7 +638,7 @@ func (ing *IngressRes) GetIngressDetail(namespace string, id int64) (*IngressDet
 func (ing *IngressRes) GetSimpleIngressDetail(namespace, appname string) ([]SimpleIngressDetail, error) {
 	listopt, _ := GetListOption(keyword.LABEL_APPNAME_KEY, appname)
 	// Ingress
-	inglist, err := ing.client.ExtensionsV1beta1().Ingresses(namespace).List(listopt)
+	inglist, err := kubeutil.ListIngresses(ing.client, ing.apiVersions(), namespace, listopt)
 	if err != nil {
 		beego.Error("Get ingress information failed: " + err.Error())
 		if !errors.IsNotFound(err) {
@@ -534,7 +646,7 @@ func (ing *IngressRes) GetSimpleIngressDetail(namespace, appname string) ([]Simp
 		}
 	}
 	var ilist []SimpleIngressDetail
-	getSimpleIngressDetail := func(kubeing v1beta1.Ingress) SimpleIngressDetail {
+	getSimpleIngressDetail := func(kubeing networkingv1.Ingress) SimpleIngressDetail {
 		var detail SimpleIngressDetail
 
 		var rules []SimpleIngressRule
@@ -551,7 +663,7 @@ func (ing *IngressRes) GetSimpleIngressDetail(namespace, appname string) ([]Simp
 
 		return detail
 	}
-	for _, item := range inglist.Items {
+	for _, item := range inglist {
 		ilist = append(ilist, getSimpleIngressDetail(item))
 	}
 
@@ -576,19 +688,22 @@ func (ing *IngressRes) GetConfig(rule *models.K8sIngressRule) IngressConfig {
 	if rule.Ingress.Annotation != "" {
 		utils.SimpleJsonUnmarshal(rule.Ingress.Annotation, &ingAnno)
 	}
-	confer := NewIngressConfer(nil, []string{rule.Path}, rule.Host)
-	config = confer.GetConfig(ingAnno, svcAnno)
+	config = ing.provider.GetConfig(ingAnno, svcAnno, rule.Path, rule.Host)
 	//set tls
 	config.Protocol = PROTOCOL_HTTP
 	if rule.IsTls {
 		config.Protocol = PROTOCOL_HTTPS
 		config.SecretName = rule.SecretName
+		tls := getTLSConfig(ingAnno, rule.Host)
+		config.TLSMode = tls.Mode
+		config.Issuer = tls.Issuer
+		config.IssuerKind = tls.IssuerKind
 	}
 
 	return config
 }
 
-func (ing *IngressRes) addRule(base Ingress, old *v1beta1.Ingress) (*v1beta1.Ingress, error) {
+func (ing *IngressRes) addRule(base Ingress, old *networkingv1.Ingress) (*networkingv1.Ingress, error) {
 	obj := old.DeepCopy()
 	ir := -1
 	for i, r := range old.Spec.Rules {
@@ -629,7 +744,7 @@ func (ing *IngressRes) addRule(base Ingress, old *v1beta1.Ingress) (*v1beta1.Ing
 }
 
 // modify
-func (ing *IngressRes) modifyRule(base Ingress, old *v1beta1.Ingress, oldrule models.K8sIngressRule) (*v1beta1.Ingress, error) {
+func (ing *IngressRes) modifyRule(base Ingress, old *networkingv1.Ingress, oldrule models.K8sIngressRule) (*networkingv1.Ingress, error) {
 	obj := old.DeepCopy()
 	ir := -1
 	for i, r := range obj.Spec.Rules {
@@ -648,10 +763,9 @@ func (ing *IngressRes) modifyRule(base Ingress, old *v1beta1.Ingress, oldrule mo
 		for i, path := range http.Paths {
 			if utils.PathsIsEqual(path.Path, item.Path) {
 				// update backend
-				if path.Backend.ServiceName != item.ServiceName ||
-					path.Backend.ServicePort.IntValue() != item.ServicePort {
-					http.Paths[i].Backend.ServiceName = item.ServiceName
-					http.Paths[i].Backend.ServicePort = intstr.FromInt(item.ServicePort)
+				if svcname, svcport := kubeutil.IngressBackendService(path.Backend); svcname != item.ServiceName ||
+					svcport != item.ServicePort {
+					http.Paths[i].Backend = kubeutil.NewIngressBackend(item.ServiceName, item.ServicePort)
 				}
 				found = true
 				break
@@ -659,13 +773,7 @@ func (ing *IngressRes) modifyRule(base Ingress, old *v1beta1.Ingress, oldrule mo
 		}
 		if !found {
 			// append a new path
-			http.Paths = append(http.Paths, v1beta1.HTTPIngressPath{
-				Path: item.Path,
-				Backend: v1beta1.IngressBackend{
-					ServiceName: item.ServiceName,
-					ServicePort: intstr.FromInt(item.ServicePort),
-				},
-			})
+			http.Paths = append(http.Paths, kubeutil.NewIngressPath(item.Path, item.ServiceName, item.ServicePort))
 		}
 	}
 
@@ -678,15 +786,15 @@ func (ing *IngressRes) modifyRule(base Ingress, old *v1beta1.Ingress, oldrule mo
 		}
 	}
 	if deletePath != "" {
-		paths := []v1beta1.HTTPIngressPath{}
+		paths := []networkingv1.HTTPIngressPath{}
 		for _, path := range http.Paths {
 			if path.Path != deletePath {
 				paths = append(paths, path)
 			}
 		}
 		http.Paths = paths
-		// delete path's rate limit config
-		DeletePathRateLimit(obj.Annotations, []string{deletePath})
+		// delete path's config
+		ing.provider.DeletePaths(obj.Annotations, base.Host, []string{deletePath})
 	}
 
 	// modify tls
@@ -699,9 +807,9 @@ func (ing *IngressRes) modifyRule(base Ingress, old *v1beta1.Ingress, oldrule mo
 }
 
 // deleteRule from memory
-func (ing *IngressRes) deleteRule(rule models.K8sIngressRule, old *v1beta1.Ingress) (*v1beta1.Ingress, error) {
+func (ing *IngressRes) deleteRule(rule models.K8sIngressRule, old *networkingv1.Ingress) (*networkingv1.Ingress, error) {
 	obj := old.DeepCopy()
-	obj.Spec.Rules = []v1beta1.IngressRule{}
+	obj.Spec.Rules = []networkingv1.IngressRule{}
 	index := -1
 	// delete rule
 	for i, r := range old.Spec.Rules {
@@ -728,7 +836,7 @@ func (ing *IngressRes) deleteRule(rule models.K8sIngressRule, old *v1beta1.Ingre
 	destRule := old.Spec.Rules[index]
 	if destRule.HTTP != nil {
 		// delete path
-		paths := []v1beta1.HTTPIngressPath{}
+		paths := []networkingv1.HTTPIngressPath{}
 		for _, path := range destRule.HTTP.Paths {
 			if !utils.PathsIsEqual(path.Path, rule.Path) {
 				paths = append(paths, path)
@@ -742,8 +850,8 @@ func (ing *IngressRes) deleteRule(rule models.K8sIngressRule, old *v1beta1.Ingre
 			destRule.HTTP.Paths = paths
 			obj.Spec.Rules = append(obj.Spec.Rules, destRule)
 		}
-		// delete path's rate limit config
-		DeletePathRateLimit(obj.Annotations, []string{rule.Path})
+		// delete path's config
+		ing.provider.DeletePaths(obj.Annotations, rule.Host, []string{rule.Path})
 	}
 
 	if rule.SecretName == "" || len(destRule.HTTP.Paths) != 0 {
@@ -752,36 +860,39 @@ func (ing *IngressRes) deleteRule(rule models.K8sIngressRule, old *v1beta1.Ingre
 	// delete host from tls
 	// if tls.hosts is empty, delete tls from Spec.TLS
 	obj.Spec.TLS = kubeutil.DeleteHostFromTLS(old.Spec.TLS, rule.Host)
+	if err := setIngressTLS(obj, rule.Host, nil); err != nil {
+		return nil, err
+	}
 	return obj, nil
 }
 
-func (ing *IngressRes) setService(namespace, svcname string, confer *IngressConfer) error {
-	svc, err := ing.client.CoreV1().Services(namespace).Get(svcname, metav1.GetOptions{})
+func (ing *IngressRes) setService(namespace, svcname string, config *IngressConfig) error {
+	svc, err := ing.client.CoreV1().Services(namespace).Get(context.TODO(), svcname, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
-	if confer == nil {
+	if config == nil {
 		return nil
 	}
 	if svc.Annotations == nil {
 		svc.Annotations = make(map[string]string)
 	}
-	if err = confer.SetService(svc.Annotations); err != nil {
+	if err = ing.provider.SetService(svc, config); err != nil {
 		return err
 	}
-	if confer.config.Affinity != nil {
-		if confer.config.Affinity.Affinity {
+	if config.Affinity != nil {
+		if config.Affinity.Affinity {
 			svc.Spec.SessionAffinity = apiv1.ServiceAffinityClientIP
 		} else {
 			svc.Spec.SessionAffinity = apiv1.ServiceAffinityNone
 		}
 	}
-	_, err = ing.client.CoreV1().Services(svc.Namespace).Update(svc)
+	_, err = ing.client.CoreV1().Services(svc.Namespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
 	return err
 }
 
-func modifyTLS(TLS []v1beta1.IngressTLS, host, secret string) []v1beta1.IngressTLS {
-	tlsList := []v1beta1.IngressTLS{}
+func modifyTLS(TLS []networkingv1.IngressTLS, host, secret string) []networkingv1.IngressTLS {
+	tlsList := []networkingv1.IngressTLS{}
 	index := -1
 	for it, tls := range TLS {
 		if tls.SecretName == secret {
@@ -802,7 +913,7 @@ func modifyTLS(TLS []v1beta1.IngressTLS, host, secret string) []v1beta1.IngressT
 	}
 	if index < 0 && secret != "" {
 		// just add a new tls
-		TLS = append(TLS, v1beta1.IngressTLS{
+		TLS = append(TLS, networkingv1.IngressTLS{
 			Hosts:      []string{host},
 			SecretName: secret,
 		})
@@ -0,0 +1,170 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"kubecloud/backend/util/kubeutil"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	"golang.org/x/crypto/bcrypt"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	authTypeBasic   = "basic"
+	authTypeForward = "forward"
+
+	// the ingress controllers read the users of basic auth from the htpasswd file in the secret,
+	// it is generated from the basic auth secret with this suffix and has only the key htpasswdSecretKey
+	htpasswdSecretSuffix = "-htpasswd"
+	htpasswdSecretKey    = "auth"
+	// HtpasswdSecretLabelKey is set on the htpasswd secrets, its value is the name of the basic auth secret
+	HtpasswdSecretLabelKey = "kubecloud/htpasswd-of"
+)
+
+func htpasswdSecretName(secretName string) string {
+	return secretName + htpasswdSecretSuffix
+}
+
+// validateAccess checks the access policies of ingress, the secret of basic auth must be a basic auth secret
+func (ing *IngressRes) validateAccess(ingress *Ingress) error {
+	if ingress.BasicAuth.enabled() && ingress.ForwardAuth.enabled() {
+		return fmt.Errorf("basic auth and forward auth can not be used at the same time!")
+	}
+	if !ingress.BasicAuth.enabled() {
+		return nil
+	}
+	secret, err := ing.modelSecret.GetSecret(ing.cluster, ingress.Namespace, ingress.BasicAuth.SecretName)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return fmt.Errorf("basic auth secret %s is not existed!", ingress.BasicAuth.SecretName)
+		}
+		return err
+	}
+	if secret.Type != string(apiv1.SecretTypeBasicAuth) {
+		return fmt.Errorf("the type of basic auth secret %s is %s, but it must be %s!", secret.Name, secret.Type, apiv1.SecretTypeBasicAuth)
+	}
+	return nil
+}
+
+// applyHtpasswdSecrets applies the htpasswd secrets of the basic auth secrets referenced by the ingresses
+// in namespace, and deletes the htpasswd secrets which are not referenced any more
+func (ing *IngressRes) applyHtpasswdSecrets(namespace string) error {
+	ings, err := kubeutil.ListIngresses(ing.client, ing.apiVersions(), namespace, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	desired := make(map[string]bool)
+	for _, item := range ings {
+		if item.Labels[IngressOwnerLabelKey] != "" {
+			continue
+		}
+		for _, rule := range item.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				config := ing.provider.GetConfig(item.Annotations, nil, path.Path, rule.Host)
+				if config.BasicAuth.enabled() {
+					desired[config.BasicAuth.SecretName] = true
+				}
+			}
+		}
+	}
+	names := []string{}
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		secret, err := ing.client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				beego.Warn(fmt.Sprintf("the basic auth secret %s/%s is not found", namespace, name))
+				continue
+			}
+			return err
+		}
+		if err := applyHtpasswdSecret(ing.client, secret, true); err != nil {
+			return err
+		}
+	}
+	list, err := ing.client.CoreV1().Secrets(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: HtpasswdSecretLabelKey})
+	if err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		if desired[item.Labels[HtpasswdSecretLabelKey]] {
+			continue
+		}
+		if err := ing.client.CoreV1().Secrets(namespace).Delete(context.TODO(), item.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("delete htpasswd secret %s failed: %v", item.Name, err)
+		}
+	}
+	return nil
+}
+
+// htpasswdEntry returns the htpasswd line of the user with the bcrypt password, which is supported by all
+// ingress controllers. The old line is kept if it is of the same user and password, because the bcrypt hash
+// is salted randomly and the ingress controllers reload the secret once it is changed
+func htpasswdEntry(username, password, old []byte) ([]byte, error) {
+	prefix := append(append([]byte{}, username...), ':')
+	if bytes.HasPrefix(old, prefix) &&
+		bcrypt.CompareHashAndPassword(bytes.TrimSpace(old[len(prefix):]), password) == nil {
+		return old, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%s%s\n", prefix, hash)), nil
+}
+
+// applyHtpasswdSecret updates the htpasswd secret of the basic auth secret, it is created if create is true
+func applyHtpasswdSecret(client kubernetes.Interface, secret *apiv1.Secret, create bool) error {
+	if secret.Type != apiv1.SecretTypeBasicAuth {
+		return fmt.Errorf("the type of basic auth secret %s is %s, but it must be %s", secret.Name, secret.Type, apiv1.SecretTypeBasicAuth)
+	}
+	htpasswd := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      htpasswdSecretName(secret.Name),
+			Namespace: secret.Namespace,
+			Labels:    map[string]string{HtpasswdSecretLabelKey: secret.Name},
+		},
+		Type: apiv1.SecretTypeOpaque,
+	}
+	old, err := client.CoreV1().Secrets(secret.Namespace).Get(context.TODO(), htpasswd.Name, metav1.GetOptions{})
+	if err != nil && (!errors.IsNotFound(err) || !create) {
+		return err
+	}
+	existed := err == nil
+	var oldEntry []byte
+	if existed {
+		if old.Labels[HtpasswdSecretLabelKey] != secret.Name {
+			return fmt.Errorf("the secret %s is not the htpasswd secret of %s", old.Name, secret.Name)
+		}
+		oldEntry = old.Data[htpasswdSecretKey]
+	}
+	entry, err := htpasswdEntry(secret.Data[apiv1.BasicAuthUsernameKey], secret.Data[apiv1.BasicAuthPasswordKey], oldEntry)
+	if err != nil {
+		return fmt.Errorf("hash the password of basic auth secret %s failed: %v", secret.Name, err)
+	}
+	htpasswd.Data = map[string][]byte{htpasswdSecretKey: entry}
+	if !existed {
+		_, err = client.CoreV1().Secrets(secret.Namespace).Create(context.TODO(), htpasswd, metav1.CreateOptions{})
+	} else if !bytes.Equal(entry, oldEntry) {
+		htpasswd.ResourceVersion = old.ResourceVersion
+		_, err = client.CoreV1().Secrets(secret.Namespace).Update(context.TODO(), htpasswd, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("apply htpasswd secret %s failed: %v", htpasswd.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApplyHtpasswdSecret(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "auth", Namespace: "default"},
+		Type:       apiv1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			apiv1.BasicAuthUsernameKey: []byte("admin"),
+			apiv1.BasicAuthPasswordKey: []byte("secret"),
+		},
+	}
+	getEntry := func() []byte {
+		htpasswd, err := client.CoreV1().Secrets("default").Get(context.TODO(), htpasswdSecretName("auth"), metav1.GetOptions{})
+		assert.Nil(t, err)
+		return htpasswd.Data[htpasswdSecretKey]
+	}
+	assert.NotNil(t, applyHtpasswdSecret(client, secret, false))
+	assert.Nil(t, applyHtpasswdSecret(client, secret, true))
+	entry := getEntry()
+	assert.True(t, bytes.HasPrefix(entry, []byte("admin:$2")), string(entry))
+	assert.Nil(t, bcrypt.CompareHashAndPassword(bytes.TrimSpace(entry[len("admin:"):]), []byte("secret")))
+
+	// the hash is kept if the password is not changed
+	assert.Nil(t, applyHtpasswdSecret(client, secret, false))
+	assert.Equal(t, entry, getEntry())
+
+	secret.Data[apiv1.BasicAuthPasswordKey] = []byte("changed")
+	assert.Nil(t, applyHtpasswdSecret(client, secret, false))
+	entry = getEntry()
+	assert.Nil(t, bcrypt.CompareHashAndPassword(bytes.TrimSpace(entry[len("admin:"):]), []byte("changed")))
+
+	secret.Data[apiv1.BasicAuthUsernameKey] = []byte("root")
+	assert.Nil(t, applyHtpasswdSecret(client, secret, false))
+	assert.True(t, bytes.HasPrefix(getEntry(), []byte("root:$2")))
+}
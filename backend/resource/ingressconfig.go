@@ -2,6 +2,8 @@ package resource
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/astaxie/beego"
 	"gopkg.in/yaml.v2"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -28,13 +31,20 @@ const (
 
 	ingressPrefix = "traefik."
 	// ingress annotation
-	annotationKubernetesRedirectPermanent   = ingressPrefix + "ingress.kubernetes.io/redirect-permanent"
-	annotationKubernetesRedirectRegex       = ingressPrefix + "ingress.kubernetes.io/redirect-regex"
-	annotationKubernetesRedirectReplacement = ingressPrefix + "ingress.kubernetes.io/redirect-replacement"
-	annotationKubernetesRateLimit           = ingressPrefix + "ingress.kubernetes.io/rate-limit"
-	annotationKubernetesBuffering           = ingressPrefix + "ingress.kubernetes.io/buffering"
-	annotationKubernetesRuleType            = ingressPrefix + "ingress.kubernetes.io/rule-type"
-	annotationKubernetesRewriter            = ingressPrefix + "ingress.kubernetes.io/rewrite-target"
+	annotationKubernetesRedirectPermanent      = ingressPrefix + "ingress.kubernetes.io/redirect-permanent"
+	annotationKubernetesRedirectRegex          = ingressPrefix + "ingress.kubernetes.io/redirect-regex"
+	annotationKubernetesRedirectReplacement    = ingressPrefix + "ingress.kubernetes.io/redirect-replacement"
+	annotationKubernetesRateLimit              = ingressPrefix + "ingress.kubernetes.io/rate-limit"
+	annotationKubernetesBuffering              = ingressPrefix + "ingress.kubernetes.io/buffering"
+	annotationKubernetesRuleType               = ingressPrefix + "ingress.kubernetes.io/rule-type"
+	annotationKubernetesRewriter               = ingressPrefix + "ingress.kubernetes.io/rewrite-target"
+	annotationKubernetesWhiteListSourceRange   = ingressPrefix + "ingress.kubernetes.io/whitelist-source-range"
+	annotationKubernetesWhiteListXForwardedFor = ingressPrefix + "ingress.kubernetes.io/whitelist-x-forwarded-for"
+	annotationKubernetesAuthType               = ingressPrefix + "ingress.kubernetes.io/auth-type"
+	annotationKubernetesAuthSecret             = ingressPrefix + "ingress.kubernetes.io/auth-secret"
+	annotationKubernetesAuthURL                = ingressPrefix + "ingress.kubernetes.io/auth-url"
+	annotationKubernetesAuthTrustHeaders       = ingressPrefix + "ingress.kubernetes.io/auth-trust-headers"
+	annotationKubernetesAuthResponseHeaders    = ingressPrefix + "ingress.kubernetes.io/auth-response-headers"
 	// service annotation
 	annotationKubernetesMaxConnAmount            = ingressPrefix + "ingress.kubernetes.io/max-conn-amount"
 	annotationKubernetesMaxConnExtractorFunc     = ingressPrefix + "ingress.kubernetes.io/max-conn-extractor-func"
@@ -114,9 +124,33 @@ type rewriter struct {
 	Target string `json:"target"`
 }
 
+// whitelist allows the requests from the source ranges, the client ip is the first ip
+// of X-Forwarded-For header if UseXForwardedFor is true
+type whitelist struct {
+	SourceRange      []string `json:"source_range"`
+	UseXForwardedFor bool     `json:"use_x_forwarded_for"`
+}
+
+// basicAuth authenticates the requests by the user of the basic auth secret
+type basicAuth struct {
+	SecretName string `json:"secret_name"`
+}
+
+// forwardAuth authenticates the requests by the auth server of url, the request is allowed if
+// the auth server responds 2xx, and the response headers are copied to the request
+type forwardAuth struct {
+	URL             string   `json:"url"`
+	TrustHeaders    bool     `json:"trust_headers"`
+	ResponseHeaders []string `json:"response_headers,omitempty"`
+}
+
 type IngressConfig struct {
-	Protocol   string      `json:"protocol,omitempty"`
-	SecretName string      `json:"secret_name,omitempty"`
+	Protocol   string `json:"protocol,omitempty"`
+	SecretName string `json:"secret_name,omitempty"`
+	// TLSMode is how the certificate of https host is provided, Issuer and IssuerKind are the issuer of cert-manager
+	TLSMode    string      `json:"tls_mode,omitempty"`
+	Issuer     string      `json:"issuer,omitempty"`
+	IssuerKind string      `json:"issuer_kind,omitempty"`
 	Affinity   *affinity   `json:"affinity,omitempty"`
 	Breaker    *breaker    `json:"breaker,omitempty"`
 	Redirect   *redirect   `json:"redirect,omitempty"`
@@ -125,6 +159,12 @@ type IngressConfig struct {
 	Connection *connection `json:"connection,omitempty"`
 	RuleType   *ruleType   `json:"rule_type,omitempty"`
 	Rewriter   *rewriter   `json:"rewriter,omitempty"`
+	// the access policies of the ingress
+	Whitelist   *whitelist   `json:"whitelist,omitempty"`
+	BasicAuth   *basicAuth   `json:"basic_auth,omitempty"`
+	ForwardAuth *forwardAuth `json:"forward_auth,omitempty"`
+	// Matches route the requests to the versions of application by header, cookie or query param
+	Matches []*trafficMatch `json:"matches,omitempty"`
 }
 
 type IngressConfer struct {
@@ -146,12 +186,18 @@ func NewIngressConfer(config *IngressConfig, paths []string, host string) *Ingre
 		newConfig.RateLimit = &rateLimit{paths: paths}
 		newConfig.RuleType = &ruleType{}
 		newConfig.Rewriter = &rewriter{}
+		newConfig.Whitelist = &whitelist{}
+		newConfig.BasicAuth = &basicAuth{}
+		newConfig.ForwardAuth = &forwardAuth{}
 		confer.ingressConfers = []ConfigInterface{
 			newConfig.Buffering,
 			newConfig.Redirect,
 			newConfig.RateLimit,
 			newConfig.RuleType,
 			newConfig.Rewriter,
+			newConfig.Whitelist,
+			newConfig.BasicAuth,
+			newConfig.ForwardAuth,
 		}
 		confer.config = newConfig
 		return confer
@@ -182,11 +228,14 @@ func NewIngressConfer(config *IngressConfig, paths []string, host string) *Ingre
 		Connection: config.Connection,
 	})
 	confer.ingressConfers = getConferList(IngressConfig{
-		Redirect:  config.Redirect,
-		Buffering: config.Buffering,
-		RateLimit: config.RateLimit,
-		RuleType:  config.RuleType,
-		Rewriter:  config.Rewriter,
+		Redirect:    config.Redirect,
+		Buffering:   config.Buffering,
+		RateLimit:   config.RateLimit,
+		RuleType:    config.RuleType,
+		Rewriter:    config.Rewriter,
+		Whitelist:   config.Whitelist,
+		BasicAuth:   config.BasicAuth,
+		ForwardAuth: config.ForwardAuth,
 	})
 	return confer
 }
@@ -633,3 +682,124 @@ func (config *rewriter) validate() error {
 	}
 	return nil
 }
+
+func (config *whitelist) get(anno map[string]string) {
+	config.SourceRange = []string{}
+	for _, item := range strings.Split(utils.GetLabelStringValue(anno, annotationKubernetesWhiteListSourceRange, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			config.SourceRange = append(config.SourceRange, item)
+		}
+	}
+	config.UseXForwardedFor = utils.GetLabelStringValue(anno, annotationKubernetesWhiteListXForwardedFor, "false") == "true"
+}
+
+func (config *whitelist) set(anno map[string]string, param interface{}) error {
+	if len(config.SourceRange) == 0 {
+		// close
+		delete(anno, annotationKubernetesWhiteListSourceRange)
+		delete(anno, annotationKubernetesWhiteListXForwardedFor)
+		return nil
+	}
+	anno[annotationKubernetesWhiteListSourceRange] = strings.Join(config.SourceRange, ",")
+	if config.UseXForwardedFor {
+		anno[annotationKubernetesWhiteListXForwardedFor] = "true"
+	} else {
+		delete(anno, annotationKubernetesWhiteListXForwardedFor)
+	}
+	return nil
+}
+
+func (config *whitelist) validate() error {
+	for _, item := range config.SourceRange {
+		if _, _, err := net.ParseCIDR(item); err != nil && net.ParseIP(item) == nil {
+			return fmt.Errorf("whitelist source range %s must be an ip or cidr!", item)
+		}
+	}
+	if config.UseXForwardedFor && len(config.SourceRange) == 0 {
+		return fmt.Errorf("whitelist source range must be given if X-Forwarded-For is used!")
+	}
+	return nil
+}
+
+func (config *basicAuth) get(anno map[string]string) {
+	config.SecretName = ""
+	if utils.GetLabelStringValue(anno, annotationKubernetesAuthType, "") == authTypeBasic {
+		config.SecretName = strings.TrimSuffix(utils.GetLabelStringValue(anno, annotationKubernetesAuthSecret, ""), htpasswdSecretSuffix)
+	}
+}
+
+func (config *basicAuth) set(anno map[string]string, param interface{}) error {
+	if config.SecretName != "" {
+		anno[annotationKubernetesAuthType] = authTypeBasic
+		anno[annotationKubernetesAuthSecret] = htpasswdSecretName(config.SecretName)
+	} else if anno[annotationKubernetesAuthType] == authTypeBasic {
+		// close
+		delete(anno, annotationKubernetesAuthType)
+		delete(anno, annotationKubernetesAuthSecret)
+	}
+	return nil
+}
+
+func (config *basicAuth) validate() error {
+	if config.SecretName != "" {
+		return validate.ValidateString(config.SecretName)
+	}
+	return nil
+}
+
+func (config *forwardAuth) get(anno map[string]string) {
+	config.URL = ""
+	config.TrustHeaders = false
+	config.ResponseHeaders = nil
+	if utils.GetLabelStringValue(anno, annotationKubernetesAuthType, "") != authTypeForward {
+		return
+	}
+	config.URL = utils.GetLabelStringValue(anno, annotationKubernetesAuthURL, "")
+	config.TrustHeaders = utils.GetLabelStringValue(anno, annotationKubernetesAuthTrustHeaders, "false") == "true"
+	for _, item := range strings.Split(utils.GetLabelStringValue(anno, annotationKubernetesAuthResponseHeaders, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			config.ResponseHeaders = append(config.ResponseHeaders, item)
+		}
+	}
+}
+
+func (config *forwardAuth) set(anno map[string]string, param interface{}) error {
+	if config.URL == "" {
+		if anno[annotationKubernetesAuthType] == authTypeForward {
+			// close
+			delete(anno, annotationKubernetesAuthType)
+		}
+		delete(anno, annotationKubernetesAuthURL)
+		delete(anno, annotationKubernetesAuthTrustHeaders)
+		delete(anno, annotationKubernetesAuthResponseHeaders)
+		return nil
+	}
+	anno[annotationKubernetesAuthType] = authTypeForward
+	anno[annotationKubernetesAuthURL] = config.URL
+	anno[annotationKubernetesAuthTrustHeaders] = strconv.FormatBool(config.TrustHeaders)
+	if len(config.ResponseHeaders) != 0 {
+		anno[annotationKubernetesAuthResponseHeaders] = strings.Join(config.ResponseHeaders, ",")
+	} else {
+		delete(anno, annotationKubernetesAuthResponseHeaders)
+	}
+	return nil
+}
+
+func (config *forwardAuth) validate() error {
+	if config.URL == "" {
+		if len(config.ResponseHeaders) != 0 {
+			return fmt.Errorf("forward auth url must be given if the response headers are given!")
+		}
+		return nil
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("forward auth url %s must be a http or https url!", config.URL)
+	}
+	for _, item := range config.ResponseHeaders {
+		if errs := k8svalidation.IsHTTPHeaderName(item); len(errs) != 0 {
+			return fmt.Errorf("forward auth response header %s is not right: %s", item, strings.Join(errs, ";"))
+		}
+	}
+	return nil
+}
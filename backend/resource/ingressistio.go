@@ -0,0 +1,188 @@
+package resource
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common/utils"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	istioVirtualServiceKind  = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "VirtualService"}
+	istioDestinationRuleKind = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "DestinationRule"}
+)
+
+// istioProvider serves the ingress by a VirtualService of each host bound to the ingress gateway,
+// and the session affinity and connection limit are the traffic policy of the DestinationRule of service
+type istioProvider struct {
+	storedConfig
+	// gateway is namespace/name of the istio gateway, its tls is configured by itself
+	gateway string
+}
+
+func (p *istioProvider) Name() string {
+	return IngressProviderIstio
+}
+
+func (p *istioProvider) Validate(ingress *Ingress) error {
+	config := &ingress.IngressConfig
+	if err := NewIngressConfer(config, nil, ingress.Host).Validate(); err != nil {
+		return err
+	}
+	features := []string{}
+	if ingress.Protocol == PROTOCOL_HTTPS {
+		features = append(features, "the certificate of ingress (configure the tls of gateway "+p.gateway+" instead)")
+	}
+	if config.Redirect.enabled() {
+		features = append(features, "the redirect by regex")
+	}
+	if config.RateLimit.enabled() {
+		features = append(features, "the rate limit")
+	}
+	if config.Breaker.enabled() {
+		features = append(features, "the circuit breaker by ratio or latency")
+	}
+	if config.Buffering.sizeEnabled() {
+		features = append(features, "the body sizes of buffering")
+	}
+	if config.Whitelist.enabled() || config.BasicAuth.enabled() || config.ForwardAuth.enabled() {
+		features = append(features, "the access policies (configure the AuthorizationPolicy of gateway "+p.gateway+" instead)")
+	}
+	return unsupportedFeatures(p.Name(), features)
+}
+
+func (p *istioProvider) SetIngress(obj *networkingv1.Ingress, config *IngressConfig, paths []string, host string) error {
+	setIngressClass(obj, IngressRecordClass)
+	return p.storeIngress(obj.Annotations, config, paths, host)
+}
+
+func (p *istioProvider) ObjectKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{istioVirtualServiceKind, istioDestinationRuleKind}
+}
+
+func (p *istioProvider) Objects(obj *networkingv1.Ingress, svcConfigs map[string]IngressConfig) ([]*unstructured.Unstructured, error) {
+	objs := []*unstructured.Unstructured{}
+	configs := getPathConfigs(obj.Annotations)
+	for _, rule := range obj.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		paths := append([]networkingv1.HTTPIngressPath{}, rule.HTTP.Paths...)
+		// the routes of virtual service are matched in order, so the longer paths are matched firstly
+		sort.SliceStable(paths, func(i, j int) bool {
+			return len(utils.GetRootPath(paths[i].Path)) > len(utils.GetRootPath(paths[j].Path))
+		})
+		routes := []interface{}{}
+		for _, path := range paths {
+			svcname, port := kubeutil.IngressBackendService(path.Backend)
+			config := configs[rule.Host+utils.GetRootPath(path.Path)]
+			uri := map[string]interface{}{"prefix": utils.GetRootPath(path.Path)}
+			if config.RuleType.exact() {
+				uri = map[string]interface{}{"exact": utils.GetRootPath(path.Path)}
+			}
+			newRoute := func(name string, match map[string]interface{}, host string) map[string]interface{} {
+				match["uri"] = uri
+				route := map[string]interface{}{
+					"name":  name,
+					"match": []interface{}{match},
+					"route": []interface{}{map[string]interface{}{
+						"destination": map[string]interface{}{
+							"host": host,
+							"port": map[string]interface{}{"number": port},
+						},
+					}},
+				}
+				// the matched prefix is replaced by the rewrite uri
+				if config.Rewriter.enabled() {
+					route["rewrite"] = map[string]interface{}{"uri": utils.AddRootPath(config.Rewriter.Target)}
+				} else if config.RuleType.strip() {
+					route["rewrite"] = map[string]interface{}{"uri": "/"}
+				}
+				if config.Buffering.retryEnabled() {
+					route["retries"] = map[string]interface{}{
+						"attempts": config.Buffering.Retry,
+						"retryOn":  "connect-failure,refused-stream,reset",
+					}
+				}
+				return route
+			}
+			// the routes of match rules are matched before the route of path
+			for i, item := range config.Matches {
+				match := map[string]interface{}{}
+				switch item.Type {
+				case TrafficMatchHeader:
+					match["headers"] = map[string]interface{}{
+						strings.ToLower(item.Name): map[string]interface{}{"exact": item.Value},
+					}
+				case TrafficMatchCookie:
+					match["headers"] = map[string]interface{}{
+						"cookie": map[string]interface{}{"regex": ".*" + item.cookieRegexp() + ".*"},
+					}
+				case TrafficMatchQuery:
+					match["queryParams"] = map[string]interface{}{
+						item.Name: map[string]interface{}{"exact": item.Value},
+					}
+				}
+				name := providerObjectName(path.Path, "match", strconv.Itoa(i))
+				routes = append(routes, newRoute(name, match, versionServiceName(svcname, item.PodVersion)))
+			}
+			routes = append(routes, newRoute(providerObjectName(path.Path), map[string]interface{}{}, svcname))
+		}
+		host := rule.Host
+		if host == "" {
+			host = "*"
+		}
+		vs, err := newProviderObject(istioVirtualServiceKind, obj, providerObjectName(obj.Name, rule.Host), map[string]interface{}{
+			"hosts":    []string{host},
+			"gateways": []string{p.gateway},
+			"http":     routes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, vs)
+	}
+	svcnames := []string{}
+	for svcname := range svcConfigs {
+		svcnames = append(svcnames, svcname)
+	}
+	sort.Strings(svcnames)
+	for _, svcname := range svcnames {
+		config := svcConfigs[svcname]
+		policy := map[string]interface{}{}
+		if config.Affinity.enabled() {
+			cookie := config.Affinity.SessionCookieName
+			if cookie == "" {
+				cookie = "kubecloud-session"
+			}
+			policy["loadBalancer"] = map[string]interface{}{
+				"consistentHash": map[string]interface{}{
+					"httpCookie": map[string]interface{}{"name": cookie, "ttl": "0s"},
+				},
+			}
+		}
+		if config.Connection.enabled() {
+			policy["connectionPool"] = map[string]interface{}{
+				"tcp": map[string]interface{}{"maxConnections": config.Connection.MaxConnAmount},
+			}
+		}
+		if len(policy) == 0 {
+			continue
+		}
+		dr, err := newProviderObject(istioDestinationRuleKind, obj, svcname, map[string]interface{}{
+			"host":          svcname,
+			"trafficPolicy": policy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, dr)
+	}
+	return objs, nil
+}
@@ -0,0 +1,218 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common/keyword"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	TrafficMatchHeader = "header"
+	TrafficMatchCookie = "cookie"
+	TrafficMatchQuery  = "query"
+
+	// VersionServiceLabelKey is set on the services selecting the pods of a version of the backend service,
+	// its value is the name of the backend service
+	VersionServiceLabelKey = "kubecloud/version-service-of"
+)
+
+var trafficMatchNameRegexp = regexp.MustCompile("^[A-Za-z0-9_.-]+$")
+
+// trafficMatch routes the requests with the header, cookie or query param to the pods of
+// a version of the application regardless of the traffic weights of versions
+type trafficMatch struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Version is the version of application, and PodVersion is the pod version label of its pods
+	Version    string `json:"version"`
+	PodVersion string `json:"pod_version,omitempty"`
+}
+
+// validateMatches checks the match rules of ingress, and resolves their versions by the versions of application,
+// the paths of the ingress with match rules must have the same application as backend
+func (ing *IngressRes) validateMatches(ingress *Ingress, appnames []string) error {
+	if len(ingress.Matches) == 0 {
+		return nil
+	}
+	if len(appnames) != 1 {
+		return fmt.Errorf("the paths with match rules must have the same application as backend!")
+	}
+	versions, err := dao.NewVersionModel().GetVersionList(ing.cluster, ingress.Namespace, appnames[0])
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]bool)
+	for _, match := range ingress.Matches {
+		if match == nil {
+			return fmt.Errorf("the match rule can not be empty!")
+		}
+		switch match.Type {
+		case TrafficMatchHeader:
+			if errs := validation.IsHTTPHeaderName(match.Name); len(errs) != 0 {
+				return fmt.Errorf("the header name %s is not right: %s", match.Name, strings.Join(errs, ";"))
+			}
+		case TrafficMatchCookie, TrafficMatchQuery:
+			if !trafficMatchNameRegexp.MatchString(match.Name) {
+				return fmt.Errorf("the %s name %s must consist of alphanumeric characters, '-', '_' or '.'!", match.Type, match.Name)
+			}
+		default:
+			return fmt.Errorf("the match type must be %s, %s or %s!", TrafficMatchHeader, TrafficMatchCookie, TrafficMatchQuery)
+		}
+		if match.Value == "" || strings.ContainsAny(match.Value, ";`\"\r\n") {
+			return fmt.Errorf("the value of %s %s can not be empty or contain ;`\" and line breaks!", match.Type, match.Name)
+		}
+		key := match.Type + "/" + strings.ToLower(match.Name) + "=" + match.Value
+		if keys[key] {
+			return fmt.Errorf("the match rule of %s %s=%s is duplicated!", match.Type, match.Name, match.Value)
+		}
+		keys[key] = true
+		match.PodVersion = ""
+		for _, version := range versions {
+			if match.Version == version.Version || match.Version == version.PodVersion {
+				match.PodVersion = version.PodVersion
+				break
+			}
+		}
+		if match.PodVersion == "" {
+			return fmt.Errorf("the version %s of application %s is not existed!", match.Version, appnames[0])
+		}
+		for _, path := range ingress.Paths {
+			if name := versionServiceName(path.ServiceName, match.PodVersion); len(name) > validation.DNS1035LabelMaxLength {
+				return fmt.Errorf("the name %s of the service of version %s is longer than %d characters!",
+					name, match.Version, validation.DNS1035LabelMaxLength)
+			}
+		}
+	}
+	return nil
+}
+
+// versionServiceName returns the name of the service selecting the pods of the version of backend service
+func versionServiceName(svcname, podVersion string) string {
+	return providerObjectName(svcname, podVersion)
+}
+
+// traefikRule returns the matcher of traefik v2 rule
+func (match *trafficMatch) traefikRule() string {
+	switch match.Type {
+	case TrafficMatchHeader:
+		return fmt.Sprintf("Headers(`%s`, `%s`)", match.Name, match.Value)
+	case TrafficMatchCookie:
+		return fmt.Sprintf("HeadersRegexp(`Cookie`, `%s`)", match.cookieRegexp())
+	}
+	return fmt.Sprintf("Query(`%s=%s`)", match.Name, match.Value)
+}
+
+func (match *trafficMatch) cookieRegexp() string {
+	return "(^|;\\s*)" + regexp.QuoteMeta(match.Name) + "=" + regexp.QuoteMeta(match.Value) + "(;|$)"
+}
+
+// applyVersionServices applies the services of the versions referenced by the match rules of the ingresses
+// in namespace, and deletes the version services which are not referenced any more
+func (ing *IngressRes) applyVersionServices(namespace string) error {
+	ings, err := kubeutil.ListIngresses(ing.client, ing.apiVersions(), namespace, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	// the version services keyed by name, the values are the backend service and pod version
+	desired := make(map[string][2]string)
+	for _, item := range ings {
+		configs := getPathConfigs(item.Annotations)
+		for _, rule := range item.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				svcname, _ := kubeutil.IngressBackendService(path.Backend)
+				for _, match := range configs[rule.Host+utils.GetRootPath(path.Path)].Matches {
+					desired[versionServiceName(svcname, match.PodVersion)] = [2]string{svcname, match.PodVersion}
+				}
+			}
+		}
+	}
+	names := []string{}
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ing.applyVersionService(namespace, name, desired[name][0], desired[name][1]); err != nil {
+			return err
+		}
+	}
+	list, err := ing.client.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: VersionServiceLabelKey})
+	if err != nil {
+		return err
+	}
+	for _, svc := range list.Items {
+		if _, ok := desired[svc.Name]; ok {
+			continue
+		}
+		if err := ing.client.CoreV1().Services(namespace).Delete(context.TODO(), svc.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("delete version service %s failed: %v", svc.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyVersionService creates or updates the service which has the ports of backend service,
+// and selects the pods of its selector with the pod version
+func (ing *IngressRes) applyVersionService(namespace, name, svcname, podVersion string) error {
+	backend, err := ing.client.CoreV1().Services(namespace).Get(context.TODO(), svcname, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			beego.Warn(fmt.Sprintf("the backend service %s of version service %s is not found", svcname, name))
+			return nil
+		}
+		return err
+	}
+	svc := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{VersionServiceLabelKey: svcname},
+		},
+		Spec: apiv1.ServiceSpec{
+			Type:     apiv1.ServiceTypeClusterIP,
+			Selector: map[string]string{keyword.LABEL_PODVERSION_KEY: podVersion},
+		},
+	}
+	for key, value := range backend.Spec.Selector {
+		if key != keyword.LABEL_PODVERSION_KEY {
+			svc.Spec.Selector[key] = value
+		}
+	}
+	for _, port := range backend.Spec.Ports {
+		port.NodePort = 0
+		svc.Spec.Ports = append(svc.Spec.Ports, port)
+	}
+	old, err := ing.client.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = ing.client.CoreV1().Services(namespace).Create(context.TODO(), svc, metav1.CreateOptions{})
+	} else {
+		svc.ResourceVersion = old.ResourceVersion
+		svc.Spec.ClusterIP = old.Spec.ClusterIP
+		svc.Spec.ClusterIPs = old.Spec.ClusterIPs
+		_, err = ing.client.CoreV1().Services(namespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("apply version service %s failed: %v", name, err)
+	}
+	return nil
+}
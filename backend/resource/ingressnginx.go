@@ -0,0 +1,230 @@
+package resource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common/utils"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	nginxIngressClass      = "nginx"
+	nginxAnnotationPrefix  = "nginx.ingress.kubernetes.io/"
+	nginxLimitRPS          = nginxAnnotationPrefix + "limit-rps"
+	nginxLimitBurst        = nginxAnnotationPrefix + "limit-burst-multiplier"
+	nginxLimitConnections  = nginxAnnotationPrefix + "limit-connections"
+	nginxProxyBodySize     = nginxAnnotationPrefix + "proxy-body-size"
+	nginxClientBodyBuffer  = nginxAnnotationPrefix + "client-body-buffer-size"
+	nginxProxyBufferSize   = nginxAnnotationPrefix + "proxy-buffer-size"
+	nginxProxyUpstreamTry  = nginxAnnotationPrefix + "proxy-next-upstream-tries"
+	nginxRewriteTarget     = nginxAnnotationPrefix + "rewrite-target"
+	nginxAffinity          = nginxAnnotationPrefix + "affinity"
+	nginxSessionCookieName = nginxAnnotationPrefix + "session-cookie-name"
+	nginxWhitelist         = nginxAnnotationPrefix + "whitelist-source-range"
+	nginxAuthType          = nginxAnnotationPrefix + "auth-type"
+	nginxAuthSecret        = nginxAnnotationPrefix + "auth-secret"
+	nginxAuthURL           = nginxAnnotationPrefix + "auth-url"
+	nginxAuthRespHeaders   = nginxAnnotationPrefix + "auth-response-headers"
+	nginxCanary            = nginxAnnotationPrefix + "canary"
+	nginxCanaryByHeader    = nginxAnnotationPrefix + "canary-by-header"
+	nginxCanaryHeaderValue = nginxAnnotationPrefix + "canary-by-header-value"
+	nginxCanaryByCookie    = nginxAnnotationPrefix + "canary-by-cookie"
+	// the canary by cookie routes the requests whose cookie has this value
+	nginxCanaryCookieValue = "always"
+)
+
+var nginxIngressKind = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+
+// nginxProvider configures ingress-nginx by the annotations of ingress, the annotations
+// are shared by all paths of the ingress, so the config of the latest paths takes effect.
+// The match rule of the path is served by a canary ingress of the path
+type nginxProvider struct {
+	storedConfig
+}
+
+func (p *nginxProvider) Name() string {
+	return IngressProviderNginx
+}
+
+func (p *nginxProvider) Validate(ingress *Ingress) error {
+	config := &ingress.IngressConfig
+	if err := NewIngressConfer(config, nil, ingress.Host).Validate(); err != nil {
+		return err
+	}
+	features := []string{}
+	if config.Redirect.enabled() {
+		features = append(features, "the redirect by regex")
+	}
+	if config.Breaker.enabled() {
+		features = append(features, "the circuit breaker")
+	}
+	if config.RuleType.strip() {
+		features = append(features, "the rule type "+config.RuleType.RuleType+" (use the rewriter instead)")
+	}
+	if config.Buffering != nil && config.Buffering.MaxResponseBodyBytes != 0 && config.Buffering.MaxResponseBodyBytes != -1 {
+		features = append(features, "the max response body size of buffering")
+	}
+	if config.Whitelist != nil && config.Whitelist.UseXForwardedFor {
+		features = append(features, "the whitelist by X-Forwarded-For (configure use-forwarded-headers of ingress-nginx instead)")
+	}
+	if config.ForwardAuth != nil && config.ForwardAuth.TrustHeaders {
+		features = append(features, "the trust headers of forward auth")
+	}
+	if len(config.Matches) > 1 {
+		features = append(features, "more than one match rule of a path")
+	}
+	for _, match := range config.Matches {
+		if match != nil && match.Type == TrafficMatchQuery {
+			features = append(features, "the match rule by query param")
+		}
+		if match != nil && match.Type == TrafficMatchCookie && match.Value != nginxCanaryCookieValue {
+			features = append(features, "the match rule by cookie value except "+nginxCanaryCookieValue)
+		}
+	}
+	return unsupportedFeatures(p.Name(), features)
+}
+
+func (p *nginxProvider) SharedConfig(config *IngressConfig) *IngressConfig {
+	// the rule type is the path type of each path
+	return newSharedConfig(config, true, false)
+}
+
+func (p *nginxProvider) SetIngress(obj *networkingv1.Ingress, config *IngressConfig, paths []string, host string) error {
+	if err := p.storeIngress(obj.Annotations, config, paths, host); err != nil {
+		return err
+	}
+	setIngressClass(obj, nginxIngressClass)
+	anno := obj.Annotations
+	setOrDelete := func(key, value string, set bool) {
+		if set {
+			anno[key] = value
+		} else {
+			delete(anno, key)
+		}
+	}
+	if config.RateLimit != nil {
+		// the rate limit of traefik is average requests per period*average ms
+		rps := int64(1)
+		burst := int64(1)
+		if config.RateLimit.Period > 0 && 1000/config.RateLimit.Period > 1 {
+			rps = 1000 / config.RateLimit.Period
+		}
+		if config.RateLimit.Burst/rps > 1 {
+			burst = config.RateLimit.Burst / rps
+		}
+		setOrDelete(nginxLimitRPS, strconv.FormatInt(rps, 10), config.RateLimit.enabled())
+		setOrDelete(nginxLimitBurst, strconv.FormatInt(burst, 10), config.RateLimit.enabled())
+	}
+	if config.Connection != nil {
+		setOrDelete(nginxLimitConnections, strconv.FormatInt(config.Connection.MaxConnAmount, 10), config.Connection.enabled())
+	}
+	if config.Buffering != nil {
+		size := func(mb int64, unlimited string) string {
+			if mb == -1 {
+				return unlimited
+			}
+			return fmt.Sprintf("%dm", mb)
+		}
+		buf := config.Buffering
+		setOrDelete(nginxProxyBodySize, size(buf.MaxRequestBodyBytes, "0"), buf.sizeEnabled() && buf.MaxRequestBodyBytes != 0)
+		setOrDelete(nginxClientBodyBuffer, size(buf.MemRequestBodyBytes, ""), buf.sizeEnabled() && buf.MemRequestBodyBytes > 0)
+		setOrDelete(nginxProxyBufferSize, size(buf.MemResponseBodyBytes, ""), buf.sizeEnabled() && buf.MemResponseBodyBytes > 0)
+		setOrDelete(nginxProxyUpstreamTry, strconv.Itoa(buf.Retry+1), buf.retryEnabled())
+	}
+	if config.Rewriter != nil {
+		setOrDelete(nginxRewriteTarget, utils.AddRootPath(config.Rewriter.Target), config.Rewriter.enabled())
+	}
+	if config.Affinity != nil {
+		setOrDelete(nginxAffinity, "cookie", config.Affinity.enabled())
+		setOrDelete(nginxSessionCookieName, config.Affinity.SessionCookieName, config.Affinity.enabled() && config.Affinity.SessionCookieName != "")
+	}
+	if config.Whitelist != nil {
+		setOrDelete(nginxWhitelist, strings.Join(config.Whitelist.SourceRange, ","), config.Whitelist.enabled())
+	}
+	if config.BasicAuth != nil {
+		setOrDelete(nginxAuthType, authTypeBasic, config.BasicAuth.enabled())
+		setOrDelete(nginxAuthSecret, htpasswdSecretName(config.BasicAuth.SecretName), config.BasicAuth.enabled())
+	}
+	if config.ForwardAuth != nil {
+		setOrDelete(nginxAuthURL, config.ForwardAuth.URL, config.ForwardAuth.enabled())
+		setOrDelete(nginxAuthRespHeaders, strings.Join(config.ForwardAuth.ResponseHeaders, ","),
+			config.ForwardAuth.enabled() && len(config.ForwardAuth.ResponseHeaders) != 0)
+	}
+	if config.RuleType != nil {
+		pathType := networkingv1.PathTypePrefix
+		if config.RuleType.exact() {
+			pathType = networkingv1.PathTypeExact
+		}
+		for i, rule := range obj.Spec.Rules {
+			if rule.Host != host || rule.HTTP == nil {
+				continue
+			}
+			for j, item := range rule.HTTP.Paths {
+				// the empty path is only valid for the implementation specific type
+				if item.Path == "" {
+					continue
+				}
+				for _, path := range paths {
+					if utils.PathsIsEqual(item.Path, path) {
+						obj.Spec.Rules[i].HTTP.Paths[j].PathType = &pathType
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *nginxProvider) ObjectKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{nginxIngressKind}
+}
+
+func (p *nginxProvider) Objects(obj *networkingv1.Ingress, svcConfigs map[string]IngressConfig) ([]*unstructured.Unstructured, error) {
+	objs := []*unstructured.Unstructured{}
+	configs := getPathConfigs(obj.Annotations)
+	for _, rule := range obj.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			config := configs[rule.Host+utils.GetRootPath(path.Path)]
+			if len(config.Matches) == 0 {
+				continue
+			}
+			match := config.Matches[0]
+			svcname, port := kubeutil.IngressBackendService(path.Backend)
+			canaryPath := path
+			canaryPath.Backend = kubeutil.NewIngressBackend(versionServiceName(svcname, match.PodVersion), port)
+			canary, err := newProviderObject(nginxIngressKind, obj, providerObjectName(obj.Name, rule.Host, path.Path, "canary"), networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{
+					Host: rule.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{Paths: []networkingv1.HTTPIngressPath{canaryPath}},
+					},
+				}},
+			})
+			if err != nil {
+				return nil, err
+			}
+			anno := map[string]string{
+				AnnotationKubernetesIngressClass: nginxIngressClass,
+				nginxCanary:                      "true",
+			}
+			if match.Type == TrafficMatchHeader {
+				anno[nginxCanaryByHeader] = match.Name
+				anno[nginxCanaryHeaderValue] = match.Value
+			} else {
+				anno[nginxCanaryByCookie] = match.Name
+			}
+			canary.SetAnnotations(anno)
+			objs = append(objs, canary)
+		}
+	}
+	return objs, nil
+}
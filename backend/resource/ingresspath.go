@@ -0,0 +1,237 @@
+package resource
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+
+	"kubecloud/backend/models"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// selectIngress returns the name of the ingress serving the paths of host with config. The config shared by the
+// paths of ingress must be the same, so it is the ingress of host having the same shared config, the preferred
+// one is checked firstly. If there is not such ingress, it is the preferred or base name if the ingress is not
+// existed, or the base name with the hash of shared config
+func (ing *IngressRes) selectIngress(namespace, host, preferred, base string, config *IngressConfig) (string, error) {
+	names, err := ing.kubeRule.ListIngressNamesByHost(ing.cluster, namespace, host)
+	if err != nil {
+		return "", err
+	}
+	shared := ing.provider.SharedConfig(config)
+	if shared == nil {
+		if preferred != "" {
+			return preferred, nil
+		}
+		if len(names) != 0 {
+			return names[0], nil
+		}
+		return base, nil
+	}
+	key := sharedConfigKey(shared)
+	if preferred != "" {
+		others := []string{preferred}
+		for _, name := range names {
+			if name != preferred {
+				others = append(others, name)
+			}
+		}
+		names = others
+	}
+	existing := make(map[string]bool)
+	for _, name := range names {
+		obj, err := kubeutil.GetIngress(ing.client, ing.apiVersions(), namespace, name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		existing[name] = true
+		if ing.ingressSharedKey(obj, host) == key {
+			return name, nil
+		}
+	}
+	for _, name := range []string{preferred, base} {
+		if name == "" || existing[name] {
+			continue
+		}
+		if _, err := kubeutil.GetIngress(ing.client, ing.apiVersions(), namespace, name); err != nil {
+			if errors.IsNotFound(err) {
+				return name, nil
+			}
+			return "", err
+		}
+	}
+	sum := sha1.Sum([]byte(key))
+	return base + "-" + hex.EncodeToString(sum[:])[:8], nil
+}
+
+// ingressSharedKey returns the key of the shared config of ingress, it is read from the first path of host
+func (ing *IngressRes) ingressSharedKey(obj *networkingv1.Ingress, host string) string {
+	path, pathHost := "", host
+	found := false
+	for _, rule := range obj.Spec.Rules {
+		if rule.HTTP == nil || len(rule.HTTP.Paths) == 0 {
+			continue
+		}
+		if !found || rule.Host == host {
+			path, pathHost = rule.HTTP.Paths[0].Path, rule.Host
+			found = true
+		}
+		if rule.Host == host {
+			break
+		}
+	}
+	config := ing.provider.GetConfig(obj.Annotations, nil, path, pathHost)
+	return sharedConfigKey(ing.provider.SharedConfig(&config))
+}
+
+func sharedConfigKey(shared *IngressConfig) string {
+	data, err := json.Marshal(shared)
+	if err != nil {
+		beego.Error("encode the shared config of ingress failed:", err)
+	}
+	return string(data)
+}
+
+// fillIngressConfig sets the features of config which are not given to the old config of the path,
+// so they are not changed when the path is moved to another ingress
+func fillIngressConfig(config *IngressConfig, old IngressConfig) {
+	if config.Affinity == nil {
+		config.Affinity = old.Affinity
+	}
+	if config.Breaker == nil {
+		config.Breaker = old.Breaker
+	}
+	if config.Redirect == nil {
+		config.Redirect = old.Redirect
+	}
+	if config.Buffering == nil {
+		config.Buffering = old.Buffering
+	}
+	if config.RateLimit == nil {
+		config.RateLimit = old.RateLimit
+	}
+	if config.Connection == nil {
+		config.Connection = old.Connection
+	}
+	if config.RuleType == nil {
+		config.RuleType = old.RuleType
+	}
+	if config.Rewriter == nil {
+		config.Rewriter = old.Rewriter
+	}
+	if config.Whitelist == nil {
+		config.Whitelist = old.Whitelist
+	}
+	if config.BasicAuth == nil {
+		config.BasicAuth = old.BasicAuth
+	}
+	if config.ForwardAuth == nil {
+		config.ForwardAuth = old.ForwardAuth
+	}
+}
+
+// syncHostTLS sets the tls of host to the other ingresses of host, which serve the paths with different shared configs
+func (ing *IngressRes) syncHostTLS(ingress *Ingress, exclude string) error {
+	names, err := ing.kubeRule.ListIngressNamesByHost(ing.cluster, ingress.Namespace, ingress.Host)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == exclude {
+			continue
+		}
+		old, err := kubeutil.GetIngress(ing.client, ing.apiVersions(), ingress.Namespace, name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		obj := old.DeepCopy()
+		if obj.Annotations == nil {
+			obj.Annotations = make(map[string]string)
+		}
+		if ingress.Protocol == PROTOCOL_HTTPS {
+			obj.Spec.TLS = modifyTLS(obj.Spec.TLS, ingress.Host, ingress.SecretName)
+		} else {
+			obj.Spec.TLS = kubeutil.DeleteHostFromTLS(obj.Spec.TLS, ingress.Host)
+		}
+		if err := setIngressTLS(obj, ingress.Host, newTLSConfig(ingress)); err != nil {
+			return err
+		}
+		if utils.ObjectIsEqual(old, obj) {
+			continue
+		}
+		if _, err := kubeutil.UpdateIngress(ing.client, ing.apiVersions(), kubeutil.DeleteCreatedDefaultAnno(obj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingressHasOnlyPaths checks the ingress has no paths except the paths of host
+func ingressHasOnlyPaths(obj *networkingv1.Ingress, host string, paths []string) bool {
+	for _, rule := range obj.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			found := false
+			for _, item := range paths {
+				if rule.Host == host && utils.PathsIsEqual(path.Path, item) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// movePaths moves the paths of the rule to the target ingress, the paths are added to the target firstly,
+// so they are served all the time
+func (ing *IngressRes) movePaths(oldRule models.K8sIngressRule, ingress Ingress, target string) error {
+	ingress.Name = target
+	if err := ing.addPaths(&ingress); err != nil {
+		return err
+	}
+	old, err := kubeutil.GetIngress(ing.client, ing.apiVersions(), oldRule.Namespace, oldRule.IngressName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return common.NewInternalServerError().SetCause(err)
+	}
+	obj, err := ing.deleteRule(oldRule, old)
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if len(obj.Spec.Rules) == 0 {
+		err = kubeutil.DeleteIngress(ing.client, ing.apiVersions(), obj.Namespace, obj.Name)
+	} else {
+		_, err = kubeutil.UpdateIngress(ing.client, ing.apiVersions(), kubeutil.DeleteCreatedDefaultAnno(obj))
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err = ing.applyProviderObjects(obj); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	// the auth secrets which are only used by the old ingress are deleted
+	if err = ing.applyHtpasswdSecrets(obj.Namespace); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return nil
+}
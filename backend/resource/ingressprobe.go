@@ -0,0 +1,162 @@
+package resource
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+const (
+	defaultIngressProbeTimeout       = 10
+	defaultIngressProbeRetentionDays = 7
+	defaultIngressProbeWorkers       = 10
+	defaultIngressProbeHTTPSPort     = "443"
+	defaultIngressProbeHTTPPort      = "80"
+)
+
+// the windows of the uptime of ingress rules
+var ingressProbeWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// IngressUptime is the percentage of the successful probes of an ingress rule in the window
+type IngressUptime struct {
+	Window  string  `json:"window"`
+	Total   int     `json:"total"`
+	Success int     `json:"success"`
+	Uptime  float64 `json:"uptime"`
+}
+
+// IngressProbeStatus is the latest probe result and the uptimes of an ingress rule
+type IngressProbeStatus struct {
+	Latest  *models.ZcloudIngressProbe `json:"latest,omitempty"`
+	Uptimes []IngressUptime            `json:"uptimes"`
+}
+
+// ProbeIngressRules requests the host and path of every ingress rule of cluster through the load balancer
+// of cluster, and records the results. The results before the retention days are deleted
+func ProbeIngressRules(cluster string) error {
+	item, err := dao.GetCluster(cluster)
+	if err != nil {
+		return err
+	}
+	lbHost := item.LoadbalancerIP
+	if lbHost == "" {
+		lbHost = item.LoadbalancerDomainName
+	}
+	if lbHost == "" {
+		beego.Debug(fmt.Sprintf("the load balancer of cluster %s is not set, the ingress rules are not probed", cluster))
+		return nil
+	}
+	httpPort := item.LoadbalancerPort
+	if httpPort == "" {
+		httpPort = defaultIngressProbeHTTPPort
+	}
+	httpsPort := beego.AppConfig.DefaultString("ingressprobe::httpsPort", defaultIngressProbeHTTPSPort)
+	timeout := time.Duration(beego.AppConfig.DefaultInt("ingressprobe::timeout", defaultIngressProbeTimeout)) * time.Second
+	workers := beego.AppConfig.DefaultInt("ingressprobe::workers", defaultIngressProbeWorkers)
+	if workers <= 0 {
+		workers = defaultIngressProbeWorkers
+	}
+
+	rules, err := dao.NewIngressRuleModel().ListClusterRules(cluster)
+	if err != nil {
+		return err
+	}
+	probeModel := dao.NewIngressProbeModel()
+	ch := make(chan models.K8sIngressRule)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rule := range ch {
+				scheme, address := "http", net.JoinHostPort(lbHost, httpPort)
+				if rule.IsTls {
+					scheme, address = "https", net.JoinHostPort(lbHost, httpsPort)
+				}
+				probe := newIngressProbe(rule, utils.ProbeHTTP(address, scheme, rule.Host, utils.GetRootPath(rule.Path), timeout, nil))
+				if err := probeModel.CreateProbe(probe); err != nil {
+					beego.Warn(fmt.Sprintf("record the probe result of %s%s of cluster %s failed: %v", rule.Host, rule.Path, cluster, err))
+				}
+			}
+		}()
+	}
+	for _, rule := range rules {
+		if rule.Host == "" {
+			continue
+		}
+		ch <- rule
+	}
+	close(ch)
+	wg.Wait()
+
+	retentionDays := beego.AppConfig.DefaultInt("ingressprobe::retentionDays", defaultIngressProbeRetentionDays)
+	return probeModel.DeleteProbesBefore(cluster, ingressProbeNow().AddDate(0, 0, -retentionDays))
+}
+
+func newIngressProbe(rule models.K8sIngressRule, result utils.HTTPProbeResult) *models.ZcloudIngressProbe {
+	probe := &models.ZcloudIngressProbe{
+		Cluster:     rule.Cluster,
+		Namespace:   rule.Namespace,
+		IngressName: rule.IngressName,
+		Host:        rule.Host,
+		Path:        rule.Path,
+		StatusCode:  result.StatusCode,
+		LatencyMs:   result.Latency.Milliseconds(),
+		IsTls:       rule.IsTls,
+		TlsValid:    result.TLSValid,
+		Error:       result.Error,
+		ProbeAt:     ingressProbeNow(),
+	}
+	if probe.Error == "" && rule.IsTls && !result.TLSValid {
+		probe.Error = "the certificate is not valid: " + result.TLSError
+	}
+	return probe
+}
+
+// the time is stored as the local time like the other records
+func ingressProbeNow() time.Time {
+	now, _ := time.Parse("2006-01-02 15:04:05", time.Now().Local().Format("2006-01-02 15:04:05"))
+	return now
+}
+
+// GetIngressProbeStatus returns the latest probe result and the uptimes of the rule
+func GetIngressProbeStatus(rule *models.K8sIngressRule) (*IngressProbeStatus, error) {
+	model := dao.NewIngressProbeModel()
+	status := &IngressProbeStatus{Uptimes: []IngressUptime{}}
+	latest, err := model.GetLatestProbe(rule.Cluster, rule.Namespace, rule.Host, rule.Path)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	status.Latest = latest
+	now := ingressProbeNow()
+	for _, window := range ingressProbeWindows {
+		uptime := IngressUptime{Window: window.name}
+		if latest != nil {
+			total, success, err := model.CountProbes(rule.Cluster, rule.Namespace, rule.Host, rule.Path, now.Add(-window.duration))
+			if err != nil {
+				return nil, err
+			}
+			uptime.Total, uptime.Success = int(total), int(success)
+		}
+		if uptime.Total != 0 {
+			uptime.Uptime = float64(uptime.Success*10000/uptime.Total) / 100
+		}
+		status.Uptimes = append(status.Uptimes, uptime)
+	}
+	return status, nil
+}
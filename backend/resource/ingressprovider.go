@@ -0,0 +1,483 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/service"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	IngressProviderTraefik   = "traefik"
+	IngressProviderNginx     = "nginx"
+	IngressProviderTraefikV2 = "traefik-v2"
+	IngressProviderIstio     = "istio"
+
+	// the ingress of the providers which serve it by custom objects is kept as the record of rules,
+	// it has this class so that it is not served by any ingress controller
+	IngressRecordClass = "kubecloud-record"
+
+	// the config of the providers except traefik is stored in the annotations as json,
+	// the config of ingress is keyed by host and path
+	IngressConfigAnnotationKey = "kubecloud/ingress-config"
+	ServiceConfigAnnotationKey = "kubecloud/service-config"
+	// IngressOwnerLabelKey is set on the custom objects generated for the ingress
+	IngressOwnerLabelKey = "kubecloud/ingress"
+)
+
+var ingressProviders = []string{IngressProviderTraefik, IngressProviderNginx, IngressProviderTraefikV2, IngressProviderIstio}
+
+// IngressProvider translates the IngressConfig of ingress rules into the configuration of an ingress controller
+type IngressProvider interface {
+	Name() string
+	// Validate checks the config, and returns an error for the features which the provider does not support
+	Validate(ingress *Ingress) error
+	// SetIngress sets the config of the paths of host to the ingress
+	SetIngress(obj *networkingv1.Ingress, config *IngressConfig, paths []string, host string) error
+	// SetService sets the config of the backend service to the service
+	SetService(svc *apiv1.Service, config *IngressConfig) error
+	// SharedConfig returns the enabled features of config which are shared by all paths of the ingress, the paths
+	// with different shared configs are served by different ingresses of host. It is nil if the config of each path
+	// is kept separately by the provider
+	SharedConfig(config *IngressConfig) *IngressConfig
+	// GetConfig returns the config of the path of host from the annotations of ingress and its backend service
+	GetConfig(ingAnno, svcAnno map[string]string, path, host string) IngressConfig
+	// DeletePaths deletes the config of the deleted paths of host from the annotations of ingress
+	DeletePaths(ingAnno map[string]string, host string, paths []string)
+	// ObjectKinds are the kinds of the custom objects serving the ingress
+	ObjectKinds() []schema.GroupVersionKind
+	// Objects returns the custom objects serving the ingress, svcConfigs are the configs of its backend services
+	Objects(obj *networkingv1.Ingress, svcConfigs map[string]IngressConfig) ([]*unstructured.Unstructured, error)
+	// TrafficWeightKey returns the service annotation key of the traffic weight of the pod version
+	TrafficWeightKey(podVersion string) (string, error)
+}
+
+func ValidateIngressProvider(name string) error {
+	if name == "" || utils.ContainsString(ingressProviders, name) {
+		return nil
+	}
+	return fmt.Errorf("ingress provider must be one of %s!", strings.Join(ingressProviders, "/"))
+}
+
+// GetIngressProvider returns the ingress provider of cluster, it is traefik if it is not set
+func GetIngressProvider(cluster string) (IngressProvider, error) {
+	c, err := dao.GetCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return NewIngressProvider(c.IngressProvider)
+}
+
+func NewIngressProvider(name string) (IngressProvider, error) {
+	switch name {
+	case "", IngressProviderTraefik:
+		return &traefikProvider{}, nil
+	case IngressProviderNginx:
+		return &nginxProvider{}, nil
+	case IngressProviderTraefikV2:
+		return &traefikV2Provider{}, nil
+	case IngressProviderIstio:
+		return &istioProvider{gateway: beego.AppConfig.DefaultString("istio::ingressGateway", "istio-system/ingressgateway")}, nil
+	}
+	return nil, ValidateIngressProvider(name)
+}
+
+// traefikProvider configures traefik 1.x by the annotations of ingress and service
+type traefikProvider struct{}
+
+func (p *traefikProvider) Name() string {
+	return IngressProviderTraefik
+}
+
+func (p *traefikProvider) Validate(ingress *Ingress) error {
+	if err := NewIngressConfer(&ingress.IngressConfig, nil, ingress.Host).Validate(); err != nil {
+		return err
+	}
+	features := []string{}
+	if len(ingress.Matches) != 0 {
+		features = append(features, "the match rules of versions (use the traffic weights instead)")
+	}
+	return unsupportedFeatures(p.Name(), features)
+}
+
+func (p *traefikProvider) SetIngress(obj *networkingv1.Ingress, config *IngressConfig, paths []string, host string) error {
+	return NewIngressConfer(config, paths, host).SetIngress(obj.Annotations)
+}
+
+func (p *traefikProvider) SetService(svc *apiv1.Service, config *IngressConfig) error {
+	return NewIngressConfer(config, nil, "").SetService(svc.Annotations)
+}
+
+func (p *traefikProvider) SharedConfig(config *IngressConfig) *IngressConfig {
+	// the rate limits of the paths are kept in the annotation separately
+	return newSharedConfig(config, false, true)
+}
+
+func (p *traefikProvider) GetConfig(ingAnno, svcAnno map[string]string, path, host string) IngressConfig {
+	return NewIngressConfer(nil, []string{path}, host).GetConfig(ingAnno, svcAnno)
+}
+
+func (p *traefikProvider) DeletePaths(ingAnno map[string]string, host string, paths []string) {
+	DeletePathRateLimit(ingAnno, paths)
+}
+
+func (p *traefikProvider) ObjectKinds() []schema.GroupVersionKind {
+	return nil
+}
+
+func (p *traefikProvider) Objects(obj *networkingv1.Ingress, svcConfigs map[string]IngressConfig) ([]*unstructured.Unstructured, error) {
+	return nil, nil
+}
+
+func (p *traefikProvider) TrafficWeightKey(podVersion string) (string, error) {
+	return IngressWeightAnnotationKeyPre + podVersion, nil
+}
+
+// storedConfig keeps the config in the annotations for the providers which can not read it back from their configuration
+type storedConfig struct{}
+
+func (s storedConfig) storeIngress(ingAnno map[string]string, config *IngressConfig, paths []string, host string) error {
+	configs := getPathConfigs(ingAnno)
+	for _, path := range paths {
+		configs[host+utils.GetRootPath(path)] = IngressConfig{
+			Redirect:    config.Redirect,
+			Buffering:   config.Buffering,
+			RateLimit:   config.RateLimit,
+			RuleType:    config.RuleType,
+			Rewriter:    config.Rewriter,
+			Matches:     config.Matches,
+			Whitelist:   config.Whitelist,
+			BasicAuth:   config.BasicAuth,
+			ForwardAuth: config.ForwardAuth,
+		}
+	}
+	return setPathConfigs(ingAnno, configs)
+}
+
+func (s storedConfig) SetService(svc *apiv1.Service, config *IngressConfig) error {
+	data, err := json.Marshal(IngressConfig{
+		Affinity:   config.Affinity,
+		Breaker:    config.Breaker,
+		Connection: config.Connection,
+	})
+	if err != nil {
+		return err
+	}
+	svc.Annotations[ServiceConfigAnnotationKey] = string(data)
+	return nil
+}
+
+func (s storedConfig) SharedConfig(config *IngressConfig) *IngressConfig {
+	return nil
+}
+
+func (s storedConfig) GetConfig(ingAnno, svcAnno map[string]string, path, host string) IngressConfig {
+	config := getPathConfigs(ingAnno)[host+utils.GetRootPath(path)]
+	svcConfig := getServiceConfig(svcAnno)
+	config.Affinity = svcConfig.Affinity
+	config.Breaker = svcConfig.Breaker
+	config.Connection = svcConfig.Connection
+	return config
+}
+
+func (s storedConfig) DeletePaths(ingAnno map[string]string, host string, paths []string) {
+	configs := getPathConfigs(ingAnno)
+	for _, path := range paths {
+		delete(configs, host+utils.GetRootPath(path))
+	}
+	if err := setPathConfigs(ingAnno, configs); err != nil {
+		beego.Error(err)
+	}
+}
+
+func (s storedConfig) TrafficWeightKey(podVersion string) (string, error) {
+	return "", fmt.Errorf("use the match rules of versions in the ingress config instead")
+}
+
+func getPathConfigs(ingAnno map[string]string) map[string]IngressConfig {
+	configs := make(map[string]IngressConfig)
+	if raw := ingAnno[IngressConfigAnnotationKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+			beego.Error(fmt.Sprintf("decode the ingress config %s failed: %v", raw, err))
+		}
+	}
+	return configs
+}
+
+func setPathConfigs(ingAnno map[string]string, configs map[string]IngressConfig) error {
+	if len(configs) == 0 {
+		delete(ingAnno, IngressConfigAnnotationKey)
+		return nil
+	}
+	data, err := json.Marshal(configs)
+	if err != nil {
+		return err
+	}
+	ingAnno[IngressConfigAnnotationKey] = string(data)
+	return nil
+}
+
+func getServiceConfig(svcAnno map[string]string) IngressConfig {
+	config := IngressConfig{}
+	if raw := svcAnno[ServiceConfigAnnotationKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &config); err != nil {
+			beego.Error(fmt.Sprintf("decode the service config %s failed: %v", raw, err))
+		}
+	}
+	return config
+}
+
+func setIngressClass(obj *networkingv1.Ingress, class string) {
+	obj.Annotations[AnnotationKubernetesIngressClass] = class
+	obj.Spec.IngressClassName = nil
+}
+
+// newSharedConfig returns the normalized enabled features of config which are set to the annotations of ingress,
+// the rate limit and rule type are included if they are shared by the paths of ingress
+func newSharedConfig(config *IngressConfig, withRateLimit, withRuleType bool) *IngressConfig {
+	shared := &IngressConfig{}
+	if config.Redirect.enabled() {
+		shared.Redirect = &redirect{Src: config.Redirect.Src, Dest: config.Redirect.Dest}
+	}
+	if config.Buffering.sizeEnabled() || config.Buffering.retryEnabled() {
+		shared.Buffering = config.Buffering.normalize()
+	}
+	if withRateLimit && config.RateLimit.enabled() {
+		shared.RateLimit = &rateLimit{Period: config.RateLimit.Period, Average: config.RateLimit.Average, Burst: config.RateLimit.Burst}
+	}
+	if withRuleType && config.RuleType != nil && config.RuleType.RuleType != "" && config.RuleType.RuleType != ruleTypePathPrefix {
+		shared.RuleType = &ruleType{RuleType: config.RuleType.RuleType}
+	}
+	if config.Rewriter.enabled() {
+		shared.Rewriter = &rewriter{Target: utils.AddRootPath(config.Rewriter.Target)}
+	}
+	if config.Whitelist.enabled() {
+		shared.Whitelist = &whitelist{SourceRange: config.Whitelist.SourceRange, UseXForwardedFor: config.Whitelist.UseXForwardedFor}
+	}
+	if config.BasicAuth.enabled() {
+		shared.BasicAuth = &basicAuth{SecretName: config.BasicAuth.SecretName}
+	}
+	if config.ForwardAuth.enabled() {
+		shared.ForwardAuth = &forwardAuth{URL: config.ForwardAuth.URL, TrustHeaders: config.ForwardAuth.TrustHeaders}
+		if len(config.ForwardAuth.ResponseHeaders) != 0 {
+			shared.ForwardAuth.ResponseHeaders = config.ForwardAuth.ResponseHeaders
+		}
+	}
+	return shared
+}
+
+func unsupportedFeatures(provider string, features []string) error {
+	if len(features) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the ingress provider %s does not support %s!", provider, strings.Join(features, ", "))
+}
+
+// the features are enabled if they are not the values which mean closed or the defaults of traefik
+func (config *redirect) enabled() bool {
+	return config != nil && (config.Src != "" || config.Dest != "")
+}
+
+func (config *breaker) enabled() bool {
+	return config != nil && config.BreakerType != "" && config.ThresholdValue != 0
+}
+
+func (config *rateLimit) enabled() bool {
+	return config != nil && config.Average != 0
+}
+
+func (config *connection) enabled() bool {
+	return config != nil && config.MaxConnAmount != 0
+}
+
+func (config *affinity) enabled() bool {
+	return config != nil && config.Affinity
+}
+
+func (config *rewriter) enabled() bool {
+	return config != nil && config.Target != ""
+}
+
+func (config *whitelist) enabled() bool {
+	return config != nil && len(config.SourceRange) != 0
+}
+
+func (config *basicAuth) enabled() bool {
+	return config != nil && config.SecretName != ""
+}
+
+func (config *forwardAuth) enabled() bool {
+	return config != nil && config.URL != ""
+}
+
+func (config *ruleType) strip() bool {
+	return config != nil && (config.RuleType == ruleTypePathStrip || config.RuleType == ruleTypePathPrefixStrip)
+}
+
+func (config *ruleType) exact() bool {
+	return config != nil && (config.RuleType == ruleTypePath || config.RuleType == ruleTypePathStrip)
+}
+
+func (config *buffering) sizeEnabled() bool {
+	if config == nil {
+		return false
+	}
+	isDefault := func(value, def int64) bool {
+		return value == 0 || value == def
+	}
+	return !(isDefault(config.MaxRequestBodyBytes, -1) && isDefault(config.MemRequestBodyBytes, 10) &&
+		isDefault(config.MaxResponseBodyBytes, -1) && isDefault(config.MemResponseBodyBytes, 10))
+}
+
+func (config *buffering) retryEnabled() bool {
+	return config != nil && config.Retry > 1
+}
+
+// normalize returns the buffering whose sizes which are not set are the defaults of traefik
+func (config *buffering) normalize() *buffering {
+	buf := &buffering{Retry: 1}
+	orDefault := func(value, def int64) int64 {
+		if value == 0 {
+			return def
+		}
+		return value
+	}
+	buf.MaxRequestBodyBytes = orDefault(config.MaxRequestBodyBytes, -1)
+	buf.MemRequestBodyBytes = orDefault(config.MemRequestBodyBytes, 10)
+	buf.MaxResponseBodyBytes = orDefault(config.MaxResponseBodyBytes, -1)
+	buf.MemResponseBodyBytes = orDefault(config.MemResponseBodyBytes, 10)
+	if config.retryEnabled() {
+		buf.Retry = config.Retry
+	}
+	return buf
+}
+
+var objectNameInvalidChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// providerObjectName returns the name of the custom object generated for the items of ingress
+func providerObjectName(items ...string) string {
+	parts := []string{}
+	for _, item := range items {
+		item = strings.Trim(objectNameInvalidChars.ReplaceAllString(strings.ToLower(item), "-"), "-")
+		if item == "" {
+			item = "root"
+		}
+		parts = append(parts, item)
+	}
+	return strings.Join(parts, "-")
+}
+
+// newProviderObject returns the custom object owned by the ingress, spec is converted to the json values
+func newProviderObject(gvk schema.GroupVersionKind, ing *networkingv1.Ingress, name string, spec interface{}) (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(ing.Namespace)
+	obj.SetName(name)
+	obj.SetLabels(map[string]string{IngressOwnerLabelKey: ing.Name})
+	obj.Object["spec"] = content
+	return obj, nil
+}
+
+// applyProviderObjects applies the custom objects serving the ingress,
+// and deletes the objects of the ingress which are not needed any more
+func (ing *IngressRes) applyProviderObjects(obj *networkingv1.Ingress) error {
+	kinds := ing.provider.ObjectKinds()
+	if len(kinds) == 0 {
+		return nil
+	}
+	svcConfigs := make(map[string]IngressConfig)
+	for _, rule := range obj.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			svcname, _ := kubeutil.IngressBackendService(path.Backend)
+			if _, ok := svcConfigs[svcname]; ok || svcname == "" {
+				continue
+			}
+			svc, err := ing.client.CoreV1().Services(obj.Namespace).Get(context.TODO(), svcname, metav1.GetOptions{})
+			if err != nil {
+				beego.Warn(fmt.Sprintf("get service %s of ingress %s failed: %v", svcname, obj.Name, err))
+				svcConfigs[svcname] = IngressConfig{}
+				continue
+			}
+			svcConfigs[svcname] = getServiceConfig(svc.Annotations)
+		}
+	}
+	objs, err := ing.provider.Objects(obj, svcConfigs)
+	if err != nil {
+		return err
+	}
+	client, err := service.GetDynamicClient(ing.cluster)
+	if err != nil {
+		return err
+	}
+	for _, gvk := range kinds {
+		mapping, err := kubeutil.GetRESTMapping(ing.cluster, ing.client.Discovery(), gvk)
+		if err != nil {
+			// the kinds which are not installed can not have the objects to be deleted
+			needed := false
+			for _, item := range objs {
+				needed = needed || item.GroupVersionKind() == gvk
+			}
+			if !needed {
+				beego.Warn(fmt.Sprintf("%s of the ingress provider %s is not installed: %v", gvk.Kind, ing.provider.Name(), err))
+				continue
+			}
+			return fmt.Errorf("%s of the ingress provider %s is not installed: %v", gvk.Kind, ing.provider.Name(), err)
+		}
+		res := client.Resource(mapping.Resource).Namespace(obj.Namespace)
+		desired := make(map[string]bool)
+		for _, item := range objs {
+			if item.GroupVersionKind() != gvk {
+				continue
+			}
+			desired[item.GetName()] = true
+			old, err := res.Get(context.TODO(), item.GetName(), metav1.GetOptions{})
+			if err == nil {
+				item.SetResourceVersion(old.GetResourceVersion())
+				_, err = res.Update(context.TODO(), item, metav1.UpdateOptions{})
+			} else if errors.IsNotFound(err) {
+				_, err = res.Create(context.TODO(), item, metav1.CreateOptions{})
+			}
+			if err != nil {
+				return fmt.Errorf("apply %s %s failed: %v", gvk.Kind, item.GetName(), err)
+			}
+		}
+		list, err := res.List(context.TODO(), metav1.ListOptions{LabelSelector: IngressOwnerLabelKey + "=" + obj.Name})
+		if err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			if desired[item.GetName()] {
+				continue
+			}
+			if err := res.Delete(context.TODO(), item.GetName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("delete %s %s failed: %v", gvk.Kind, item.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+const (
+	IngressRouteConflictReason = "IngressRouteConflict"
+
+	ingressRouteComponent = "ingress-route-registry"
+)
+
+type IngressRouteOwner struct {
+	Cluster     string `json:"cluster"`
+	Namespace   string `json:"namespace"`
+	IngressName string `json:"ingress_name"`
+}
+
+// IngressRouteConflict is the route of load balancer registered by several ingresses,
+// the owners are in the order of registration
+type IngressRouteConflict struct {
+	LoadBalancer string              `json:"load_balancer"`
+	Host         string              `json:"host"`
+	Path         string              `json:"path"`
+	Owners       []IngressRouteOwner `json:"owners"`
+}
+
+// IngressLoadBalancer returns the load balancer serving the ingresses of cluster, it is the ingress slb
+// or the load balancer domain name shared by the clusters, or the cluster itself if neither is set
+func IngressLoadBalancer(cluster *models.ZcloudCluster) string {
+	if cluster.IngressSLB != "" {
+		return "slb:" + cluster.IngressSLB
+	}
+	if cluster.LoadbalancerDomainName != "" {
+		return "lb:" + cluster.LoadbalancerDomainName
+	}
+	return "cluster:" + cluster.ClusterId
+}
+
+func getIngressLoadBalancer(cluster string) (string, error) {
+	item, err := dao.GetCluster(cluster)
+	if err != nil {
+		return "", err
+	}
+	return IngressLoadBalancer(item), nil
+}
+
+// CheckIngressRoutes checks the paths of host are not registered by the ingresses of the other namespaces
+// on the load balancer of cluster, the paths of the ingresses of namespace are checked by their rules
+func CheckIngressRoutes(cluster, namespace, host string, paths []string) error {
+	lb, err := getIngressLoadBalancer(cluster)
+	if err != nil {
+		return err
+	}
+	routes, err := dao.NewIngressRouteModel().ListRoutes(lb, host)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		if route.Cluster == cluster && route.Namespace == namespace {
+			continue
+		}
+		for _, path := range paths {
+			if utils.PathsIsEqual(route.Path, path) {
+				return fmt.Errorf("the route %s%s of load balancer %s is owned by ingress %s/%s/%s!",
+					host, utils.GetRootPath(path), lb, route.Cluster, route.Namespace, route.IngressName)
+			}
+		}
+	}
+	return nil
+}
+
+// SyncIngressRoutes registers the routes of the ingress on the load balancer of cluster, and deletes
+// its routes which do not exist any more. The ingresses created out of kubecloud may conflict with the others,
+// the conflicts of the new routes are recorded as the events of the ingress
+func SyncIngressRoutes(cluster string, ing *networkingv1.Ingress) error {
+	lb, err := getIngressLoadBalancer(cluster)
+	if err != nil {
+		return err
+	}
+	model := dao.NewIngressRouteModel()
+	old, err := model.ListIngressRoutes(cluster, ing.Namespace, ing.Name)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	desired := make(map[string]bool)
+	routes := []models.ZcloudIngressRoute{}
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			key := rule.Host + utils.GetRootPath(path.Path)
+			if desired[key] {
+				continue
+			}
+			desired[key] = true
+			routes = append(routes, models.ZcloudIngressRoute{
+				LoadBalancer: lb,
+				Host:         rule.Host,
+				Path:         utils.GetRootPath(path.Path),
+				Cluster:      cluster,
+				Namespace:    ing.Namespace,
+				IngressName:  ing.Name,
+			})
+		}
+	}
+	for _, route := range old {
+		key := route.Host + route.Path
+		if !desired[key] || route.LoadBalancer != lb || existing[key] {
+			if err := model.DeleteRoute(route.Id); err != nil {
+				return err
+			}
+			continue
+		}
+		existing[key] = true
+	}
+	for i := range routes {
+		route := &routes[i]
+		if existing[route.Host+route.Path] {
+			continue
+		}
+		if err := model.CreateRoute(route); err != nil {
+			return err
+		}
+		others, err := model.ListRoutes(lb, route.Host)
+		if err != nil {
+			return err
+		}
+		for _, other := range others {
+			// the paths are moved between the ingresses of namespace when their configs are changed
+			if other.Path != route.Path || (other.Cluster == cluster && other.Namespace == ing.Namespace) {
+				continue
+			}
+			recordIngressRouteEvent(route, fmt.Sprintf("the route %s%s of load balancer %s conflicts with ingress %s/%s/%s",
+				route.Host, route.Path, lb, other.Cluster, other.Namespace, other.IngressName))
+		}
+	}
+	return nil
+}
+
+// DeleteIngressRoutes deletes the routes registered by the ingress
+func DeleteIngressRoutes(cluster, namespace, ingressName string) error {
+	return dao.NewIngressRouteModel().DeleteIngressRoutes(cluster, namespace, ingressName)
+}
+
+// ListIngressRouteConflicts returns the routes registered by several ingresses on the load balancer of cluster,
+// or on all load balancers if cluster is empty
+func ListIngressRouteConflicts(cluster string) ([]IngressRouteConflict, error) {
+	lb := ""
+	if cluster != "" {
+		var err error
+		if lb, err = getIngressLoadBalancer(cluster); err != nil {
+			return nil, err
+		}
+	}
+	routes, err := dao.NewIngressRouteModel().ListRoutes(lb, "")
+	if err != nil {
+		return nil, err
+	}
+	conflictMap := make(map[string]*IngressRouteConflict)
+	keys := []string{}
+	for _, route := range routes {
+		key := route.LoadBalancer + " " + route.Host + route.Path
+		conflict, ok := conflictMap[key]
+		if !ok {
+			conflict = &IngressRouteConflict{
+				LoadBalancer: route.LoadBalancer,
+				Host:         route.Host,
+				Path:         route.Path,
+			}
+			conflictMap[key] = conflict
+			keys = append(keys, key)
+		}
+		conflict.Owners = append(conflict.Owners, IngressRouteOwner{
+			Cluster:     route.Cluster,
+			Namespace:   route.Namespace,
+			IngressName: route.IngressName,
+		})
+	}
+	sort.Strings(keys)
+	conflicts := []IngressRouteConflict{}
+	for _, key := range keys {
+		if len(conflictMap[key].Owners) > 1 {
+			conflicts = append(conflicts, *conflictMap[key])
+		}
+	}
+	return conflicts, nil
+}
+
+func recordIngressRouteEvent(route *models.ZcloudIngressRoute, message string) {
+	beego.Warn(fmt.Sprintf("cluster %s: %s", route.Cluster, message))
+	now, _ := time.Parse("2006-01-02 15:04:05", time.Now().Local().Format("2006-01-02 15:04:05"))
+	event := models.ZcloudEvent{
+		EventUid:        utils.NewUUID(),
+		EventType:       "Warning",
+		Cluster:         route.Cluster,
+		Namespace:       route.Namespace,
+		SourceComponent: ingressRouteComponent,
+		ObjectKind:      "Ingress",
+		ObjectName:      route.IngressName,
+		Reason:          IngressRouteConflictReason,
+		Message:         message,
+		Count:           1,
+		FirstTimestamp:  now,
+		LastTimestamp:   now,
+	}
+	if err := dao.CreateEvent(event); err != nil {
+		beego.Error(fmt.Sprintf("record event of ingress route %s%s failed: %v", route.Host, route.Path, err))
+	}
+}
@@ -0,0 +1,233 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"kubecloud/backend/models"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+const (
+	// the certificate of https host is an existing tls secret, or issued into the secret by cert-manager or the built-in acme client
+	TLSModeSecret      = "secret"
+	TLSModeCertManager = "cert-manager"
+	TLSModeACME        = "acme"
+
+	IssuerKindIssuer        = "Issuer"
+	IssuerKindClusterIssuer = "ClusterIssuer"
+
+	// the tls config of the https hosts is stored in the annotation of ingress as json keyed by host
+	IngressTLSAnnotationKey = "kubecloud/tls-config"
+
+	certManagerIssuerAnnotation        = "cert-manager.io/issuer"
+	certManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+)
+
+type tlsConfig struct {
+	Mode       string `json:"mode"`
+	Issuer     string `json:"issuer,omitempty"`
+	IssuerKind string `json:"issuer_kind,omitempty"`
+}
+
+// validateTLS checks the tls mode of https ingress, the secret name is generated by host
+// if the certificate is issued by cert-manager or acme
+func (ing *IngressRes) validateTLS(ingress *Ingress) error {
+	switch ingress.TLSMode {
+	case "", TLSModeSecret:
+		ingress.TLSMode = TLSModeSecret
+		ingress.Issuer = ""
+		ingress.IssuerKind = ""
+		if ingress.SecretName == "" {
+			return fmt.Errorf("https must have certificate secret name!")
+		}
+		secret, err := ing.modelSecret.GetSecret(ing.cluster, ingress.Namespace, ingress.SecretName)
+		if err != nil {
+			if err == orm.ErrNoRows {
+				return fmt.Errorf("certificate secret %s is not existed!", ingress.SecretName)
+			}
+			return err
+		}
+		if secret.Type != string(apiv1.SecretTypeTLS) {
+			return fmt.Errorf("the type of certificate secret %s is %s, but it must be %s!", ingress.SecretName, secret.Type, apiv1.SecretTypeTLS)
+		}
+	case TLSModeCertManager:
+		if ingress.Issuer == "" {
+			return fmt.Errorf("the issuer of cert-manager must be specified!")
+		}
+		if ingress.IssuerKind == "" {
+			ingress.IssuerKind = IssuerKindClusterIssuer
+		}
+		if ingress.IssuerKind != IssuerKindIssuer && ingress.IssuerKind != IssuerKindClusterIssuer {
+			return fmt.Errorf("the issuer kind must be %s or %s!", IssuerKindIssuer, IssuerKindClusterIssuer)
+		}
+	case TLSModeACME:
+		ingress.Issuer = ""
+		ingress.IssuerKind = ""
+		// the http-01 challenge can not validate the wildcard domain
+		if ingress.Host == "" || strings.Contains(ingress.Host, "*") {
+			return fmt.Errorf("the acme certificate can only be issued for the host without wildcard!")
+		}
+	default:
+		return fmt.Errorf("the tls mode must be %s, %s or %s!", TLSModeSecret, TLSModeCertManager, TLSModeACME)
+	}
+	if ingress.SecretName == "" {
+		ingress.SecretName = providerObjectName("tls", ingress.Host)
+	}
+	return nil
+}
+
+// setIngressTLS sets the tls config of host to the ingress, and the config of the hosts which are not https
+// any more is deleted. The issuer annotations of cert-manager take effect on all tls hosts of the ingress,
+// so the hosts of the ingress using cert-manager must use the same issuer
+func setIngressTLS(obj *networkingv1.Ingress, host string, config *tlsConfig) error {
+	configs := getTLSConfigs(obj.Annotations)
+	if config != nil {
+		configs[host] = *config
+	} else {
+		delete(configs, host)
+	}
+	tlsHosts := make(map[string]bool)
+	for _, tls := range obj.Spec.TLS {
+		for _, item := range tls.Hosts {
+			tlsHosts[item] = true
+		}
+	}
+	hosts := []string{}
+	for item := range configs {
+		if !tlsHosts[item] {
+			delete(configs, item)
+			continue
+		}
+		hosts = append(hosts, item)
+	}
+	sort.Strings(hosts)
+	var issuer *tlsConfig
+	for _, item := range hosts {
+		if configs[item].Mode != TLSModeCertManager {
+			continue
+		}
+		one := configs[item]
+		issuer = &one
+		break
+	}
+	if issuer != nil {
+		for _, item := range hosts {
+			if one := configs[item]; one != *issuer {
+				return fmt.Errorf("the tls host %s of ingress %s must use the %s %s of cert-manager, which issues the certificates of all tls hosts of the ingress", item, obj.Name, strings.ToLower(issuer.IssuerKind), issuer.Issuer)
+			}
+		}
+	}
+	delete(obj.Annotations, certManagerIssuerAnnotation)
+	delete(obj.Annotations, certManagerClusterIssuerAnnotation)
+	if issuer != nil {
+		if issuer.IssuerKind == IssuerKindIssuer {
+			obj.Annotations[certManagerIssuerAnnotation] = issuer.Issuer
+		} else {
+			obj.Annotations[certManagerClusterIssuerAnnotation] = issuer.Issuer
+		}
+	}
+	if len(configs) == 0 {
+		delete(obj.Annotations, IngressTLSAnnotationKey)
+		return nil
+	}
+	data, err := json.Marshal(configs)
+	if err != nil {
+		return err
+	}
+	obj.Annotations[IngressTLSAnnotationKey] = string(data)
+	return nil
+}
+
+func getTLSConfigs(ingAnno map[string]string) map[string]tlsConfig {
+	configs := make(map[string]tlsConfig)
+	if raw := ingAnno[IngressTLSAnnotationKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+			beego.Error(fmt.Sprintf("decode the tls config %s failed: %v", raw, err))
+		}
+	}
+	return configs
+}
+
+// getTLSConfig returns the tls config of https host, the hosts configured before the tls modes use existing secrets
+func getTLSConfig(ingAnno map[string]string, host string) tlsConfig {
+	config, ok := getTLSConfigs(ingAnno)[host]
+	if !ok {
+		config.Mode = TLSModeSecret
+	}
+	return config
+}
+
+func newTLSConfig(ingress *Ingress) *tlsConfig {
+	if ingress.Protocol != PROTOCOL_HTTPS {
+		return nil
+	}
+	return &tlsConfig{
+		Mode:       ingress.TLSMode,
+		Issuer:     ingress.Issuer,
+		IssuerKind: ingress.IssuerKind,
+	}
+}
+
+func tlsSecretOfHost(obj *networkingv1.Ingress, host string) string {
+	for _, tls := range obj.Spec.TLS {
+		if utils.ContainsString(tls.Hosts, host) {
+			return tls.SecretName
+		}
+	}
+	return ""
+}
+
+// syncAcmeCertificate records the host of ingress whose certificate is issued by the built-in acme client,
+// the certificate is issued and renewed by the acme certificate controller, and the record of the host
+// which does not use acme any more is deleted
+func (ing *IngressRes) syncAcmeCertificate(obj *networkingv1.Ingress, host string) error {
+	cert, err := ing.modelAcme.GetCertificate(ing.cluster, obj.Namespace, host)
+	if err != nil && err != orm.ErrNoRows {
+		return err
+	}
+	secretName := tlsSecretOfHost(obj, host)
+	if secretName == "" || getTLSConfig(obj.Annotations, host).Mode != TLSModeACME {
+		if cert == nil {
+			return nil
+		}
+		return ing.modelAcme.DeleteCertificate(ing.cluster, obj.Namespace, host)
+	}
+	if cert == nil {
+		return ing.modelAcme.CreateCertificate(&models.ZcloudAcmeCertificate{
+			Cluster:    ing.cluster,
+			Namespace:  obj.Namespace,
+			Host:       host,
+			SecretName: secretName,
+			Status:     models.ACME_CERTIFICATE_STATUS_PENDING,
+		})
+	}
+	if cert.SecretName == secretName {
+		return nil
+	}
+	cert.SecretName = secretName
+	cert.Status = models.ACME_CERTIFICATE_STATUS_PENDING
+	cert.Message = ""
+	return ing.modelAcme.UpdateCertificate(cert, "secret_name", "status", "message")
+}
+
+// getCertNotAfter returns the expiry time of the certificate in the tls secret
+func (ing *IngressRes) getCertNotAfter(namespace, secretName string) (time.Time, error) {
+	secret, err := ing.modelSecret.GetSecret(ing.cluster, namespace, secretName)
+	if err != nil {
+		return time.Time{}, err
+	}
+	data := make(map[string][]byte)
+	if err := json.Unmarshal([]byte(secret.Data), &data); err != nil {
+		return time.Time{}, err
+	}
+	return utils.CertificateNotAfter(data[apiv1.TLSCertKey])
+}
@@ -0,0 +1,224 @@
+package resource
+
+import (
+	"fmt"
+	"regexp"
+
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common/utils"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	traefikIngressRouteKind = schema.GroupVersionKind{Group: "traefik.containo.us", Version: "v1alpha1", Kind: "IngressRoute"}
+	traefikMiddlewareKind   = schema.GroupVersionKind{Group: "traefik.containo.us", Version: "v1alpha1", Kind: "Middleware"}
+)
+
+// traefikV2Provider serves the ingress by an IngressRoute of each host, the features are
+// the Middlewares of the routes, and the sticky session is set to the service of route
+type traefikV2Provider struct {
+	storedConfig
+}
+
+func (p *traefikV2Provider) Name() string {
+	return IngressProviderTraefikV2
+}
+
+func (p *traefikV2Provider) Validate(ingress *Ingress) error {
+	if err := NewIngressConfer(&ingress.IngressConfig, nil, ingress.Host).Validate(); err != nil {
+		return err
+	}
+	features := []string{}
+	// the challenge ingress of acme is not served by traefik v2
+	if ingress.Protocol == PROTOCOL_HTTPS && ingress.TLSMode == TLSModeACME {
+		features = append(features, "the built-in acme certificate (use cert-manager instead)")
+	}
+	return unsupportedFeatures(p.Name(), features)
+}
+
+func (p *traefikV2Provider) SetIngress(obj *networkingv1.Ingress, config *IngressConfig, paths []string, host string) error {
+	setIngressClass(obj, IngressRecordClass)
+	return p.storeIngress(obj.Annotations, config, paths, host)
+}
+
+func (p *traefikV2Provider) ObjectKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{traefikIngressRouteKind, traefikMiddlewareKind}
+}
+
+func (p *traefikV2Provider) Objects(obj *networkingv1.Ingress, svcConfigs map[string]IngressConfig) ([]*unstructured.Unstructured, error) {
+	objs := []*unstructured.Unstructured{}
+	configs := getPathConfigs(obj.Annotations)
+	for _, rule := range obj.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		routeName := providerObjectName(obj.Name, rule.Host)
+		routes := []interface{}{}
+		for _, path := range rule.HTTP.Paths {
+			svcname, port := kubeutil.IngressBackendService(path.Backend)
+			config := configs[rule.Host+utils.GetRootPath(path.Path)]
+			svcConfig := svcConfigs[svcname]
+			middlewares := []interface{}{}
+			addMiddleware := func(feature string, spec interface{}) error {
+				name := providerObjectName(routeName, path.Path, feature)
+				mw, err := newProviderObject(traefikMiddlewareKind, obj, name, spec)
+				if err != nil {
+					return err
+				}
+				objs = append(objs, mw)
+				middlewares = append(middlewares, map[string]interface{}{"name": name})
+				return nil
+			}
+			features := []struct {
+				enabled bool
+				name    string
+				spec    func() interface{}
+			}{
+				// the access policies are checked before the other middlewares
+				{config.Whitelist.enabled(), "whitelist", func() interface{} {
+					spec := map[string]interface{}{"sourceRange": config.Whitelist.SourceRange}
+					if config.Whitelist.UseXForwardedFor {
+						spec["ipStrategy"] = map[string]interface{}{"depth": 1}
+					}
+					return map[string]interface{}{"ipWhiteList": spec}
+				}},
+				{config.BasicAuth.enabled(), "basicauth", func() interface{} {
+					return map[string]interface{}{"basicAuth": map[string]interface{}{
+						"secret": htpasswdSecretName(config.BasicAuth.SecretName),
+					}}
+				}},
+				{config.ForwardAuth.enabled(), "forwardauth", func() interface{} {
+					spec := map[string]interface{}{
+						"address":            config.ForwardAuth.URL,
+						"trustForwardHeader": config.ForwardAuth.TrustHeaders,
+					}
+					if len(config.ForwardAuth.ResponseHeaders) != 0 {
+						spec["authResponseHeaders"] = config.ForwardAuth.ResponseHeaders
+					}
+					return map[string]interface{}{"forwardAuth": spec}
+				}},
+				{config.Redirect.enabled(), "redirect", func() interface{} {
+					return map[string]interface{}{"redirectRegex": map[string]interface{}{
+						"regex":       config.Redirect.Src,
+						"replacement": config.Redirect.Dest,
+						"permanent":   true,
+					}}
+				}},
+				{config.RateLimit.enabled(), "ratelimit", func() interface{} {
+					return map[string]interface{}{"rateLimit": map[string]interface{}{
+						"average": config.RateLimit.Average,
+						"period":  fmt.Sprintf("%dms", config.RateLimit.Period*config.RateLimit.Average),
+						"burst":   config.RateLimit.Burst,
+					}}
+				}},
+				{config.Buffering.sizeEnabled() || config.Buffering.retryEnabled(), "buffering", func() interface{} {
+					spec := map[string]interface{}{
+						"retryExpression": fmt.Sprintf("IsNetworkError() && Attempts() <= %d", config.Buffering.Retry),
+					}
+					sizes := map[string]int64{
+						"maxRequestBodyBytes":  config.Buffering.MaxRequestBodyBytes,
+						"memRequestBodyBytes":  config.Buffering.MemRequestBodyBytes,
+						"maxResponseBodyBytes": config.Buffering.MaxResponseBodyBytes,
+						"memResponseBodyBytes": config.Buffering.MemResponseBodyBytes,
+					}
+					for key, mb := range sizes {
+						if mb > 0 {
+							spec[key] = mb << 20
+						}
+					}
+					return map[string]interface{}{"buffering": spec}
+				}},
+				{config.RuleType.strip(), "strip", func() interface{} {
+					return map[string]interface{}{"stripPrefix": map[string]interface{}{
+						"prefixes": []string{utils.GetRootPath(path.Path)},
+					}}
+				}},
+				{config.Rewriter.enabled(), "rewrite", func() interface{} {
+					return map[string]interface{}{"replacePathRegex": map[string]interface{}{
+						"regex":       "^" + regexp.QuoteMeta(utils.GetRootPath(path.Path)) + "(.*)",
+						"replacement": utils.AddRootPath(config.Rewriter.Target) + "$1",
+					}}
+				}},
+				{svcConfig.Breaker.enabled(), "breaker", func() interface{} {
+					anno := make(map[string]string)
+					svcConfig.Breaker.set(anno, nil)
+					return map[string]interface{}{"circuitBreaker": map[string]interface{}{
+						"expression": anno[annotationKubernetesCircuitBreakerExpression],
+					}}
+				}},
+				{svcConfig.Connection.enabled(), "inflight", func() interface{} {
+					return map[string]interface{}{"inFlightReq": map[string]interface{}{
+						"amount": svcConfig.Connection.MaxConnAmount,
+						// limit the connections of each client ip as traefik 1.x
+						"sourceCriterion": map[string]interface{}{"ipStrategy": map[string]interface{}{"depth": 0}},
+					}}
+				}},
+			}
+			for _, feature := range features {
+				if !feature.enabled {
+					continue
+				}
+				if err := addMiddleware(feature.name, feature.spec()); err != nil {
+					return nil, err
+				}
+			}
+			backend := map[string]interface{}{"name": svcname, "port": port}
+			if svcConfig.Affinity.enabled() {
+				cookie := map[string]interface{}{}
+				if svcConfig.Affinity.SessionCookieName != "" {
+					cookie["name"] = svcConfig.Affinity.SessionCookieName
+				}
+				backend["sticky"] = map[string]interface{}{"cookie": cookie}
+			}
+			matcher := "PathPrefix"
+			if config.RuleType.exact() {
+				matcher = "Path"
+			}
+			match := fmt.Sprintf("%s(`%s`)", matcher, utils.GetRootPath(path.Path))
+			if rule.Host != "" {
+				match = fmt.Sprintf("Host(`%s`) && %s", rule.Host, match)
+			}
+			// the routes of match rules are longer than the route of path, so they have higher priorities
+			for _, item := range config.Matches {
+				versionBackend := map[string]interface{}{"name": versionServiceName(svcname, item.PodVersion), "port": port}
+				if sticky, ok := backend["sticky"]; ok {
+					versionBackend["sticky"] = sticky
+				}
+				route := map[string]interface{}{
+					"kind":     "Rule",
+					"match":    match + " && " + item.traefikRule(),
+					"services": []interface{}{versionBackend},
+				}
+				if len(middlewares) != 0 {
+					route["middlewares"] = middlewares
+				}
+				routes = append(routes, route)
+			}
+			route := map[string]interface{}{
+				"kind":     "Rule",
+				"match":    match,
+				"services": []interface{}{backend},
+			}
+			if len(middlewares) != 0 {
+				route["middlewares"] = middlewares
+			}
+			routes = append(routes, route)
+		}
+		spec := map[string]interface{}{"routes": routes}
+		for _, tls := range obj.Spec.TLS {
+			if utils.ContainsString(tls.Hosts, rule.Host) {
+				spec["tls"] = map[string]interface{}{"secretName": tls.SecretName}
+				break
+			}
+		}
+		route, err := newProviderObject(traefikIngressRouteKind, obj, routeName, spec)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, route)
+	}
+	return objs, nil
+}
@@ -0,0 +1,117 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"kubecloud/common/keyword"
+
+	"github.com/astaxie/beego"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"kubecloud/backend/util/labels"
+)
+
+type JobRes struct {
+	Cluster   string
+	Namespace string
+	client    kubernetes.Interface
+}
+
+func NewJobRes(client kubernetes.Interface, cluster, namespace string) KubeAppInterface {
+	return &JobRes{
+		Cluster:   cluster,
+		Namespace: namespace,
+		client:    client,
+	}
+}
+
+func (kr *JobRes) CreateOrUpdate(obj interface{}) (interface{}, error) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return nil, fmt.Errorf("can not generate job object!")
+	}
+	beego.Info("creating or updating job, " + job.Name)
+	return job, nil
+}
+
+func (kr *JobRes) Status(appname, suffix string) (*AppStatus, error) {
+	job, err := kr.client.BatchV1().Jobs(kr.Namespace).Get(context.TODO(), GenerateDeployName(appname, suffix), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return GetJobStatus(job), nil
+}
+
+func (kr *JobRes) Delete(obj interface{}) (interface{}, error) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return nil, fmt.Errorf("can not generate job object!")
+	}
+	job.ObjectMeta.Annotations = labels.AddLabel(job.ObjectMeta.Annotations, keyword.DELETE_LABLE, keyword.DELETE_LABLE_VALUE)
+	beego.Warn(fmt.Sprintf("delete job %s successfully!", job.Name))
+	return job, nil
+}
+
+func (kr *JobRes) AppIsExisted(appname, suffix string) (bool, error) {
+	name := GenerateDeployName(appname, suffix)
+	_, err := kr.client.BatchV1().Jobs(kr.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		} else {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (kr *JobRes) Scale(obj interface{}, replicas int) error {
+	return fmt.Errorf("job can not be scaled, please reconfigure its parallelism!")
+}
+
+func (kr *JobRes) Restart(obj interface{}) error {
+	// the pod template of job is immutable
+	return fmt.Errorf("job can not be restarted, please reconfigure it to run a new one!")
+}
+
+func (kr *JobRes) GetOwnerForPod(pod apiv1.Pod, ref *metav1.OwnerReference) interface{} {
+	if ref == nil || ref.Kind != batchv1.SchemeGroupVersion.WithKind("Job").Kind {
+		return nil
+	}
+	job, err := kr.client.BatchV1().Jobs(pod.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	if err != nil || job.UID != ref.UID {
+		beego.Warn(fmt.Sprintf("Cannot get job %s for pod %s: %v", ref.Name, pod.Name, err))
+		return nil
+	}
+	return job
+}
+
+func GetJobStatus(job *batchv1.Job) *AppStatus {
+	completions := int32(default_replicas)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+	status := &AppStatus{
+		StatusReplicas:    completions,
+		ReadyReplicas:     job.Status.Succeeded,
+		AvailableReplicas: job.Status.Succeeded,
+		UpdatedReplicas:   job.Status.Active,
+		AvailableStatus:   string(apiv1.ConditionFalse),
+	}
+	for _, condition := range job.Status.Conditions {
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.AvailableStatus = string(condition.Status)
+			status.Message = condition.Message
+		case batchv1.JobFailed:
+			if condition.Status == apiv1.ConditionTrue {
+				status.Failed = true
+				status.Message = fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+			}
+		}
+	}
+	return status
+}
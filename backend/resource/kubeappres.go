@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"kubecloud/backend/dao"
 	"kubecloud/backend/models"
@@ -13,9 +14,10 @@ import (
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/orm"
 	apiv1 "k8s.io/api/core/v1"
-	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -37,11 +39,25 @@ func NewKubeAppRes(client kubernetes.Interface, cluster, namespace, domainSuffix
 		Namespace:    namespace,
 		client:       client,
 	}
-	if kind == AppKindDeployment {
+	res.kubeAppHandle = NewKubeAppHandle(client, cluster, namespace, kind)
+	return res
+}
+
+func NewKubeAppHandle(client kubernetes.Interface, cluster, namespace, kind string) KubeAppInterface {
+	switch kind {
+	case AppKindDeployment:
 		//default is deployment
-		res.kubeAppHandle = NewDeploymentRes(client, cluster, namespace)
+		return NewDeploymentRes(client, cluster, namespace)
+	case AppKindStatefulSet:
+		return NewStatefulSetRes(client, cluster, namespace)
+	case AppKindDaemonSet:
+		return NewDaemonSetRes(client, cluster, namespace)
+	case AppKindJob:
+		return NewJobRes(client, cluster, namespace)
+	case AppKindCronJob:
+		return NewCronJobRes(client, cluster, namespace)
 	}
-	return res
+	return nil
 }
 
 func (kr *KubeAppRes) CreateAppResource(template AppTemplate, podVersion string) error {
@@ -58,6 +74,21 @@ func (kr *KubeAppRes) CreateAppResource(template AppTemplate, podVersion string)
 	if err != nil {
 		return err
 	}
+	// create the other resources before the app, the pods may depend on them
+	if obj, existed := objMap[UnstructuredKind]; existed {
+		objList, ok := obj.([]*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("resource object list is not right")
+		}
+		// only the objects created by this app are rolled back, the existing ones may be shared
+		created, err := kr.CreateObjects(objList)
+		if len(created) != 0 {
+			rollbackFuncList = append(rollbackFuncList, func() error { return kr.deleteObjects(created) })
+		}
+		if err != nil {
+			return err
+		}
+	}
 	// create svc
 	objs, existed := objMap[ServiceKind]
 	if existed {
@@ -74,7 +105,7 @@ func (kr *KubeAppRes) CreateAppResource(template AppTemplate, podVersion string)
 	// create ing
 	obj, existed := objMap[IngressKind]
 	if existed {
-		ings, ok := obj.([]*extensions.Ingress)
+		ings, ok := obj.([]*networkingv1.Ingress)
 		if !ok {
 			return fmt.Errorf("ingress object list is not right")
 		}
@@ -137,15 +168,46 @@ func (kr *KubeAppRes) UpdateAppResource(app *models.ZcloudApplication, new, old
 	if err := kr.updateIngResource(oldMap[IngressKind], objMap[IngressKind], new); err != nil {
 		beego.Warn("update ingress resource failed:", err)
 	}
+	if err := kr.updateObjects(oldMap[UnstructuredKind], objMap[UnstructuredKind]); err != nil {
+		beego.Warn("update other resources failed:", err)
+	}
 	go gitops.CommitK8sResource(kr.cluster, kr.gitOpsResList)
 	return nil
 }
 
-func (kr *KubeAppRes) DeleteAppResource(template AppTemplate, podVersion string) error {
+// DeleteAppResource deletes the workload of the pod version, the services, ingresses and other objects
+// shared by the versions of the application are deleted only if all is set or it is the last version.
+// Deleting one version, such as the new version whose deploying failed, must not break the traffic
+// routed to the other versions by the shared objects
+func (kr *KubeAppRes) DeleteAppResource(template AppTemplate, podVersion string, all bool) error {
 	objMap, err := template.GenerateKubeObject(kr.cluster, kr.Namespace, podVersion, kr.DomainSuffix)
 	if err != nil && objMap == nil {
 		return err
 	}
+	if !all {
+		last, err := kr.isLastVersion(template.GetAppName(), podVersion)
+		if err != nil {
+			return err
+		}
+		all = last
+	}
+	if all {
+		if err := kr.deleteSharedResource(objMap); err != nil {
+			return err
+		}
+	}
+	if obj, existed := objMap[template.GetAppKind()]; existed {
+		app, err := kr.kubeAppHandle.Delete(obj)
+		if err != nil {
+			return err
+		}
+		kr.gitOpsResList = append(kr.gitOpsResList, app)
+	}
+	go gitops.CommitK8sResource(kr.cluster, kr.gitOpsResList)
+	return nil
+}
+
+func (kr *KubeAppRes) deleteSharedResource(objMap map[string]interface{}) error {
 	if obj, existed := objMap[ServiceKind]; existed {
 		svcList, ok := obj.([]*apiv1.Service)
 		if !ok {
@@ -158,7 +220,7 @@ func (kr *KubeAppRes) DeleteAppResource(template AppTemplate, podVersion string)
 	}
 
 	if obj, existed := objMap[IngressKind]; existed {
-		ings, ok := obj.([]*extensions.Ingress)
+		ings, ok := obj.([]*networkingv1.Ingress)
 		if !ok {
 			return fmt.Errorf("ingress object list is not right")
 		}
@@ -167,17 +229,36 @@ func (kr *KubeAppRes) DeleteAppResource(template AppTemplate, podVersion string)
 			return err
 		}
 	}
-	if obj, existed := objMap[template.GetAppKind()]; existed {
-		app, err := kr.kubeAppHandle.Delete(obj)
-		if err != nil {
+	if obj, existed := objMap[UnstructuredKind]; existed {
+		objList, ok := obj.([]*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("resource object list is not right")
+		}
+		if err := kr.deleteObjects(objList); err != nil {
 			return err
 		}
-		kr.gitOpsResList = append(kr.gitOpsResList, app)
 	}
-	go gitops.CommitK8sResource(kr.cluster, kr.gitOpsResList)
 	return nil
 }
 
+// isLastVersion checks no other version of the application is recorded
+func (kr *KubeAppRes) isLastVersion(appname, podVersion string) (bool, error) {
+	versions, err := dao.NewVersionModel().GetVersionList(kr.cluster, kr.Namespace, appname)
+	if err != nil {
+		return false, err
+	}
+	return !hasOtherVersion(versions, podVersion), nil
+}
+
+func hasOtherVersion(versions []models.ZcloudVersion, podVersion string) bool {
+	for _, version := range versions {
+		if version.PodVersion != podVersion {
+			return true
+		}
+	}
+	return false
+}
+
 func (kr *KubeAppRes) updateSvcResource(oldObjList, newObjList interface{}) error {
 	delSvcs := []*apiv1.Service{}
 	newSvcs := []*apiv1.Service{}
@@ -215,11 +296,11 @@ func (kr *KubeAppRes) updateSvcResource(oldObjList, newObjList interface{}) erro
 }
 
 func (kr *KubeAppRes) updateIngResource(oldObjList, newObjList interface{}, newTpl AppTemplate) error {
-	delIngs := []*extensions.Ingress{}
-	newIngs := []*extensions.Ingress{}
+	delIngs := []*networkingv1.Ingress{}
+	newIngs := []*networkingv1.Ingress{}
 	ok := false
 	if newObjList != nil {
-		newIngs, ok = newObjList.([]*extensions.Ingress)
+		newIngs, ok = newObjList.([]*networkingv1.Ingress)
 		if !ok {
 			return fmt.Errorf("ingress object list is not right")
 		}
@@ -229,7 +310,7 @@ func (kr *KubeAppRes) updateIngResource(oldObjList, newObjList interface{}, newT
 		}
 	}
 	if oldObjList != nil {
-		oldIngs, _ := oldObjList.([]*extensions.Ingress)
+		oldIngs, _ := oldObjList.([]*networkingv1.Ingress)
 		for _, old := range oldIngs {
 			found := false
 			for _, new := range newIngs {
@@ -241,7 +322,7 @@ func (kr *KubeAppRes) updateIngResource(oldObjList, newObjList interface{}, newT
 			if found {
 				continue
 			}
-			ing, _ := kr.client.ExtensionsV1beta1().Ingresses(kr.Namespace).Get(old.Name, metav1.GetOptions{})
+			ing, _ := kubeutil.GetIngress(kr.client, kubeutil.GetAPIVersions(kr.cluster, kr.client.Discovery()), kr.Namespace, old.Name)
 			if kubeutil.IngressIsCreatedDefault(ing) {
 				delIngs = append(delIngs, old)
 			}
@@ -323,6 +404,21 @@ func (kr *KubeAppRes) UpdateTrafficWeight(vs []models.ZcloudVersion) error {
 	if len(vs) == 0 {
 		return fmt.Errorf("versions must be given!")
 	}
+	provider, err := GetIngressProvider(kr.cluster)
+	if err != nil {
+		return err
+	}
+	// reject the weights before changing anything if the provider does not support them
+	keys := make(map[string]string)
+	for _, vw := range vs {
+		// use podVersion replace version, because traefik will get pod version from pod name.
+		key, err := provider.TrafficWeightKey(vw.PodVersion)
+		if err != nil {
+			return fmt.Errorf("set the traffic weights of application %s failed, the ingress provider %s of cluster %s does not support them: %v",
+				vw.Name, provider.Name(), kr.cluster, err)
+		}
+		keys[vw.PodVersion] = key
+	}
 	service, err := dao.NewK8sServiceModel().Get(vs[0].Cluster, vs[0].Namespace, vs[0].Name, "")
 	if err != nil {
 		if err == orm.ErrNoRows {
@@ -330,26 +426,25 @@ func (kr *KubeAppRes) UpdateTrafficWeight(vs []models.ZcloudVersion) error {
 		}
 		return err
 	}
-	svc, err := kr.client.CoreV1().Services(kr.Namespace).Get(service.Name, metav1.GetOptions{})
+	svc, err := kr.client.CoreV1().Services(kr.Namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 	for _, vw := range vs {
-		// use podVersion replace version, because traefik will get pod version from pod name.
 		if vw.Weight == models.MIN_WEIGHT || vw.Weight == models.MAX_WEIGHT {
-			delete(svc.Annotations, IngressWeightAnnotationKeyPre+vw.PodVersion)
+			delete(svc.Annotations, keys[vw.PodVersion])
 		} else {
-			kubeutil.SetTrafficWeight(svc, IngressWeightAnnotationKeyPre+vw.PodVersion, vw.Weight)
+			kubeutil.SetTrafficWeight(svc, keys[vw.PodVersion], vw.Weight)
 		}
 	}
-	_, err = kr.client.CoreV1().Services(svc.Namespace).Update(svc)
+	_, err = kr.client.CoreV1().Services(svc.Namespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
 	return err
 }
 
 func (kr *KubeAppRes) CreateService(svcList []*apiv1.Service, commit bool) error {
 	for _, svc := range svcList {
 		//svcIsExisted := false
-		old, err := kr.client.CoreV1().Services(svc.Namespace).Get(svc.Name, metav1.GetOptions{})
+		old, err := kr.client.CoreV1().Services(svc.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
 		if err == nil {
 			//svcIsExisted = true
 			svc.ResourceVersion = old.ResourceVersion
@@ -400,10 +495,10 @@ func (kr *KubeAppRes) deleteService(svcList []*apiv1.Service) error {
 	return nil
 }
 
-func (kr *KubeAppRes) CreateIngress(ings []*extensions.Ingress, template AppTemplate, commit bool) error {
+func (kr *KubeAppRes) CreateIngress(ings []*networkingv1.Ingress, template AppTemplate, commit bool) error {
 	for _, ing := range ings {
 		//ingIsExist := false
-		ingress, err := kr.client.ExtensionsV1beta1().Ingresses(kr.Namespace).Get(ing.Name, metav1.GetOptions{})
+		ingress, err := kubeutil.GetIngress(kr.client, kubeutil.GetAPIVersions(kr.cluster, kr.client.Discovery()), kr.Namespace, ing.Name)
 		if err == nil {
 			//ingIsExist = true
 			updateIngressObj(ing, ingress)
@@ -423,7 +518,7 @@ func (kr *KubeAppRes) CreateIngress(ings []*extensions.Ingress, template AppTemp
 	return nil
 }
 
-func (kr *KubeAppRes) deleteIngress(ings []*extensions.Ingress) error {
+func (kr *KubeAppRes) deleteIngress(ings []*networkingv1.Ingress) error {
 	for _, ing := range ings {
 		ing.ObjectMeta.Annotations = labels.AddLabel(ing.ObjectMeta.Annotations, keyword.DELETE_LABLE, keyword.DELETE_LABLE_VALUE)
 		//go gitops.CommitK8sResource(kr.cluster, []interface{}{ing})
@@ -433,7 +528,7 @@ func (kr *KubeAppRes) deleteIngress(ings []*extensions.Ingress) error {
 	return nil
 }
 
-func updateIngressObj(new, old *extensions.Ingress) {
+func updateIngressObj(new, old *networkingv1.Ingress) {
 	new.ResourceVersion = old.ResourceVersion
 	// check annotation, if not existed in new ing, then add it
 	updateMap(new.Annotations, old.Annotations)
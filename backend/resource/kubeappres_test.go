@@ -0,0 +1,19 @@
+package resource
+
+import (
+	"testing"
+
+	"kubecloud/backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasOtherVersion(t *testing.T) {
+	versions := []models.ZcloudVersion{{Name: "web", PodVersion: "v1"}, {Name: "web", PodVersion: "v2"}}
+	// the shared objects are kept while the other version routes traffic by them
+	assert.True(t, hasOtherVersion(versions, "v2"))
+	assert.True(t, hasOtherVersion(versions, "v3"))
+	// the shared objects are deleted with the last version
+	assert.False(t, hasOtherVersion(versions[:1], "v1"))
+	assert.False(t, hasOtherVersion(nil, "v1"))
+}
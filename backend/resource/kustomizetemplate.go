@@ -0,0 +1,272 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"kubecloud/backend/dao"
+
+	"github.com/astaxie/beego"
+	yamlencoder "github.com/ghodss/yaml"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+)
+
+const kustomizeDir = "/template"
+
+// KustomizeOverlay is the customization of an environment, it is applied to the base in the order of
+// replicas, images and patches
+type KustomizeOverlay struct {
+	// Registry replaces the registry address of all images in the base, such as "prod-registry.example.com:5000"
+	Registry string          `json:"registry,omitempty"`
+	Replicas []types.Replica `json:"replicas,omitempty"`
+	// Images replaces the name, tag or digest of the images, it takes precedence over the registry
+	Images []types.Image `json:"images,omitempty"`
+	// NodeSelector is merged into the node selector of all applications
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+	// Patches are the inline strategic merge or json6902 patches, such as the resources of containers
+	Patches []types.Patch `json:"patches,omitempty"`
+}
+
+// kustomize template is the base yaml and the overlays of environments, the overlay of the env of cluster
+// is applied in-process and the result is deployed as native template
+type KustomizeTemplate struct {
+	Base string `json:"base"`
+	// Overlays are keyed by the env of cluster, such as "dev", "test" and "prod"
+	Overlays map[string]KustomizeOverlay `json:"overlays"`
+	Config   DeployConfig                `json:"config"`
+
+	// env of the cluster which the template is deployed to
+	env string
+}
+
+func NewKustomizeTemplate() *KustomizeTemplate {
+	return &KustomizeTemplate{}
+}
+
+// set default value for template config and the env of cluster
+func (t *KustomizeTemplate) Default(cluster string) Template {
+	native := &NativeTemplate{Config: t.Config}
+	native.Default(cluster)
+	t.Config = native.Config
+	if cluster != "" {
+		cinfo, err := dao.GetCluster(cluster)
+		if err != nil {
+			beego.Error("get env of cluster failed:", err)
+		} else {
+			t.env = cinfo.Env
+		}
+	}
+	return t
+}
+
+// Validate validates the result of each overlay, or the base if there is no overlay
+func (t *KustomizeTemplate) Validate() error {
+	if strings.TrimSpace(t.Base) == "" {
+		return fmt.Errorf("the base of kustomize template can not be empty!")
+	}
+	if len(t.Base) > TemplateMaxSize {
+		return fmt.Errorf("the length of template can not be above %vKB!", TemplateMaxSize>>10)
+	}
+	envs := []string{}
+	for env, overlay := range t.Overlays {
+		for _, patch := range overlay.Patches {
+			if patch.Path != "" || strings.TrimSpace(patch.Patch) == "" {
+				return fmt.Errorf("the patch of env %s must be given inline!", env)
+			}
+		}
+		envs = append(envs, env)
+	}
+	if len(envs) == 0 {
+		envs = append(envs, "")
+	}
+	sort.Strings(envs)
+	for _, env := range envs {
+		if err := t.validateEnv(env); err != nil {
+			if env == "" {
+				return err
+			}
+			return fmt.Errorf("the overlay of env %s is invalid: %v", env, err)
+		}
+	}
+	return nil
+}
+
+// Deploy deploys the base with the overlay of the env of cluster as native template
+func (t *KustomizeTemplate) Deploy(projectid int64, cluster, namespace, tname string, eparam *ExtensionParam) error {
+	manifest, err := t.Render(t.env)
+	if err != nil {
+		return err
+	}
+	native := &NativeTemplate{Template: manifest, Config: t.Config}
+	// the overlays are validated, but the env of cluster may have no overlay
+	if err := native.Validate(); err != nil {
+		return err
+	}
+	return native.Deploy(projectid, cluster, namespace, tname, eparam)
+}
+
+func (t *KustomizeTemplate) GetExample() []byte {
+	spec := "{\n" +
+		"  \"base\": \"apiVersion: apps/v1\\nkind: Deployment\\nmetadata:\\n  name: helloworld\\n...\",\n" +
+		"  \"overlays\": {\n" +
+		"    \"prod\": {\n" +
+		"      \"registry\": \"prod-registry.zhonganinfo.com:5000\",\n" +
+		"      \"replicas\": [{\"name\": \"helloworld\", \"count\": 3}],\n" +
+		"      \"node_selector\": {\"com.zhonganinfo.bizcluster\": \"zis\"},\n" +
+		"      \"patches\": [{\"patch\": \"[{\\\"op\\\": \\\"replace\\\", \\\"path\\\": \\\"/spec/template/spec/containers/0/resources/limits/memory\\\", \\\"value\\\": \\\"2Gi\\\"}]\", " +
+		"\"target\": {\"kind\": \"Deployment\", \"name\": \"helloworld\"}}]\n" +
+		"    }\n" +
+		"  }\n" +
+		"}"
+	return []byte(spec)
+}
+
+// Env returns the env of cluster which is set by Default
+func (t *KustomizeTemplate) Env() string {
+	return t.env
+}
+
+// Render applies the overlay of env to the base, the base is returned as it is built if env has no overlay
+func (t *KustomizeTemplate) Render(env string) (string, error) {
+	fs := filesys.MakeFsInMemory()
+	if err := fs.WriteFile(kustomizeDir+"/base.yaml", []byte(t.Base)); err != nil {
+		return "", err
+	}
+	kustomization := types.Kustomization{
+		TypeMeta: types.TypeMeta{
+			APIVersion: types.KustomizationVersion,
+			Kind:       types.KustomizationKind,
+		},
+		Resources: []string{"base.yaml"},
+	}
+	if overlay, ok := t.Overlays[env]; ok {
+		kustomization.Replicas = overlay.Replicas
+		kustomization.Images = overlay.Images
+		if overlay.Registry != "" {
+			images, err := t.registryImages(overlay.Registry, overlay.Images)
+			if err != nil {
+				return "", err
+			}
+			kustomization.Images = append(kustomization.Images, images...)
+		}
+		if len(overlay.NodeSelector) != 0 {
+			patches, err := nodeSelectorPatches(overlay.NodeSelector)
+			if err != nil {
+				return "", err
+			}
+			kustomization.Patches = append(kustomization.Patches, patches...)
+		}
+		kustomization.Patches = append(kustomization.Patches, overlay.Patches...)
+	}
+	data, err := yamlencoder.Marshal(kustomization)
+	if err != nil {
+		return "", err
+	}
+	if err := fs.WriteFile(kustomizeDir+"/kustomization.yaml", data); err != nil {
+		return "", err
+	}
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, kustomizeDir)
+	if err != nil {
+		return "", fmt.Errorf("kustomize template failed: %v", err)
+	}
+	out, err := resMap.AsYaml()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (t *KustomizeTemplate) validateEnv(env string) error {
+	manifest, err := t.Render(env)
+	if err != nil {
+		return err
+	}
+	native := &NativeTemplate{Template: manifest, Config: t.Config}
+	return native.Validate()
+}
+
+// registryImages returns the images which replace the registry of the images in base
+func (t *KustomizeTemplate) registryImages(registry string, excludes []types.Image) ([]types.Image, error) {
+	tplList, _, err := (&NativeTemplate{Template: t.Base}).GenNativeAppTemplate("", INIT_APPNAME)
+	if err != nil {
+		return nil, err
+	}
+	replaced := make(map[string]bool)
+	for _, image := range excludes {
+		replaced[image.Name] = true
+	}
+	images := []types.Image{}
+	for _, tpl := range tplList {
+		spec := tpl.podTemplate().Spec
+		for _, c := range append(spec.InitContainers, spec.Containers...) {
+			name := imageName(c.Image)
+			if replaced[name] {
+				continue
+			}
+			replaced[name] = true
+			images = append(images, types.Image{Name: name, NewName: replaceImageRegistry(name, registry)})
+		}
+	}
+	return images, nil
+}
+
+// nodeSelectorPatches merges the node selector into the pod template of applications
+func nodeSelectorPatches(nodeSelector map[string]string) ([]types.Patch, error) {
+	podSpec := map[string]interface{}{
+		"template": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"nodeSelector": nodeSelector,
+			},
+		},
+	}
+	patches := []types.Patch{}
+	for _, item := range []struct {
+		apiVersion string
+		kind       string
+		spec       map[string]interface{}
+	}{
+		{"apps/v1", "Deployment|StatefulSet|DaemonSet", podSpec},
+		{"batch/v1", "Job", podSpec},
+		{"batch/v1", "CronJob", map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": podSpec}}},
+	} {
+		data, err := yamlencoder.Marshal(map[string]interface{}{
+			"apiVersion": item.apiVersion,
+			"kind":       strings.Split(item.kind, "|")[0],
+			// the name is ignored, the patch is applied to the target
+			"metadata": map[string]interface{}{"name": "node-selector"},
+			"spec":     item.spec,
+		})
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, types.Patch{
+			Patch:  string(data),
+			Target: &types.Selector{ResId: resid.ResId{Gvk: resid.Gvk{Kind: item.kind}}},
+		})
+	}
+	return patches, nil
+}
+
+// imageName returns the image without tag and digest
+func imageName(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// replaceImageRegistry replaces the registry of image name, or adds the registry if the image is in docker hub
+func replaceImageRegistry(name, registry string) string {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		name = parts[1]
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + name
+}
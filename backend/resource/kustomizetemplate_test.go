@@ -0,0 +1,216 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+)
+
+const testKustomizeBase = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      initContainers:
+      - name: init
+        image: busybox@sha256:1ac6a6e9d3e6f9b8b8c2c2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3
+      containers:
+      - name: web
+        image: harbor.example.com:5000/library/web:1.0
+        resources:
+          limits:
+            cpu: 500m
+            memory: 512Mi
+      - name: proxy
+        image: nginx:1.21
+        resources:
+          limits:
+            cpu: 100m
+            memory: 128Mi
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  schedule: "0 2 * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          restartPolicy: OnFailure
+          containers:
+          - name: backup
+            image: harbor.example.com/tools/backup:2.0
+`
+
+func TestImageName(t *testing.T) {
+	cases := map[string]string{
+		"nginx":                         "nginx",
+		"nginx:1.21":                    "nginx",
+		"library/nginx:1.21":            "library/nginx",
+		"harbor.example.com/lib/nginx":  "harbor.example.com/lib/nginx",
+		"harbor.example.com/lib/web:v1": "harbor.example.com/lib/web",
+		// the port of registry is not a tag
+		"harbor.example.com:5000/lib/web":    "harbor.example.com:5000/lib/web",
+		"harbor.example.com:5000/lib/web:v1": "harbor.example.com:5000/lib/web",
+		"localhost:5000/web":                 "localhost:5000/web",
+		// the digest is removed with or without the tag
+		"nginx@sha256:0123456789abcdef":                          "nginx",
+		"harbor.example.com:5000/web@sha256:0123456789abcdef":    "harbor.example.com:5000/web",
+		"harbor.example.com:5000/web:v1@sha256:0123456789abcdef": "harbor.example.com:5000/web",
+	}
+	for image, name := range cases {
+		assert.Equal(t, name, imageName(image), image)
+	}
+}
+
+func TestReplaceImageRegistry(t *testing.T) {
+	cases := []struct {
+		name     string
+		registry string
+		replaced string
+	}{
+		{"harbor.example.com/library/web", "prod.example.com", "prod.example.com/library/web"},
+		{"harbor.example.com:5000/library/web", "prod.example.com:5000", "prod.example.com:5000/library/web"},
+		{"harbor.example.com/web", "prod.example.com:5000/", "prod.example.com:5000/web"},
+		{"localhost/web", "prod.example.com", "prod.example.com/web"},
+		{"localhost:5000/web", "prod.example.com", "prod.example.com/web"},
+		// the images in docker hub have no registry, the registry is added
+		{"nginx", "prod.example.com:5000", "prod.example.com:5000/nginx"},
+		{"library/nginx", "prod.example.com:5000", "prod.example.com:5000/library/nginx"},
+		{"bitnami/redis", "prod.example.com", "prod.example.com/bitnami/redis"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.replaced, replaceImageRegistry(c.name, c.registry), "%s to %s", c.name, c.registry)
+	}
+}
+
+func TestRegistryImages(t *testing.T) {
+	tpl := &KustomizeTemplate{Base: testKustomizeBase}
+	images, err := tpl.registryImages("prod.example.com:5000", nil)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []types.Image{
+		{Name: "busybox", NewName: "prod.example.com:5000/busybox"},
+		{Name: "harbor.example.com:5000/library/web", NewName: "prod.example.com:5000/library/web"},
+		{Name: "nginx", NewName: "prod.example.com:5000/nginx"},
+		{Name: "harbor.example.com/tools/backup", NewName: "prod.example.com:5000/tools/backup"},
+	}, images)
+
+	// the images given by the overlay are not replaced by the registry
+	images, err = tpl.registryImages("prod.example.com:5000", []types.Image{{Name: "nginx", NewTag: "1.23"}})
+	assert.Nil(t, err)
+	for _, image := range images {
+		assert.NotEqual(t, "nginx", image.Name)
+	}
+	assert.Equal(t, 3, len(images))
+
+	_, err = (&KustomizeTemplate{Base: "kind: ["}).registryImages("prod.example.com", nil)
+	assert.NotNil(t, err)
+}
+
+// renderKustomizeApps renders the overlay of env and returns the application templates by name
+func renderKustomizeApps(t *testing.T, tpl *KustomizeTemplate, env string) map[string]*NativeAppTemplate {
+	out, err := tpl.Render(env)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	tplList, _, err := (&NativeTemplate{Template: out}).GenNativeAppTemplate("", INIT_APPNAME)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	apps := make(map[string]*NativeAppTemplate)
+	for _, item := range tplList {
+		apps[item.GetAppName()] = item
+	}
+	return apps
+}
+
+func containerImages(spec apiv1.PodSpec) []string {
+	images := []string{}
+	for _, c := range append(append([]apiv1.Container{}, spec.InitContainers...), spec.Containers...) {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+func TestKustomizeRenderBase(t *testing.T) {
+	tpl := &KustomizeTemplate{Base: testKustomizeBase, Overlays: map[string]KustomizeOverlay{
+		"prod": {Replicas: []types.Replica{{Name: "web", Count: 3}}},
+	}}
+	// the base is rendered as it is if the env has no overlay
+	apps := renderKustomizeApps(t, tpl, "dev")
+	assert.Equal(t, int32(1), apps["web"].getReplicas())
+	assert.Equal(t, 2, len(apps))
+}
+
+func TestKustomizeRenderReplicas(t *testing.T) {
+	tpl := &KustomizeTemplate{Base: testKustomizeBase, Overlays: map[string]KustomizeOverlay{
+		"prod": {Replicas: []types.Replica{{Name: "web", Count: 3}}},
+	}}
+	apps := renderKustomizeApps(t, tpl, "prod")
+	assert.Equal(t, int32(3), apps["web"].getReplicas())
+}
+
+func TestKustomizeRenderImages(t *testing.T) {
+	tpl := &KustomizeTemplate{Base: testKustomizeBase, Overlays: map[string]KustomizeOverlay{
+		"prod": {
+			Registry: "prod.example.com:5000",
+			Images: []types.Image{
+				{Name: "nginx", NewTag: "1.23"},
+				{Name: "harbor.example.com:5000/library/web", Digest: "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"},
+			},
+		},
+	}}
+	apps := renderKustomizeApps(t, tpl, "prod")
+	assert.Equal(t, []string{
+		"prod.example.com:5000/busybox@sha256:1ac6a6e9d3e6f9b8b8c2c2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3",
+		// the images given by the overlay take precedence over the registry
+		"harbor.example.com:5000/library/web@sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+		"nginx:1.23",
+	}, containerImages(apps["web"].podTemplate().Spec))
+	assert.Equal(t, []string{"prod.example.com:5000/tools/backup:2.0"}, containerImages(apps["backup"].podTemplate().Spec))
+}
+
+func TestKustomizeRenderNodeSelector(t *testing.T) {
+	tpl := &KustomizeTemplate{Base: testKustomizeBase, Overlays: map[string]KustomizeOverlay{
+		"prod": {NodeSelector: map[string]string{"role": "app"}},
+	}}
+	apps := renderKustomizeApps(t, tpl, "prod")
+	for _, name := range []string{"web", "backup"} {
+		assert.Equal(t, map[string]string{"role": "app"}, apps[name].podTemplate().Spec.NodeSelector, name)
+	}
+	// the other fields of the pod template are kept
+	assert.Equal(t, 2, len(apps["web"].podTemplate().Spec.Containers))
+	assert.Equal(t, "web", apps["web"].Deployment.Name)
+}
+
+func TestKustomizeRenderPatches(t *testing.T) {
+	tpl := &KustomizeTemplate{Base: testKustomizeBase, Overlays: map[string]KustomizeOverlay{
+		"prod": {Patches: []types.Patch{
+			{
+				Patch:  `[{"op": "replace", "path": "/spec/template/spec/containers/0/resources/limits/memory", "value": "2Gi"}]`,
+				Target: &types.Selector{ResId: resid.ResId{Gvk: resid.Gvk{Kind: "Deployment"}, Name: "web"}},
+			},
+			{
+				Patch: "apiVersion: batch/v1\nkind: CronJob\nmetadata:\n  name: backup\nspec:\n  schedule: \"0 3 * * *\"\n",
+			},
+		}},
+	}}
+	apps := renderKustomizeApps(t, tpl, "prod")
+	memory := apps["web"].podTemplate().Spec.Containers[0].Resources.Limits[apiv1.ResourceMemory]
+	assert.Equal(t, "2Gi", memory.String())
+	assert.Equal(t, "0 3 * * *", apps["backup"].CronJob.Spec.Schedule)
+}
@@ -1,9 +1,11 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/logs"
+	"kubecloud/backend/util/kubeutil"
 	"kubecloud/backend/util/labels"
 	"kubecloud/gitops"
 	"strings"
@@ -194,17 +196,17 @@ func NamespaceDelete(cluster, namespace string) (err error) {
 	var wg utils.WaitGroup
 	// check deployments
 	wg.Go(func(...interface{}) {
-		lst, err := client.AppsV1beta1().Deployments(namespace).List(metav1.ListOptions{})
+		deployments, err := kubeutil.ListDeployments(client, kubeutil.GetAPIVersions(cluster, client.Discovery()), namespace, metav1.ListOptions{})
 		if err != nil {
 			setError(err)
 		}
-		if n := len(lst.Items); n > 0 {
+		if n := len(deployments); n > 0 {
 			ch <- fmt.Sprintf(`deployment count: %v`, n)
 		}
 	})
 	// check pods
 	wg.Go(func(...interface{}) {
-		lst, err := client.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+		lst, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
 			setError(err)
 		}
@@ -214,7 +216,7 @@ func NamespaceDelete(cluster, namespace string) (err error) {
 	})
 	// check services
 	wg.Go(func(...interface{}) {
-		lst, err := client.CoreV1().Services(namespace).List(metav1.ListOptions{})
+		lst, err := client.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
 			setError(err)
 		}
@@ -224,7 +226,7 @@ func NamespaceDelete(cluster, namespace string) (err error) {
 	})
 	// check pvcs
 	wg.Go(func(...interface{}) {
-		lst, err := client.CoreV1().PersistentVolumeClaims(namespace).List(metav1.ListOptions{})
+		lst, err := client.CoreV1().PersistentVolumeClaims(namespace).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
 			setError(err)
 		}
@@ -234,7 +236,7 @@ func NamespaceDelete(cluster, namespace string) (err error) {
 	})
 	// check config maps
 	wg.Go(func(...interface{}) {
-		lst, err := client.CoreV1().ConfigMaps(namespace).List(metav1.ListOptions{})
+		lst, err := client.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
 			setError(err)
 		}
@@ -244,7 +246,7 @@ func NamespaceDelete(cluster, namespace string) (err error) {
 	})
 	// check replication controllers
 	wg.Go(func(...interface{}) {
-		lst, err := client.CoreV1().ReplicationControllers(namespace).List(metav1.ListOptions{})
+		lst, err := client.CoreV1().ReplicationControllers(namespace).List(context.TODO(), metav1.ListOptions{})
 		if err != nil {
 			setError(err)
 		}
@@ -290,7 +292,7 @@ func NamespaceDelete(cluster, namespace string) (err error) {
 		return err
 	}
 	// delete k8s resource
-	k8sNamespace, err := client.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	k8sNamespace, err := client.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
 	if err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
@@ -351,7 +353,7 @@ func KubeNamespaceCreate(client kubernetes.Interface, cluster, name string) erro
 		kubeClient = c
 	}
 
-	_, err := kubeClient.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	_, err := kubeClient.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
 	if errors.IsNotFound(err) {
 		// create
 		res := &apiv1.Namespace{
@@ -14,10 +14,13 @@ import (
 	"kubecloud/common/validate"
 
 	"github.com/astaxie/beego"
-	v1beta1 "k8s.io/api/apps/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
-	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	v1 "k8s.io/apiserver/pkg/storage/names"
 )
@@ -26,10 +29,70 @@ import (
 type NativeAppTemplate struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
-	Deployment        *v1beta1.Deployment   `json:"deployment,omitempty"`
-	Services          []*apiv1.Service      `json:"services,omitempty"`
-	Ingresses         []*extensions.Ingress `json:"ingresses,omitempty"`
-	Config            DeployConfig          `json:"config"`
+	Deployment        *appsv1.Deployment           `json:"deployment,omitempty"`
+	StatefulSet       *appsv1.StatefulSet          `json:"statefulset,omitempty"`
+	DaemonSet         *appsv1.DaemonSet            `json:"daemonset,omitempty"`
+	Job               *batchv1.Job                 `json:"job,omitempty"`
+	CronJob           *batchv1.CronJob             `json:"cronjob,omitempty"`
+	Services          []*apiv1.Service             `json:"services,omitempty"`
+	Ingresses         []*networkingv1.Ingress      `json:"ingresses,omitempty"`
+	Objects           []*unstructured.Unstructured `json:"objects,omitempty"`
+	Config            DeployConfig                 `json:"config"`
+}
+
+// UnmarshalJSON converts the extensions/v1beta1 ingresses of the template saved before to networking.k8s.io/v1,
+// the workload objects of beta versions have the same schema as the GA ones
+func (tp *NativeAppTemplate) UnmarshalJSON(data []byte) error {
+	type nativeAppTemplate NativeAppTemplate
+	aux := struct {
+		*nativeAppTemplate
+		Ingresses []json.RawMessage `json:"ingresses,omitempty"`
+	}{nativeAppTemplate: (*nativeAppTemplate)(tp)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	tp.Ingresses = nil
+	for _, raw := range aux.Ingresses {
+		ing, err := kubeutil.DecodeIngress(raw)
+		if err != nil {
+			return err
+		}
+		tp.Ingresses = append(tp.Ingresses, ing)
+	}
+	return nil
+}
+
+// decode application object of the given kind to native app template
+func decodeNativeAppTemplate(kind string, data []byte) (*NativeAppTemplate, error) {
+	tpl := &NativeAppTemplate{}
+	switch kind {
+	case AppKindDeployment:
+		tpl.Deployment = &appsv1.Deployment{}
+	case AppKindStatefulSet:
+		tpl.StatefulSet = &appsv1.StatefulSet{}
+	case AppKindDaemonSet:
+		tpl.DaemonSet = &appsv1.DaemonSet{}
+	case AppKindJob:
+		tpl.Job = &batchv1.Job{}
+	case AppKindCronJob:
+		tpl.CronJob = &batchv1.CronJob{}
+	default:
+		return nil, fmt.Errorf("cant support this application kind: %s", kind)
+	}
+	tpl.Kind = kind
+	obj := tpl.appObject()
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	tpl.TypeMeta = metav1.TypeMeta{
+		Kind:       gvk.Kind,
+		APIVersion: gvk.GroupVersion().String(),
+	}
+	if accessor, ok := obj.(metav1.ObjectMetaAccessor); ok {
+		tpl.ObjectMeta = *accessor.GetObjectMeta().(*metav1.ObjectMeta)
+	}
+	return tpl, nil
 }
 
 //context is file context of native template,
@@ -83,13 +146,10 @@ func (tp *NativeAppTemplate) GenerateAppObject(cluster, namespace, tplname, doma
 	}
 	mainImage := ""
 	var containers []apiv1.Container
-	replicas := int32(default_replicas)
-	if tp.GetAppKind() == AppKindDeployment {
-		containers = tp.Deployment.Spec.Template.Spec.Containers
-		if tp.Deployment.Spec.Replicas != nil {
-			replicas = *tp.Deployment.Spec.Replicas
-		}
+	if podTemplate := tp.podTemplate(); podTemplate != nil {
+		containers = podTemplate.Spec.Containers
 	}
+	replicas := tp.getReplicas()
 	if len(containers) > 0 {
 		mainImage = containers[0].Image
 	}
@@ -155,9 +215,10 @@ func (tp *NativeAppTemplate) UpdateAppObject(app *models.ZcloudApplication, doma
 func (tp *NativeAppTemplate) GenerateKubeObject(cluster, namespace, podVersion, domainSuffix string) (map[string]interface{}, error) {
 	// translate template to kubernetes resource objects
 	objs := make(map[string]interface{})
+	var headlessSvc *apiv1.Service
 	switch tp.GetAppKind() {
 	case AppKindDeployment:
-		deploy := &v1beta1.Deployment{
+		deploy := &appsv1.Deployment{
 			TypeMeta:   tp.Deployment.TypeMeta,
 			ObjectMeta: tp.Deployment.ObjectMeta,
 			Spec:       tp.Deployment.Spec,
@@ -171,10 +232,87 @@ func (tp *NativeAppTemplate) GenerateKubeObject(cluster, namespace, podVersion,
 			podVersion)
 		deploy.Spec.Selector = tp.newAppSelector(deploy.Spec.Selector, deploy.Spec.Template, podVersion)
 		objs[AppKindDeployment] = deploy
+	case AppKindStatefulSet:
+		sts := &appsv1.StatefulSet{
+			TypeMeta:   tp.StatefulSet.TypeMeta,
+			ObjectMeta: tp.StatefulSet.ObjectMeta,
+			Spec:       tp.StatefulSet.Spec,
+		}
+		sts.Name = genAppName(sts.Name, podVersion)
+		sts.Spec.Template = tp.newPodTemplateSpec(sts.Spec.Template, podVersion)
+		sts.ObjectMeta = tp.newAppObjectMeta(tp.StatefulSet.ObjectMeta,
+			sts.Spec.Template.Labels,
+			namespace,
+			sts.Name,
+			podVersion)
+		sts.Spec.Selector = tp.newAppSelector(sts.Spec.Selector, sts.Spec.Template, podVersion)
+		if sts.Spec.ServiceName == "" {
+			sts.Spec.ServiceName = GenHeadlessSvcName(AppKindStatefulSet, tp.GetAppName())
+		}
+		// pods are created, updated and deleted one by one in order
+		if sts.Spec.PodManagementPolicy == "" {
+			sts.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+		}
+		if sts.Spec.UpdateStrategy.Type == "" {
+			sts.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+		}
+		objs[AppKindStatefulSet] = sts
+		headlessSvc = tp.genHeadlessService(namespace, sts)
+	case AppKindDaemonSet:
+		ds := &appsv1.DaemonSet{
+			TypeMeta:   tp.DaemonSet.TypeMeta,
+			ObjectMeta: tp.DaemonSet.ObjectMeta,
+			Spec:       tp.DaemonSet.Spec,
+		}
+		ds.Name = genAppName(ds.Name, podVersion)
+		ds.Spec.Template = tp.newPodTemplateSpec(ds.Spec.Template, podVersion)
+		ds.ObjectMeta = tp.newAppObjectMeta(tp.DaemonSet.ObjectMeta,
+			ds.Spec.Template.Labels,
+			namespace,
+			ds.Name,
+			podVersion)
+		ds.Spec.Selector = tp.newAppSelector(ds.Spec.Selector, ds.Spec.Template, podVersion)
+		objs[AppKindDaemonSet] = ds
+	case AppKindJob:
+		// the selector of job is generated by kubernetes
+		job := &batchv1.Job{
+			TypeMeta:   tp.Job.TypeMeta,
+			ObjectMeta: tp.Job.ObjectMeta,
+			Spec:       tp.Job.Spec,
+		}
+		job.Name = genAppName(job.Name, podVersion)
+		job.Spec.Template = tp.newPodTemplateSpec(job.Spec.Template, podVersion)
+		job.ObjectMeta = tp.newAppObjectMeta(tp.Job.ObjectMeta,
+			job.Spec.Template.Labels,
+			namespace,
+			job.Name,
+			podVersion)
+		objs[AppKindJob] = job
+	case AppKindCronJob:
+		cj := &batchv1.CronJob{
+			TypeMeta:   tp.CronJob.TypeMeta,
+			ObjectMeta: tp.CronJob.ObjectMeta,
+			Spec:       tp.CronJob.Spec,
+		}
+		cj.Name = genAppName(cj.Name, podVersion)
+		cj.Spec.JobTemplate.Spec.Template = tp.newPodTemplateSpec(cj.Spec.JobTemplate.Spec.Template, podVersion)
+		cj.Spec.JobTemplate.ObjectMeta = tp.newObjectMeta(cj.Spec.JobTemplate.ObjectMeta,
+			cj.Spec.JobTemplate.Spec.Template.Labels,
+			namespace,
+			"")
+		cj.ObjectMeta = tp.newAppObjectMeta(tp.CronJob.ObjectMeta,
+			cj.Spec.JobTemplate.Spec.Template.Labels,
+			namespace,
+			cj.Name,
+			podVersion)
+		objs[AppKindCronJob] = cj
 	default:
 		beego.Warn("cant support this application kind:", tp.GetAppKind())
 		return nil, fmt.Errorf("cant support this application kind: %s", tp.GetAppKind())
 	}
+	if err := injectConfigSets(cluster, namespace, tp.Config.ConfigSets, kubeObjectPodSpec(objs[tp.GetAppKind()])); err != nil {
+		return nil, err
+	}
 	svcList := []*apiv1.Service{}
 	for _, svc := range tp.Services {
 		if err := NewKubeSvcValidator(cluster, namespace, tp.GetAppName()).Validator(svc); err != nil {
@@ -183,10 +321,13 @@ func (tp *NativeAppTemplate) GenerateKubeObject(cluster, namespace, podVersion,
 		svc.ObjectMeta = tp.newObjectMeta(svc.ObjectMeta, svc.Labels, namespace, svc.Name)
 		svcList = append(svcList, svc)
 	}
+	if headlessSvc != nil {
+		svcList = append(svcList, headlessSvc)
+	}
 	if len(svcList) > 0 {
 		objs[ServiceKind] = svcList
 	}
-	ingList := []*extensions.Ingress{}
+	ingList := []*networkingv1.Ingress{}
 	tp.genDefaultIngressObjects(namespace, tp.GetAppName(), domainSuffix)
 	err := error(nil)
 	for _, ing := range tp.Ingresses {
@@ -201,6 +342,13 @@ func (tp *NativeAppTemplate) GenerateKubeObject(cluster, namespace, podVersion,
 	if len(svcList) > 0 {
 		objs[IngressKind] = ingList
 	}
+	if len(tp.Objects) > 0 {
+		objList := []*unstructured.Unstructured{}
+		for _, item := range tp.Objects {
+			objList = append(objList, tp.newUnstructuredObject(item, namespace))
+		}
+		objs[UnstructuredKind] = objList
+	}
 	return objs, err
 }
 
@@ -226,12 +374,11 @@ func (tp *NativeAppTemplate) String() (string, error) {
 }
 
 func (tp *NativeAppTemplate) Image(param []ContainerParam) AppTemplate {
+	podSpec := tp.podTemplate()
+	if podSpec == nil {
+		return tp
+	}
 	for _, item := range param {
-		podSpec := &apiv1.PodTemplateSpec{}
-		switch tp.GetAppKind() {
-		case AppKindDeployment:
-			podSpec = &tp.Deployment.Spec.Template
-		}
 		for index, ctn := range podSpec.Spec.Containers {
 			if item.Name == ctn.Name {
 				podSpec.Spec.Containers[index].Image = item.Image
@@ -247,6 +394,8 @@ func (tp *NativeAppTemplate) Replicas(replicas int) AppTemplate {
 	switch tp.GetAppKind() {
 	case AppKindDeployment:
 		tp.Deployment.Spec.Replicas = &num
+	case AppKindStatefulSet:
+		tp.StatefulSet.Spec.Replicas = &num
 	}
 	return tp
 }
@@ -268,11 +417,8 @@ func (tp *NativeAppTemplate) replaceImagePullAddr(cluster string) AppTemplate {
 	if initAddr == "" || pullAddr == "" {
 		return tp
 	}
-	podSpec := &apiv1.PodTemplateSpec{}
-	switch tp.GetAppKind() {
-	case AppKindDeployment:
-		podSpec = &tp.Deployment.Spec.Template
-	default:
+	podSpec := tp.podTemplate()
+	if podSpec == nil {
 		return tp
 	}
 	for i, c := range podSpec.Spec.InitContainers {
@@ -367,11 +513,129 @@ func (tp *NativeAppTemplate) getAppLabelSelector() string {
 }
 
 func (tp *NativeAppTemplate) getAppPodLabel() map[string]string {
-	return tp.Deployment.Spec.Template.Labels
+	podTemplate := tp.podTemplate()
+	if podTemplate == nil {
+		return nil
+	}
+	return podTemplate.Labels
+}
+
+// get the application object, the kind of it is given by template
+func (tp *NativeAppTemplate) appObject() runtime.Object {
+	switch tp.GetAppKind() {
+	case AppKindDeployment:
+		if tp.Deployment != nil {
+			return tp.Deployment
+		}
+	case AppKindStatefulSet:
+		if tp.StatefulSet != nil {
+			return tp.StatefulSet
+		}
+	case AppKindDaemonSet:
+		if tp.DaemonSet != nil {
+			return tp.DaemonSet
+		}
+	case AppKindJob:
+		if tp.Job != nil {
+			return tp.Job
+		}
+	case AppKindCronJob:
+		if tp.CronJob != nil {
+			return tp.CronJob
+		}
+	}
+	return nil
+}
+
+func (tp *NativeAppTemplate) podTemplate() *apiv1.PodTemplateSpec {
+	return getPodTemplateSpec(tp.appObject())
+}
+
+// daemonset and cronjob have no fixed replicas, and completions is used as replicas of job
+func (tp *NativeAppTemplate) getReplicas() int32 {
+	var replicas *int32
+	switch tp.GetAppKind() {
+	case AppKindDeployment:
+		replicas = tp.Deployment.Spec.Replicas
+	case AppKindStatefulSet:
+		replicas = tp.StatefulSet.Spec.Replicas
+	case AppKindJob:
+		replicas = tp.Job.Spec.Completions
+	default:
+		return 0
+	}
+	if replicas == nil {
+		return default_replicas
+	}
+	return *replicas
+}
+
+// generate the headless service which is governing the statefulset, if it is not given by template
+func (tp *NativeAppTemplate) genHeadlessService(namespace string, sts *appsv1.StatefulSet) *apiv1.Service {
+	for _, svc := range tp.Services {
+		if svc.Name == sts.Spec.ServiceName {
+			return nil
+		}
+	}
+	selector := make(map[string]string)
+	for k, v := range sts.Spec.Template.Labels {
+		if k != keyword.LABEL_PODVERSION_KEY {
+			selector[k] = v
+		}
+	}
+	svc := &apiv1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: SvcApiVersion,
+		},
+		Spec: apiv1.ServiceSpec{
+			ClusterIP: apiv1.ClusterIPNone,
+			Selector:  selector,
+			// the pods must be resolvable before they are ready for ordered rollout
+			PublishNotReadyAddresses: true,
+		},
+	}
+	svc.ObjectMeta = tp.newObjectMeta(metav1.ObjectMeta{}, sts.Spec.Template.Labels, namespace, sts.Spec.ServiceName)
+	for _, c := range sts.Spec.Template.Spec.Containers {
+		for _, port := range c.Ports {
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = apiv1.ProtocolTCP
+			}
+			name := port.Name
+			if name == "" {
+				name = fmt.Sprintf("%s-%d", strings.ToLower(string(protocol)), port.ContainerPort)
+			}
+			svc.Spec.Ports = append(svc.Spec.Ports, apiv1.ServicePort{
+				Name:       name,
+				Protocol:   protocol,
+				Port:       port.ContainerPort,
+				TargetPort: intstr.FromInt(int(port.ContainerPort)),
+			})
+		}
+	}
+	return svc
+}
+
+// get the pod template of application workload object
+func getPodTemplateSpec(obj interface{}) *apiv1.PodTemplateSpec {
+	switch app := obj.(type) {
+	case *appsv1.Deployment:
+		return &app.Spec.Template
+	case *appsv1.StatefulSet:
+		return &app.Spec.Template
+	case *appsv1.DaemonSet:
+		return &app.Spec.Template
+	case *batchv1.Job:
+		return &app.Spec.Template
+	case *batchv1.CronJob:
+		return &app.Spec.JobTemplate.Spec.Template
+	}
+	return nil
 }
 
 //generate ingress object
-func (tp *NativeAppTemplate) genDefaultIngressObjects(namespace, appname, domainSuffix string) []*extensions.Ingress {
+func (tp *NativeAppTemplate) genDefaultIngressObjects(namespace, appname, domainSuffix string) []*networkingv1.Ingress {
 	//var err error
 	if domainSuffix == "" {
 		return nil
@@ -379,7 +643,7 @@ func (tp *NativeAppTemplate) genDefaultIngressObjects(namespace, appname, domain
 	objectMeta := tp.newObjectMeta(metav1.ObjectMeta{}, nil, namespace, appname)
 	objectMeta.Name = GenIngressName(appname)
 	domainName := GenerateDomainName(appname)
-	var newIng *extensions.Ingress
+	var newIng *networkingv1.Ingress
 	for _, svc := range tp.Services {
 		defPort := tp.Config.DefaultPort
 		if len(svc.Spec.Ports) == 1 {
@@ -415,12 +679,12 @@ func (tp *NativeAppTemplate) genDefaultIngressObjects(namespace, appname, domain
 	return tp.Ingresses
 }
 
-func ingressRuleIsExisted(defIng *extensions.Ingress, ings []*extensions.Ingress) bool {
+func ingressRuleIsExisted(defIng *networkingv1.Ingress, ings []*networkingv1.Ingress) bool {
 	if defIng == nil {
 		return false
 	}
 	// check host
-	getSameHostRules := func() (*extensions.IngressRule, *extensions.IngressRule) {
+	getSameHostRules := func() (*networkingv1.IngressRule, *networkingv1.IngressRule) {
 		for _, rule := range defIng.Spec.Rules {
 			for _, ing := range ings {
 				for _, r := range ing.Spec.Rules {
@@ -459,7 +723,7 @@ func genAppName(virginName, suffix string) string {
 	return GenerateDeployName(virginName, suffix)
 }
 
-func GenDefaultIngressObject(svc *apiv1.Service, ingObjMeta metav1.ObjectMeta, defPort int32, domainName, domainSuffix string) *extensions.Ingress {
+func GenDefaultIngressObject(svc *apiv1.Service, ingObjMeta metav1.ObjectMeta, defPort int32, domainName, domainSuffix string) *networkingv1.Ingress {
 	if svc == nil {
 		return nil
 	}
@@ -470,28 +734,20 @@ func GenDefaultIngressObject(svc *apiv1.Service, ingObjMeta metav1.ObjectMeta, d
 	if len(svc.Spec.Ports) == 0 {
 		return nil
 	}
-	ingress := &extensions.Ingress{
+	ingress := &networkingv1.Ingress{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Ingress",
 			APIVersion: IngApiVersion,
 		},
 	}
-	var rules []extensions.IngressRule
+	var rules []networkingv1.IngressRule
 	for _, port := range svc.Spec.Ports {
 		dPort := port.Port
 		// set ingress rule
-		ruleValue := extensions.IngressRuleValue{
-			HTTP: &extensions.HTTPIngressRuleValue{
-				Paths: []extensions.HTTPIngressPath{
-					extensions.HTTPIngressPath{
-						Backend: extensions.IngressBackend{
-							ServiceName: svc.Name,
-							ServicePort: intstr.IntOrString{
-								Type:   intstr.Int,
-								IntVal: dPort,
-							},
-						},
-					},
+		ruleValue := networkingv1.IngressRuleValue{
+			HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{
+					kubeutil.NewIngressPath("", svc.Name, int(dPort)),
 				},
 			},
 		}
@@ -499,14 +755,14 @@ func GenDefaultIngressObject(svc *apiv1.Service, ingObjMeta metav1.ObjectMeta, d
 		if defPort != port.Port {
 			hostSuffix = strconv.Itoa(int(port.Port))
 		}
-		rules = append(rules, extensions.IngressRule{
+		rules = append(rules, networkingv1.IngressRule{
 			Host:             GenerateIngressHost(domainName, domainSuffix, hostSuffix),
 			IngressRuleValue: ruleValue,
 		})
 	}
 	ingress.ObjectMeta = ingObjMeta
 	//ingress.ObjectMeta.ResourceVersion = ingrv
-	var specRules []extensions.IngressRule
+	var specRules []networkingv1.IngressRule
 	// update rule
 	for _, rule := range rules {
 		ir := -1
@@ -546,7 +802,7 @@ func GenDefaultIngressObject(svc *apiv1.Service, ingObjMeta metav1.ObjectMeta, d
 			for _, path := range rule.HTTP.Paths {
 				found := false
 				for _, port := range svc.Spec.Ports {
-					if path.Backend.ServicePort.IntValue() == int(port.Port) {
+					if _, svcport := kubeutil.IngressBackendService(path.Backend); svcport == int(port.Port) {
 						found = true
 						break
 					}
@@ -561,7 +817,7 @@ func GenDefaultIngressObject(svc *apiv1.Service, ingObjMeta metav1.ObjectMeta, d
 		}
 	}
 	// delete tls host if host is not existed in rule
-	var specTLS []extensions.IngressTLS
+	var specTLS []networkingv1.IngressTLS
 	for _, item := range ingress.Spec.TLS {
 		var hosts []string
 		for _, host := range item.Hosts {
@@ -577,7 +833,7 @@ func GenDefaultIngressObject(svc *apiv1.Service, ingObjMeta metav1.ObjectMeta, d
 			}
 		}
 		if len(hosts) != 0 {
-			specTLS = append(specTLS, extensions.IngressTLS{
+			specTLS = append(specTLS, networkingv1.IngressTLS{
 				Hosts:      hosts,
 				SecretName: item.SecretName,
 			})
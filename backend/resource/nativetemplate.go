@@ -2,18 +2,20 @@ package resource
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
 
+	"kubecloud/backend/util/kubeutil"
 	"kubecloud/common"
 	"kubecloud/common/validate"
 
 	"github.com/astaxie/beego"
-	v1beta1 "k8s.io/api/apps/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
-	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -50,6 +52,11 @@ type DeployConfig struct {
 	DeployStrategy    string `json:"deploy_strategy"`
 	ImagePullSecret   string `json:"image_pull_secret"`
 	Description       string `json:"description"`
+	ApplyUnstructured bool   `json:"apply_unstructured"` //部署模板中的其它资源对象, 如PVC, RBAC, 自定义资源等
+	// the config sets of namespace which are injected to the containers as env vars or files
+	ConfigSets []string `json:"config_sets,omitempty"`
+	// the seconds to wait for the application to be ready if other applications depend on it
+	DependencyTimeout int `json:"dependency_timeout,omitempty"`
 }
 
 // native template and api
@@ -97,16 +104,16 @@ func (t *NativeTemplate) Validate() error {
 		podSpec := apiv1.PodSpec{}
 		replicas := int32(default_replicas)
 		switch strings.ToLower(kind) {
-		case AppKindDeployment:
-			deploy := &v1beta1.Deployment{}
-			err := json.Unmarshal(obj.RawData, &deploy)
+		case AppKindDeployment, AppKindStatefulSet, AppKindDaemonSet, AppKindJob, AppKindCronJob:
+			tpl, err := decodeNativeAppTemplate(strings.ToLower(kind), obj.RawData)
 			if err != nil {
 				return err
 			}
-			podSpec = deploy.Spec.Template.Spec
-			if deploy.Spec.Replicas != nil {
-				replicas = *deploy.Spec.Replicas
+			if err := validateAppObject(tpl); err != nil {
+				return err
 			}
+			podSpec = tpl.podTemplate().Spec
+			replicas = tpl.getReplicas()
 		case IngressKind, SecretKind, ConfigMapKind:
 			continue
 		case ServiceKind:
@@ -120,7 +127,13 @@ func (t *NativeTemplate) Validate() error {
 			svcList = append(svcList, svc)
 			continue
 		default:
-			return fmt.Errorf("the system does not support this resource kind:%s!", strings.ToLower(kind))
+			if !t.Config.ApplyUnstructured {
+				return fmt.Errorf("the system does not support this resource kind:%s!", strings.ToLower(kind))
+			}
+			if err := validateUnstructuredObject(obj); err != nil {
+				return err
+			}
+			continue
 		}
 		if podSpec.NodeName == "" {
 			if len(podSpec.NodeSelector) == 0 {
@@ -157,6 +170,18 @@ func (t *NativeTemplate) Validate() error {
 			}
 		}
 	}
+	// the dependencies of applications must be in the template and not circular
+	tplList, _, err := t.GenNativeAppTemplate("", INIT_APPNAME)
+	if err != nil {
+		return err
+	}
+	var appTplList []AppTemplate
+	for _, tpl := range tplList {
+		appTplList = append(appTplList, tpl)
+	}
+	if _, err := SortAppTemplates(appTplList); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -227,24 +252,19 @@ func (t *NativeTemplate) GenNativeAppTemplate(namespace, appname string) ([]*Nat
 		}
 		obj.Namespace = namespace
 		switch strings.ToLower(kind) {
-		case AppKindDeployment:
-			extend := t.Config
-			deploy := &v1beta1.Deployment{}
-			err := json.Unmarshal(obj.RawData, &deploy)
+		case AppKindDeployment, AppKindStatefulSet, AppKindDaemonSet, AppKindJob, AppKindCronJob:
+			tpl, err := decodeNativeAppTemplate(strings.ToLower(kind), obj.RawData)
 			if err != nil {
 				return nil, nil, err
 			}
-			appmeta := deploy.ObjectMeta
 			if appname != INIT_APPNAME {
-				appmeta.Name = appname
-			}
-			deploy.Namespace = namespace
-			tplList = append(tplList, &NativeAppTemplate{
-				TypeMeta:   deploy.TypeMeta,
-				ObjectMeta: appmeta,
-				Deployment: deploy,
-				Config:     extend,
-			})
+				tpl.Name = appname
+			}
+			if accessor, ok := tpl.appObject().(metav1.Object); ok {
+				accessor.SetNamespace(namespace)
+			}
+			tpl.Config = t.Config
+			tplList = append(tplList, tpl)
 		default:
 			noAppObjList = append(noAppObjList, *obj)
 		}
@@ -277,8 +297,7 @@ func (t *NativeTemplate) GenNativeAppTemplate(namespace, appname string) ([]*Nat
 		hasOwner := false
 		kind, _ := metaAccessor.Kind(obj.Object)
 		if strings.ToLower(kind) == IngressKind {
-			ing := &extensions.Ingress{}
-			err := json.Unmarshal(obj.RawData, ing)
+			ing, err := kubeutil.DecodeIngress(obj.RawData)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -293,6 +312,26 @@ func (t *NativeTemplate) GenNativeAppTemplate(namespace, appname string) ([]*Nat
 			otherObjList = append(otherObjList, obj)
 		}
 	}
+	if !t.Config.ApplyUnstructured {
+		return tplList, otherObjList, nil
+	}
+	// match the other resources, they are deployed and deleted with the application
+	noAppObjList = otherObjList
+	otherObjList = []ResObject{}
+	for _, obj := range noAppObjList {
+		kind, _ := metaAccessor.Kind(obj.Object)
+		u, ok := obj.Object.(*unstructured.Unstructured)
+		if !ok || isTypedResourceKind(strings.ToLower(kind)) {
+			otherObjList = append(otherObjList, obj)
+			continue
+		}
+		app := getObjectOwner(u, tplList)
+		if app == nil {
+			return nil, nil, fmt.Errorf("can not find the application of %s %s, please set the annotation %s!", kind, u.GetName(), OwnerNameAnnotationKey)
+		}
+		u.SetNamespace(namespace)
+		app.Objects = append(app.Objects, u)
+	}
 	return tplList, otherObjList, nil
 }
 
@@ -305,6 +344,11 @@ func (t *NativeTemplate) Deploy(projectid int64, cluster, namespace, tname strin
 	if err != nil {
 		return err
 	}
+	for _, tpl := range tplList {
+		if err := CheckAdmissionPolicies(cluster, namespace, tpl); err != nil {
+			return err
+		}
+	}
 	// create otherObjList
 	t.CreateNoAppResource(ar.Client, cluster, namespace, otherObjList)
 	var appTplList []AppTemplate
@@ -315,12 +359,15 @@ func (t *NativeTemplate) Deploy(projectid int64, cluster, namespace, tname strin
 }
 
 func (t *NativeTemplate) GetExample() []byte {
-	spec := "apiVersion: extensions/v1beta1\n" +
+	spec := "apiVersion: apps/v1\n" +
 		"kind: Deployment\n" +
 		"metadata:\n" +
 		"  name: helloworld\n" +
 		"  namespace: tech\n" +
 		"spec:\n" +
+		"  selector:\n" +
+		"    matchLabels:\n" +
+		"      app: helloworld\n" +
 		"  template:\n" +
 		"    metadata:\n" +
 		"      labels:\n" +
@@ -340,7 +387,8 @@ func (t *NativeTemplate) validateContainer(appname string, c apiv1.Container, ap
 	if c.Image == "" {
 		return fmt.Errorf("the container(%s) has no image!", c.Name)
 	}
-	if appkind == AppKindDeployment {
+	// long running application must limit its resource
+	if appkind == AppKindDeployment || appkind == AppKindStatefulSet || appkind == AppKindDaemonSet {
 		if c.Resources.Limits.Memory().Value() == 0 ||
 			c.Resources.Limits.Cpu().Value() == 0 {
 			return fmt.Errorf("the container has no CPU or memory limits!")
@@ -425,8 +473,7 @@ func (t *NativeTemplate) CreateNoAppResource(client kubernetes.Interface, cluste
 			svcList.serviceList = append(svcList.serviceList, svc)
 			resMap[ServiceKind] = svcList
 		case IngressKind:
-			ing := &extensions.Ingress{}
-			err := json.Unmarshal(obj.RawData, ing)
+			ing, err := kubeutil.DecodeIngress(obj.RawData)
 			if err != nil {
 				beego.Error(err)
 				continue
@@ -476,7 +523,7 @@ type kubeServices struct {
 
 type kubeIngesses struct {
 	kubeAppHandler *KubeAppRes
-	ingressList    []*extensions.Ingress
+	ingressList    []*networkingv1.Ingress
 }
 
 type configList []*apiv1.ConfigMap
@@ -493,7 +540,7 @@ func (svcs kubeServices) create(client kubernetes.Interface) error {
 func (confs configList) create(client kubernetes.Interface) error {
 	for _, config := range confs {
 		isExist := false
-		_, err := client.CoreV1().ConfigMaps(config.Namespace).Get(config.Name, metav1.GetOptions{})
+		_, err := client.CoreV1().ConfigMaps(config.Namespace).Get(context.TODO(), config.Name, metav1.GetOptions{})
 		if err == nil {
 			isExist = true
 		} else {
@@ -502,11 +549,11 @@ func (confs configList) create(client kubernetes.Interface) error {
 			}
 		}
 		if isExist {
-			if _, err := client.CoreV1().ConfigMaps(config.Namespace).Update(config); err != nil {
+			if _, err := client.CoreV1().ConfigMaps(config.Namespace).Update(context.TODO(), config, metav1.UpdateOptions{}); err != nil {
 				beego.Warn(fmt.Errorf("update configmap error: %v", err))
 			}
 		} else {
-			if _, err := client.CoreV1().ConfigMaps(config.Namespace).Create(config); err != nil {
+			if _, err := client.CoreV1().ConfigMaps(config.Namespace).Create(context.TODO(), config, metav1.CreateOptions{}); err != nil {
 				beego.Warn(fmt.Errorf("create configmap error: %v", err))
 			}
 		}
@@ -517,7 +564,7 @@ func (confs configList) create(client kubernetes.Interface) error {
 func (secrets secretList) create(client kubernetes.Interface) error {
 	for _, secret := range secrets {
 		isExist := false
-		oldsecret, err := client.CoreV1().Secrets(secret.Namespace).Get(secret.Name, metav1.GetOptions{})
+		oldsecret, err := client.CoreV1().Secrets(secret.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
 		if err == nil {
 			isExist = true
 			secret.ResourceVersion = oldsecret.ResourceVersion
@@ -527,11 +574,11 @@ func (secrets secretList) create(client kubernetes.Interface) error {
 			}
 		}
 		if isExist {
-			if _, err := client.CoreV1().Secrets(secret.Namespace).Update(secret); err != nil {
+			if _, err := client.CoreV1().Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
 				beego.Warn(fmt.Errorf("update configmap error: %v", err))
 			}
 		} else {
-			if _, err := client.CoreV1().Secrets(secret.Namespace).Create(secret); err != nil {
+			if _, err := client.CoreV1().Secrets(secret.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
 				beego.Warn(fmt.Errorf("create configmap error: %v", err))
 			}
 		}
@@ -563,7 +610,7 @@ func getServiceOwner(selector map[string]string, appTpls []*NativeAppTemplate) *
 	return nil
 }
 
-func getIngressOwner(ing *extensions.Ingress, apptpls []*NativeAppTemplate) *NativeAppTemplate {
+func getIngressOwner(ing *networkingv1.Ingress, apptpls []*NativeAppTemplate) *NativeAppTemplate {
 	svcNames := getIngressService(ing)
 	if len(svcNames) != 1 {
 		return nil
@@ -583,21 +630,23 @@ func getIngressOwner(ing *extensions.Ingress, apptpls []*NativeAppTemplate) *Nat
 	return nil
 }
 
-func getIngressService(ing *extensions.Ingress) []string {
+func getIngressService(ing *networkingv1.Ingress) []string {
 	names := []string{}
 	if ing == nil {
 		return names
 	}
 	label := make(map[string]interface{})
-	if ing.Spec.Backend != nil {
-		label[ing.Spec.Backend.ServiceName] = nil
+	if ing.Spec.DefaultBackend != nil && ing.Spec.DefaultBackend.Service != nil {
+		label[ing.Spec.DefaultBackend.Service.Name] = nil
 	}
 	for _, rule := range ing.Spec.Rules {
 		if rule.HTTP == nil {
 			continue
 		}
 		for _, path := range rule.HTTP.Paths {
-			label[path.Backend.ServiceName] = nil
+			if path.Backend.Service != nil {
+				label[path.Backend.Service.Name] = nil
+			}
 		}
 	}
 	for name, _ := range label {
@@ -627,9 +676,55 @@ func validateService(svc *apiv1.Service) error {
 	return nil
 }
 
+// validate the special spec of application kinds
+func validateAppObject(tpl *NativeAppTemplate) error {
+	switch tpl.GetAppKind() {
+	case AppKindStatefulSet:
+		for _, claim := range tpl.StatefulSet.Spec.VolumeClaimTemplates {
+			if strings.TrimSpace(claim.Name) == "" {
+				return fmt.Errorf("the name of volume claim template can not be empty!")
+			}
+		}
+	case AppKindJob:
+		if err := validateJobSpec(tpl.Job.Spec); err != nil {
+			return err
+		}
+	case AppKindCronJob:
+		if strings.TrimSpace(tpl.CronJob.Spec.Schedule) == "" {
+			return fmt.Errorf("the schedule of cronjob must be set!")
+		}
+		if err := validateJobSpec(tpl.CronJob.Spec.JobTemplate.Spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateJobSpec(spec batchv1.JobSpec) error {
+	policy := spec.Template.Spec.RestartPolicy
+	if policy != apiv1.RestartPolicyNever && policy != apiv1.RestartPolicyOnFailure {
+		return fmt.Errorf("the restart policy of job must be Never or OnFailure!")
+	}
+	if spec.Completions != nil && *spec.Completions < 0 {
+		return fmt.Errorf("the completions of job must be equal or above 0!")
+	}
+	if spec.Parallelism != nil && *spec.Parallelism < 0 {
+		return fmt.Errorf("the parallelism of job must be equal or above 0!")
+	}
+	return nil
+}
+
 func validateDeployConfig(config DeployConfig) error {
 	if err := validate.ValidateAppVersion(config.Version); err != nil {
 		return err
 	}
+	for _, name := range config.ConfigSets {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("the name of config set can not be empty!")
+		}
+	}
+	if config.DependencyTimeout < 0 {
+		return fmt.Errorf("the dependency timeout must be equal or above 0!")
+	}
 	return nil
 }
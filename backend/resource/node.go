@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -226,14 +227,14 @@ func UpdateNode(cluster, name string, nodeUpdate NodeUpdate) error {
 		if err != nil {
 			return fmt.Errorf("get client error: %v", err)
 		}
-		node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+		node, err := client.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
 		if err != nil {
 			return err
 		}
 		for key, value := range nodeUpdate.Labels {
 			node.ObjectMeta.Labels[key] = value
 		}
-		if _, err := client.CoreV1().Nodes().Update(node); err != nil {
+		if _, err := client.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{}); err != nil {
 			return fmt.Errorf("update node in k8s has error: %v", err)
 		}
 		item.Labels = string(labels)
@@ -261,14 +262,14 @@ func FreezeNode(cluster, name string, deletePods bool) error {
 	}
 
 	// set node to unschedulable
-	node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	node, err := client.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 	if !node.Spec.Unschedulable {
 		node.Spec.Unschedulable = true
 
-		if _, err := client.CoreV1().Nodes().Update(node); err != nil {
+		if _, err := client.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{}); err != nil {
 			return fmt.Errorf("update node in k8s has error: %v", err)
 		}
 	}
@@ -282,7 +283,7 @@ func FreezeNode(cluster, name string, deletePods bool) error {
 			return err
 		}
 
-		podList, err := client.CoreV1().Pods(corev1.NamespaceAll).List(metav1.ListOptions{
+		podList, err := client.CoreV1().Pods(corev1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
 			FieldSelector: fieldSelector.String(),
 		})
 		if err != nil {
@@ -293,7 +294,7 @@ func FreezeNode(cluster, name string, deletePods bool) error {
 			if pod.Namespace == "kube-system" || pod.Namespace == "kube-public" || pod.Namespace == "istio-system" {
 				continue
 			}
-			if err := client.CoreV1().Pods(pod.Namespace).Delete(pod.Name, &metav1.DeleteOptions{}); err != nil {
+			if err := client.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); err != nil {
 				return err
 			}
 		}
@@ -318,14 +319,14 @@ func UnfreezeNode(cluster, name string) error {
 	if err != nil {
 		return fmt.Errorf("get client error: %v", err)
 	}
-	node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	node, err := client.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 	if node.Spec.Unschedulable {
 		node.Spec.Unschedulable = false
 
-		if _, err := client.CoreV1().Nodes().Update(node); err != nil {
+		if _, err := client.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{}); err != nil {
 			return fmt.Errorf("update node in k8s has error: %v", err)
 		}
 	}
@@ -348,7 +349,7 @@ func DeleteNode(cluster, name string) error {
 	if err != nil {
 		return fmt.Errorf("get client error: %v", err.Error())
 	}
-	if err := client.CoreV1().Nodes().Delete(name, &metav1.DeleteOptions{}); err != nil {
+	if err := client.CoreV1().Nodes().Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
 		beego.Error(fmt.Sprintf("Delete node error: %v", err.Error()))
 		return err
 	}
@@ -570,7 +571,7 @@ func GetNodeDetail(cluster, nodeName string) (*NodeDetail, error) {
 	if err != nil {
 		return nil, fmt.Errorf("get client error %v", err)
 	}
-	k8sNode, err := client.CoreV1().Nodes().Get(item.Name, metav1.GetOptions{})
+	k8sNode, err := client.CoreV1().Nodes().Get(context.TODO(), item.Name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -659,7 +660,7 @@ func GetNodeUsedResource(cluster, node string) (*NodeUsedResource, error) {
 		return nil, err
 	}
 
-	podList, err := client.CoreV1().Pods(corev1.NamespaceAll).List(metav1.ListOptions{
+	podList, err := client.CoreV1().Pods(corev1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
 		FieldSelector: fieldSelector.String(),
 	})
 	if err != nil {
@@ -776,7 +777,7 @@ func GetSimpleNodes(cluster string) ([]corev1.Node, error) {
 		return nil, fmt.Errorf("get client error %v", err)
 	}
 
-	nodeList, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	nodeList, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -837,7 +838,7 @@ func CleanNodes() error {
 		}
 		nodes := res.List.([]*models.ZcloudNode)
 		for _, inode := range nodes {
-			if _, err := client.CoreV1().Nodes().Get(inode.Name, metav1.GetOptions{}); err != nil {
+			if _, err := client.CoreV1().Nodes().Get(context.TODO(), inode.Name, metav1.GetOptions{}); err != nil {
 				dao.DeleteNode(icluster.Name, inode.Name)
 			}
 		}
@@ -1,10 +1,10 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/astaxie/beego"
-	v1beta1 "k8s.io/api/apps/v1beta1"
 	apiv1 "k8s.io/api/core/v1"
 	errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,12 +34,12 @@ func (validator *KubeAppValidator) Validator(obj interface{}) error {
 	if obj == nil {
 		return nil
 	}
-	if dp, ok := obj.(*v1beta1.Deployment); ok {
-		volumes = dp.Spec.Template.Spec.Volumes
+	if podTemplate := getPodTemplateSpec(obj); podTemplate != nil {
+		volumes = podTemplate.Spec.Volumes
 	}
 	for _, vol := range volumes {
 		if vol.PersistentVolumeClaim != nil {
-			_, err := validator.client.CoreV1().PersistentVolumeClaims(validator.namespace).Get(vol.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
+			_, err := validator.client.CoreV1().PersistentVolumeClaims(validator.namespace).Get(context.TODO(), vol.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
 			if errors.IsNotFound(err) {
 				return fmt.Errorf("PVC(%s) is not existed in namespace %s!", vol.PersistentVolumeClaim.ClaimName, validator.namespace)
 			}
@@ -49,7 +49,7 @@ func (validator *KubeAppValidator) Validator(obj interface{}) error {
 			}
 		}
 		if vol.ConfigMap != nil {
-			_, err := validator.client.CoreV1().ConfigMaps(validator.namespace).Get(vol.ConfigMap.Name, metav1.GetOptions{})
+			_, err := validator.client.CoreV1().ConfigMaps(validator.namespace).Get(context.TODO(), vol.ConfigMap.Name, metav1.GetOptions{})
 			if errors.IsNotFound(err) {
 				return fmt.Errorf("Configmap:%s is not existed in namespace %s!", vol.ConfigMap.Name, validator.namespace)
 			}
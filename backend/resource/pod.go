@@ -1,13 +1,14 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"kubecloud/backend/service"
 
 	"github.com/astaxie/beego"
 
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
-	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/kubernetes"
@@ -118,7 +119,7 @@ func GetPods(cluster, namespace, labelSelector string, replicas int) ([]*Pod, er
 		beego.Error(fmt.Sprintf("Get %v pods on namespace %v in cluster %v Error: %v", labelSelector, namespace, cluster, err.Error()))
 		return nil, err
 	}
-	k8sPods, err := client.CoreV1().Pods(namespace).List(metav1.ListOptions{
+	k8sPods, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
 		LabelSelector: labelSelector,
 	})
 	if err != nil {
@@ -161,7 +162,7 @@ func GetPods(cluster, namespace, labelSelector string, replicas int) ([]*Pod, er
 			beego.Warn(fmt.Sprintf("%v trash or evicted pods about %v will be deleted!", len(delPods), selector))
 			for _, pod := range pods {
 				gracePeriod := int64(0)
-				client.CoreV1().Pods(namespace).Delete(pod.Name, &metav1.DeleteOptions{
+				client.CoreV1().Pods(namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{
 					GracePeriodSeconds: &gracePeriod,
 				})
 			}
@@ -182,7 +183,7 @@ func GetPodsByNode(cluster, node string) ([]*Pod, error) {
 	if err != nil {
 		return nil, err
 	}
-	k8sPods, err := client.CoreV1().Pods(v1.NamespaceAll).List(metav1.ListOptions{
+	k8sPods, err := client.CoreV1().Pods(v1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
 		FieldSelector: fieldSelector.String(),
 	})
 	if err != nil {
@@ -202,8 +203,14 @@ func isTrashPod(client kubernetes.Interface, cluster string, pod v1.Pod) bool {
 		return true
 	}
 	switch ref.Kind {
-	case extensions.SchemeGroupVersion.WithKind("ReplicaSet").Kind:
+	case appsv1.SchemeGroupVersion.WithKind("ReplicaSet").Kind:
 		return NewDeploymentRes(client, cluster, pod.Namespace).GetOwnerForPod(pod, ref) == nil
+	case "StatefulSet":
+		return NewStatefulSetRes(client, cluster, pod.Namespace).GetOwnerForPod(pod, ref) == nil
+	case "DaemonSet":
+		return NewDaemonSetRes(client, cluster, pod.Namespace).GetOwnerForPod(pod, ref) == nil
+	case "Job":
+		return NewJobRes(client, cluster, pod.Namespace).GetOwnerForPod(pod, ref) == nil
 	}
 	return false
 }
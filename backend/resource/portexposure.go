@@ -0,0 +1,529 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/backend/service"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	PortExposureTypeNodePort     = "NodePort"
+	PortExposureTypeLoadBalancer = "LoadBalancer"
+
+	// PortExposureLabelKey is set on the objects serving the port exposures, its value is the id of exposure
+	PortExposureLabelKey = "kubecloud/port-exposure"
+
+	// the node port range of kubernetes is used if the node port pool of cluster is not set
+	defaultNodePortPool = "30000-32767"
+	// ingress-nginx reads the tcp and udp services from the configmaps in its namespace
+	nginxTCPServicesConfigMap = "tcp-services"
+	nginxUDPServicesConfigMap = "udp-services"
+)
+
+var (
+	// the entry point of the port exposed by traefik 2.x must be defined as tcp-{port} or udp-{port}
+	traefikIngressRouteTCPKind = schema.GroupVersionKind{Group: "traefik.containo.us", Version: "v1alpha1", Kind: "IngressRouteTCP"}
+	traefikIngressRouteUDPKind = schema.GroupVersionKind{Group: "traefik.containo.us", Version: "v1alpha1", Kind: "IngressRouteUDP"}
+
+	// the ports are allocated one by one, so that an unused port is not allocated twice
+	portExposureLocker sync.Mutex
+)
+
+type PortExposureParam struct {
+	AppName     string `json:"app_name"`
+	ServicePort int    `json:"service_port"`
+	// Protocol is TCP or UDP, it is TCP if not given
+	Protocol string `json:"protocol"`
+	Type     string `json:"type"`
+	// Port is the port to be allocated, a free port of the pool is allocated if it is 0
+	Port        int    `json:"port"`
+	Description string `json:"description"`
+}
+
+type externalAddress struct {
+	Port         int    `json:"port"`
+	Protocol     string `json:"protocol"`
+	Type         string `json:"type"`
+	ExternalAddr string `json:"external_addr"`
+}
+
+func (param *PortExposureParam) Validate() error {
+	if param.AppName == "" {
+		return fmt.Errorf("application name must be given!")
+	}
+	param.Protocol = strings.ToUpper(param.Protocol)
+	if param.Protocol == "" {
+		param.Protocol = string(apiv1.ProtocolTCP)
+	}
+	if param.Protocol != string(apiv1.ProtocolTCP) && param.Protocol != string(apiv1.ProtocolUDP) {
+		return fmt.Errorf("protocol must be %s or %s!", apiv1.ProtocolTCP, apiv1.ProtocolUDP)
+	}
+	if param.Type != PortExposureTypeNodePort && param.Type != PortExposureTypeLoadBalancer {
+		return fmt.Errorf("type must be %s or %s!", PortExposureTypeNodePort, PortExposureTypeLoadBalancer)
+	}
+	if param.Port < 0 || param.Port > 65535 {
+		return fmt.Errorf("port must be in the range of 1 to 65535!")
+	}
+	return nil
+}
+
+func PortExposureList(cluster, namespace, appname string) ([]models.ZcloudPortExposure, error) {
+	list, err := dao.NewPortExposureModel().ListExposures(cluster, namespace, appname)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return list, nil
+}
+
+// PortExposureCreate allocates the port from the pool of the type, and exposes the service port of application by it
+func PortExposureCreate(cluster, namespace string, param PortExposureParam) (*models.ZcloudPortExposure, error) {
+	if err := param.Validate(); err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	svc, err := dao.NewK8sServiceModel().Get(cluster, namespace, param.AppName, "")
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewBadRequest().SetCause(fmt.Errorf("the service of application %s is not existed!", param.AppName))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	found := false
+	for _, port := range svc.Ports {
+		found = found || (port.Port == param.ServicePort && port.Protocol == param.Protocol)
+	}
+	if !found {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("the service of application %s has no %s port %d!",
+			param.AppName, param.Protocol, param.ServicePort))
+	}
+	item, err := dao.GetCluster(cluster)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if param.Type == PortExposureTypeLoadBalancer {
+		if err := validateLoadBalancerExposure(item); err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+	}
+
+	portExposureLocker.Lock()
+	defer portExposureLocker.Unlock()
+	model := dao.NewPortExposureModel()
+	exposures, err := model.ListExposures(cluster, namespace, param.AppName)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	for _, exposure := range exposures {
+		if exposure.ServicePort == param.ServicePort && exposure.Protocol == param.Protocol && exposure.Type == param.Type {
+			return nil, common.NewConflict().SetCause(fmt.Errorf("the %s port %d of application %s has been exposed by %s port %d!",
+				param.Protocol, param.ServicePort, param.AppName, exposure.Type, exposure.Port))
+		}
+	}
+	port, err := allocateExposurePort(item, param)
+	if err != nil {
+		return nil, err
+	}
+	exposure := &models.ZcloudPortExposure{
+		Cluster:     cluster,
+		Namespace:   namespace,
+		AppName:     param.AppName,
+		ServiceName: svc.Name,
+		ServicePort: param.ServicePort,
+		Protocol:    param.Protocol,
+		Type:        param.Type,
+		Port:        port,
+		PoolOwner:   portExposurePoolOwner(item, param.Type),
+		Description: param.Description,
+	}
+	if name := portExposureObjectName(exposure); len(name) > validation.DNS1035LabelMaxLength {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("the name %s of the object of exposure is longer than %d characters!",
+			name, validation.DNS1035LabelMaxLength))
+	}
+	if err := model.CreateExposure(exposure); err != nil {
+		// the port may be allocated by another kubecloud replica at the same time
+		if dao.IsDuplicateError(err) {
+			return nil, common.NewConflict().SetCause(fmt.Errorf("the %s port %d is used!", param.Type, port))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if err := applyPortExposure(item, exposure); err != nil {
+		if err := model.DeleteExposure(exposure.Id); err != nil {
+			beego.Warn(fmt.Sprintf("release the port %d of exposure %v failed: %v", exposure.Port, exposure.Id, err))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return exposure, nil
+}
+
+// PortExposureDelete deletes the objects serving the exposure, and releases its port
+func PortExposureDelete(cluster, namespace string, id int64) error {
+	model := dao.NewPortExposureModel()
+	exposure, err := model.GetExposure(cluster, namespace, id)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil
+		}
+		return common.NewInternalServerError().SetCause(err)
+	}
+	item, err := dao.GetCluster(cluster)
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err := deletePortExposure(item, exposure); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if err := model.DeleteExposure(exposure.Id); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return nil
+}
+
+// ReleasePortExposures deletes the exposures of the application
+func ReleasePortExposures(cluster, namespace, appname string) error {
+	exposures, err := dao.NewPortExposureModel().ListExposures(cluster, namespace, appname)
+	if err != nil {
+		return err
+	}
+	for _, exposure := range exposures {
+		if err := PortExposureDelete(cluster, namespace, exposure.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateLoadBalancerExposure(cluster *models.ZcloudCluster) error {
+	if cluster.LoadbalancerPortPool == "" {
+		return fmt.Errorf("the load balancer port pool of cluster %s is not set!", cluster.ClusterId)
+	}
+	switch cluster.IngressProvider {
+	case IngressProviderNginx, IngressProviderTraefikV2:
+		return nil
+	}
+	return fmt.Errorf("the ingress provider of cluster %s can not expose tcp/udp ports, only %s and %s are supported!",
+		cluster.ClusterId, IngressProviderNginx, IngressProviderTraefikV2)
+}
+
+// allocateExposurePort returns the given port if it is in the pool and not used, or the first free port of the pool.
+// The node ports must be unique in the cluster, and the ports of load balancer must be unique for each protocol
+// among the clusters sharing the load balancer
+func allocateExposurePort(cluster *models.ZcloudCluster, param PortExposureParam) (int, error) {
+	used := make(map[int]bool)
+	spec := cluster.NodePortPool
+	if param.Type == PortExposureTypeNodePort {
+		if spec == "" {
+			spec = defaultNodePortPool
+		}
+		exposures, err := dao.NewPortExposureModel().ListExposuresByType(cluster.ClusterId, param.Type)
+		if err != nil {
+			return 0, common.NewInternalServerError().SetCause(err)
+		}
+		for _, exposure := range exposures {
+			used[exposure.Port] = true
+		}
+		ports, err := dao.NewK8sServiceModel().List(cluster.ClusterId, common.AllNamespace, 0)
+		if err != nil {
+			return 0, common.NewInternalServerError().SetCause(err)
+		}
+		for _, port := range ports {
+			if port.NodePort != 0 {
+				used[port.NodePort] = true
+			}
+		}
+	} else {
+		spec = cluster.LoadbalancerPortPool
+		exposures, err := dao.NewPortExposureModel().ListExposuresByType("", param.Type)
+		if err != nil {
+			return 0, common.NewInternalServerError().SetCause(err)
+		}
+		lb := IngressLoadBalancer(cluster)
+		lbs := map[string]string{cluster.ClusterId: lb}
+		for _, exposure := range exposures {
+			if _, ok := lbs[exposure.Cluster]; !ok {
+				item, err := dao.GetCluster(exposure.Cluster)
+				if err != nil {
+					beego.Warn(fmt.Sprintf("get the cluster %s of port exposure %v failed: %v", exposure.Cluster, exposure.Id, err))
+					continue
+				}
+				lbs[exposure.Cluster] = IngressLoadBalancer(item)
+			}
+			if lbs[exposure.Cluster] == lb && exposure.Protocol == param.Protocol {
+				used[exposure.Port] = true
+			}
+		}
+	}
+	pool, err := utils.ParsePortPool(spec)
+	if err != nil {
+		return 0, common.NewInternalServerError().SetCause(fmt.Errorf("the %s port pool of cluster %s is not right: %v", param.Type, cluster.ClusterId, err))
+	}
+	if param.Port != 0 {
+		if !pool.Contains(param.Port) {
+			return 0, common.NewBadRequest().SetCause(fmt.Errorf("the port %d is not in the %s port pool %s of cluster %s!",
+				param.Port, param.Type, spec, cluster.ClusterId))
+		}
+		if used[param.Port] {
+			return 0, common.NewConflict().SetCause(fmt.Errorf("the %s port %d is used!", param.Type, param.Port))
+		}
+		return param.Port, nil
+	}
+	port := pool.Allocate(used)
+	if port == 0 {
+		return 0, common.NewConflict().SetCause(fmt.Errorf("all ports of the %s port pool %s of cluster %s are used!",
+			param.Type, spec, cluster.ClusterId))
+	}
+	return port, nil
+}
+
+// portExposurePoolOwner returns the owner of the port pool of the type, which the exposed port is unique in
+func portExposurePoolOwner(cluster *models.ZcloudCluster, exposureType string) string {
+	if exposureType == PortExposureTypeNodePort {
+		return "cluster:" + cluster.ClusterId
+	}
+	return IngressLoadBalancer(cluster)
+}
+
+// portExposureObjectName returns the name of the service or ingress route serving the exposure
+func portExposureObjectName(exposure *models.ZcloudPortExposure) string {
+	return providerObjectName(exposure.ServiceName, exposure.Protocol, strconv.Itoa(exposure.Port))
+}
+
+func portExposureLabels(exposure *models.ZcloudPortExposure) map[string]string {
+	return map[string]string{PortExposureLabelKey: strconv.FormatInt(exposure.Id, 10)}
+}
+
+// applyPortExposure creates the NodePort service of the exposure, or the ingress controller config of the port
+// of load balancer, which forwards the connections to the service port
+func applyPortExposure(cluster *models.ZcloudCluster, exposure *models.ZcloudPortExposure) error {
+	client, err := service.GetClientset(cluster.ClusterId)
+	if err != nil {
+		return err
+	}
+	if exposure.Type == PortExposureTypeNodePort {
+		return applyNodePortService(client, exposure)
+	}
+	switch cluster.IngressProvider {
+	case IngressProviderNginx:
+		return setNginxStreamService(client, exposure, true)
+	case IngressProviderTraefikV2:
+		return applyTraefikStreamRoute(cluster.ClusterId, client, exposure)
+	}
+	return validateLoadBalancerExposure(cluster)
+}
+
+func deletePortExposure(cluster *models.ZcloudCluster, exposure *models.ZcloudPortExposure) error {
+	client, err := service.GetClientset(cluster.ClusterId)
+	if err != nil {
+		return err
+	}
+	name := portExposureObjectName(exposure)
+	if exposure.Type == PortExposureTypeNodePort {
+		err = client.CoreV1().Services(exposure.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	} else if cluster.IngressProvider == IngressProviderNginx {
+		err = setNginxStreamService(client, exposure, false)
+	} else {
+		err = deleteTraefikStreamRoute(cluster.ClusterId, client, exposure)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("delete the objects of port exposure %v failed: %v", exposure.Id, err)
+	}
+	return nil
+}
+
+func applyNodePortService(client kubernetes.Interface, exposure *models.ZcloudPortExposure) error {
+	backend, err := client.CoreV1().Services(exposure.Namespace).Get(context.TODO(), exposure.ServiceName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	svc := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      portExposureObjectName(exposure),
+			Namespace: exposure.Namespace,
+			Labels:    portExposureLabels(exposure),
+		},
+		Spec: apiv1.ServiceSpec{
+			Type:     apiv1.ServiceTypeNodePort,
+			Selector: backend.Spec.Selector,
+		},
+	}
+	for _, port := range backend.Spec.Ports {
+		if int(port.Port) == exposure.ServicePort && string(port.Protocol) == exposure.Protocol {
+			port.NodePort = int32(exposure.Port)
+			svc.Spec.Ports = append(svc.Spec.Ports, port)
+		}
+	}
+	if len(svc.Spec.Ports) == 0 {
+		return fmt.Errorf("the service %s has no %s port %d", exposure.ServiceName, exposure.Protocol, exposure.ServicePort)
+	}
+	old, err := client.CoreV1().Services(exposure.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = client.CoreV1().Services(exposure.Namespace).Create(context.TODO(), svc, metav1.CreateOptions{})
+	} else {
+		svc.ResourceVersion = old.ResourceVersion
+		svc.Spec.ClusterIP = old.Spec.ClusterIP
+		svc.Spec.ClusterIPs = old.Spec.ClusterIPs
+		_, err = client.CoreV1().Services(exposure.Namespace).Update(context.TODO(), svc, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("apply node port service %s failed: %v", svc.Name, err)
+	}
+	return nil
+}
+
+// setNginxStreamService sets the service port of the exposure to the tcp or udp services configmap of
+// ingress-nginx, it is deleted from the configmap if set is false
+func setNginxStreamService(client kubernetes.Interface, exposure *models.ZcloudPortExposure, set bool) error {
+	namespace := beego.AppConfig.DefaultString("nginx::controllerNamespace", "ingress-nginx")
+	name := nginxTCPServicesConfigMap
+	if exposure.Protocol == string(apiv1.ProtocolUDP) {
+		name = nginxUDPServicesConfigMap
+	}
+	key := strconv.Itoa(exposure.Port)
+	value := fmt.Sprintf("%s/%s:%d", exposure.Namespace, exposure.ServiceName, exposure.ServicePort)
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) || !set {
+			return err
+		}
+		cm = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{key: value},
+		}
+		_, err = client.CoreV1().ConfigMaps(namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	if set {
+		if cm.Data[key] == value {
+			return nil
+		}
+		cm.Data[key] = value
+	} else {
+		// the port may be set by others if it is not the service of exposure
+		if cm.Data[key] != value {
+			return nil
+		}
+		delete(cm.Data, key)
+	}
+	_, err = client.CoreV1().ConfigMaps(namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return err
+}
+
+// applyTraefikStreamRoute creates the IngressRouteTCP or IngressRouteUDP of the entry point of exposure port
+func applyTraefikStreamRoute(cluster string, client kubernetes.Interface, exposure *models.ZcloudPortExposure) error {
+	gvk := traefikIngressRouteTCPKind
+	route := map[string]interface{}{
+		"match": "HostSNI(`*`)",
+		"services": []interface{}{
+			map[string]interface{}{"name": exposure.ServiceName, "port": int64(exposure.ServicePort)},
+		},
+	}
+	if exposure.Protocol == string(apiv1.ProtocolUDP) {
+		gvk = traefikIngressRouteUDPKind
+		delete(route, "match")
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(exposure.Namespace)
+	obj.SetName(portExposureObjectName(exposure))
+	obj.SetLabels(portExposureLabels(exposure))
+	obj.Object["spec"] = map[string]interface{}{
+		"entryPoints": []interface{}{strings.ToLower(exposure.Protocol) + "-" + strconv.Itoa(exposure.Port)},
+		"routes":      []interface{}{route},
+	}
+	mapping, err := kubeutil.GetRESTMapping(cluster, client.Discovery(), gvk)
+	if err != nil {
+		return fmt.Errorf("%s of the ingress provider %s is not installed: %v", gvk.Kind, IngressProviderTraefikV2, err)
+	}
+	dynamicClient, err := service.GetDynamicClient(cluster)
+	if err != nil {
+		return err
+	}
+	res := dynamicClient.Resource(mapping.Resource).Namespace(exposure.Namespace)
+	old, err := res.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+	if err == nil {
+		obj.SetResourceVersion(old.GetResourceVersion())
+		_, err = res.Update(context.TODO(), obj, metav1.UpdateOptions{})
+	} else if errors.IsNotFound(err) {
+		_, err = res.Create(context.TODO(), obj, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("apply %s %s failed: %v", gvk.Kind, obj.GetName(), err)
+	}
+	return nil
+}
+
+// deleteTraefikStreamRoute deletes the IngressRouteTCP or IngressRouteUDP of the exposure, it is not deleted
+// if its kind is not installed
+func deleteTraefikStreamRoute(cluster string, client kubernetes.Interface, exposure *models.ZcloudPortExposure) error {
+	gvk := traefikIngressRouteTCPKind
+	if exposure.Protocol == string(apiv1.ProtocolUDP) {
+		gvk = traefikIngressRouteUDPKind
+	}
+	mapping, err := kubeutil.GetRESTMapping(cluster, client.Discovery(), gvk)
+	if err != nil {
+		beego.Warn(fmt.Sprintf("%s of the ingress provider %s is not installed: %v", gvk.Kind, IngressProviderTraefikV2, err))
+		return nil
+	}
+	dynamicClient, err := service.GetDynamicClient(cluster)
+	if err != nil {
+		return err
+	}
+	return dynamicClient.Resource(mapping.Resource).Namespace(exposure.Namespace).Delete(context.TODO(), portExposureObjectName(exposure), metav1.DeleteOptions{})
+}
+
+// getExternalAddresses returns the addresses of the exposures of application, the node ports are accessed by nodeip
+func getExternalAddresses(cluster, namespace, appname, nodeip string) ([]externalAddress, error) {
+	exposures, err := dao.NewPortExposureModel().ListExposures(cluster, namespace, appname)
+	if err != nil || len(exposures) == 0 {
+		return nil, err
+	}
+	item, err := dao.GetCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	lbHost := item.LoadbalancerIP
+	if lbHost == "" {
+		lbHost = item.LoadbalancerDomainName
+	}
+	addrs := []externalAddress{}
+	for _, exposure := range exposures {
+		addr := externalAddress{
+			Port:         exposure.ServicePort,
+			Protocol:     exposure.Protocol,
+			Type:         exposure.Type,
+			ExternalAddr: "<none>",
+		}
+		host := nodeip
+		if exposure.Type == PortExposureTypeLoadBalancer {
+			host = lbHost
+		}
+		if host != "" {
+			addr.ExternalAddr = fmt.Sprintf("%s:%v", host, exposure.Port)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,408 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/common"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	"github.com/astaxie/beego/toolbox"
+)
+
+const (
+	scaleScheduleComponent = "scale-scheduler"
+	// the triggers missed for longer than it are ignored, such as kubecloud is stopped for days
+	scaleScheduleMaxLookback = 24 * time.Hour
+
+	scaleScheduleReasonScaled  = "ScheduledScaleDown"
+	scaleScheduleReasonSkipped = "ScheduledScaleSkipped"
+)
+
+type ScaleScheduleParam struct {
+	// application name, all applications of the namespace are scaled if it is empty
+	Name            string `json:"name"`
+	ScaleSchedule   string `json:"scale_schedule"`
+	Replicas        int    `json:"replicas"`
+	RestoreSchedule string `json:"restore_schedule"`
+	Description     string `json:"description"`
+}
+
+type ScaleScheduleDetail struct {
+	models.ZcloudScaleSchedule
+	NextScaleTime   string `json:"next_scale_time"`
+	NextRestoreTime string `json:"next_restore_time"`
+}
+
+// ParseCronSchedule parses the standard cron spec of 5 fields: minute, hour, day of month, month and day of week
+func ParseCronSchedule(spec string) (schedule *toolbox.Schedule, err error) {
+	if len(strings.Fields(spec)) != 5 {
+		return nil, fmt.Errorf("cron spec %q must have 5 fields: minute, hour, day of month, month and day of week!", spec)
+	}
+	// the parser of toolbox panics if the spec is invalid
+	defer func() {
+		if r := recover(); r != nil {
+			schedule = nil
+			err = fmt.Errorf("cron spec %q is invalid: %v!", spec, r)
+		}
+	}()
+	return toolbox.NewTask("", "0 "+spec, nil).Spec, nil
+}
+
+// LastCronTrigger returns the latest trigger time of the schedule in (from, to], it is zero if not triggered
+func LastCronTrigger(schedule *toolbox.Schedule, from, to time.Time) time.Time {
+	last := time.Time{}
+	for t := schedule.Next(from); !t.IsZero() && !t.After(to); t = schedule.Next(t) {
+		last = t
+	}
+	return last
+}
+
+func (param ScaleScheduleParam) Validate() error {
+	if param.Replicas < common.ReplicasMin || param.Replicas > common.ReplicasMax {
+		return fmt.Errorf("replicas must be in the range of %v to %v!", common.ReplicasMin, common.ReplicasMax)
+	}
+	if _, err := ParseCronSchedule(param.ScaleSchedule); err != nil {
+		return err
+	}
+	if _, err := ParseCronSchedule(param.RestoreSchedule); err != nil {
+		return err
+	}
+	return nil
+}
+
+func ScaleScheduleList(cluster, namespace string) ([]ScaleScheduleDetail, error) {
+	list, err := dao.NewScaleScheduleModel().GetScheduleList(cluster, namespace)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	details := []ScaleScheduleDetail{}
+	for _, schedule := range list {
+		details = append(details, scaleScheduleToDetail(schedule))
+	}
+	return details, nil
+}
+
+func ScaleScheduleCreate(cluster, namespace string, param ScaleScheduleParam) (*ScaleScheduleDetail, error) {
+	if err := validateScaleSchedule(cluster, namespace, param); err != nil {
+		return nil, err
+	}
+	schedule := &models.ZcloudScaleSchedule{
+		Cluster:         cluster,
+		Namespace:       namespace,
+		Name:            param.Name,
+		ScaleSchedule:   param.ScaleSchedule,
+		Replicas:        param.Replicas,
+		RestoreSchedule: param.RestoreSchedule,
+		Description:     param.Description,
+		Status:          models.SCALE_SCHEDULE_STATUS_NORMAL,
+		CheckAt:         time.Now(),
+	}
+	if err := dao.NewScaleScheduleModel().CreateSchedule(schedule); err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	detail := scaleScheduleToDetail(*schedule)
+	return &detail, nil
+}
+
+func ScaleScheduleUpdate(cluster, namespace string, id int64, param ScaleScheduleParam) (*ScaleScheduleDetail, error) {
+	model := dao.NewScaleScheduleModel()
+	schedule, err := model.GetSchedule(cluster, namespace, id)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(fmt.Errorf("scale schedule %v is not found!", id))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if param.Name != schedule.Name {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("the application of scale schedule can not be changed!"))
+	}
+	if err := validateScaleSchedule(cluster, namespace, param); err != nil {
+		return nil, err
+	}
+	schedule.ScaleSchedule = param.ScaleSchedule
+	schedule.Replicas = param.Replicas
+	schedule.RestoreSchedule = param.RestoreSchedule
+	schedule.Description = param.Description
+	// the triggers before updating are not taken
+	schedule.CheckAt = time.Now()
+	if err := model.UpdateSchedule(schedule); err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	detail := scaleScheduleToDetail(*schedule)
+	return &detail, nil
+}
+
+// ScaleScheduleDelete deletes the schedule, the applications scaled by it are restored
+func ScaleScheduleDelete(cluster, namespace string, id int64) error {
+	model := dao.NewScaleScheduleModel()
+	schedule, err := model.GetSchedule(cluster, namespace, id)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil
+		}
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if schedule.Status == models.SCALE_SCHEDULE_STATUS_SCALED {
+		ar, err := NewAppRes(cluster, nil)
+		if err != nil {
+			return err
+		}
+		restoreScaleSchedule(ar, schedule)
+	}
+	if err := model.DeleteSchedule(cluster, namespace, id); err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	return nil
+}
+
+// RunScaleSchedules scales or restores the applications of the cluster by the schedules triggered since last checking,
+// it should be run by only one kubecloud replica
+func RunScaleSchedules(cluster string) error {
+	model := dao.NewScaleScheduleModel()
+	list, err := model.GetScheduleList(cluster, "")
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	ar, err := NewAppRes(cluster, nil)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range list {
+		schedule := &list[i]
+		from := schedule.CheckAt
+		if now.Sub(from) > scaleScheduleMaxLookback {
+			from = now.Add(-scaleScheduleMaxLookback)
+		}
+		scaleSpec, err := ParseCronSchedule(schedule.ScaleSchedule)
+		if err != nil {
+			beego.Error(fmt.Sprintf("parse scale schedule %v of cluster %s failed: %v", schedule.Id, cluster, err))
+			continue
+		}
+		restoreSpec, err := ParseCronSchedule(schedule.RestoreSchedule)
+		if err != nil {
+			beego.Error(fmt.Sprintf("parse restore schedule %v of cluster %s failed: %v", schedule.Id, cluster, err))
+			continue
+		}
+		// only the latest trigger is taken if both of them are triggered
+		scaleAt := LastCronTrigger(scaleSpec, from, now)
+		restoreAt := LastCronTrigger(restoreSpec, from, now)
+		if !scaleAt.IsZero() && scaleAt.After(restoreAt) {
+			scaleScheduleApps(ar, schedule)
+		} else if !restoreAt.IsZero() {
+			restoreScaleSchedule(ar, schedule)
+		}
+		schedule.CheckAt = now
+		if err := model.UpdateSchedule(schedule, "status", "original_replicas", "check_at"); err != nil {
+			beego.Error(fmt.Sprintf("update scale schedule %v of cluster %s failed: %v", schedule.Id, cluster, err))
+		}
+	}
+	return nil
+}
+
+// scaleScheduleApps scales the applications to the replicas of the schedule, and records the original replicas
+func scaleScheduleApps(ar *AppRes, schedule *models.ZcloudScaleSchedule) {
+	apps, skipped, err := scaleScheduleTargets(ar, schedule)
+	if err != nil {
+		beego.Error(fmt.Sprintf("get applications of scale schedule %v failed: %v", schedule.Id, err))
+		return
+	}
+	for _, name := range skipped {
+		recordScaleScheduleSkipped(schedule, name)
+	}
+	originals := scheduleOriginalReplicas(schedule)
+	scaled := 0
+	for _, app := range apps {
+		if app.Replicas == schedule.Replicas {
+			continue
+		}
+		if err := ar.ScaleApp(app.Namespace, app.Name, schedule.Replicas); err != nil {
+			beego.Error(fmt.Sprintf("scale application(%s/%s/%s) by schedule failed: %v", ar.Cluster, app.Namespace, app.Name, err))
+			recordScaleScheduleEvent(schedule, app.Name, "Warning", "ScheduledScaleFailed",
+				fmt.Sprintf("scale application to %v replicas failed: %v", schedule.Replicas, err))
+			continue
+		}
+		// keep the replicas before the first scaling, the application may be scaled again before restoring
+		if _, ok := originals[app.Name]; !ok {
+			originals[app.Name] = app.Replicas
+		}
+		scaled++
+		recordScaleScheduleEvent(schedule, app.Name, "Normal", scaleScheduleReasonScaled,
+			fmt.Sprintf("scale application from %v to %v replicas by schedule %q", app.Replicas, schedule.Replicas, schedule.ScaleSchedule))
+	}
+	setScheduleOriginalReplicas(schedule, originals)
+	// nothing to restore if none of the applications is scaled, the status is kept
+	if scaled > 0 {
+		schedule.Status = models.SCALE_SCHEDULE_STATUS_SCALED
+	}
+}
+
+// restoreScaleSchedule restores the applications to the original replicas, the failed ones are kept to retry next time
+func restoreScaleSchedule(ar *AppRes, schedule *models.ZcloudScaleSchedule) {
+	originals := scheduleOriginalReplicas(schedule)
+	for name, replicas := range originals {
+		err := ar.ScaleApp(schedule.Namespace, name, replicas)
+		if err == orm.ErrNoRows {
+			// the application has been deleted
+			delete(originals, name)
+			continue
+		}
+		if err != nil {
+			beego.Error(fmt.Sprintf("restore application(%s/%s/%s) by schedule failed: %v", ar.Cluster, schedule.Namespace, name, err))
+			recordScaleScheduleEvent(schedule, name, "Warning", "ScheduledScaleFailed",
+				fmt.Sprintf("restore application to %v replicas failed: %v", replicas, err))
+			continue
+		}
+		delete(originals, name)
+		recordScaleScheduleEvent(schedule, name, "Normal", "ScheduledRestore",
+			fmt.Sprintf("restore application to %v replicas by schedule %q", replicas, schedule.RestoreSchedule))
+	}
+	setScheduleOriginalReplicas(schedule, originals)
+	schedule.Status = models.SCALE_SCHEDULE_STATUS_NORMAL
+}
+
+// scaleScheduleTargets returns the applications which can be scaled by the schedule,
+// and the names of the skipped ones whose replicas are managed by their autoscalers
+func scaleScheduleTargets(ar *AppRes, schedule *models.ZcloudScaleSchedule) ([]models.ZcloudApplication, []string, error) {
+	apps := []models.ZcloudApplication{}
+	if schedule.Name != "" {
+		app, err := ar.Appmodel.GetAppByName(ar.Cluster, schedule.Namespace, schedule.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		apps = append(apps, *app)
+	} else {
+		defFilter := utils.NewDefaultFilter().AppendFilter("cluster", ar.Cluster, utils.FilterOperatorEqual).
+			AppendFilter("namespace", schedule.Namespace, utils.FilterOperatorEqual)
+		res, err := ar.Appmodel.GetAppList(defFilter, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		apps = res.List.([]models.ZcloudApplication)
+	}
+	return filterScaleTargets(apps, func(app models.ZcloudApplication) (bool, error) {
+		_, existed, err := autoscalerExisted(ar.autoscalerModel, ar.Cluster, app.Namespace, app.Name)
+		return existed, err
+	})
+}
+
+func filterScaleTargets(apps []models.ZcloudApplication, autoscaled func(models.ZcloudApplication) (bool, error)) ([]models.ZcloudApplication, []string, error) {
+	targets := []models.ZcloudApplication{}
+	skipped := []string{}
+	for _, app := range apps {
+		if app.Kind != AppKindDeployment && app.Kind != AppKindStatefulSet {
+			continue
+		}
+		existed, err := autoscaled(app)
+		if err != nil {
+			return nil, nil, err
+		}
+		if existed {
+			skipped = append(skipped, app.Name)
+			continue
+		}
+		targets = append(targets, app)
+	}
+	return targets, skipped, nil
+}
+
+func validateScaleSchedule(cluster, namespace string, param ScaleScheduleParam) error {
+	if err := param.Validate(); err != nil {
+		return common.NewBadRequest().SetCause(err)
+	}
+	if param.Name == "" {
+		return nil
+	}
+	app, err := dao.NewAppModel().GetAppByName(cluster, namespace, param.Name)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return common.NewNotFound().SetCause(fmt.Errorf("application %s is not found!", param.Name))
+		}
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if app.Kind != AppKindDeployment && app.Kind != AppKindStatefulSet {
+		return common.NewBadRequest().SetCause(fmt.Errorf("%s application can not be scaled!", app.Kind))
+	}
+	_, existed, err := autoscalerExisted(dao.NewAutoscalerModel(), cluster, namespace, param.Name)
+	if err != nil {
+		return common.NewInternalServerError().SetCause(err)
+	}
+	if existed {
+		return common.NewConflict().SetCause(fmt.Errorf("the replicas of application %s is managed by its autoscaler!", param.Name))
+	}
+	return nil
+}
+
+func scheduleOriginalReplicas(schedule *models.ZcloudScaleSchedule) map[string]int {
+	originals := make(map[string]int)
+	if schedule.OriginalReplicas != "" {
+		if err := json.Unmarshal([]byte(schedule.OriginalReplicas), &originals); err != nil {
+			beego.Warn(fmt.Sprintf("decode original replicas of scale schedule %v failed: %v", schedule.Id, err))
+		}
+	}
+	return originals
+}
+
+func setScheduleOriginalReplicas(schedule *models.ZcloudScaleSchedule, originals map[string]int) {
+	if len(originals) == 0 {
+		schedule.OriginalReplicas = ""
+		return
+	}
+	data, _ := json.Marshal(originals)
+	schedule.OriginalReplicas = string(data)
+}
+
+func recordScaleScheduleEvent(schedule *models.ZcloudScaleSchedule, appname, eventType, reason, message string) {
+	now, _ := time.Parse("2006-01-02 15:04:05", time.Now().Local().Format("2006-01-02 15:04:05"))
+	event := models.ZcloudEvent{
+		EventUid:        utils.NewUUID(),
+		EventType:       eventType,
+		Cluster:         schedule.Cluster,
+		Namespace:       schedule.Namespace,
+		SourceComponent: scaleScheduleComponent,
+		ObjectKind:      "Application",
+		ObjectName:      appname,
+		Reason:          reason,
+		Message:         message,
+		Count:           1,
+		FirstTimestamp:  now,
+		LastTimestamp:   now,
+	}
+	if err := dao.CreateEvent(event); err != nil {
+		beego.Error(fmt.Sprintf("record event of scale schedule %v failed: %v", schedule.Id, err))
+	}
+}
+
+// recordScaleScheduleSkipped records the skipped application once, the event is not recorded again
+// by the following triggers of the schedule until the application is scaled by it
+func recordScaleScheduleSkipped(schedule *models.ZcloudScaleSchedule, appname string) {
+	message := fmt.Sprintf("skip the application whose replicas is managed by its autoscaler, schedule %v", schedule.Id)
+	if event, err := dao.GetLatestEvent(schedule.Cluster, schedule.Namespace, "Application", appname, scaleScheduleReasonSkipped); err == nil && event.Message == message {
+		if latest, err := dao.GetLatestEvent(schedule.Cluster, schedule.Namespace, "Application", appname, scaleScheduleReasonScaled); err != nil || latest.LastTimestamp.Before(event.LastTimestamp) {
+			return
+		}
+	}
+	recordScaleScheduleEvent(schedule, appname, "Normal", scaleScheduleReasonSkipped, message)
+}
+
+func scaleScheduleToDetail(schedule models.ZcloudScaleSchedule) ScaleScheduleDetail {
+	detail := ScaleScheduleDetail{ZcloudScaleSchedule: schedule}
+	now := time.Now()
+	if spec, err := ParseCronSchedule(schedule.ScaleSchedule); err == nil {
+		detail.NextScaleTime = spec.Next(now).Format("2006-01-02 15:04:05")
+	}
+	if spec, err := ParseCronSchedule(schedule.RestoreSchedule); err == nil {
+		detail.NextRestoreTime = spec.Next(now).Format("2006-01-02 15:04:05")
+	}
+	return detail
+}
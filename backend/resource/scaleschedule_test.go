@@ -0,0 +1,37 @@
+package resource
+
+import (
+	"fmt"
+	"testing"
+
+	"kubecloud/backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterScaleTargets(t *testing.T) {
+	apps := []models.ZcloudApplication{
+		{Name: "web", Kind: AppKindDeployment},
+		{Name: "api", Kind: AppKindDeployment},
+		{Name: "db", Kind: AppKindStatefulSet},
+		{Name: "agent", Kind: AppKindDaemonSet},
+		{Name: "backup", Kind: AppKindCronJob},
+	}
+	autoscaled := map[string]bool{"api": true, "agent": true}
+	targets, skipped, err := filterScaleTargets(apps, func(app models.ZcloudApplication) (bool, error) {
+		return autoscaled[app.Name], nil
+	})
+	assert.Nil(t, err)
+	names := []string{}
+	for _, app := range targets {
+		names = append(names, app.Name)
+	}
+	assert.Equal(t, []string{"web", "db"}, names)
+	// the applications of other kinds are not scaled by the schedule at all
+	assert.Equal(t, []string{"api"}, skipped)
+
+	_, _, err = filterScaleTargets(apps, func(app models.ZcloudApplication) (bool, error) {
+		return false, fmt.Errorf("db error")
+	})
+	assert.NotNil(t, err)
+}
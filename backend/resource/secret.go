@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
@@ -172,7 +173,7 @@ func (res *SecretRes) CreateSecret(namespace string, request *SecretRequest) err
 		return common.NewConflict().SetCode("SecretAlreadyExists").SetMessage("secret already exists")
 	}
 	k8sSecret := newK8sSecret(namespace, request)
-	_, err := res.client.CoreV1().Secrets(namespace).Create(k8sSecret)
+	_, err := res.client.CoreV1().Secrets(namespace).Create(context.TODO(), k8sSecret, metav1.CreateOptions{})
 	if err != nil {
 		return common.FromK8sError(err)
 	}
@@ -192,7 +193,7 @@ func (res *SecretRes) UpdateSecret(namespace string, request *SecretRequest) err
 	if err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
-	curSecret, err := client.CoreV1().Secrets(namespace).Get(request.Name, metav1.GetOptions{})
+	curSecret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), request.Name, metav1.GetOptions{})
 	if err != nil {
 		return common.FromK8sError(err)
 	}
@@ -207,16 +208,22 @@ func (res *SecretRes) UpdateSecret(namespace string, request *SecretRequest) err
 	newSecret := newK8sSecret(namespace, request)
 	curSecret.Annotations = newSecret.Annotations
 	curSecret.Data = newSecret.Data
-	_, err = res.client.CoreV1().Secrets(namespace).Update(curSecret)
+	updated, err := res.client.CoreV1().Secrets(namespace).Update(context.TODO(), curSecret, metav1.UpdateOptions{})
 	if err != nil {
 		return common.NewInternalServerError().SetCause(err)
 	}
+	// the htpasswd secret of the basic auth of ingresses has the new user
+	if updated.Type == apiv1.SecretTypeBasicAuth {
+		if err := applyHtpasswdSecret(res.client, updated, false); err != nil && !errors.IsNotFound(err) {
+			return common.NewInternalServerError().SetCause(err)
+		}
+	}
 
 	return nil
 }
 
 func (res *SecretRes) DeleteSecret(namespace, name string) error {
-	err := res.client.CoreV1().Secrets(namespace).Delete(name, &metav1.DeleteOptions{})
+	err := res.client.CoreV1().Secrets(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			err = res.model.DeleteSecret(res.cluster, namespace, name)
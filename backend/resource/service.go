@@ -40,6 +40,8 @@ type ServiceDetail struct {
 	ClusterIP      string           `json:"cluster_ip"`
 	AddressList    []serviceAddress `json:"address_list"`
 	PodsvcAddrList []podSvcAddress  `json:"podsvc_addr_list"`
+	// ExternalAddrList is the addresses of the tcp/udp ports exposed by the port exposures
+	ExternalAddrList []externalAddress `json:"external_addr_list,omitempty"`
 }
 
 type ServiceRes struct {
@@ -136,13 +138,16 @@ func (sr *ServiceRes) GetServiceDetail(namespace, name, nodeip string) (ServiceD
 		}
 		svcDetail.AddressList = append(svcDetail.AddressList, address)
 	}
+	if svcDetail.ExternalAddrList, err = getExternalAddresses(sr.cluster, namespace, svc.OwnerName, nodeip); err != nil {
+		beego.Warn("get the external addresses of service failed:", err)
+	}
 
 	return svcDetail, nil
 }
 
 func (sr *ServiceRes) GetHeadlessSvcDetail(namespace, name string) (ServiceDetail, error) {
 	svcDetail := ServiceDetail{}
-	svc, err := sr.modelSvc.Get(sr.cluster, namespace, name, "")
+	svc, err := sr.modelSvc.Get(sr.cluster, namespace, name, GenHeadlessSvcName(AppKindStatefulSet, name))
 	if err != nil {
 		beego.Error("Get service information failed:", err)
 		return svcDetail, nil
@@ -0,0 +1,132 @@
+package resource
+
+import (
+	"fmt"
+	"kubecloud/common/keyword"
+	"kubecloud/gitops"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/backend/util/labels"
+)
+
+type StatefulSetRes struct {
+	Cluster   string
+	Namespace string
+	client    kubernetes.Interface
+}
+
+func NewStatefulSetRes(client kubernetes.Interface, cluster, namespace string) KubeAppInterface {
+	return &StatefulSetRes{
+		Cluster:   cluster,
+		Namespace: namespace,
+		client:    client,
+	}
+}
+
+func (kr *StatefulSetRes) apiVersions() kubeutil.APIVersions {
+	return kubeutil.GetAPIVersions(kr.Cluster, kr.client.Discovery())
+}
+
+func (kr *StatefulSetRes) CreateOrUpdate(obj interface{}) (interface{}, error) {
+	sts, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		return nil, fmt.Errorf("can not generate statefulset object!")
+	}
+	beego.Info("creating or updating statefulset, " + sts.Name)
+	return sts, nil
+}
+
+func (kr *StatefulSetRes) Status(appname, suffix string) (*AppStatus, error) {
+	sts, err := kubeutil.GetStatefulSet(kr.client, kr.apiVersions(), kr.Namespace, GenerateDeployName(appname, suffix))
+	if err != nil {
+		return nil, err
+	}
+	return GetStatefulSetStatus(sts), nil
+}
+
+func (kr *StatefulSetRes) Delete(obj interface{}) (interface{}, error) {
+	sts, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		return nil, fmt.Errorf("can not generate statefulset object!")
+	}
+	sts.ObjectMeta.Annotations = labels.AddLabel(sts.ObjectMeta.Annotations, keyword.DELETE_LABLE, keyword.DELETE_LABLE_VALUE)
+	beego.Warn(fmt.Sprintf("delete statefulset %s successfully!", sts.Name))
+	return sts, nil
+}
+
+func (kr *StatefulSetRes) AppIsExisted(appname, suffix string) (bool, error) {
+	name := GenerateDeployName(appname, suffix)
+	_, err := kubeutil.GetStatefulSet(kr.client, kr.apiVersions(), kr.Namespace, name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		} else {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (kr *StatefulSetRes) Scale(obj interface{}, replicas int) error {
+	sts, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		return fmt.Errorf("can not generate statefulset object!")
+	}
+	num := int32(replicas)
+	if sts.Spec.Replicas != nil && *sts.Spec.Replicas == num {
+		return nil
+	}
+	sts.Spec.Replicas = &num
+	go gitops.CommitK8sResource(kr.Cluster, []interface{}{sts})
+	return nil
+}
+
+func (kr *StatefulSetRes) Restart(obj interface{}) error {
+	sts, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		return fmt.Errorf("can not generate statefulset object!")
+	}
+	sts.Spec.Template.ObjectMeta.Annotations = labels.AddLabel(sts.Spec.Template.ObjectMeta.Annotations, keyword.RESTART_LABLE, strconv.FormatInt(time.Now().Unix(), 10))
+	go gitops.CommitK8sResource(kr.Cluster, []interface{}{sts})
+	return nil
+}
+
+func (kr *StatefulSetRes) GetOwnerForPod(pod apiv1.Pod, ref *metav1.OwnerReference) interface{} {
+	if ref == nil || ref.Kind != appsv1.SchemeGroupVersion.WithKind("StatefulSet").Kind {
+		return nil
+	}
+	sts, err := kubeutil.GetStatefulSet(kr.client, kr.apiVersions(), pod.Namespace, ref.Name)
+	if err != nil || sts.UID != ref.UID {
+		beego.Warn(fmt.Sprintf("Cannot get statefulset %s for pod %s: %v", ref.Name, pod.Name, err))
+		return nil
+	}
+	return sts
+}
+
+func GetStatefulSetStatus(sts *appsv1.StatefulSet) *AppStatus {
+	status := &AppStatus{
+		StatusReplicas:    sts.Status.Replicas,
+		ReadyReplicas:     sts.Status.ReadyReplicas,
+		AvailableReplicas: sts.Status.ReadyReplicas,
+		UpdatedReplicas:   sts.Status.UpdatedReplicas,
+		AvailableStatus:   string(apiv1.ConditionFalse),
+	}
+	replicas := int32(default_replicas)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	if sts.Status.ReadyReplicas >= replicas {
+		status.AvailableStatus = string(apiv1.ConditionTrue)
+	} else {
+		status.Message = fmt.Sprintf("%v of %v pods are ready", sts.Status.ReadyReplicas, replicas)
+	}
+	return status
+}
@@ -1,22 +1,75 @@
 package resource
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/astaxie/beego/orm"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"kubecloud/backend/dao"
 	"kubecloud/backend/models"
+	"kubecloud/backend/service"
 	"kubecloud/common"
 	"kubecloud/common/utils"
 )
 
+const (
+	TemplateKindNative    = "native"
+	TemplateKindHelm      = "helm"
+	TemplateKindKustomize = "kustomize"
+)
+
 type TemplateInfo struct {
 	models.ZcloudTemplate
-	CreateAt string `json:"create_at"`
-	UpdateAt string `json:"update_at"`
-	DeleteAt string `json:"delete_at"`
+	Spec       json.RawMessage     `json:"spec"`
+	Parameters []TemplateParameter `json:"parameters"`
+	CreateAt   string              `json:"create_at"`
+	UpdateAt   string              `json:"update_at"`
+	DeleteAt   string              `json:"delete_at"`
+}
+
+type TemplateVersionInfo struct {
+	models.ZcloudTemplateVersion
+	Spec       json.RawMessage     `json:"spec"`
+	Parameters []TemplateParameter `json:"parameters"`
+	CreateAt   string              `json:"create_at"`
+}
+
+// TemplateParam is the content of a template version, spec is decided by the kind,
+// such as NativeTemplate for native kind, HelmTemplate for helm kind and KustomizeTemplate for kustomize kind
+type TemplateParam struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Kind        string              `json:"kind"`
+	Spec        json.RawMessage     `json:"spec"`
+	Parameters  []TemplateParameter `json:"parameters"`
+}
+
+// TemplateRender is the template rendered by the values of parameters
+type TemplateRender struct {
+	Name     string          `json:"name"`
+	Version  int             `json:"version"`
+	Kind     string          `json:"kind"`
+	Template json.RawMessage `json:"template"`
+}
+
+// TemplatePreview is the final yaml of the template version which is deployed to the cluster
+type TemplatePreview struct {
+	Name     string `json:"name"`
+	Version  int    `json:"version"`
+	Kind     string `json:"kind"`
+	Cluster  string `json:"cluster"`
+	Env      string `json:"env"`
+	Template string `json:"template"`
+}
+
+type TemplateDeployParam struct {
+	// the latest version is used if it is 0
+	Version int                    `json:"version"`
+	Values  map[string]interface{} `json:"values"`
 }
 
 type TemplateRes struct {
@@ -31,7 +84,7 @@ func NewTemplateRes(get NamespaceListFunction) *TemplateRes {
 	}
 }
 
-// template interface, nativetemplate support this interface
+// template interface, nativetemplate, helmtemplate and kustomizetemplate support this interface
 type Template interface {
 	Default(cluster string) Template
 	Validate() error
@@ -43,8 +96,16 @@ func NewTemplate() Template {
 	return NewNativeTemplate()
 }
 
-func (tr *TemplateRes) CreateTemplate(template models.ZcloudTemplate) (*models.ZcloudTemplate, error) {
-	texist, err := tr.modelHandle.GetTemplate(template.Namespace, template.Name)
+func (tr *TemplateRes) CreateTemplate(namespace string, param TemplateParam) (*TemplateInfo, error) {
+	param.Name = strings.TrimSpace(param.Name)
+	if errs := validation.IsDNS1123Label(param.Name); len(errs) != 0 {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("template name %q is invalid: %s!", param.Name, strings.Join(errs, ",")))
+	}
+	version, err := param.toVersion()
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	texist, err := tr.modelHandle.GetTemplate(namespace, param.Name)
 	if texist != nil {
 		return nil, common.NewConflict().SetCode("TemplateAlreadyExists").SetMessage("template already exists")
 	} else {
@@ -54,12 +115,21 @@ func (tr *TemplateRes) CreateTemplate(template models.ZcloudTemplate) (*models.Z
 			}
 		}
 	}
-	temp, err := tr.modelHandle.CreateTemplate(template)
+	temp, err := tr.modelHandle.CreateTemplate(models.ZcloudTemplate{
+		Name:        param.Name,
+		Namespace:   namespace,
+		Description: version.Description,
+		Kind:        version.Kind,
+	})
 	if err != nil {
 		return nil, common.NewInternalServerError().SetCause(err)
 	}
+	if err := tr.modelHandle.CreateTemplateVersion(temp, version); err != nil {
+		tr.modelHandle.DeleteTemplate(namespace, param.Name)
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
 
-	return temp, nil
+	return templateToInfo(*temp), nil
 }
 
 func (tr *TemplateRes) DeleteTemplate(namespace, name string) error {
@@ -81,25 +151,22 @@ func (tr *TemplateRes) DeleteTemplate(namespace, name string) error {
 	return nil
 }
 
-func (tr *TemplateRes) UpdateTemplate(template models.ZcloudTemplate) (*models.ZcloudTemplate, error) {
-	told, err := tr.modelHandle.GetTemplate(template.Namespace, template.Name)
+// UpdateTemplate creates a new version of the template, the old versions are immutable
+func (tr *TemplateRes) UpdateTemplate(namespace, name string, param TemplateParam) (*TemplateInfo, error) {
+	// the spec of legacy template is kept as its version 1
+	told, err := tr.getTemplate(namespace, name)
 	if err != nil {
-		if err == orm.ErrNoRows {
-			return nil, common.NewNotFound().SetCause(err)
-		} else {
-			return nil, common.NewInternalServerError().SetCause(err)
-		}
+		return nil, err
 	}
-	told.Kind = template.Kind
-	told.Spec = template.Spec
-	told.Description = template.Description
-
-	err = tr.modelHandle.UpdateTemplate(*told)
+	version, err := param.toVersion()
 	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	if err = tr.modelHandle.CreateTemplateVersion(told, version); err != nil {
 		return nil, common.NewInternalServerError().SetCause(err)
 	}
 
-	return told, nil
+	return templateToInfo(*told), nil
 }
 
 func (tr *TemplateRes) GetTemplateList(namespace string, filterQuery *utils.FilterQuery) (*utils.QueryResult, error) {
@@ -122,11 +189,7 @@ func (tr *TemplateRes) GetTemplateList(namespace string, filterQuery *utils.Filt
 	}
 	list := []TemplateInfo{}
 	for _, item := range tList {
-		temp := TemplateInfo{}
-		temp.ZcloudTemplate = item
-		temp.UpdateAt = item.UpdateAt.Format("2006-01-02 15:04:05")
-		temp.CreateAt = item.CreateAt.Format("2006-01-02 15:04:05")
-		list = append(list, temp)
+		list = append(list, *templateToInfo(item))
 	}
 	res.List = list
 	return res, nil
@@ -155,3 +218,356 @@ func (tr *TemplateRes) GetTemplateByID(id int64) (*models.ZcloudTemplate, int, e
 
 	return template, http.StatusOK, nil
 }
+
+func (tr *TemplateRes) GetTemplateVersionList(namespace, name string) ([]TemplateVersionInfo, error) {
+	template, err := tr.getTemplate(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := tr.modelHandle.GetTemplateVersionList(template.Id)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	list := []TemplateVersionInfo{}
+	for _, item := range versions {
+		list = append(list, *templateVersionToInfo(item))
+	}
+	return list, nil
+}
+
+// GetTemplateVersion returns the version of template, the latest one is returned if version is 0
+func (tr *TemplateRes) GetTemplateVersion(namespace, name string, version int) (*TemplateVersionInfo, error) {
+	tv, err := tr.getTemplateVersion(namespace, name, version)
+	if err != nil {
+		return nil, err
+	}
+	return templateVersionToInfo(*tv), nil
+}
+
+// RenderTemplate substitutes the parameters of the template version by the values
+func (tr *TemplateRes) RenderTemplate(namespace, name string, version int, values map[string]interface{}) (*TemplateRender, error) {
+	tv, err := tr.getTemplateVersion(namespace, name, version)
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := renderTemplateVersion(tv, values)
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	data, err := json.Marshal(tpl)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return &TemplateRender{
+		Name:     tv.Name,
+		Version:  tv.Version,
+		Kind:     tv.Kind,
+		Template: data,
+	}, nil
+}
+
+// PreviewTemplate renders the template version by the values for the cluster, such as the overlay
+// of kustomize template for the env of cluster
+func (tr *TemplateRes) PreviewTemplate(cluster, namespace, name string, param TemplateDeployParam) (*TemplatePreview, error) {
+	tv, err := tr.getTemplateVersion(namespace, name, param.Version)
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := renderTemplateVersion(tv, param.Values)
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	preview := &TemplatePreview{
+		Name:    tv.Name,
+		Version: tv.Version,
+		Kind:    tv.Kind,
+		Cluster: cluster,
+	}
+	switch t := tpl.Default(cluster).(type) {
+	case *NativeTemplate:
+		preview.Template = t.Template
+	case *HelmTemplate:
+		client, err := service.GetClientset(cluster)
+		if err != nil {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+		caps, err := helmCapabilities(client)
+		if err != nil {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+		if preview.Template, err = t.Render(namespace, 1, caps); err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+	case *KustomizeTemplate:
+		preview.Env = t.Env()
+		if preview.Template, err = t.Render(t.Env()); err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+	}
+	return preview, nil
+}
+
+// TemplateDependencyGraph returns the dependency graph of the applications of the template version,
+// the helm template is not supported because its applications are deployed by the helm release
+func (tr *TemplateRes) TemplateDependencyGraph(cluster, namespace, name string, param TemplateDeployParam) (*utils.VisGraph, error) {
+	tv, err := tr.getTemplateVersion(namespace, name, param.Version)
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := renderTemplateVersion(tv, param.Values)
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	native := &NativeTemplate{}
+	switch t := tpl.Default(cluster).(type) {
+	case *NativeTemplate:
+		native = t
+	case *KustomizeTemplate:
+		if native.Template, err = t.Render(t.Env()); err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+	default:
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("the dependency graph of %s template is not supported!", tv.Kind))
+	}
+	nativeList, _, err := native.GenNativeAppTemplate(namespace, INIT_APPNAME)
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	tplList := []AppTemplate{}
+	for _, item := range nativeList {
+		tplList = append(tplList, item)
+	}
+	graph, err := AppDependencyGraph(tplList)
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	return graph, nil
+}
+
+// DeployTemplate deploys the applications of the template version to the namespace of cluster,
+// and the applications record the template version which they are deployed from
+func (tr *TemplateRes) DeployTemplate(cluster, namespace, name string, projectid int64, param TemplateDeployParam, eparam *ExtensionParam) error {
+	tv, err := tr.getTemplateVersion(namespace, name, param.Version)
+	if err != nil {
+		return err
+	}
+	tpl, err := renderTemplateVersion(tv, param.Values)
+	if err != nil {
+		return common.NewBadRequest().SetCause(err)
+	}
+	ar, err := NewAppRes(cluster, nil)
+	if err != nil {
+		return err
+	}
+	return ar.InstallApp(projectid, namespace, TemplateVersionName(tv.Name, tv.Version), tpl, eparam)
+}
+
+// TemplateVersionName is recorded as the template name of application, such as "nginx:v2"
+func TemplateVersionName(name string, version int) string {
+	return fmt.Sprintf("%s:v%d", name, version)
+}
+
+// getTemplate returns the template, the spec of the legacy template which was created before
+// the versions are recorded is recorded as its version 1
+func (tr *TemplateRes) getTemplate(namespace, name string) (*models.ZcloudTemplate, error) {
+	template, err := tr.modelHandle.GetTemplate(namespace, name)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(fmt.Errorf("template %s is not found!", name))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if template.LatestVersion != 0 {
+		return template, nil
+	}
+	if err := tr.modelHandle.CreateTemplateVersion(template, legacyTemplateVersion(template)); err != nil {
+		// the version may be recorded by a concurrent request
+		latest, getErr := tr.modelHandle.GetTemplate(namespace, name)
+		if getErr != nil || latest.LatestVersion == 0 {
+			return nil, common.NewInternalServerError().SetCause(fmt.Errorf("record version of legacy template %s failed: %v", name, err))
+		}
+		template = latest
+	}
+	return template, nil
+}
+
+func legacyTemplateVersion(template *models.ZcloudTemplate) *models.ZcloudTemplateVersion {
+	kind := template.Kind
+	if kind == "" {
+		kind = TemplateKindNative
+	}
+	return &models.ZcloudTemplateVersion{
+		Description: template.Description,
+		Kind:        kind,
+		Spec:        template.Spec,
+		Parameters:  template.Parameters,
+	}
+}
+
+func (tr *TemplateRes) getTemplateVersion(namespace, name string, version int) (*models.ZcloudTemplateVersion, error) {
+	template, err := tr.getTemplate(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = template.LatestVersion
+	}
+	tv, err := tr.modelHandle.GetTemplateVersion(template.Id, version)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(fmt.Errorf("version %v of template %s is not found!", version, name))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return tv, nil
+}
+
+// toVersion validates the param and converts it to the template version
+func (param TemplateParam) toVersion() (*models.ZcloudTemplateVersion, error) {
+	if param.Kind == "" {
+		param.Kind = TemplateKindNative
+	}
+	if err := ValidateTemplateParameters(param.Parameters); err != nil {
+		return nil, err
+	}
+	params, err := json.Marshal(param.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	version := &models.ZcloudTemplateVersion{
+		Description: param.Description,
+		Kind:        param.Kind,
+		Spec:        string(param.Spec),
+		Parameters:  string(params),
+	}
+	if _, err := decodeTemplateSpec(param.Kind, version.Spec); err != nil {
+		return nil, err
+	}
+	// the template is validated by the default values, it can not be rendered
+	// if some required parameters have no default value
+	for _, p := range param.Parameters {
+		if p.Required && p.Default == nil {
+			return version, nil
+		}
+	}
+	if _, err := renderTemplateVersion(version, nil); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// decodeTemplateSpec decodes the spec of template by its kind
+func decodeTemplateSpec(kind, spec string) (Template, error) {
+	switch kind {
+	case TemplateKindNative:
+		tpl := NewNativeTemplate()
+		if err := json.Unmarshal([]byte(spec), tpl); err != nil {
+			return nil, fmt.Errorf("decode native template failed: %v", err)
+		}
+		if strings.TrimSpace(tpl.Template) == "" {
+			return nil, fmt.Errorf("the template of native kind can not be empty!")
+		}
+		return tpl, nil
+	case TemplateKindHelm:
+		tpl := NewHelmTemplate()
+		if err := json.Unmarshal([]byte(spec), tpl); err != nil {
+			return nil, fmt.Errorf("decode helm template failed: %v", err)
+		}
+		return tpl, nil
+	case TemplateKindKustomize:
+		tpl := NewKustomizeTemplate()
+		if err := json.Unmarshal([]byte(spec), tpl); err != nil {
+			return nil, fmt.Errorf("decode kustomize template failed: %v", err)
+		}
+		return tpl, nil
+	}
+	return nil, fmt.Errorf("template kind %q is not supported!", kind)
+}
+
+// renderTemplateVersion substitutes the parameters of template version and validates the result
+func renderTemplateVersion(tv *models.ZcloudTemplateVersion, values map[string]interface{}) (Template, error) {
+	params := []TemplateParameter{}
+	if tv.Parameters != "" {
+		if err := json.Unmarshal([]byte(tv.Parameters), &params); err != nil {
+			return nil, fmt.Errorf("decode parameters of template failed: %v", err)
+		}
+	}
+	tpl, err := decodeTemplateSpec(tv.Kind, tv.Spec)
+	if err != nil {
+		return nil, err
+	}
+	switch t := tpl.(type) {
+	case *NativeTemplate:
+		if t.Template, err = RenderTemplateSpec(t.Template, params, values); err != nil {
+			return nil, err
+		}
+	case *HelmTemplate:
+		// the parameters of helm template are referred by its values
+		if t.Values, err = RenderTemplateSpec(t.Values, params, values); err != nil {
+			return nil, err
+		}
+	case *KustomizeTemplate:
+		if t.Base, err = RenderTemplateSpec(t.Base, params, values); err != nil {
+			return nil, err
+		}
+		for env, overlay := range t.Overlays {
+			for i := range overlay.Patches {
+				if overlay.Patches[i].Patch, err = RenderTemplateSpec(overlay.Patches[i].Patch, params, values); err != nil {
+					return nil, err
+				}
+			}
+			t.Overlays[env] = overlay
+		}
+	}
+	if err := tpl.Validate(); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+func templateToInfo(template models.ZcloudTemplate) *TemplateInfo {
+	info := &TemplateInfo{
+		ZcloudTemplate: template,
+		Spec:           templateSpecJSON(template.Spec),
+		Parameters:     []TemplateParameter{},
+		CreateAt:       template.CreateAt.Format("2006-01-02 15:04:05"),
+		UpdateAt:       template.UpdateAt.Format("2006-01-02 15:04:05"),
+	}
+	if template.Parameters != "" {
+		json.Unmarshal([]byte(template.Parameters), &info.Parameters)
+	}
+	return info
+}
+
+func templateVersionToInfo(tv models.ZcloudTemplateVersion) *TemplateVersionInfo {
+	info := &TemplateVersionInfo{
+		ZcloudTemplateVersion: tv,
+		Spec:                  templateSpecJSON(tv.Spec),
+		Parameters:            []TemplateParameter{},
+		CreateAt:              tv.CreateAt.Format("2006-01-02 15:04:05"),
+	}
+	if tv.Parameters != "" {
+		json.Unmarshal([]byte(tv.Parameters), &info.Parameters)
+	}
+	return info
+}
+
+// templateSpecJSON returns the spec as json, the spec of legacy template may be yaml text
+func templateSpecJSON(spec string) json.RawMessage {
+	if json.Valid([]byte(spec)) {
+		return json.RawMessage(spec)
+	}
+	data, _ := json.Marshal(spec)
+	return data
+}
+
+func (tr *TemplateRes) GetTemplateInfo(namespace, name string) (*TemplateInfo, error) {
+	template, err := tr.modelHandle.GetTemplate(namespace, name)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			return nil, common.NewNotFound().SetCause(fmt.Errorf("template %s is not found!", name))
+		}
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return templateToInfo(*template), nil
+}
@@ -0,0 +1,277 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/common"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	yamlencoder "github.com/ghodss/yaml"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// the annotations set by kubectl and controllers, they are not captured
+var capturedIgnoredAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+}
+
+// TemplateCaptureParam selects the live objects of namespace which are captured as a native template
+type TemplateCaptureParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Selector is the label selector of objects, all objects of the namespace are captured if it is empty
+	Selector string `json:"selector"`
+	// NodeSelector is set to the applications which have no schedule policy, the template can not be validated without it
+	NodeSelector map[string]string `json:"node_selector"`
+	// Adopt creates the applications of the captured deployments, the deployments are kept as they are
+	Adopt bool `json:"adopt"`
+}
+
+// TemplateCapture is the result of capturing, Objects are the kind/name of the captured objects
+type TemplateCapture struct {
+	Template     *TemplateInfo `json:"template"`
+	Objects      []string      `json:"objects"`
+	Applications []string      `json:"applications"`
+}
+
+// CaptureTemplate reads the deployments, services, ingresses, configmaps and secrets of namespace,
+// and creates a native template of them without the status and the fields set by the api server
+func (tr *TemplateRes) CaptureTemplate(cluster, namespace string, param TemplateCaptureParam) (*TemplateCapture, error) {
+	if _, err := labels.Parse(param.Selector); err != nil {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("selector %q is invalid: %v", param.Selector, err))
+	}
+	ar, err := NewAppRes(cluster, nil)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := captureObjects(ar.Client, cluster, namespace, param)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if len(objs) == 0 {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("there are no objects to capture in namespace %s!", namespace))
+	}
+	native, names, err := capturedNativeTemplate(objs, param.Description)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	result := &TemplateCapture{Objects: names, Applications: []string{}}
+	spec, err := json.Marshal(native)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if result.Template, err = tr.CreateTemplate(namespace, TemplateParam{
+		Name:        param.Name,
+		Description: param.Description,
+		Kind:        TemplateKindNative,
+		Spec:        spec,
+	}); err != nil {
+		return nil, err
+	}
+	if !param.Adopt {
+		return result, nil
+	}
+	tplList, _, err := native.GenNativeAppTemplate(namespace, INIT_APPNAME)
+	if err != nil {
+		return nil, common.NewBadRequest().SetCause(err)
+	}
+	for _, tpl := range tplList {
+		_, err := ar.Appmodel.GetAppByName(cluster, namespace, tpl.GetAppName())
+		if err == nil {
+			beego.Warn(fmt.Sprintf("application %s/%s already exists, it is not adopted!", namespace, tpl.GetAppName()))
+			continue
+		}
+		if err != orm.ErrNoRows {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+		app, err := tpl.GenerateAppObject(cluster, namespace, TemplateVersionName(param.Name, result.Template.LatestVersion), ar.DomainSuffix)
+		if err != nil {
+			return nil, common.NewBadRequest().SetCause(err)
+		}
+		// the live deployment is named without pod version, it is kept by the empty pod version
+		app.PodVersion = ""
+		app.Version = adoptedAppVersion(tpl, app.Image)
+		if err := ar.Appmodel.CreateApp(*app); err != nil {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+		result.Applications = append(result.Applications, app.Name)
+	}
+	return result, nil
+}
+
+// capturedNativeTemplate returns the native template of the captured objects and the kind/name of them
+func capturedNativeTemplate(objs []runtime.Object, description string) (*NativeTemplate, []string, error) {
+	docs, names := []string{}, []string{}
+	for _, obj := range objs {
+		data, err := capturedYaml(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		docs = append(docs, string(data))
+		accessor := obj.(metav1.Object)
+		names = append(names, fmt.Sprintf("%s/%s", obj.GetObjectKind().GroupVersionKind().Kind, accessor.GetName()))
+	}
+	native := NewNativeTemplate()
+	native.Template = strings.Join(docs, YamlSeparator)
+	native.Config.Version = DefaultVersion
+	native.Config.Description = description
+	return native, names, nil
+}
+
+// adoptedAppVersion returns the version of the adopted application, it is read from the live workload
+// like the application controller does, otherwise the status of application is never synchronized
+func adoptedAppVersion(tpl *NativeAppTemplate, image string) string {
+	return GetResourceVersion(tpl.appObject(), ResTypeDeploy, image)
+}
+
+// captureObjects lists the objects of namespace, the objects owned by other objects
+// and the ones maintained by kubernetes are skipped
+func captureObjects(client kubernetes.Interface, cluster, namespace string, param TemplateCaptureParam) ([]runtime.Object, error) {
+	opts := metav1.ListOptions{LabelSelector: param.Selector}
+	objs := []runtime.Object{}
+	versions := kubeutil.GetAPIVersions(cluster, client.Discovery())
+	deploys, err := kubeutil.ListDeployments(client, versions, namespace, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range deploys {
+		if len(item.OwnerReferences) != 0 {
+			continue
+		}
+		deploy := &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: AppAPIVersion},
+			ObjectMeta: capturedObjectMeta(item.ObjectMeta),
+			Spec:       item.Spec,
+		}
+		if deploy.Spec.Template.Spec.NodeName == "" && len(deploy.Spec.Template.Spec.NodeSelector) == 0 {
+			deploy.Spec.Template.Spec.NodeSelector = param.NodeSelector
+		}
+		objs = append(objs, deploy)
+	}
+	svcs, err := client.CoreV1().Services(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range svcs.Items {
+		if len(item.OwnerReferences) != 0 {
+			continue
+		}
+		svc := &apiv1.Service{
+			TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: SvcApiVersion},
+			ObjectMeta: capturedObjectMeta(item.ObjectMeta),
+			Spec:       item.Spec,
+		}
+		// the cluster ip is allocated by the api server, except the headless service
+		if svc.Spec.ClusterIP != apiv1.ClusterIPNone {
+			svc.Spec.ClusterIP = ""
+			svc.Spec.ClusterIPs = nil
+		}
+		svc.Spec.HealthCheckNodePort = 0
+		objs = append(objs, svc)
+	}
+	ings, err := kubeutil.ListIngresses(client, versions, namespace, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range ings {
+		if len(item.OwnerReferences) != 0 {
+			continue
+		}
+		objs = append(objs, &networkingv1.Ingress{
+			TypeMeta:   metav1.TypeMeta{Kind: "Ingress", APIVersion: IngApiVersion},
+			ObjectMeta: capturedObjectMeta(item.ObjectMeta),
+			Spec:       item.Spec,
+		})
+	}
+	configs, err := client.CoreV1().ConfigMaps(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range configs.Items {
+		// the root ca is published to every namespace by kubernetes
+		if len(item.OwnerReferences) != 0 || item.Name == "kube-root-ca.crt" {
+			continue
+		}
+		objs = append(objs, &apiv1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: capturedObjectMeta(item.ObjectMeta),
+			Data:       item.Data,
+			BinaryData: item.BinaryData,
+		})
+	}
+	secrets, err := client.CoreV1().Secrets(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range secrets.Items {
+		// the tokens of service accounts and the releases of helm are not deployed by templates
+		if len(item.OwnerReferences) != 0 ||
+			item.Type == apiv1.SecretTypeServiceAccountToken ||
+			strings.HasPrefix(string(item.Type), "helm.sh/") {
+			continue
+		}
+		objs = append(objs, &apiv1.Secret{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: capturedObjectMeta(item.ObjectMeta),
+			Type:       item.Type,
+			Data:       item.Data,
+		})
+	}
+	return objs, nil
+}
+
+func capturedObjectMeta(old metav1.ObjectMeta) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{
+		Name:   old.Name,
+		Labels: old.Labels,
+	}
+	for k, v := range old.Annotations {
+		ignored := false
+		for _, key := range capturedIgnoredAnnotations {
+			if k == key {
+				ignored = true
+				break
+			}
+		}
+		if ignored {
+			continue
+		}
+		if meta.Annotations == nil {
+			meta.Annotations = make(map[string]string)
+		}
+		meta.Annotations[k] = v
+	}
+	return meta
+}
+
+// capturedYaml returns the yaml of obj without the status and the empty creation timestamps
+func capturedYaml(obj runtime.Object) ([]byte, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(content, "status")
+	if meta, ok := content["metadata"].(map[string]interface{}); ok {
+		delete(meta, "creationTimestamp")
+	}
+	if spec, ok := content["spec"].(map[string]interface{}); ok {
+		if template, ok := spec["template"].(map[string]interface{}); ok {
+			if meta, ok := template["metadata"].(map[string]interface{}); ok {
+				delete(meta, "creationTimestamp")
+			}
+		}
+	}
+	return yamlencoder.Marshal(content)
+}
@@ -0,0 +1,69 @@
+package resource
+
+import (
+	"context"
+	"testing"
+
+	"kubecloud/backend/models"
+	"kubecloud/common/keyword"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestLiveDeployment(name string, labels map[string]string, images ...string) *appsv1.Deployment {
+	replicas := int32(2)
+	containers := []apiv1.Container{}
+	for i, image := range images {
+		c := apiv1.Container{Name: "sidecar", Image: image}
+		if i == len(images)-1 {
+			c.Name = name
+		}
+		containers = append(containers, c)
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"run": name}},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"run": name}},
+				Spec:       apiv1.PodSpec{Containers: containers, NodeSelector: map[string]string{"role": "app"}},
+			},
+		},
+	}
+}
+
+// the adopted application must pass the version check of the application controller, or its status is never synchronized
+func TestAdoptedAppVersion(t *testing.T) {
+	cases := []*appsv1.Deployment{
+		// the version is taken from the image of the main container
+		newTestLiveDeployment("web", map[string]string{"team": "infra"}, "harbor.example.com/library/proxy:0.9", "harbor.example.com/library/web:1.2.3"),
+		newTestLiveDeployment("api", map[string]string{keyword.LABEL_PODVERSION_KEY: "v2"}, "harbor.example.com/library/api:2.0.0"),
+		newTestLiveDeployment("worker", map[string]string{keyword.LABEL_APPVERSION_KEY: "2.1"}, "harbor.example.com/library/worker:2.1.0"),
+	}
+	for _, live := range cases {
+		client := fake.NewSimpleClientset()
+		_, err := client.AppsV1().Deployments("default").Create(context.TODO(), live, metav1.CreateOptions{})
+		assert.Nil(t, err)
+		objs, err := captureObjects(client, "test", "default", TemplateCaptureParam{})
+		assert.Nil(t, err)
+		native, names, err := capturedNativeTemplate(objs, "")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Deployment/" + live.Name}, names)
+		tplList, _, err := native.GenNativeAppTemplate("default", INIT_APPNAME)
+		assert.Nil(t, err)
+		if !assert.Equal(t, 1, len(tplList)) {
+			continue
+		}
+		tpl := tplList[0]
+		image := live.Spec.Template.Spec.Containers[len(live.Spec.Template.Spec.Containers)-1].Image
+		app := &models.ZcloudApplication{Name: tpl.GetAppName(), Image: image, Version: tpl.Config.Version}
+		app.Version = adoptedAppVersion(tpl, app.Image)
+		assert.Equal(t, GetResourceVersion(live, ResTypeDeploy, app.Image), GetResourceVersion(app, ResTypeApp, ""), live.Name)
+		assert.NotEqual(t, DefaultVersion, app.Version, live.Name)
+	}
+}
@@ -0,0 +1,196 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kubecloud/common/validate"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	TemplateParamTypeString = "string"
+	// TemplateParamTypeName is the name of kubernetes object, such as application name
+	TemplateParamTypeName  = "name"
+	TemplateParamTypeImage = "image"
+	TemplateParamTypeInt   = "int"
+	// TemplateParamTypePorts is the list of container ports
+	TemplateParamTypePorts = "ports"
+	// TemplateParamTypeEnv is the list of env vars or the map of env name and value
+	TemplateParamTypeEnv = "env"
+)
+
+// the image may be in docker hub, such as "nginx:1.19", so it is not checked by harbor
+var templateImagePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._:/@-]*$`)
+
+// TemplateParameter is referred by ${name} in the template. The value of string and image is rendered as
+// a double-quoted scalar, so it must be referred as a whole value such as "image: ${image}", the value of
+// name is a dns label and rendered as it is, so it can be a part of other names such as "${name}-svc".
+// The value of ports and env is rendered as json which is a valid flow of yaml, such as "ports: ${ports}",
+// the undeclared references such as the shell variables are kept as they are
+type TemplateParameter struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+}
+
+// ValidateTemplateParameters checks the declarations of the parameters
+func ValidateTemplateParameters(params []TemplateParameter) error {
+	declared := make(map[string]bool)
+	for _, param := range params {
+		if errs := validation.IsCIdentifier(param.Name); len(errs) != 0 {
+			return fmt.Errorf("parameter name %q is invalid: %s!", param.Name, strings.Join(errs, ","))
+		}
+		if declared[param.Name] {
+			return fmt.Errorf("parameter %s is declared more than once!", param.Name)
+		}
+		declared[param.Name] = true
+		switch param.Type {
+		case TemplateParamTypeString, TemplateParamTypeName, TemplateParamTypeImage,
+			TemplateParamTypeInt, TemplateParamTypePorts, TemplateParamTypeEnv:
+		default:
+			return fmt.Errorf("the type %q of parameter %s is not supported!", param.Type, param.Name)
+		}
+		if param.Default != nil {
+			if _, err := formatTemplateParam(param, param.Default); err != nil {
+				return fmt.Errorf("the default value of parameter %s is invalid: %v", param.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RenderTemplateSpec substitutes the parameters of the template by the values or their defaults
+func RenderTemplateSpec(spec string, params []TemplateParameter, values map[string]interface{}) (string, error) {
+	declared := make(map[string]TemplateParameter)
+	for _, param := range params {
+		declared[param.Name] = param
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return "", fmt.Errorf("parameter %s is not declared by the template!", name)
+		}
+	}
+	replaces := []string{}
+	for _, param := range params {
+		value, ok := values[param.Name]
+		if !ok || value == nil {
+			value = param.Default
+		}
+		if value == nil {
+			if param.Required {
+				return "", fmt.Errorf("parameter %s is required!", param.Name)
+			}
+			switch param.Type {
+			case TemplateParamTypePorts, TemplateParamTypeEnv:
+				value = []interface{}{}
+			case TemplateParamTypeInt:
+				value = 0
+			default:
+				value = ""
+			}
+		}
+		str, err := formatTemplateParam(param, value)
+		if err != nil {
+			return "", fmt.Errorf("the value of parameter %s is invalid: %v", param.Name, err)
+		}
+		replaces = append(replaces, "${"+param.Name+"}", str)
+	}
+	return strings.NewReplacer(replaces...).Replace(spec), nil
+}
+
+// formatTemplateParam checks the value by the type of parameter and returns its text in the template
+func formatTemplateParam(param TemplateParameter, value interface{}) (string, error) {
+	switch param.Type {
+	case TemplateParamTypeString, TemplateParamTypeName, TemplateParamTypeImage:
+		str, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("%v is not a string", value)
+		}
+		if param.Type == TemplateParamTypeName {
+			if str == "" {
+				return str, nil
+			}
+			if errs := validation.IsDNS1123Label(str); len(errs) != 0 {
+				return "", fmt.Errorf("%s", strings.Join(errs, ","))
+			}
+			return str, nil
+		}
+		if param.Type == TemplateParamTypeImage && str != "" && !templateImagePattern.MatchString(str) {
+			return "", fmt.Errorf("%s is not a valid image", str)
+		}
+		// the escapes of the quoted string of go are valid in the double-quoted scalar of yaml
+		return strconv.Quote(str), nil
+	case TemplateParamTypeInt:
+		var num float64
+		switch v := value.(type) {
+		case float64:
+			num = v
+		case int:
+			num = float64(v)
+		case string:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return "", err
+			}
+			num = float64(n)
+		default:
+			return "", fmt.Errorf("%v is not an integer", value)
+		}
+		if num != math.Trunc(num) {
+			return "", fmt.Errorf("%v is not an integer", value)
+		}
+		return strconv.FormatInt(int64(num), 10), nil
+	case TemplateParamTypePorts:
+		ports := []apiv1.ContainerPort{}
+		if err := convertTemplateParam(value, &ports); err != nil {
+			return "", err
+		}
+		for _, port := range ports {
+			if err := validate.ValidatePortNum(port.ContainerPort); err != nil {
+				return "", err
+			}
+		}
+		data, err := json.Marshal(ports)
+		return string(data), err
+	case TemplateParamTypeEnv:
+		envs := []apiv1.EnvVar{}
+		if m, ok := value.(map[string]interface{}); ok {
+			keys := []string{}
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				envs = append(envs, apiv1.EnvVar{Name: k, Value: fmt.Sprint(m[k])})
+			}
+		} else if err := convertTemplateParam(value, &envs); err != nil {
+			return "", err
+		}
+		for _, env := range envs {
+			if errs := validation.IsEnvVarName(env.Name); len(errs) != 0 {
+				return "", fmt.Errorf("env name %q is invalid: %s", env.Name, strings.Join(errs, ","))
+			}
+		}
+		data, err := json.Marshal(envs)
+		return string(data), err
+	}
+	return "", fmt.Errorf("the type %q is not supported", param.Type)
+}
+
+func convertTemplateParam(value, out interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
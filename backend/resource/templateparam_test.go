@@ -0,0 +1,100 @@
+package resource
+
+import (
+	"encoding/json"
+	"testing"
+
+	"kubecloud/backend/models"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTemplateParameters(t *testing.T) {
+	assert.Nil(t, ValidateTemplateParameters([]TemplateParameter{
+		{Name: "name", Type: TemplateParamTypeName, Default: "nginx"},
+		{Name: "image", Type: TemplateParamTypeImage, Default: "nginx:1.19"},
+		{Name: "replicas", Type: TemplateParamTypeInt, Default: float64(2)},
+		{Name: "env", Type: TemplateParamTypeEnv, Default: map[string]interface{}{"A": "1"}},
+	}))
+
+	for _, params := range [][]TemplateParameter{
+		{{Name: "1name", Type: TemplateParamTypeString}},
+		{{Name: "name", Type: TemplateParamTypeString}, {Name: "name", Type: TemplateParamTypeInt}},
+		{{Name: "name", Type: "bool"}},
+		{{Name: "name", Type: TemplateParamTypeName, Default: "Nginx"}},
+		{{Name: "image", Type: TemplateParamTypeImage, Default: "nginx 1.19"}},
+		{{Name: "replicas", Type: TemplateParamTypeInt, Default: 1.5}},
+		{{Name: "ports", Type: TemplateParamTypePorts, Default: []interface{}{map[string]interface{}{"containerPort": 70000}}}},
+		{{Name: "env", Type: TemplateParamTypeEnv, Default: map[string]interface{}{"A=B": "1"}}},
+	} {
+		assert.NotNil(t, ValidateTemplateParameters(params), params[0].Name)
+	}
+}
+
+func TestRenderTemplateSpec(t *testing.T) {
+	params := []TemplateParameter{
+		{Name: "name", Type: TemplateParamTypeName, Required: true},
+		{Name: "image", Type: TemplateParamTypeImage, Default: "nginx:1.19"},
+		{Name: "replicas", Type: TemplateParamTypeInt},
+		{Name: "message", Type: TemplateParamTypeString},
+		{Name: "ports", Type: TemplateParamTypePorts},
+		{Name: "env", Type: TemplateParamTypeEnv},
+	}
+	spec := `name: ${name}-svc
+image: ${image}
+replicas: ${replicas}
+message: ${message}
+ports: ${ports}
+env: ${env}
+shell: $${HOME} ${HOME}
+`
+	out, err := RenderTemplateSpec(spec, params, map[string]interface{}{
+		"name":     "web",
+		"replicas": "3",
+		"message":  "yes: #1\n\"quoted\" 'single'",
+		"ports":    []interface{}{map[string]interface{}{"containerPort": 80}},
+		"env":      map[string]interface{}{"B": 2, "A": "x"},
+	})
+	assert.Nil(t, err)
+	rendered := map[string]interface{}{}
+	assert.Nil(t, yaml.Unmarshal([]byte(out), &rendered), out)
+	assert.Equal(t, "web-svc", rendered["name"])
+	assert.Equal(t, "nginx:1.19", rendered["image"])
+	assert.Equal(t, float64(3), rendered["replicas"])
+	assert.Equal(t, "yes: #1\n\"quoted\" 'single'", rendered["message"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"containerPort": float64(80)}}, rendered["ports"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "A", "value": "x"},
+		map[string]interface{}{"name": "B", "value": "2"},
+	}, rendered["env"])
+	assert.Equal(t, "$${HOME} ${HOME}", rendered["shell"])
+
+	// the empty string is rendered as a quoted scalar, so it is not null
+	out, err = RenderTemplateSpec("message: ${message}", params[3:4], nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `message: ""`, out)
+
+	_, err = RenderTemplateSpec(spec, params, map[string]interface{}{})
+	assert.NotNil(t, err, "the required parameter is not set")
+	_, err = RenderTemplateSpec(spec, params, map[string]interface{}{"name": "web", "other": "x"})
+	assert.NotNil(t, err, "the parameter is not declared")
+	_, err = RenderTemplateSpec(spec, params, map[string]interface{}{"name": "web", "image": "nginx:1.19\nhostNetwork: true"})
+	assert.NotNil(t, err, "the image is not valid")
+	_, err = RenderTemplateSpec(spec, params, map[string]interface{}{"name": "web", "replicas": true})
+	assert.NotNil(t, err, "the replicas is not an integer")
+}
+
+// the legacy template has no kind, parameters and versions
+func TestLegacyTemplateVersion(t *testing.T) {
+	manifest := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 1\n" +
+		"  template:\n    spec:\n      nodeSelector:\n        role: app\n      containers:\n      - name: web\n        image: nginx:1.21\n" +
+		"        resources:\n          limits:\n            cpu: 500m\n            memory: 512Mi\n"
+	spec, _ := json.Marshal(map[string]string{"template": manifest})
+	tv := legacyTemplateVersion(&models.ZcloudTemplate{Name: "web", Spec: string(spec)})
+	assert.Equal(t, TemplateKindNative, tv.Kind)
+	tpl, err := renderTemplateVersion(tv, nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, manifest, tpl.(*NativeTemplate).Template)
+	}
+}
@@ -0,0 +1,211 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"kubecloud/backend/dao"
+	"kubecloud/backend/models"
+	"kubecloud/common"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	TopologyFormatVis = "vis"
+	TopologyFormatDot = "dot"
+
+	// the request rates of istio are the average of the last window
+	istioRequestRateWindow = "5m"
+	istioRequestRateQuery  = `sum(rate(istio_requests_total{reporter="destination",destination_service_namespace="%s"}[%s])) by (destination_service_name, destination_version)`
+)
+
+// TopologyRes builds the service topology of namespace from the ingress rules, services and endpoints
+// synchronized from the cluster
+type TopologyRes struct {
+	cluster       string
+	modelRule     *dao.IngressRuleModel
+	modelSvc      *dao.K8sServiceModel
+	modelEndpoint *dao.K8sEndpointModel
+	modelApp      *dao.AppModel
+	modelVersion  *dao.VersionModel
+}
+
+func NewTopologyRes(cluster string) *TopologyRes {
+	return &TopologyRes{
+		cluster:       cluster,
+		modelRule:     dao.NewIngressRuleModel(),
+		modelSvc:      dao.NewK8sServiceModel(),
+		modelEndpoint: dao.NewK8sEndpointModel(),
+		modelApp:      dao.NewAppModel(),
+		modelVersion:  dao.NewVersionModel(),
+	}
+}
+
+// GetTopology returns the graph of ingress host/path -> service -> application version -> pods,
+// the edges are labeled by the traffic weights and the istio request rates of the mesh applications,
+// the graph is the vis.js graph or the DOT of graphviz by format
+func (tr *TopologyRes) GetTopology(namespace, format string) (interface{}, error) {
+	if format != TopologyFormatVis && format != TopologyFormatDot {
+		return nil, common.NewBadRequest().SetCause(fmt.Errorf("the topology format must be %s or %s!", TopologyFormatVis, TopologyFormatDot))
+	}
+	graph, err := tr.buildTopology(namespace)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	if format == TopologyFormatVis {
+		return graph.ToVisGraph(), nil
+	}
+	var buf bytes.Buffer
+	if err := utils.GenerateDot(&buf, graph); err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	return buf.String(), nil
+}
+
+func (tr *TopologyRes) buildTopology(namespace string) (*utils.Dynamic, error) {
+	graph := utils.NewVizGraph()
+	res, err := tr.modelRule.List(tr.cluster, []string{namespace}, "", &utils.FilterQuery{})
+	if err != nil {
+		return nil, err
+	}
+	rules, _ := res.List.([]models.K8sIngressRule)
+	for _, rule := range rules {
+		path := rule.Path
+		if path == "" {
+			path = "/"
+		}
+		graph.AddEdge("ingress:"+rule.Host+path, "service:"+rule.ServiceName, map[string]string{
+			"port": strconv.Itoa(rule.ServicePort),
+		})
+	}
+	svcs, err := tr.modelSvc.ListServices(tr.cluster, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var rates map[string]map[string]float64
+	for i := range svcs {
+		svc := &svcs[i]
+		svcNode := "service:" + svc.Name
+		graph.Nodes[svcNode] = struct{}{}
+		backends := tr.getServiceBackends(svc)
+		if len(backends) == 0 {
+			continue
+		}
+		versionNames := tr.getVersionNames(namespace, svc.OwnerName)
+		if rates == nil && tr.isMeshApp(namespace, svc.OwnerName) {
+			if rates, err = tr.getRequestRates(namespace); err != nil {
+				beego.Warn(fmt.Sprintf("get the istio request rates of namespace %s failed: %v", namespace, err))
+				rates = make(map[string]map[string]float64)
+			}
+		}
+		versionWeight := make(map[string]int)
+		versionList := []string{}
+		for _, be := range backends {
+			podVersion := getVersionFromPodName(be.Name)
+			if _, ok := versionWeight[podVersion]; !ok {
+				versionList = append(versionList, podVersion)
+			}
+			versionWeight[podVersion] += be.Weight
+			graph.AddEdge(topologyVersionNode(svc, versionNames, podVersion), "pod:"+be.Name, map[string]string{
+				"weight": strconv.Itoa(be.Weight),
+			})
+		}
+		for _, podVersion := range versionList {
+			lbls := map[string]string{"weight": strconv.Itoa(versionWeight[podVersion])}
+			if rate, ok := rates[svc.Name][podVersion]; ok {
+				lbls["rps"] = strconv.FormatFloat(rate, 'f', 2, 64)
+			}
+			graph.AddEdge(svcNode, topologyVersionNode(svc, versionNames, podVersion), lbls)
+		}
+	}
+	return graph, nil
+}
+
+// getServiceBackends returns the pods of service and their weights, the pods of all ports are the same,
+// so the endpoint of the first port which has one is used
+func (tr *TopologyRes) getServiceBackends(svc *models.K8sService) []BackendServer {
+	for _, port := range svc.Ports {
+		ep, err := tr.modelEndpoint.Get(tr.cluster, svc.Namespace, svc.Name, int32(port.TargetPort))
+		if err != nil || len(ep.Addresses) == 0 {
+			continue
+		}
+		return getBackendServer(svc, ep)
+	}
+	return nil
+}
+
+// getVersionNames maps the pod versions of application to its versions
+func (tr *TopologyRes) getVersionNames(namespace, appname string) map[string]string {
+	names := make(map[string]string)
+	if appname == "" {
+		return names
+	}
+	versions, err := tr.modelVersion.GetVersionList(tr.cluster, namespace, appname)
+	if err != nil {
+		beego.Warn(fmt.Sprintf("get the versions of application %s failed: %v", appname, err))
+		return names
+	}
+	for _, item := range versions {
+		names[item.PodVersion] = item.Version
+	}
+	return names
+}
+
+func (tr *TopologyRes) isMeshApp(namespace, appname string) bool {
+	if appname == "" {
+		return false
+	}
+	app, err := tr.modelApp.GetAppByName(tr.cluster, namespace, appname)
+	if err != nil {
+		return false
+	}
+	return app.InjectServiceMesh == "true"
+}
+
+// getRequestRates returns the istio request rates of the services of namespace by pod version
+func (tr *TopologyRes) getRequestRates(namespace string) (map[string]map[string]float64, error) {
+	client, err := GetPromethusClient(tr.cluster)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	value, _, err := client.Query(ctx, fmt.Sprintf(istioRequestRateQuery, namespace, istioRequestRateWindow), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("the result type %s of request rates is not vector", value.Type())
+	}
+	rates := make(map[string]map[string]float64)
+	for _, sample := range vector {
+		svc := string(sample.Metric["destination_service_name"])
+		if rates[svc] == nil {
+			rates[svc] = make(map[string]float64)
+		}
+		rates[svc][string(sample.Metric["destination_version"])] = float64(sample.Value)
+	}
+	return rates, nil
+}
+
+func topologyVersionNode(svc *models.K8sService, versionNames map[string]string, podVersion string) string {
+	owner := svc.OwnerName
+	if owner == "" {
+		owner = svc.Name
+	}
+	version := podVersion
+	if name, ok := versionNames[podVersion]; ok && name != "" {
+		version = name
+	}
+	if version == "" {
+		version = DefaultVersion
+	}
+	return "version:" + owner + "/" + version
+}
@@ -0,0 +1,352 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kubecloud/backend/models"
+	"kubecloud/common"
+	"kubecloud/common/utils"
+
+	"github.com/astaxie/beego/orm"
+)
+
+const (
+	UnreachableReasonNoService   = "ServiceNotFound"
+	UnreachableReasonNoPort      = "ServicePortNotFound"
+	UnreachableReasonNoEndpoints = "NoEndpoints"
+	UnreachableReasonZeroWeight  = "ZeroWeight"
+
+	// the ip of the pods added by the simulated scaling, they are not running yet
+	simulatedPodIP = "pending"
+)
+
+// TrafficChange is the proposed change of the versions of an application, the versions are given by
+// the version or pod version of application
+type TrafficChange struct {
+	AppName string `json:"app_name"`
+	// Weights are the new traffic weights of the versions
+	Weights map[string]int `json:"weights,omitempty"`
+	// Replicas are the new replicas of the versions
+	Replicas       map[string]int `json:"replicas,omitempty"`
+	DeleteVersions []string       `json:"delete_versions,omitempty"`
+}
+
+type TrafficSimulationParam struct {
+	Changes []TrafficChange `json:"changes"`
+}
+
+// VersionTraffic is the pods and traffic weight of a version of service before and after the changes
+type VersionTraffic struct {
+	Version       string `json:"version"`
+	PodVersion    string `json:"pod_version"`
+	CurrentPods   int    `json:"current_pods"`
+	CurrentWeight int    `json:"current_weight"`
+	Pods          int    `json:"pods"`
+	Weight        int    `json:"weight"`
+}
+
+type ServiceTraffic struct {
+	Name     string           `json:"name"`
+	AppName  string           `json:"app_name,omitempty"`
+	Versions []VersionTraffic `json:"versions"`
+	// Backends are the pods and their weights after the changes
+	Backends []BackendServer `json:"backends"`
+}
+
+// UnreachablePath is the ingress path which can not be served after the changes
+type UnreachablePath struct {
+	ID          int64  `json:"id"`
+	Host        string `json:"host"`
+	Path        string `json:"path"`
+	ServiceName string `json:"service_name"`
+	ServicePort int    `json:"service_port"`
+	Reason      string `json:"reason"`
+	// WasReachable is false if the path can not be served before the changes either
+	WasReachable bool `json:"was_reachable"`
+}
+
+// TrafficSimulation is the traffic distribution of namespace after the proposed changes
+type TrafficSimulation struct {
+	Services         []ServiceTraffic  `json:"services"`
+	UnreachablePaths []UnreachablePath `json:"unreachable_paths"`
+	// ZeroEndpointServices are the services which have no endpoints after the changes
+	ZeroEndpointServices []string `json:"zero_endpoint_services"`
+}
+
+// trafficChange is the change of the pod versions of application
+type trafficChange struct {
+	weights  map[string]int
+	replicas map[string]int
+	deleted  map[string]bool
+}
+
+// SimulateTraffic computes the traffic distribution of the services and ingress paths of namespace after the changes
+// from the services and endpoints synchronized from the cluster, the weights of pods are computed by the same way as
+// the backends of ingress, and nothing is changed
+func (tr *TopologyRes) SimulateTraffic(namespace string, param TrafficSimulationParam) (*TrafficSimulation, error) {
+	changes, err := tr.resolveTrafficChanges(namespace, param)
+	if err != nil {
+		return nil, err
+	}
+	svcs, err := tr.modelSvc.ListServices(tr.cluster, namespace)
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	result := &TrafficSimulation{
+		Services:             []ServiceTraffic{},
+		UnreachablePaths:     []UnreachablePath{},
+		ZeroEndpointServices: []string{},
+	}
+	svcMap := make(map[string]*models.K8sService)
+	for i := range svcs {
+		svc := &svcs[i]
+		svcMap[svc.Name] = svc
+		if len(svc.Ports) == 0 {
+			continue
+		}
+		// the pods of all ports are the same, so the traffic is computed by the first port
+		current, simulated := tr.simulateBackends(svc, svc.Ports[0].TargetPort, changes[svc.OwnerName])
+		if len(simulated) == 0 {
+			result.ZeroEndpointServices = append(result.ZeroEndpointServices, svc.Name)
+		}
+		result.Services = append(result.Services, ServiceTraffic{
+			Name:     svc.Name,
+			AppName:  svc.OwnerName,
+			Versions: tr.getVersionTraffics(namespace, svc.OwnerName, current, simulated),
+			Backends: simulated,
+		})
+	}
+
+	res, err := tr.modelRule.List(tr.cluster, []string{namespace}, "", &utils.FilterQuery{})
+	if err != nil {
+		return nil, common.NewInternalServerError().SetCause(err)
+	}
+	rules, _ := res.List.([]models.K8sIngressRule)
+	for _, rule := range rules {
+		svc, ok := svcMap[rule.ServiceName]
+		var reason, currentReason string
+		if !ok {
+			reason, currentReason = UnreachableReasonNoService, UnreachableReasonNoService
+		} else {
+			targetPort := -1
+			for _, port := range svc.Ports {
+				if port.Port == rule.ServicePort {
+					targetPort = port.TargetPort
+				}
+			}
+			if targetPort == -1 {
+				reason, currentReason = UnreachableReasonNoPort, UnreachableReasonNoPort
+			} else {
+				current, simulated := tr.simulateBackends(svc, targetPort, changes[svc.OwnerName])
+				reason, currentReason = unreachableReason(simulated), unreachableReason(current)
+			}
+		}
+		if reason == "" {
+			continue
+		}
+		result.UnreachablePaths = append(result.UnreachablePaths, UnreachablePath{
+			ID:           rule.Id,
+			Host:         rule.Host,
+			Path:         utils.GetRootPath(rule.Path),
+			ServiceName:  rule.ServiceName,
+			ServicePort:  rule.ServicePort,
+			Reason:       reason,
+			WasReachable: currentReason == "",
+		})
+	}
+	return result, nil
+}
+
+// resolveTrafficChanges checks the changes, and returns the changes of pod versions by application
+func (tr *TopologyRes) resolveTrafficChanges(namespace string, param TrafficSimulationParam) (map[string]*trafficChange, error) {
+	changes := make(map[string]*trafficChange)
+	for _, item := range param.Changes {
+		if item.AppName == "" {
+			return nil, common.NewBadRequest().SetCause(fmt.Errorf("application name must be given!"))
+		}
+		if _, ok := changes[item.AppName]; ok {
+			return nil, common.NewBadRequest().SetCause(fmt.Errorf("the changes of application %s are duplicated!", item.AppName))
+		}
+		if _, err := tr.modelApp.GetAppByName(tr.cluster, namespace, item.AppName); err != nil {
+			if err == orm.ErrNoRows {
+				return nil, common.NewBadRequest().SetCause(fmt.Errorf("application %s is not existed!", item.AppName))
+			}
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+		versions, err := tr.modelVersion.GetVersionList(tr.cluster, namespace, item.AppName)
+		if err != nil {
+			return nil, common.NewInternalServerError().SetCause(err)
+		}
+		podVersion := func(version string) (string, error) {
+			for _, v := range versions {
+				if version == v.Version || version == v.PodVersion {
+					return v.PodVersion, nil
+				}
+			}
+			return "", common.NewBadRequest().SetCause(fmt.Errorf("the version %s of application %s is not existed!", version, item.AppName))
+		}
+		change := &trafficChange{
+			weights:  make(map[string]int),
+			replicas: make(map[string]int),
+			deleted:  make(map[string]bool),
+		}
+		for _, version := range item.DeleteVersions {
+			pv, err := podVersion(version)
+			if err != nil {
+				return nil, err
+			}
+			change.deleted[pv] = true
+		}
+		for version, weight := range item.Weights {
+			pv, err := podVersion(version)
+			if err != nil {
+				return nil, err
+			}
+			if weight < models.MIN_WEIGHT || weight > models.MAX_WEIGHT {
+				return nil, common.NewBadRequest().SetCause(fmt.Errorf("the weight of version %s must be in the range of %v to %v!",
+					version, models.MIN_WEIGHT, models.MAX_WEIGHT))
+			}
+			change.weights[pv] = weight
+		}
+		for version, replicas := range item.Replicas {
+			pv, err := podVersion(version)
+			if err != nil {
+				return nil, err
+			}
+			if replicas < common.ReplicasMin || replicas > common.ReplicasMax {
+				return nil, common.NewBadRequest().SetCause(fmt.Errorf("the replicas of version %s must be in the range of %v to %v!",
+					version, common.ReplicasMin, common.ReplicasMax))
+			}
+			change.replicas[pv] = replicas
+		}
+		for pv := range change.deleted {
+			if _, ok := change.weights[pv]; ok {
+				return nil, common.NewBadRequest().SetCause(fmt.Errorf("the weight of the deleted version %s can not be changed!", pv))
+			}
+			if _, ok := change.replicas[pv]; ok {
+				return nil, common.NewBadRequest().SetCause(fmt.Errorf("the replicas of the deleted version %s can not be changed!", pv))
+			}
+		}
+		changes[item.AppName] = change
+	}
+	return changes, nil
+}
+
+// simulateBackends returns the backends of the target port of service before and after the change
+func (tr *TopologyRes) simulateBackends(svc *models.K8sService, targetPort int, change *trafficChange) ([]BackendServer, []BackendServer) {
+	ep, err := tr.modelEndpoint.Get(tr.cluster, svc.Namespace, svc.Name, int32(targetPort))
+	if err != nil {
+		ep = &models.K8sEndpoint{Name: svc.Name, Namespace: svc.Namespace, Port: int32(targetPort)}
+	}
+	current := []BackendServer{}
+	if len(ep.Addresses) != 0 {
+		current = getBackendServer(svc, ep)
+	}
+	if change == nil {
+		return current, current
+	}
+	simSvc, simEp := simulateService(svc, ep, change)
+	simulated := []BackendServer{}
+	if len(simEp.Addresses) != 0 {
+		simulated = getBackendServer(simSvc, simEp)
+	}
+	return current, simulated
+}
+
+// simulateService returns the copies of the service and endpoint with the change, the weights are set to
+// the annotations of service like UpdateTrafficWeight, and the pods of endpoint are added or removed by replicas
+func simulateService(svc *models.K8sService, ep *models.K8sEndpoint, change *trafficChange) (*models.K8sService, *models.K8sEndpoint) {
+	annos := make(map[string]string)
+	utils.SimpleJsonUnmarshal(svc.Annotation, &annos)
+	for pv, weight := range change.weights {
+		if weight == models.MIN_WEIGHT || weight == models.MAX_WEIGHT {
+			delete(annos, IngressWeightAnnotationKeyPre+pv)
+		} else {
+			annos[IngressWeightAnnotationKeyPre+pv] = strconv.Itoa(weight)
+		}
+	}
+	for pv := range change.deleted {
+		delete(annos, IngressWeightAnnotationKeyPre+pv)
+	}
+	simSvc := *svc
+	simSvc.Annotation = utils.SimpleJsonMarshal(annos, "")
+
+	simEp := *ep
+	simEp.Addresses = []*models.K8sEndpointAddress{}
+	podNum := make(map[string]int)
+	for _, address := range ep.Addresses {
+		pv := getVersionFromPodName(address.TargetRefName)
+		if change.deleted[pv] {
+			continue
+		}
+		if replicas, ok := change.replicas[pv]; ok && podNum[pv] >= replicas {
+			continue
+		}
+		podNum[pv]++
+		simEp.Addresses = append(simEp.Addresses, address)
+	}
+	pvs := []string{}
+	for pv := range change.replicas {
+		pvs = append(pvs, pv)
+	}
+	sort.Strings(pvs)
+	for _, pv := range pvs {
+		for i := podNum[pv]; i < change.replicas[pv]; i++ {
+			// the version is read from the third last part of pod name
+			simEp.Addresses = append(simEp.Addresses, &models.K8sEndpointAddress{
+				IP:            simulatedPodIP,
+				TargetRefName: strings.Join([]string{svc.OwnerName, pv, "simulated", strconv.Itoa(i + 1)}, "-"),
+			})
+		}
+	}
+	return &simSvc, &simEp
+}
+
+func unreachableReason(backends []BackendServer) string {
+	if len(backends) == 0 {
+		return UnreachableReasonNoEndpoints
+	}
+	for _, be := range backends {
+		if be.Weight != models.MIN_WEIGHT {
+			return ""
+		}
+	}
+	return UnreachableReasonZeroWeight
+}
+
+// getVersionTraffics sums the pods and weights of the backends by version
+func (tr *TopologyRes) getVersionTraffics(namespace, appname string, current, simulated []BackendServer) []VersionTraffic {
+	return sumVersionTraffics(tr.getVersionNames(namespace, appname), current, simulated)
+}
+
+// sumVersionTraffics sums the pods and weights of the backends by pod version, versionNames are the versions of them
+func sumVersionTraffics(versionNames map[string]string, current, simulated []BackendServer) []VersionTraffic {
+	traffics := []VersionTraffic{}
+	index := make(map[string]int)
+	get := func(podVersion string) *VersionTraffic {
+		if i, ok := index[podVersion]; ok {
+			return &traffics[i]
+		}
+		version := versionNames[podVersion]
+		if version == "" {
+			version = podVersion
+		}
+		index[podVersion] = len(traffics)
+		traffics = append(traffics, VersionTraffic{Version: version, PodVersion: podVersion})
+		return &traffics[len(traffics)-1]
+	}
+	for _, be := range current {
+		traffic := get(getVersionFromPodName(be.Name))
+		traffic.CurrentPods++
+		traffic.CurrentWeight += be.Weight
+	}
+	for _, be := range simulated {
+		traffic := get(getVersionFromPodName(be.Name))
+		traffic.Pods++
+		traffic.Weight += be.Weight
+	}
+	return traffics
+}
@@ -0,0 +1,151 @@
+package resource
+
+import (
+	"testing"
+
+	"kubecloud/backend/models"
+	"kubecloud/common/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTrafficApp = "my-web-app"
+
+func newTestTrafficService(weights map[string]string) *models.K8sService {
+	annos := make(map[string]string)
+	for pv, weight := range weights {
+		annos[IngressWeightAnnotationKeyPre+pv] = weight
+	}
+	return &models.K8sService{
+		Name:       testTrafficApp,
+		Namespace:  "default",
+		OwnerName:  testTrafficApp,
+		Annotation: utils.SimpleJsonMarshal(annos, ""),
+	}
+}
+
+// newTestTrafficEndpoint returns the endpoint with the pods of deployment of the pod versions
+func newTestTrafficEndpoint(pods map[string]int) *models.K8sEndpoint {
+	ep := &models.K8sEndpoint{Name: testTrafficApp, Namespace: "default", Port: 8080}
+	for _, pv := range []string{"v1", "v2", "v3"} {
+		for i := 0; i < pods[pv]; i++ {
+			ep.Addresses = append(ep.Addresses, &models.K8sEndpointAddress{
+				IP:            "10.0.0." + pv[1:] + string(rune('0'+i)),
+				TargetRefName: GenerateDeployName(testTrafficApp, pv) + "-5d8f7c9b6-x" + string(rune('a'+i)),
+			})
+		}
+	}
+	return ep
+}
+
+func newTestTrafficChange() *trafficChange {
+	return &trafficChange{weights: map[string]int{}, replicas: map[string]int{}, deleted: map[string]bool{}}
+}
+
+func backendWeights(backends []BackendServer) map[string]int {
+	weights := make(map[string]int)
+	for _, be := range backends {
+		weights[getVersionFromPodName(be.Name)] += be.Weight
+	}
+	return weights
+}
+
+func TestGetVersionFromPodName(t *testing.T) {
+	cases := map[string]string{
+		"my-web-app-v1-5d8f7c9b6-xa": "v1",
+		"web-v1-5d8f7c9b6-xa":        "v1",
+		// the pods added by the simulated scaling
+		"my-web-app-v2-simulated-1":  "v2",
+		"my-web-app-v2-simulated-12": "v2",
+		"web-v2-simulated-1":         "v2",
+		"web-5d8f7c9b6-xa":           "",
+	}
+	for name, pv := range cases {
+		assert.Equal(t, pv, getVersionFromPodName(name), name)
+	}
+}
+
+func TestSimulateServiceWeights(t *testing.T) {
+	svc := newTestTrafficService(map[string]string{"v1": "80", "v2": "20"})
+	ep := newTestTrafficEndpoint(map[string]int{"v1": 2, "v2": 2})
+	current := getBackendServer(svc, ep)
+	assert.Equal(t, map[string]int{"v1": 80, "v2": 20}, backendWeights(current))
+
+	// the simulation without change is the current state
+	simSvc, simEp := simulateService(svc, ep, newTestTrafficChange())
+	assert.Equal(t, current, getBackendServer(simSvc, simEp))
+
+	// the simulated weights are the same as the ones computed from the service with the weights set
+	change := newTestTrafficChange()
+	change.weights["v1"] = 30
+	change.weights["v2"] = 70
+	simSvc, simEp = simulateService(svc, ep, change)
+	expected := getBackendServer(newTestTrafficService(map[string]string{"v1": "30", "v2": "70"}), ep)
+	assert.Equal(t, expected, getBackendServer(simSvc, simEp))
+	assert.Equal(t, map[string]int{"v1": 30, "v2": 70}, backendWeights(expected))
+
+	// the weight annotation is removed for the full or zero weight like updating the weights
+	change = newTestTrafficChange()
+	change.weights["v1"] = models.MAX_WEIGHT
+	change.weights["v2"] = models.MIN_WEIGHT
+	simSvc, _ = simulateService(svc, ep, change)
+	assert.Equal(t, newTestTrafficService(nil).Annotation, simSvc.Annotation)
+	// the original service is not changed
+	assert.Equal(t, newTestTrafficService(map[string]string{"v1": "80", "v2": "20"}).Annotation, svc.Annotation)
+}
+
+func TestSimulateServiceReplicas(t *testing.T) {
+	svc := newTestTrafficService(map[string]string{"v2": "40"})
+	ep := newTestTrafficEndpoint(map[string]int{"v1": 2, "v2": 2})
+	current := getBackendServer(svc, ep)
+
+	change := newTestTrafficChange()
+	change.replicas["v1"] = 1
+	change.replicas["v2"] = 4
+	simSvc, simEp := simulateService(svc, ep, change)
+	assert.Equal(t, 4, len(ep.Addresses))
+	assert.Equal(t, 5, len(simEp.Addresses))
+	assert.Equal(t, ep.Addresses[0], simEp.Addresses[0])
+	added := []string{}
+	for _, address := range simEp.Addresses[3:] {
+		assert.Equal(t, simulatedPodIP, address.IP)
+		added = append(added, address.TargetRefName)
+	}
+	assert.Equal(t, []string{"my-web-app-v2-simulated-3", "my-web-app-v2-simulated-4"}, added)
+
+	simulated := getBackendServer(simSvc, simEp)
+	// the pods of the version share its weight, the other versions share the rest
+	assert.Equal(t, map[string]int{"v1": 60, "v2": 40}, backendWeights(simulated))
+	traffics := sumVersionTraffics(map[string]string{"v1": "1.0.0", "v2": "1.1.0"}, current, simulated)
+	assert.Equal(t, []VersionTraffic{
+		{Version: "1.0.0", PodVersion: "v1", CurrentPods: 2, CurrentWeight: 60, Pods: 1, Weight: 60},
+		{Version: "1.1.0", PodVersion: "v2", CurrentPods: 2, CurrentWeight: 40, Pods: 4, Weight: 40},
+	}, traffics)
+}
+
+func TestSimulateServiceDelete(t *testing.T) {
+	svc := newTestTrafficService(map[string]string{"v1": "70", "v2": "30"})
+	ep := newTestTrafficEndpoint(map[string]int{"v1": 2, "v2": 1})
+	current := getBackendServer(svc, ep)
+
+	change := newTestTrafficChange()
+	change.deleted["v1"] = true
+	simSvc, simEp := simulateService(svc, ep, change)
+	simulated := getBackendServer(simSvc, simEp)
+	// the simulation is the state computed from the service and endpoint without the deleted version
+	expected := getBackendServer(newTestTrafficService(map[string]string{"v2": "30"}), newTestTrafficEndpoint(map[string]int{"v2": 1}))
+	assert.Equal(t, expected, simulated)
+	assert.Equal(t, map[string]int{"v2": 30}, backendWeights(simulated))
+	assert.Equal(t, "", unreachableReason(simulated))
+
+	traffics := sumVersionTraffics(nil, current, simulated)
+	assert.Equal(t, []VersionTraffic{
+		{Version: "v1", PodVersion: "v1", CurrentPods: 2, CurrentWeight: 70},
+		{Version: "v2", PodVersion: "v2", CurrentPods: 1, CurrentWeight: 30, Pods: 1, Weight: 30},
+	}, traffics)
+
+	change.deleted["v2"] = true
+	simSvc, simEp = simulateService(svc, ep, change)
+	assert.Equal(t, 0, len(simEp.Addresses))
+	assert.Equal(t, UnreachableReasonNoEndpoints, unreachableReason(getBackendServer(simSvc, simEp)))
+}
@@ -0,0 +1,246 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kubecloud/backend/service"
+	"kubecloud/backend/util/kubeutil"
+	"kubecloud/backend/util/labels"
+	"kubecloud/common/keyword"
+
+	"github.com/astaxie/beego"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// UnstructuredFieldManager is the field manager of the server-side apply of the unstructured objects
+	UnstructuredFieldManager = "kubecloud"
+	// CreatedByAnnotationKey marks the unstructured object created by the application, the objects which existed
+	// before are only taken over by the server-side apply, they are not deleted with the application
+	CreatedByAnnotationKey = "kubecloud/created-by"
+)
+
+// the kinds deployed by the typed clients, the other kinds are deployed as unstructured objects
+func isTypedResourceKind(kind string) bool {
+	switch kind {
+	case AppKindDeployment, AppKindStatefulSet, AppKindDaemonSet, AppKindJob, AppKindCronJob,
+		ServiceKind, IngressKind, ConfigMapKind, SecretKind:
+		return true
+	}
+	return false
+}
+
+func validateUnstructuredObject(obj *ResObject) error {
+	u, ok := obj.Object.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("the resource object can not be decoded!")
+	}
+	if u.GetAPIVersion() == "" || u.GetKind() == "" {
+		return fmt.Errorf("the apiVersion and kind of the resource object must be set!")
+	}
+	if strings.TrimSpace(u.GetName()) == "" {
+		return fmt.Errorf("the name of %s can not be empty!", u.GetKind())
+	}
+	if strings.ToLower(u.GetKind()) == "namespace" {
+		return fmt.Errorf("the system does not support this resource kind:%s!", strings.ToLower(u.GetKind()))
+	}
+	return nil
+}
+
+// getObjectOwner finds the application of the object by the owner annotation or the application label,
+// the object is owned by the only application of the template if neither of them is set
+func getObjectOwner(obj *unstructured.Unstructured, appTpls []*NativeAppTemplate) *NativeAppTemplate {
+	owner := obj.GetAnnotations()[OwnerNameAnnotationKey]
+	if owner == "" {
+		owner = obj.GetLabels()[keyword.LABEL_APPNAME_KEY]
+	}
+	if owner == "" {
+		if len(appTpls) == 1 {
+			return appTpls[0]
+		}
+		return nil
+	}
+	for _, item := range appTpls {
+		if item.GetAppName() == owner {
+			return item
+		}
+	}
+	return nil
+}
+
+func (tp *NativeAppTemplate) newUnstructuredObject(old *unstructured.Unstructured, namespace string) *unstructured.Unstructured {
+	obj := old.DeepCopy()
+	obj.SetNamespace(namespace)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[OwnerNameAnnotationKey] = tp.GetAppName()
+	obj.SetAnnotations(annotations)
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = make(map[string]string)
+	}
+	objLabels[keyword.LABEL_APPNAME_KEY] = tp.GetAppName()
+	obj.SetLabels(objLabels)
+	return obj
+}
+
+// resourceInterface resolves the resource of the object by the rest mapper of the cluster,
+// only the namespaced resources can be owned by the application
+func (kr *KubeAppRes) resourceInterface(obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	mapping, err := kubeutil.GetRESTMapping(kr.cluster, kr.client.Discovery(), obj.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return nil, fmt.Errorf("%s %s is cluster scoped, it can not be owned by the application!", obj.GetKind(), obj.GetName())
+	}
+	client, err := service.GetDynamicClient(kr.cluster)
+	if err != nil {
+		return nil, err
+	}
+	return client.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+}
+
+// CreateObjects applies the unstructured objects of the application by the server-side apply,
+// the fields managed by others are kept. The objects which did not exist before are returned,
+// they are created even if an error is returned, so that the caller can roll them back
+func (kr *KubeAppRes) CreateObjects(objList []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	created := []*unstructured.Unstructured{}
+	for _, obj := range objList {
+		res, err := kr.resourceInterface(obj)
+		if err != nil {
+			return created, err
+		}
+		existed := true
+		live, err := res.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return created, fmt.Errorf("get %s %s failed: %v", obj.GetKind(), obj.GetName(), err)
+			}
+			existed = false
+		}
+		// the marker is applied again, or the server-side apply removes it as a field no longer managed
+		createdBy := obj.GetAnnotations()[OwnerNameAnnotationKey]
+		if existed {
+			createdBy = live.GetAnnotations()[CreatedByAnnotationKey]
+		}
+		if createdBy != "" {
+			obj.SetAnnotations(labels.AddLabel(obj.GetAnnotations(), CreatedByAnnotationKey, createdBy))
+		}
+		if _, err := applyUnstructured(res, obj, false); err != nil {
+			return created, fmt.Errorf("apply %s %s failed: %v", obj.GetKind(), obj.GetName(), err)
+		}
+		if !existed {
+			created = append(created, obj)
+		}
+		kr.gitOpsResList = append(kr.gitOpsResList, obj)
+	}
+	return created, nil
+}
+
+// applyUnstructured creates or patches the object by the server-side apply as the field manager of the system
+func applyUnstructured(res dynamic.ResourceInterface, obj *unstructured.Unstructured, dryRun bool) (*unstructured.Unstructured, error) {
+	applied := obj.DeepCopy()
+	applied.SetResourceVersion("")
+	applied.SetManagedFields(nil)
+	data, err := json.Marshal(applied)
+	if err != nil {
+		return nil, err
+	}
+	force := true
+	options := metav1.PatchOptions{FieldManager: UnstructuredFieldManager, Force: &force}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return res.Patch(context.TODO(), obj.GetName(), types.ApplyPatchType, data, options)
+}
+
+// deletableObject checks the live object is created by the owner application of the object,
+// the persistent volume claims are never deleted implicitly for the data in them
+func deletableObject(obj, live *unstructured.Unstructured) (bool, string) {
+	if strings.EqualFold(obj.GetKind(), "PersistentVolumeClaim") {
+		return false, "the persistent volume claim must be deleted manually"
+	}
+	owner := obj.GetAnnotations()[OwnerNameAnnotationKey]
+	if createdBy := live.GetAnnotations()[CreatedByAnnotationKey]; owner == "" || createdBy != owner {
+		return false, fmt.Sprintf("it is not created by application %s", owner)
+	}
+	return true, ""
+}
+
+// deleteObjects deletes the objects created by the application, the others are kept
+func (kr *KubeAppRes) deleteObjects(objList []*unstructured.Unstructured) error {
+	for _, obj := range objList {
+		res, err := kr.resourceInterface(obj)
+		if err != nil {
+			return err
+		}
+		live, err := res.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("get %s %s failed: %v", obj.GetKind(), obj.GetName(), err)
+		}
+		if ok, reason := deletableObject(obj, live); !ok {
+			beego.Warn(fmt.Sprintf("%s %s is kept: %s", obj.GetKind(), obj.GetName(), reason))
+			continue
+		}
+		if err := res.Delete(context.TODO(), obj.GetName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("delete %s %s failed: %v", obj.GetKind(), obj.GetName(), err)
+		}
+		obj.SetAnnotations(labels.AddLabel(obj.GetAnnotations(), keyword.DELETE_LABLE, keyword.DELETE_LABLE_VALUE))
+		kr.gitOpsResList = append(kr.gitOpsResList, obj)
+		beego.Warn(fmt.Sprintf("delete %s %s successfully!", obj.GetKind(), obj.GetName()))
+	}
+	return nil
+}
+
+func (kr *KubeAppRes) updateObjects(oldObjList, newObjList interface{}) error {
+	newObjs, _ := newObjList.([]*unstructured.Unstructured)
+	if _, err := kr.CreateObjects(newObjs); err != nil {
+		return err
+	}
+	delObjs := []*unstructured.Unstructured{}
+	oldObjs, _ := oldObjList.([]*unstructured.Unstructured)
+	for _, old := range oldObjs {
+		found := false
+		for _, new := range newObjs {
+			if old.GroupVersionKind().GroupKind() == new.GroupVersionKind().GroupKind() && old.GetName() == new.GetName() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			delObjs = append(delObjs, old)
+		}
+	}
+	// delete the objects if only the old app has them
+	return kr.deleteObjects(delObjs)
+}
+
+func (kr *KubeAppRes) getUnstructured(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	res, err := kr.resourceInterface(obj)
+	if err != nil {
+		return nil, err
+	}
+	return res.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+}
+
+func (kr *KubeAppRes) dryRunUnstructured(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	res, err := kr.resourceInterface(obj)
+	if err != nil {
+		return nil, err
+	}
+	return applyUnstructured(res, obj, true)
+}
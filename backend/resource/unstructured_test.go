@@ -0,0 +1,41 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestUnstructured(kind string, annotations map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetName("demo")
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func TestDeletableObject(t *testing.T) {
+	owned := map[string]string{OwnerNameAnnotationKey: "web"}
+	cases := []struct {
+		name      string
+		obj       *unstructured.Unstructured
+		live      *unstructured.Unstructured
+		deletable bool
+	}{
+		{"created by the application", newTestUnstructured("PodDisruptionBudget", owned),
+			newTestUnstructured("PodDisruptionBudget", map[string]string{CreatedByAnnotationKey: "web"}), true},
+		{"taken over by the application", newTestUnstructured("PodDisruptionBudget", owned),
+			newTestUnstructured("PodDisruptionBudget", owned), false},
+		{"created by other application", newTestUnstructured("PodDisruptionBudget", owned),
+			newTestUnstructured("PodDisruptionBudget", map[string]string{CreatedByAnnotationKey: "api"}), false},
+		{"no owner", newTestUnstructured("PodDisruptionBudget", nil), newTestUnstructured("PodDisruptionBudget", nil), false},
+		{"persistent volume claim", newTestUnstructured("PersistentVolumeClaim", owned),
+			newTestUnstructured("PersistentVolumeClaim", map[string]string{CreatedByAnnotationKey: "web"}), false},
+	}
+	for _, c := range cases {
+		deletable, _ := deletableObject(c.obj, c.live)
+		assert.Equal(t, c.deletable, deletable, c.name)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"path"
 
@@ -23,7 +24,7 @@ type InClusterHeapsterClient struct {
 }
 
 type RequestInterface interface {
-	DoRaw() ([]byte, error)
+	DoRaw(ctx context.Context) ([]byte, error)
 }
 
 func NewHeapsterClient(cluster string) (HeapsterClient, error) {
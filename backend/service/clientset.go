@@ -49,5 +49,6 @@ func UpdateClientset(cluster string) (client kubernetes.Interface, err error) {
 		return
 	}
 	clusterClientsetMap[cluster] = client
+	resetDynamicClient(cluster)
 	return
 }
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"sync"
+
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	clusterDynamicClientMapMutex sync.RWMutex
+	clusterDynamicClientMap      = make(map[string]dynamic.Interface)
+)
+
+// GetDynamicClient returns the dynamic client of the cluster, which is used to apply the resources
+// without typed clients, such as the custom resources
+func GetDynamicClient(cluster string) (client dynamic.Interface, err error) {
+	clusterDynamicClientMapMutex.RLock()
+	client, ok := clusterDynamicClientMap[cluster]
+	clusterDynamicClientMapMutex.RUnlock()
+	if ok {
+		return client, nil
+	}
+	clusterDynamicClientMapMutex.Lock()
+	defer clusterDynamicClientMapMutex.Unlock()
+	client, ok = clusterDynamicClientMap[cluster]
+	if !ok {
+		client, err = dynamicClientProvider(cluster)
+		if err == nil {
+			clusterDynamicClientMap[cluster] = client
+		}
+	}
+	return client, err
+}
+
+// resetDynamicClient drops the cached dynamic client of the cluster, it is rebuilt by the new config of the cluster
+func resetDynamicClient(cluster string) {
+	clusterDynamicClientMapMutex.Lock()
+	defer clusterDynamicClientMapMutex.Unlock()
+	delete(clusterDynamicClientMap, cluster)
+}
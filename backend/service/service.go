@@ -8,6 +8,9 @@ import (
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/config"
 	"github.com/astaxie/beego/utils"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/clientcmd"
@@ -15,6 +18,8 @@ import (
 
 var clientsetProvider func(cluster string) (kubernetes.Interface, error)
 
+var dynamicClientProvider func(cluster string) (dynamic.Interface, error)
+
 var appConfigProvider func() config.Configer
 
 func Init() {
@@ -27,6 +32,15 @@ func Init() {
 		return kubernetes.NewForConfig(config)
 	}
 
+	dynamicClientProvider = func(cluster string) (dynamic.Interface, error) {
+		configPath := path.Join(beego.AppConfig.String("k8s::configPath"), cluster)
+		config, err := clientcmd.BuildConfigFromFlags("", configPath)
+		if err != nil {
+			return nil, err
+		}
+		return dynamic.NewForConfig(config)
+	}
+
 	appConfigProvider = func() config.Configer {
 		return beego.AppConfig
 	}
@@ -37,6 +51,10 @@ func InitMock() {
 		return fake.NewSimpleClientset(), nil
 	}
 
+	dynamicClientProvider = func(cluster string) (dynamic.Interface, error) {
+		return dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), nil
+	}
+
 	appConfigContext := struct {
 		once     sync.Once
 		configer config.Configer
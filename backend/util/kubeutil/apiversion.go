@@ -0,0 +1,184 @@
+package kubeutil
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	AppsV1             = "apps/v1"
+	AppsV1beta1        = "apps/v1beta1"
+	ExtensionsV1beta1  = "extensions/v1beta1"
+	BatchV1            = "batch/v1"
+	BatchV1beta1       = "batch/v1beta1"
+	NetworkingV1       = "networking.k8s.io/v1"
+	AutoscalingV2      = "autoscaling/v2"
+	AutoscalingV2beta2 = "autoscaling/v2beta2"
+
+	// the negotiated versions will be refreshed after the ttl, because the cluster may be upgraded
+	apiVersionsTTL = 10 * time.Minute
+)
+
+// APIVersions is the group versions of the workload, ingress and autoscaler resources served by a cluster,
+// the GA version is preferred if the cluster serves it
+type APIVersions struct {
+	Deployment  string
+	StatefulSet string
+	DaemonSet   string
+	ReplicaSet  string
+	CronJob     string
+	Ingress     string
+	// HorizontalPodAutoscaler is empty if the cluster serves neither autoscaling/v2 nor v2beta2
+	HorizontalPodAutoscaler string
+}
+
+// GAAPIVersions is used if the versions of the cluster can not be discovered
+var GAAPIVersions = APIVersions{
+	Deployment:  AppsV1,
+	StatefulSet: AppsV1,
+	DaemonSet:   AppsV1,
+	ReplicaSet:  AppsV1,
+	CronJob:     BatchV1,
+	Ingress:     NetworkingV1,
+
+	HorizontalPodAutoscaler: AutoscalingV2,
+}
+
+type apiVersionsItem struct {
+	versions APIVersions
+	expireAt time.Time
+}
+
+var (
+	apiVersionsCache = make(map[string]apiVersionsItem)
+	apiVersionsMutex sync.RWMutex
+)
+
+// GetAPIVersions returns the negotiated versions of the cluster, the result is cached for a while,
+// the GA version is used for the resources failed to negotiate and they are negotiated again next time
+func GetAPIVersions(cluster string, client discovery.DiscoveryInterface) APIVersions {
+	apiVersionsMutex.RLock()
+	item, ok := apiVersionsCache[cluster]
+	apiVersionsMutex.RUnlock()
+	if ok && time.Now().Before(item.expireAt) {
+		return item.versions
+	}
+	versions, err := NegotiateAPIVersions(client)
+	if err != nil {
+		beego.Warn(fmt.Sprintf("negotiate api versions of cluster %s failed: %v, use the GA versions of them!", cluster, err))
+		return versions
+	}
+	apiVersionsMutex.Lock()
+	apiVersionsCache[cluster] = apiVersionsItem{versions: versions, expireAt: time.Now().Add(apiVersionsTTL)}
+	apiVersionsMutex.Unlock()
+	return versions
+}
+
+// NegotiateAPIVersions discovers the group versions served by the cluster,
+// the first one of the candidates which serves the resource is chosen,
+// the resources are negotiated one by one, the GA version is kept for the ones failed to negotiate
+// and the failures are returned together
+func NegotiateAPIVersions(client discovery.DiscoveryInterface) (APIVersions, error) {
+	served := make(map[string]map[string]bool)
+	// negotiate returns empty if none of the candidates serves the resource
+	negotiate := func(resource string, candidates ...string) (string, error) {
+		for _, gv := range candidates {
+			resources, ok := served[gv]
+			if !ok {
+				list, err := client.ServerResourcesForGroupVersion(gv)
+				// the group version is not served if it is not found
+				if err != nil && !errors.IsNotFound(err) {
+					return "", fmt.Errorf("discover %s of %s failed: %v", resource, gv, err)
+				}
+				resources = make(map[string]bool)
+				if list != nil {
+					for _, res := range list.APIResources {
+						resources[res.Name] = true
+					}
+				}
+				served[gv] = resources
+			}
+			if resources[resource] {
+				return gv, nil
+			}
+		}
+		return "", nil
+	}
+	versions := GAAPIVersions
+	errs := []error{}
+	for _, item := range []struct {
+		resource   string
+		version    *string
+		candidates []string
+	}{
+		{"deployments", &versions.Deployment, []string{AppsV1, AppsV1beta1, ExtensionsV1beta1}},
+		{"statefulsets", &versions.StatefulSet, []string{AppsV1, AppsV1beta1}},
+		{"daemonsets", &versions.DaemonSet, []string{AppsV1, ExtensionsV1beta1}},
+		{"replicasets", &versions.ReplicaSet, []string{AppsV1, ExtensionsV1beta1}},
+		{"cronjobs", &versions.CronJob, []string{BatchV1, BatchV1beta1}},
+		{"ingresses", &versions.Ingress, []string{NetworkingV1, ExtensionsV1beta1}},
+	} {
+		gv, err := negotiate(item.resource, item.candidates...)
+		if err == nil && gv == "" {
+			err = fmt.Errorf("the cluster serves none of %v for %s", item.candidates, item.resource)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		*item.version = gv
+	}
+	// the autoscaler is optional, the other resources can be used without it
+	gv, err := negotiate("horizontalpodautoscalers", AutoscalingV2, AutoscalingV2beta2)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		if gv == "" {
+			beego.Warn("the cluster serves no horizontalpodautoscalers")
+		}
+		versions.HorizontalPodAutoscaler = gv
+	}
+	return versions, utilerrors.NewAggregate(errs)
+}
+
+// GroupVersionResource returns the negotiated resource, such as deployments or ingresses
+func (v APIVersions) GroupVersionResource(resource string) schema.GroupVersionResource {
+	var gv string
+	switch resource {
+	case "deployments":
+		gv = v.Deployment
+	case "statefulsets":
+		gv = v.StatefulSet
+	case "daemonsets":
+		gv = v.DaemonSet
+	case "replicasets":
+		gv = v.ReplicaSet
+	case "cronjobs":
+		gv = v.CronJob
+	case "ingresses":
+		gv = v.Ingress
+	case "horizontalpodautoscalers":
+		gv = v.HorizontalPodAutoscaler
+	}
+	groupVersion, _ := schema.ParseGroupVersion(gv)
+	return groupVersion.WithResource(resource)
+}
+
+// Informer returns the shared informer of the negotiated version, the objects of it
+// should be converted by ToDeployment, ToIngress and so on
+func (v APIVersions) Informer(factory informers.SharedInformerFactory, resource string) (cache.SharedIndexInformer, error) {
+	informer, err := factory.ForResource(v.GroupVersionResource(resource))
+	if err != nil {
+		return nil, err
+	}
+	return informer.Informer(), nil
+}
@@ -0,0 +1,93 @@
+package kubeutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+// failingDiscovery fails to discover the given group versions
+type failingDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	failed map[string]bool
+}
+
+func (d *failingDiscovery) ServerResourcesForGroupVersion(gv string) (*metav1.APIResourceList, error) {
+	if d.failed[gv] {
+		return nil, fmt.Errorf("the server is currently unable to handle the request")
+	}
+	return d.FakeDiscovery.ServerResourcesForGroupVersion(gv)
+}
+
+func newTestDiscovery(failed ...string) *failingDiscovery {
+	resources := func(gv string, names ...string) *metav1.APIResourceList {
+		list := &metav1.APIResourceList{GroupVersion: gv}
+		for _, name := range names {
+			list.APIResources = append(list.APIResources, metav1.APIResource{Name: name})
+		}
+		return list
+	}
+	d := &failingDiscovery{
+		FakeDiscovery: &fakediscovery.FakeDiscovery{Fake: &kubetesting.Fake{}},
+		failed:        map[string]bool{},
+	}
+	d.Resources = []*metav1.APIResourceList{
+		resources(AppsV1beta1, "deployments", "statefulsets"),
+		resources(ExtensionsV1beta1, "deployments", "daemonsets", "replicasets", "ingresses"),
+		resources(BatchV1beta1, "cronjobs"),
+		resources(AutoscalingV2beta2, "horizontalpodautoscalers"),
+	}
+	for _, gv := range failed {
+		d.failed[gv] = true
+	}
+	return d
+}
+
+func TestNegotiateAPIVersions(t *testing.T) {
+	versions, err := NegotiateAPIVersions(newTestDiscovery())
+	assert.Nil(t, err)
+	assert.Equal(t, APIVersions{
+		Deployment:              AppsV1beta1,
+		StatefulSet:             AppsV1beta1,
+		DaemonSet:               ExtensionsV1beta1,
+		ReplicaSet:              ExtensionsV1beta1,
+		CronJob:                 BatchV1beta1,
+		Ingress:                 ExtensionsV1beta1,
+		HorizontalPodAutoscaler: AutoscalingV2beta2,
+	}, versions)
+}
+
+func TestNegotiateAPIVersionsPartialFailure(t *testing.T) {
+	// only the resources whose candidates failed to be discovered use the GA versions
+	versions, err := NegotiateAPIVersions(newTestDiscovery(BatchV1))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cronjobs")
+	assert.Equal(t, BatchV1, versions.CronJob)
+	assert.Equal(t, AppsV1beta1, versions.Deployment)
+	assert.Equal(t, ExtensionsV1beta1, versions.Ingress)
+	assert.Equal(t, AutoscalingV2beta2, versions.HorizontalPodAutoscaler)
+
+	versions, err = NegotiateAPIVersions(newTestDiscovery(AutoscalingV2))
+	assert.NotNil(t, err)
+	assert.Equal(t, AutoscalingV2, versions.HorizontalPodAutoscaler)
+	assert.Equal(t, BatchV1beta1, versions.CronJob)
+}
+
+func TestNegotiateAPIVersionsNotServed(t *testing.T) {
+	d := newTestDiscovery()
+	d.Resources = d.Resources[:len(d.Resources)-1]
+	versions, err := NegotiateAPIVersions(d)
+	assert.Nil(t, err)
+	assert.Equal(t, "", versions.HorizontalPodAutoscaler)
+
+	d.Resources = d.Resources[1:]
+	versions, err = NegotiateAPIVersions(d)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "statefulsets")
+	assert.Equal(t, AppsV1, versions.StatefulSet)
+	assert.Equal(t, ExtensionsV1beta1, versions.Deployment)
+}
@@ -0,0 +1,289 @@
+package kubeutil
+
+import (
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ConvertObject converts the object between the group versions which have the same schema,
+// such as apps/v1beta1 and apps/v1 deployment
+func ConvertObject(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func ToDeployment(obj interface{}) (*appsv1.Deployment, error) {
+	switch t := obj.(type) {
+	case *appsv1.Deployment:
+		return t, nil
+	case *appsv1beta1.Deployment, *extensions.Deployment:
+		deploy := &appsv1.Deployment{}
+		if err := ConvertObject(t, deploy); err != nil {
+			return nil, err
+		}
+		deploy.APIVersion = AppsV1
+		return deploy, nil
+	}
+	return nil, fmt.Errorf("object %T is not a deployment", obj)
+}
+
+func ToStatefulSet(obj interface{}) (*appsv1.StatefulSet, error) {
+	switch t := obj.(type) {
+	case *appsv1.StatefulSet:
+		return t, nil
+	case *appsv1beta1.StatefulSet:
+		sts := &appsv1.StatefulSet{}
+		if err := ConvertObject(t, sts); err != nil {
+			return nil, err
+		}
+		sts.APIVersion = AppsV1
+		return sts, nil
+	}
+	return nil, fmt.Errorf("object %T is not a statefulset", obj)
+}
+
+func ToDaemonSet(obj interface{}) (*appsv1.DaemonSet, error) {
+	switch t := obj.(type) {
+	case *appsv1.DaemonSet:
+		return t, nil
+	case *extensions.DaemonSet:
+		ds := &appsv1.DaemonSet{}
+		if err := ConvertObject(t, ds); err != nil {
+			return nil, err
+		}
+		ds.APIVersion = AppsV1
+		return ds, nil
+	}
+	return nil, fmt.Errorf("object %T is not a daemonset", obj)
+}
+
+func ToReplicaSet(obj interface{}) (*appsv1.ReplicaSet, error) {
+	switch t := obj.(type) {
+	case *appsv1.ReplicaSet:
+		return t, nil
+	case *extensions.ReplicaSet:
+		rs := &appsv1.ReplicaSet{}
+		if err := ConvertObject(t, rs); err != nil {
+			return nil, err
+		}
+		rs.APIVersion = AppsV1
+		return rs, nil
+	}
+	return nil, fmt.Errorf("object %T is not a replicaset", obj)
+}
+
+func ToCronJob(obj interface{}) (*batchv1.CronJob, error) {
+	switch t := obj.(type) {
+	case *batchv1.CronJob:
+		return t, nil
+	case *batchv1beta1.CronJob:
+		cj := &batchv1.CronJob{}
+		if err := ConvertObject(t, cj); err != nil {
+			return nil, err
+		}
+		cj.APIVersion = BatchV1
+		return cj, nil
+	}
+	return nil, fmt.Errorf("object %T is not a cronjob", obj)
+}
+
+func ToHorizontalPodAutoscaler(obj interface{}) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	switch t := obj.(type) {
+	case *autoscalingv2.HorizontalPodAutoscaler:
+		return t, nil
+	case *autoscalingv2beta2.HorizontalPodAutoscaler:
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		if err := ConvertObject(t, hpa); err != nil {
+			return nil, err
+		}
+		hpa.APIVersion = AutoscalingV2
+		return hpa, nil
+	}
+	return nil, fmt.Errorf("object %T is not a horizontal pod autoscaler", obj)
+}
+
+func ToIngress(obj interface{}) (*networkingv1.Ingress, error) {
+	switch t := obj.(type) {
+	case *networkingv1.Ingress:
+		return t, nil
+	case *extensions.Ingress:
+		return IngressFromLegacy(t), nil
+	}
+	return nil, fmt.Errorf("object %T is not an ingress", obj)
+}
+
+// DecodeIngress decodes the ingress of networking.k8s.io/v1, the ingress of other version
+// such as extensions/v1beta1 is converted, the ingress without apiVersion is taken as legacy one
+func DecodeIngress(data []byte) (*networkingv1.Ingress, error) {
+	var meta struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	if meta.APIVersion == NetworkingV1 {
+		ing := &networkingv1.Ingress{}
+		if err := json.Unmarshal(data, ing); err != nil {
+			return nil, err
+		}
+		return ing, nil
+	}
+	legacy := &extensions.Ingress{}
+	if err := json.Unmarshal(data, legacy); err != nil {
+		return nil, err
+	}
+	return IngressFromLegacy(legacy), nil
+}
+
+// IngressFromLegacy converts extensions/v1beta1 ingress to networking.k8s.io/v1
+func IngressFromLegacy(in *extensions.Ingress) *networkingv1.Ingress {
+	if in == nil {
+		return nil
+	}
+	out := &networkingv1.Ingress{
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	out.Kind = "Ingress"
+	out.APIVersion = NetworkingV1
+	out.Spec.IngressClassName = in.Spec.IngressClassName
+	if in.Spec.Backend != nil {
+		backend := ingressBackendFromLegacy(*in.Spec.Backend)
+		out.Spec.DefaultBackend = &backend
+	}
+	for _, tls := range in.Spec.TLS {
+		out.Spec.TLS = append(out.Spec.TLS, networkingv1.IngressTLS{
+			Hosts:      append([]string{}, tls.Hosts...),
+			SecretName: tls.SecretName,
+		})
+	}
+	for _, rule := range in.Spec.Rules {
+		r := networkingv1.IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			r.HTTP = &networkingv1.HTTPIngressRuleValue{}
+			for _, path := range rule.HTTP.Paths {
+				pathType := networkingv1.PathTypeImplementationSpecific
+				if path.PathType != nil {
+					pathType = networkingv1.PathType(*path.PathType)
+				}
+				r.HTTP.Paths = append(r.HTTP.Paths, networkingv1.HTTPIngressPath{
+					Path:     path.Path,
+					PathType: &pathType,
+					Backend:  ingressBackendFromLegacy(path.Backend),
+				})
+			}
+		}
+		out.Spec.Rules = append(out.Spec.Rules, r)
+	}
+	out.Status.LoadBalancer = *in.Status.LoadBalancer.DeepCopy()
+	return out
+}
+
+// IngressToLegacy converts networking.k8s.io/v1 ingress to extensions/v1beta1 for the old cluster
+func IngressToLegacy(in *networkingv1.Ingress) *extensions.Ingress {
+	if in == nil {
+		return nil
+	}
+	out := &extensions.Ingress{
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	out.Kind = "Ingress"
+	out.APIVersion = ExtensionsV1beta1
+	out.Spec.IngressClassName = in.Spec.IngressClassName
+	if in.Spec.DefaultBackend != nil {
+		backend := ingressBackendToLegacy(*in.Spec.DefaultBackend)
+		out.Spec.Backend = &backend
+	}
+	for _, tls := range in.Spec.TLS {
+		out.Spec.TLS = append(out.Spec.TLS, extensions.IngressTLS{
+			Hosts:      append([]string{}, tls.Hosts...),
+			SecretName: tls.SecretName,
+		})
+	}
+	for _, rule := range in.Spec.Rules {
+		r := extensions.IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			r.HTTP = &extensions.HTTPIngressRuleValue{}
+			for _, path := range rule.HTTP.Paths {
+				p := extensions.HTTPIngressPath{
+					Path:    path.Path,
+					Backend: ingressBackendToLegacy(path.Backend),
+				}
+				if path.PathType != nil {
+					pathType := extensions.PathType(*path.PathType)
+					p.PathType = &pathType
+				}
+				r.HTTP.Paths = append(r.HTTP.Paths, p)
+			}
+		}
+		out.Spec.Rules = append(out.Spec.Rules, r)
+	}
+	out.Status.LoadBalancer = *in.Status.LoadBalancer.DeepCopy()
+	return out
+}
+
+func ingressBackendFromLegacy(in extensions.IngressBackend) networkingv1.IngressBackend {
+	out := networkingv1.IngressBackend{Resource: in.Resource}
+	if in.ServiceName != "" {
+		out.Service = &networkingv1.IngressServiceBackend{Name: in.ServiceName}
+		if in.ServicePort.Type == intstr.String {
+			out.Service.Port.Name = in.ServicePort.StrVal
+		} else {
+			out.Service.Port.Number = in.ServicePort.IntVal
+		}
+	}
+	return out
+}
+
+func ingressBackendToLegacy(in networkingv1.IngressBackend) extensions.IngressBackend {
+	out := extensions.IngressBackend{Resource: in.Resource}
+	if in.Service != nil {
+		out.ServiceName = in.Service.Name
+		if in.Service.Port.Name != "" {
+			out.ServicePort = intstr.FromString(in.Service.Port.Name)
+		} else {
+			out.ServicePort = intstr.FromInt(int(in.Service.Port.Number))
+		}
+	}
+	return out
+}
+
+// NewIngressBackend returns the backend of the service port
+func NewIngressBackend(svcname string, port int) networkingv1.IngressBackend {
+	return networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{
+			Name: svcname,
+			Port: networkingv1.ServiceBackendPort{Number: int32(port)},
+		},
+	}
+}
+
+// IngressBackendService returns the service name and port of the backend, the port is 0 if it is named
+func IngressBackendService(backend networkingv1.IngressBackend) (string, int) {
+	if backend.Service == nil {
+		return "", 0
+	}
+	return backend.Service.Name, int(backend.Service.Port.Number)
+}
+
+// NewIngressPath returns the path of ImplementationSpecific type which is same as the legacy ingress
+func NewIngressPath(path, svcname string, port int) networkingv1.HTTPIngressPath {
+	pathType := networkingv1.PathTypeImplementationSpecific
+	return networkingv1.HTTPIngressPath{
+		Path:     path,
+		PathType: &pathType,
+		Backend:  NewIngressBackend(svcname, port),
+	}
+}
@@ -0,0 +1,113 @@
+package kubeutil
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrDryRunNotSupported means the object is served by a legacy version of the cluster,
+// which is not dry run by the api server
+var ErrDryRunNotSupported = fmt.Errorf("server-side dry run is not supported by the api version of the cluster")
+
+// GetObject returns the live object which has the same kind, namespace and name as obj
+func GetObject(client kubernetes.Interface, versions APIVersions, obj runtime.Object) (runtime.Object, error) {
+	switch t := obj.(type) {
+	case *appsv1.Deployment:
+		return GetDeployment(client, versions, t.Namespace, t.Name)
+	case *appsv1.StatefulSet:
+		return GetStatefulSet(client, versions, t.Namespace, t.Name)
+	case *appsv1.DaemonSet:
+		return GetDaemonSet(client, versions, t.Namespace, t.Name)
+	case *batchv1.Job:
+		return client.BatchV1().Jobs(t.Namespace).Get(context.TODO(), t.Name, metav1.GetOptions{})
+	case *batchv1.CronJob:
+		return GetCronJob(client, versions, t.Namespace, t.Name)
+	case *apiv1.Service:
+		return client.CoreV1().Services(t.Namespace).Get(context.TODO(), t.Name, metav1.GetOptions{})
+	case *apiv1.ConfigMap:
+		return client.CoreV1().ConfigMaps(t.Namespace).Get(context.TODO(), t.Name, metav1.GetOptions{})
+	case *apiv1.Secret:
+		return client.CoreV1().Secrets(t.Namespace).Get(context.TODO(), t.Name, metav1.GetOptions{})
+	case *networkingv1.Ingress:
+		return GetIngress(client, versions, t.Namespace, t.Name)
+	}
+	return nil, fmt.Errorf("unsupported k8s resource type: %T", obj)
+}
+
+// DryRunObject creates obj, or updates the live object by obj if update is true, in the dry run mode of the api server,
+// the result is the object which would be persisted, and nothing is changed in the cluster
+func DryRunObject(client kubernetes.Interface, versions APIVersions, obj runtime.Object, update bool) (runtime.Object, error) {
+	createOpts := metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}
+	updateOpts := metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}}
+	switch t := obj.(type) {
+	case *appsv1.Deployment:
+		if versions.Deployment != AppsV1 {
+			return nil, ErrDryRunNotSupported
+		}
+		if update {
+			return client.AppsV1().Deployments(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.AppsV1().Deployments(t.Namespace).Create(context.TODO(), t, createOpts)
+	case *appsv1.StatefulSet:
+		if versions.StatefulSet != AppsV1 {
+			return nil, ErrDryRunNotSupported
+		}
+		if update {
+			return client.AppsV1().StatefulSets(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.AppsV1().StatefulSets(t.Namespace).Create(context.TODO(), t, createOpts)
+	case *appsv1.DaemonSet:
+		if versions.DaemonSet != AppsV1 {
+			return nil, ErrDryRunNotSupported
+		}
+		if update {
+			return client.AppsV1().DaemonSets(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.AppsV1().DaemonSets(t.Namespace).Create(context.TODO(), t, createOpts)
+	case *batchv1.Job:
+		if update {
+			return client.BatchV1().Jobs(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.BatchV1().Jobs(t.Namespace).Create(context.TODO(), t, createOpts)
+	case *batchv1.CronJob:
+		if versions.CronJob != BatchV1 {
+			return nil, ErrDryRunNotSupported
+		}
+		if update {
+			return client.BatchV1().CronJobs(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.BatchV1().CronJobs(t.Namespace).Create(context.TODO(), t, createOpts)
+	case *apiv1.Service:
+		if update {
+			return client.CoreV1().Services(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.CoreV1().Services(t.Namespace).Create(context.TODO(), t, createOpts)
+	case *apiv1.ConfigMap:
+		if update {
+			return client.CoreV1().ConfigMaps(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.CoreV1().ConfigMaps(t.Namespace).Create(context.TODO(), t, createOpts)
+	case *apiv1.Secret:
+		if update {
+			return client.CoreV1().Secrets(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.CoreV1().Secrets(t.Namespace).Create(context.TODO(), t, createOpts)
+	case *networkingv1.Ingress:
+		if versions.Ingress != NetworkingV1 {
+			return nil, ErrDryRunNotSupported
+		}
+		if update {
+			return client.NetworkingV1().Ingresses(t.Namespace).Update(context.TODO(), t, updateOpts)
+		}
+		return client.NetworkingV1().Ingresses(t.Namespace).Create(context.TODO(), t, createOpts)
+	}
+	return nil, fmt.Errorf("unsupported k8s resource type: %T", obj)
+}
@@ -6,18 +6,21 @@ import (
 	"kubecloud/backend/dao"
 	"kubecloud/common/utils"
 
-	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"kubecloud/backend/util/labels"
 )
 
 const (
 	DefaultIngressAnnotationKey = "created_default"
+	// AcmeSolverLabelKey is set on the temporary ingress serving the http-01 challenge of acme,
+	// it is not recorded as the ingress rules
+	AcmeSolverLabelKey = "kubecloud/acme-solver"
 )
 
-func DeleteHostFromTLS(TLS []extensions.IngressTLS, delHost string) []extensions.IngressTLS {
+func DeleteHostFromTLS(TLS []networkingv1.IngressTLS, delHost string) []networkingv1.IngressTLS {
 	// delete host from tls
 	// if tls.hosts is empty, delete tls from Spec.TLS
-	newTLS := []extensions.IngressTLS{}
+	newTLS := []networkingv1.IngressTLS{}
 	for _, tls := range TLS {
 		exHosts := []string{}
 		for _, host := range tls.Hosts {
@@ -33,7 +36,7 @@ func DeleteHostFromTLS(TLS []extensions.IngressTLS, delHost string) []extensions
 	return newTLS
 }
 
-func MergeIngressRuleValue(first, second *extensions.IngressRule) extensions.IngressRule {
+func MergeIngressRuleValue(first, second *networkingv1.IngressRule) networkingv1.IngressRule {
 	if first.HTTP == nil {
 		return *second
 	}
@@ -56,7 +59,7 @@ func MergeIngressRuleValue(first, second *extensions.IngressRule) extensions.Ing
 	return *rule
 }
 
-func MergeIngressRuleList(firstList, secondList []extensions.IngressRule) []extensions.IngressRule {
+func MergeIngressRuleList(firstList, secondList []networkingv1.IngressRule) []networkingv1.IngressRule {
 	for _, orule := range secondList {
 		index := -1
 		for i, rule := range firstList {
@@ -74,7 +77,7 @@ func MergeIngressRuleList(firstList, secondList []extensions.IngressRule) []exte
 	return firstList
 }
 
-func MergeIngressTLSList(firstList, secondList []extensions.IngressTLS) []extensions.IngressTLS {
+func MergeIngressTLSList(firstList, secondList []networkingv1.IngressTLS) []networkingv1.IngressTLS {
 	// check TLS, if not existed in new ing, then add it
 	for _, otls := range secondList {
 		index := -1
@@ -94,7 +97,7 @@ func MergeIngressTLSList(firstList, secondList []extensions.IngressTLS) []extens
 	return firstList
 }
 
-func MergeIngressTLSHost(first, second *extensions.IngressTLS) extensions.IngressTLS {
+func MergeIngressTLSHost(first, second *networkingv1.IngressTLS) networkingv1.IngressTLS {
 	tls := first.DeepCopy()
 	for _, shost := range second.Hosts {
 		found := true
@@ -110,7 +113,7 @@ func MergeIngressTLSHost(first, second *extensions.IngressTLS) extensions.Ingres
 	return *tls
 }
 
-func CheckIngressRule(cluster, namespace string, rules []extensions.IngressRule) error {
+func CheckIngressRule(cluster, namespace string, rules []networkingv1.IngressRule) error {
 	ruleModel := dao.NewIngressRuleModel()
 	for _, rule := range rules {
 		if err := ruleModel.CheckHostUnique(cluster, namespace, rule.Host); err != nil {
@@ -132,7 +135,7 @@ func CheckIngressRule(cluster, namespace string, rules []extensions.IngressRule)
 	return nil
 }
 
-func SetCreatedDefaultAnno(ing *extensions.Ingress) *extensions.Ingress {
+func SetCreatedDefaultAnno(ing *networkingv1.Ingress) *networkingv1.Ingress {
 	if ing == nil {
 		return nil
 	}
@@ -141,7 +144,7 @@ func SetCreatedDefaultAnno(ing *extensions.Ingress) *extensions.Ingress {
 	return ing
 }
 
-func DeleteCreatedDefaultAnno(ing *extensions.Ingress) *extensions.Ingress {
+func DeleteCreatedDefaultAnno(ing *networkingv1.Ingress) *networkingv1.Ingress {
 	if ing == nil {
 		return nil
 	}
@@ -153,7 +156,7 @@ func DeleteCreatedDefaultAnno(ing *extensions.Ingress) *extensions.Ingress {
 	return ing
 }
 
-func IngressIsCreatedDefault(ing *extensions.Ingress) bool {
+func IngressIsCreatedDefault(ing *networkingv1.Ingress) bool {
 	if ing == nil {
 		return false
 	}
@@ -163,7 +166,7 @@ func IngressIsCreatedDefault(ing *extensions.Ingress) bool {
 	return (ing.ObjectMeta.Annotations[DefaultIngressAnnotationKey] == "true")
 }
 
-func deleteHostsFromTLS(TLSList []extensions.IngressTLS, one extensions.IngressTLS) []extensions.IngressTLS {
+func deleteHostsFromTLS(TLSList []networkingv1.IngressTLS, one networkingv1.IngressTLS) []networkingv1.IngressTLS {
 	for _, host := range one.Hosts {
 		TLSList = DeleteHostFromTLS(TLSList, host)
 	}
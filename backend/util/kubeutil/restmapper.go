@@ -0,0 +1,60 @@
+package kubeutil
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+)
+
+type restMapperItem struct {
+	mapper   meta.RESTMapper
+	expireAt time.Time
+}
+
+var (
+	restMapperCache = make(map[string]restMapperItem)
+	restMapperMutex sync.RWMutex
+)
+
+func getRESTMapper(cluster string, client discovery.DiscoveryInterface, refresh bool) (meta.RESTMapper, error) {
+	restMapperMutex.RLock()
+	item, ok := restMapperCache[cluster]
+	restMapperMutex.RUnlock()
+	if ok && !refresh && time.Now().Before(item.expireAt) {
+		return item.mapper, nil
+	}
+	groupResources, err := restmapper.GetAPIGroupResources(client)
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+	restMapperMutex.Lock()
+	restMapperCache[cluster] = restMapperItem{mapper: mapper, expireAt: time.Now().Add(apiVersionsTTL)}
+	restMapperMutex.Unlock()
+	return mapper, nil
+}
+
+// GetRESTMapping returns the resource of the group version kind served by the cluster,
+// the discovery is refreshed once if the kind is not found, because the CRD may be created recently
+func GetRESTMapping(cluster string, client discovery.DiscoveryInterface, gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapper, err := getRESTMapper(cluster, client, false)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		if mapper, err = getRESTMapper(cluster, client, true); err != nil {
+			return nil, err
+		}
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the cluster does not serve %s: %v", gvk.String(), err)
+	}
+	return mapping, nil
+}
@@ -0,0 +1,193 @@
+package kubeutil
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// the functions below access the resources by the negotiated versions of the cluster,
+// and the objects of the legacy versions are converted to GA ones
+
+func GetDeployment(client kubernetes.Interface, versions APIVersions, namespace, name string) (*appsv1.Deployment, error) {
+	var obj interface{}
+	var err error
+	switch versions.Deployment {
+	case AppsV1beta1:
+		obj, err = client.AppsV1beta1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	case ExtensionsV1beta1:
+		obj, err = client.ExtensionsV1beta1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	default:
+		return client.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ToDeployment(obj)
+}
+
+func ListDeployments(client kubernetes.Interface, versions APIVersions, namespace string, opts metav1.ListOptions) ([]appsv1.Deployment, error) {
+	items := []interface{}{}
+	switch versions.Deployment {
+	case AppsV1beta1:
+		lst, err := client.AppsV1beta1().Deployments(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range lst.Items {
+			items = append(items, &lst.Items[i])
+		}
+	case ExtensionsV1beta1:
+		lst, err := client.ExtensionsV1beta1().Deployments(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range lst.Items {
+			items = append(items, &lst.Items[i])
+		}
+	default:
+		lst, err := client.AppsV1().Deployments(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+		return lst.Items, nil
+	}
+	deployments := []appsv1.Deployment{}
+	for _, item := range items {
+		deploy, err := ToDeployment(item)
+		if err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, *deploy)
+	}
+	return deployments, nil
+}
+
+func GetStatefulSet(client kubernetes.Interface, versions APIVersions, namespace, name string) (*appsv1.StatefulSet, error) {
+	if versions.StatefulSet != AppsV1beta1 {
+		return client.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	obj, err := client.AppsV1beta1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return ToStatefulSet(obj)
+}
+
+func GetDaemonSet(client kubernetes.Interface, versions APIVersions, namespace, name string) (*appsv1.DaemonSet, error) {
+	if versions.DaemonSet != ExtensionsV1beta1 {
+		return client.AppsV1().DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	obj, err := client.ExtensionsV1beta1().DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return ToDaemonSet(obj)
+}
+
+func GetReplicaSet(client kubernetes.Interface, versions APIVersions, namespace, name string) (*appsv1.ReplicaSet, error) {
+	if versions.ReplicaSet != ExtensionsV1beta1 {
+		return client.AppsV1().ReplicaSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	obj, err := client.ExtensionsV1beta1().ReplicaSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return ToReplicaSet(obj)
+}
+
+func GetCronJob(client kubernetes.Interface, versions APIVersions, namespace, name string) (*batchv1.CronJob, error) {
+	if versions.CronJob != BatchV1beta1 {
+		return client.BatchV1().CronJobs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	obj, err := client.BatchV1beta1().CronJobs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return ToCronJob(obj)
+}
+
+func GetHorizontalPodAutoscaler(client kubernetes.Interface, versions APIVersions, namespace, name string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	switch versions.HorizontalPodAutoscaler {
+	case AutoscalingV2:
+		return client.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	case AutoscalingV2beta2:
+		obj, err := client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return ToHorizontalPodAutoscaler(obj)
+	}
+	return nil, fmt.Errorf("the cluster does not serve autoscaling/v2 or autoscaling/v2beta2")
+}
+
+func GetIngress(client kubernetes.Interface, versions APIVersions, namespace, name string) (*networkingv1.Ingress, error) {
+	if versions.Ingress != ExtensionsV1beta1 {
+		return client.NetworkingV1().Ingresses(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	obj, err := client.ExtensionsV1beta1().Ingresses(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return IngressFromLegacy(obj), nil
+}
+
+func ListIngresses(client kubernetes.Interface, versions APIVersions, namespace string, opts metav1.ListOptions) ([]networkingv1.Ingress, error) {
+	if versions.Ingress != ExtensionsV1beta1 {
+		lst, err := client.NetworkingV1().Ingresses(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+		return lst.Items, nil
+	}
+	lst, err := client.ExtensionsV1beta1().Ingresses(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	ings := []networkingv1.Ingress{}
+	for i := range lst.Items {
+		ings = append(ings, *IngressFromLegacy(&lst.Items[i]))
+	}
+	return ings, nil
+}
+
+func CreateIngress(client kubernetes.Interface, versions APIVersions, ing *networkingv1.Ingress) (*networkingv1.Ingress, error) {
+	if ing == nil {
+		return nil, fmt.Errorf("the ingress to create is nil")
+	}
+	if versions.Ingress != ExtensionsV1beta1 {
+		return client.NetworkingV1().Ingresses(ing.Namespace).Create(context.TODO(), ing, metav1.CreateOptions{})
+	}
+	obj, err := client.ExtensionsV1beta1().Ingresses(ing.Namespace).Create(context.TODO(), IngressToLegacy(ing), metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return IngressFromLegacy(obj), nil
+}
+
+func UpdateIngress(client kubernetes.Interface, versions APIVersions, ing *networkingv1.Ingress) (*networkingv1.Ingress, error) {
+	if ing == nil {
+		return nil, fmt.Errorf("the ingress to update is nil")
+	}
+	if versions.Ingress != ExtensionsV1beta1 {
+		return client.NetworkingV1().Ingresses(ing.Namespace).Update(context.TODO(), ing, metav1.UpdateOptions{})
+	}
+	obj, err := client.ExtensionsV1beta1().Ingresses(ing.Namespace).Update(context.TODO(), IngressToLegacy(ing), metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return IngressFromLegacy(obj), nil
+}
+
+func DeleteIngress(client kubernetes.Interface, versions APIVersions, namespace, name string) error {
+	if versions.Ingress != ExtensionsV1beta1 {
+		return client.NetworkingV1().Ingresses(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	}
+	return client.ExtensionsV1beta1().Ingresses(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
@@ -12,6 +12,8 @@ type Error struct {
 	code    string
 	message string
 	cause   error
+	// details are the structured information of the error, such as the violations of policies
+	details interface{}
 }
 
 func (this *Error) Error() string {
@@ -34,6 +36,10 @@ func (this *Error) Cause() error {
 	return this.cause
 }
 
+func (this *Error) Details() interface{} {
+	return this.details
+}
+
 func (this *Error) SetCode(code string) *Error {
 	this.code = code
 	return this
@@ -49,6 +55,11 @@ func (this *Error) SetCause(err error) *Error {
 	return this
 }
 
+func (this *Error) SetDetails(details interface{}) *Error {
+	this.details = details
+	return this
+}
+
 // FromK8sError convert a k8s error to Error
 func FromK8sError(err error) *Error {
 	if k8serrors.IsNotFound(err) {
@@ -27,6 +27,10 @@ func TestError(t *testing.T) {
 		assert.Equal(t, &err, err.SetCause(cause))
 		assert.Equal(t, cause, err.Cause())
 
+		details := []string{"the image registry is not allowed"}
+		assert.Equal(t, &err, err.SetDetails(details))
+		assert.Equal(t, details, err.Details())
+
 		assert.NotEmpty(t, err.Error())
 	})
 
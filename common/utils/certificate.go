@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// ParseCertificate returns the leaf certificate, which is the first certificate of the pem encoded chain
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate is found in the pem data")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		return x509.ParseCertificate(block.Bytes)
+	}
+}
+
+// CertificateNotAfter returns the expiry time of the leaf certificate
+func CertificateNotAfter(data []byte) (time.Time, error) {
+	cert, err := ParseCertificate(data)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
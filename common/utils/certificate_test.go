@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificateNotAfter(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	notAfter := time.Now().Add(90 * 24 * time.Hour).UTC().Truncate(time.Second)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		Issuer:       pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com", "www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	// the leaf certificate is found after the other blocks
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	got, err := CertificateNotAfter(data)
+	assert.Nil(t, err)
+	assert.True(t, notAfter.Equal(got))
+	cert, err := ParseCertificate(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", cert.Subject.CommonName)
+	assert.Equal(t, "example.com", cert.Issuer.CommonName)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, cert.DNSNames)
+
+	_, err = CertificateNotAfter(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	assert.NotNil(t, err)
+	_, err = CertificateNotAfter([]byte("not a certificate"))
+	assert.NotNil(t, err)
+	_, err = CertificateNotAfter(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("broken")}))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPProbeResult is the result of requesting a host and path through a load balancer
+type HTTPProbeResult struct {
+	StatusCode int
+	Latency    time.Duration
+	// TLSValid is true if the certificate served for the host is trusted and not expired, it is only checked for https
+	TLSValid bool
+	TLSError string
+	// Error is the error of the request, the status code is 0 if it is not empty
+	Error string
+}
+
+// ProbeHTTP requests the path of host by connecting to address directly, so the request is sent to the load balancer
+// with the Host header and SNI of host. The redirects are not followed, and the certificate is verified by rootCAs,
+// or the system roots if it is nil, but the request is still sent if the certificate is not valid
+func ProbeHTTP(address, scheme, host, path string, timeout time.Duration, rootCAs *x509.CertPool) HTTPProbeResult {
+	result := HTTPProbeResult{}
+	u := url.URL{Scheme: scheme, Host: host, Path: path}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+		TLSClientConfig: &tls.Config{
+			ServerName: host,
+			// the certificate is verified by VerifyConnection, so the validity is recorded instead of failing the request
+			InsecureSkipVerify: true,
+			VerifyConnection: func(state tls.ConnectionState) error {
+				if err := verifyPeerCertificates(state.PeerCertificates, host, rootCAs); err != nil {
+					result.TLSError = err.Error()
+				} else {
+					result.TLSValid = true
+				}
+				return nil
+			},
+		},
+		DisableKeepAlives: true,
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	return result
+}
+
+func verifyPeerCertificates(certs []*x509.Certificate, host string, rootCAs *x509.CertPool) error {
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificate is served")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         rootCAs,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
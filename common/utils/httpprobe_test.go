@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newProbeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Path {
+		case "/api":
+			w.WriteHeader(http.StatusOK)
+		case "/login":
+			http.Redirect(w, r, "/api", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+}
+
+func TestProbeHTTP(t *testing.T) {
+	server := httptest.NewServer(newProbeHandler())
+	defer server.Close()
+	address := server.Listener.Addr().String()
+
+	result := ProbeHTTP(address, "http", "example.com", "/api", 5*time.Second, nil)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, "", result.Error)
+	assert.True(t, result.Latency > 0)
+	assert.False(t, result.TLSValid)
+
+	// the request is routed by the host header
+	result = ProbeHTTP(address, "http", "other.com", "/api", 5*time.Second, nil)
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+
+	// the redirects are not followed
+	result = ProbeHTTP(address, "http", "example.com", "/login", 5*time.Second, nil)
+	assert.Equal(t, http.StatusFound, result.StatusCode)
+
+	result = ProbeHTTP(address, "http", "example.com", "/down", 5*time.Second, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+
+	server.Close()
+	result = ProbeHTTP(address, "http", "example.com", "/api", 5*time.Second, nil)
+	assert.Equal(t, 0, result.StatusCode)
+	assert.NotEqual(t, "", result.Error)
+}
+
+func TestProbeHTTPS(t *testing.T) {
+	// the certificate of the test server is valid for example.com
+	server := httptest.NewTLSServer(newProbeHandler())
+	defer server.Close()
+	address := server.Listener.Addr().String()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	result := ProbeHTTP(address, "https", "example.com", "/api", 5*time.Second, roots)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.True(t, result.TLSValid)
+	assert.Equal(t, "", result.TLSError)
+
+	// the request is sent even if the certificate does not match the host
+	result = ProbeHTTP(address, "https", "other.com", "/api", 5*time.Second, roots)
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	assert.False(t, result.TLSValid)
+	assert.NotEqual(t, "", result.TLSError)
+
+	// the certificate is not trusted by the empty roots
+	result = ProbeHTTP(address, "https", "example.com", "/api", 5*time.Second, x509.NewCertPool())
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.False(t, result.TLSValid)
+	assert.NotEqual(t, "", result.TLSError)
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortRange is the range of ports from Min to Max, both are included
+type PortRange struct {
+	Min int
+	Max int
+}
+
+// PortPool is the ports of the ranges, such as "30000-30999,31080"
+type PortPool []PortRange
+
+// ParsePortPool parses the comma separated ports and port ranges, the ranges can not overlap
+func ParsePortPool(spec string) (PortPool, error) {
+	pool := PortPool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var err error
+		portRange := PortRange{}
+		if index := strings.Index(item, "-"); index >= 0 {
+			if portRange.Min, err = parsePort(item[:index]); err == nil {
+				portRange.Max, err = parsePort(item[index+1:])
+			}
+		} else {
+			portRange.Min, err = parsePort(item)
+			portRange.Max = portRange.Min
+		}
+		if err != nil {
+			return nil, fmt.Errorf("port range %q is not right: %v", item, err)
+		}
+		if portRange.Min > portRange.Max {
+			return nil, fmt.Errorf("port range %q is not right: the first port is larger than the last one", item)
+		}
+		for _, other := range pool {
+			if portRange.Min <= other.Max && other.Min <= portRange.Max {
+				return nil, fmt.Errorf("port range %q overlaps %d-%d", item, other.Min, other.Max)
+			}
+		}
+		pool = append(pool, portRange)
+	}
+	return pool, nil
+}
+
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("port %d is not in the range of 1 to 65535", port)
+	}
+	return port, nil
+}
+
+// Contains checks the port is in the pool
+func (pool PortPool) Contains(port int) bool {
+	for _, portRange := range pool {
+		if port >= portRange.Min && port <= portRange.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// Size returns the number of ports in the pool
+func (pool PortPool) Size() int {
+	size := 0
+	for _, portRange := range pool {
+		size += portRange.Max - portRange.Min + 1
+	}
+	return size
+}
+
+// Allocate returns the first port of the pool which is not used, it is 0 if all ports are used
+func (pool PortPool) Allocate(used map[int]bool) int {
+	for _, portRange := range pool {
+		for port := portRange.Min; port <= portRange.Max; port++ {
+			if !used[port] {
+				return port
+			}
+		}
+	}
+	return 0
+}
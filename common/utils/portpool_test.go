@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePortPool(t *testing.T) {
+	pool, err := ParsePortPool("30000-30002, 31080,")
+	assert.Nil(t, err)
+	assert.Equal(t, PortPool{{Min: 30000, Max: 30002}, {Min: 31080, Max: 31080}}, pool)
+	assert.Equal(t, 4, pool.Size())
+	assert.True(t, pool.Contains(30001))
+	assert.True(t, pool.Contains(31080))
+	assert.False(t, pool.Contains(30003))
+
+	pool, err = ParsePortPool("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pool.Size())
+
+	for _, spec := range []string{"a-30000", "30000-", "0", "65536", "30002-30000", "30000-30010,30005"} {
+		_, err = ParsePortPool(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestPortPoolAllocate(t *testing.T) {
+	pool, err := ParsePortPool("30000-30001,31080")
+	assert.Nil(t, err)
+	used := map[int]bool{}
+	assert.Equal(t, 30000, pool.Allocate(used))
+	used[30000] = true
+	used[30001] = true
+	assert.Equal(t, 31080, pool.Allocate(used))
+	used[31080] = true
+	assert.Equal(t, 0, pool.Allocate(used))
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -45,7 +46,7 @@ func NewSimpleLocker(namespace, name string, client kubernetes.Interface) *Endpo
 func (cl *EndpointLocker) Get() (*LockerRecord, error) {
 	var record LockerRecord
 	var err error
-	cl.e, err = cl.Client.Endpoints(cl.Namespace).Get(cl.Name, metav1.GetOptions{})
+	cl.e, err = cl.Client.Endpoints(cl.Namespace).Get(context.TODO(), cl.Name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +64,7 @@ func (cl *EndpointLocker) Get() (*LockerRecord, error) {
 // Create attempts to create a LeaderElectionRecord annotation
 func (cl *EndpointLocker) Create(ler LockerRecord) error {
 	var err error
-	cl.e, err = cl.Client.Endpoints(cl.Namespace).Get(cl.Name, metav1.GetOptions{})
+	cl.e, err = cl.Client.Endpoints(cl.Namespace).Get(context.TODO(), cl.Name, metav1.GetOptions{})
 	if err == nil {
 		return nil
 	}
@@ -74,7 +75,7 @@ func (cl *EndpointLocker) Create(ler LockerRecord) error {
 	if err != nil {
 		return err
 	}
-	cl.e, err = cl.Client.Endpoints(cl.Namespace).Create(&v1.Endpoints{
+	cl.e, err = cl.Client.Endpoints(cl.Namespace).Create(context.TODO(), &v1.Endpoints{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      cl.Name,
 			Namespace: cl.Namespace,
@@ -82,7 +83,7 @@ func (cl *EndpointLocker) Create(ler LockerRecord) error {
 				LockerRecordAnnotationKey: string(recordBytes),
 			},
 		},
-	})
+	}, metav1.CreateOptions{})
 
 	return err
 }
@@ -93,7 +94,7 @@ func (cl *EndpointLocker) Update(ler LockerRecord) (string, error) {
 	if cl.e == nil {
 		return LockerStatusUnkown, fmt.Errorf("endpoints not initialized, call get or create first")
 	}
-	cl.e, err = cl.Client.Endpoints(cl.Namespace).Get(cl.Name, metav1.GetOptions{})
+	cl.e, err = cl.Client.Endpoints(cl.Namespace).Get(context.TODO(), cl.Name, metav1.GetOptions{})
 	recordBytes, err := json.Marshal(ler)
 	if err != nil {
 		return LockerStatusUnkown, err
@@ -114,6 +115,6 @@ func (cl *EndpointLocker) Update(ler LockerRecord) (string, error) {
 		}
 	}
 	cl.e.Annotations[LockerRecordAnnotationKey] = string(recordBytes)
-	cl.e, err = cl.Client.Endpoints(cl.Namespace).Update(cl.e)
+	cl.e, err = cl.Client.Endpoints(cl.Namespace).Update(context.TODO(), cl.e, metav1.UpdateOptions{})
 	return ler.Status, err
 }
@@ -247,6 +247,30 @@ func GoThrough(slice []interface{}, handler func(interface{}) interface{}, limit
 	return err
 }
 
+// GoEach calls handler on each item of slice with at most limit goroutines,
+// unlike GoThrough it never stops early and returns the result of each item in order
+func GoEach(slice []interface{}, handler func(interface{}) interface{}, limit int) []interface{} {
+	if limit < 1 {
+		panic("GoEach limit must be positive")
+	}
+	results := make([]interface{}, len(slice))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	wg.Add(len(slice))
+	for idx := range slice {
+		sem <- struct{}{}
+		go func(idx int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[idx] = handler(slice[idx])
+		}(idx)
+	}
+	wg.Wait()
+	return results
+}
+
 // WaitGroup ...
 type WaitGroup struct {
 	sync.WaitGroup
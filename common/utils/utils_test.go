@@ -244,6 +244,44 @@ func TestGoThrough(t *testing.T) {
 	})
 }
 
+func TestGoEach(t *testing.T) {
+	data := ToInterfaceSlice([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	for _, limit := range []int{1, 3, len(data)} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			var mux sync.Mutex
+			running, maxRunning := 0, 0
+			ret := GoEach(data, func(arg interface{}) interface{} {
+				mux.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mux.Unlock()
+				defer func() {
+					mux.Lock()
+					running--
+					mux.Unlock()
+				}()
+				item := arg.(int)
+				// the failed items do not stop the others
+				if item%2 == 0 {
+					return fmt.Sprintf("failed %v", item)
+				}
+				return nil
+			}, limit)
+			assert.Len(t, ret, len(data))
+			for i, r := range ret {
+				if i%2 == 0 {
+					assert.Equal(t, fmt.Sprintf("failed %v", i), r)
+				} else {
+					assert.Nil(t, r)
+				}
+			}
+			assert.True(t, maxRunning <= limit)
+		})
+	}
+}
+
 func TestWaitGroup(t *testing.T) {
 	var wg WaitGroup
 	count := 100
@@ -3,6 +3,7 @@ package utils
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -113,6 +114,13 @@ func (g *VisGraph) AddEdge(src, target string, lbls map[string]string) {
 	}
 }
 
+// AddNode adds a node without edges, it is ignored if the node exists.
+func (g *VisGraph) AddNode(name string) {
+	if g.getNode(name) == nil {
+		g.Nodes = append(g.Nodes, *g.newNode(name))
+	}
+}
+
 func (g *VisGraph) MakeEdgeLabel() {
 	for i, e := range g.Edges {
 		g.Edges[i].Label = labelStr(e.LabelMap)
@@ -174,8 +182,14 @@ func (g *VisGraph) newNode(name string) *VisNode {
 
 func labelStr(m map[string]string) string {
 	var labelBuf bytes.Buffer
-	for _, v := range m {
-		labelBuf.WriteString(fmt.Sprintf("%s\n", v))
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// the labels are joined in order of their keys, so the label of edge is stable
+	sort.Strings(keys)
+	for _, k := range keys {
+		labelBuf.WriteString(fmt.Sprintf("%s\n", m[k]))
 	}
 	return strings.TrimRight(labelBuf.String(), "\n")
 }
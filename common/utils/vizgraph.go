@@ -1,6 +1,10 @@
 package utils
 
-import "io"
+import (
+	"fmt"
+	"io"
+	"sort"
+)
 
 type (
 	// Static represents a service graph generated by API calls that is
@@ -47,3 +51,54 @@ func (d *Dynamic) Merge(static *Static) {
 		d.Nodes[node] = struct{}{}
 	}
 }
+
+// sortedNodes returns the nodes of the graph in order, so the output of the same graph is stable.
+func (d *Dynamic) sortedNodes() []string {
+	nodes := []string{}
+	for node := range d.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// GenerateDot writes the graph in the DOT language of graphviz, the labels of edge are joined as its label.
+func GenerateDot(w io.Writer, g *Dynamic) error {
+	if _, err := fmt.Fprintln(w, "digraph {"); err != nil {
+		return err
+	}
+	for _, node := range g.sortedNodes() {
+		if _, err := fmt.Fprintf(w, "\t%q;\n", node); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if _, err := fmt.Fprintf(w, "\t%q -> %q [label=%q];\n", e.Source, e.Target, labelStr(edgeLabels(e.Labels))); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// ToVisGraph converts the graph to the vis.js graph, the nodes without edges are kept.
+func (d *Dynamic) ToVisGraph() *VisGraph {
+	g := NewVisGraph()
+	for _, node := range d.sortedNodes() {
+		g.AddNode(node)
+	}
+	for _, e := range d.Edges {
+		g.AddEdge(e.Source, e.Target, edgeLabels(e.Labels))
+	}
+	g.MakeEdgeLabel()
+	return g
+}
+
+// edgeLabels returns the attributes as "key: value" labels.
+func edgeLabels(attrs Attributes) map[string]string {
+	lbls := make(map[string]string)
+	for k, v := range attrs {
+		lbls[k] = fmt.Sprintf("%s: %s", k, v)
+	}
+	return lbls
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestVizGraph() *Dynamic {
+	g := NewVizGraph()
+	g.AddEdge("ingress:a.com/", "service:web", map[string]string{"port": "80"})
+	g.AddEdge("service:web", "version:web/v1", map[string]string{"weight": "100", "rps": "1.50"})
+	g.Nodes["service:db"] = struct{}{}
+	return g
+}
+
+func TestGenerateDot(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, GenerateDot(&buf, newTestVizGraph()))
+	expected := `digraph {
+	"ingress:a.com/";
+	"service:db";
+	"service:web";
+	"version:web/v1";
+	"ingress:a.com/" -> "service:web" [label="port: 80"];
+	"service:web" -> "version:web/v1" [label="rps: 1.50\nweight: 100"];
+}
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestToVisGraph(t *testing.T) {
+	g := newTestVizGraph().ToVisGraph()
+	assert.Equal(t, 4, len(g.Nodes))
+	assert.Equal(t, "ingress:a.com/", g.Nodes[0].Id)
+	assert.Equal(t, "service:db", g.Nodes[1].Id)
+	assert.Equal(t, 2, len(g.Edges))
+	assert.Equal(t, "service:web", g.Edges[1].From)
+	assert.Equal(t, "version:web/v1", g.Edges[1].To)
+	assert.Equal(t, "rps: 1.50\nweight: 100", g.Edges[1].Label)
+}
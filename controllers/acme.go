@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"net/http"
+
+	"kubecloud/backend/resource"
+
+	"github.com/astaxie/beego"
+)
+
+// AcmeController serves the http-01 challenges of the acme certificates,
+// they are routed from the ingress hosts by the solver ingresses
+type AcmeController struct {
+	BaseController
+}
+
+func (this *AcmeController) Challenge() {
+	token := this.GetStringFromPath(":token")
+	response, err := resource.GetAcmeChallengeResponse(token)
+	if err != nil {
+		beego.Warn("get the response of acme challenge", token, "failed:", err)
+		this.Ctx.Output.SetStatus(http.StatusNotFound)
+		this.Ctx.Output.Body([]byte(http.StatusText(http.StatusNotFound)))
+		return
+	}
+	this.Ctx.Output.Header("Content-Type", "text/plain")
+	this.Ctx.Output.Body([]byte(response))
+}
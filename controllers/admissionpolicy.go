@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"kubecloud/backend/resource"
+	"kubecloud/common"
+)
+
+type AdmissionPolicyController struct {
+	BaseController
+}
+
+func (pc *AdmissionPolicyController) List() {
+	clusterId := pc.GetStringFromPath(":cluster")
+	namespace := pc.GetStringFromQuery("namespace")
+	result, err := resource.AdmissionPolicyList(clusterId, namespace)
+	if err != nil {
+		pc.ServeError(err)
+		return
+	}
+	pc.ServeResult(NewResult(true, result, ""))
+}
+
+func (pc *AdmissionPolicyController) Inspect() {
+	clusterId := pc.GetStringFromPath(":cluster")
+	id, err := pc.GetInt64FromPath(":id")
+	if err != nil {
+		pc.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	result, err := resource.AdmissionPolicyInspect(clusterId, id)
+	if err != nil {
+		pc.ServeError(err)
+		return
+	}
+	pc.ServeResult(NewResult(true, result, ""))
+}
+
+func (pc *AdmissionPolicyController) Create() {
+	clusterId := pc.GetStringFromPath(":cluster")
+
+	var param resource.AdmissionPolicyParam
+	pc.DecodeJSONReq(&param)
+	result, err := resource.AdmissionPolicyCreate(clusterId, param)
+	if err != nil {
+		pc.ServeError(err)
+		return
+	}
+	pc.ServeResult(NewResult(true, result, ""))
+}
+
+func (pc *AdmissionPolicyController) Update() {
+	clusterId := pc.GetStringFromPath(":cluster")
+	id, err := pc.GetInt64FromPath(":id")
+	if err != nil {
+		pc.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+
+	var param resource.AdmissionPolicyParam
+	pc.DecodeJSONReq(&param)
+	result, err := resource.AdmissionPolicyUpdate(clusterId, id, param)
+	if err != nil {
+		pc.ServeError(err)
+		return
+	}
+	pc.ServeResult(NewResult(true, result, ""))
+}
+
+func (pc *AdmissionPolicyController) Delete() {
+	clusterId := pc.GetStringFromPath(":cluster")
+	id, err := pc.GetInt64FromPath(":id")
+	if err != nil {
+		pc.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	if err := resource.AdmissionPolicyDelete(clusterId, id); err != nil {
+		pc.ServeError(err)
+		return
+	}
+	pc.ServeResult(NewResult(true, nil, ""))
+}
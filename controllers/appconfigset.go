@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"fmt"
+
+	"kubecloud/backend/resource"
+
+	"github.com/astaxie/beego"
+)
+
+type AppConfigSetController struct {
+	BaseController
+}
+
+func (cc *AppConfigSetController) List() {
+	clusterId := cc.GetStringFromPath(":cluster")
+	namespace := cc.GetStringFromPath(":namespace")
+	result, err := resource.AppConfigSetList(clusterId, namespace)
+	if err != nil {
+		cc.ServeError(err)
+		return
+	}
+	cc.ServeResult(NewResult(true, result, ""))
+}
+
+func (cc *AppConfigSetController) Inspect() {
+	clusterId := cc.GetStringFromPath(":cluster")
+	namespace := cc.GetStringFromPath(":namespace")
+	name := cc.GetStringFromPath(":name")
+	result, err := resource.AppConfigSetInspect(clusterId, namespace, name)
+	if err != nil {
+		cc.ServeError(err)
+		return
+	}
+	cc.ServeResult(NewResult(true, result, ""))
+}
+
+func (cc *AppConfigSetController) Create() {
+	clusterId := cc.GetStringFromPath(":cluster")
+	namespace := cc.GetStringFromPath(":namespace")
+
+	var param resource.AppConfigSetParam
+	cc.DecodeJSONReq(&param)
+	result, err := resource.AppConfigSetCreate(clusterId, namespace, param)
+	if err != nil {
+		cc.ServeError(err)
+		return
+	}
+	cc.ServeResult(NewResult(true, result, ""))
+}
+
+func (cc *AppConfigSetController) Update() {
+	clusterId := cc.GetStringFromPath(":cluster")
+	namespace := cc.GetStringFromPath(":namespace")
+	name := cc.GetStringFromPath(":name")
+
+	var param resource.AppConfigSetParam
+	cc.DecodeJSONReq(&param)
+	result, err := resource.AppConfigSetUpdate(clusterId, namespace, name, param)
+	if err != nil {
+		cc.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("update config set(%s/%s/%s) successfully, restarted applications: %v", clusterId, namespace, name, result.Applications))
+	cc.ServeResult(NewResult(true, result, ""))
+}
+
+func (cc *AppConfigSetController) Delete() {
+	clusterId := cc.GetStringFromPath(":cluster")
+	namespace := cc.GetStringFromPath(":namespace")
+	name := cc.GetStringFromPath(":name")
+	if err := resource.AppConfigSetDelete(clusterId, namespace, name); err != nil {
+		cc.ServeError(err)
+		return
+	}
+	cc.ServeResult(NewResult(true, nil, ""))
+}
@@ -127,6 +127,12 @@ func (this *AppController) Create() {
 		this.ServeError(common.NewBadRequest().SetCause(err))
 		return
 	}
+	dryRun, err := this.GetBool("dry_run", false)
+	if err != nil {
+		beego.Error("Created application failed for: "+err.Error(), "cluster: "+clusterId+",", "namespace: "+namespace, "!")
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
 	tpl := resource.NewTemplate()
 	this.DecodeJSONReq(&tpl)
 	if err := tpl.Validate(); err != nil {
@@ -140,6 +146,16 @@ func (this *AppController) Create() {
 		this.ServeError(common.NewInternalServerError().SetCause(err))
 		return
 	}
+	if dryRun {
+		result, err := ar.DryRunInstallApp(namespace, tpl)
+		if err != nil {
+			beego.Error("Dry run of creating application failed for: "+err.Error(), "cluster: "+clusterId+",", "namespace: "+namespace, "!")
+			this.ServeError(err)
+			return
+		}
+		this.ServeResult(NewResult(true, result, ""))
+		return
+	}
 	eparam := resource.ExtensionParam{
 		Force: force,
 	}
@@ -202,6 +218,11 @@ func (this *AppController) Reconfigure() {
 	namespace := this.GetStringFromPath(":namespace")
 	appname := this.GetStringFromPath(":app")
 
+	dryRun, err := this.GetBool("dry_run", false)
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
 	bodyContext := this.Ctx.Input.CopyBody(1 << 32)
 	if len(bodyContext) == 0 {
 		beego.Info("There is no information to be updated", "cluster: "+clusterId+",", "namespace: "+namespace+",", "name: "+appname, "!")
@@ -234,6 +255,16 @@ func (this *AppController) Reconfigure() {
 		this.ServeError(common.NewBadRequest().SetCause(err))
 		return
 	}
+	if dryRun {
+		result, err := ar.DryRunReconfigureApp(*app, template)
+		if err != nil {
+			beego.Error("Dry run of updating application failed for: "+err.Error(), "cluster: "+clusterId+",", "namespace: "+namespace+",", "name: "+appname, "!")
+			this.ServeError(err)
+			return
+		}
+		this.ServeResult(NewResult(true, result, ""))
+		return
+	}
 	appinfo, err := ar.ReconfigureApp(*app, template)
 	if err != nil {
 		beego.Error("Update application failed for:"+err.Error(), "cluster: "+clusterId+",", "namespace: "+namespace+",", "name: "+appname, "!")
@@ -255,6 +286,11 @@ func (this *AppController) Scale() {
 		this.ServeError(common.NewBadRequest().SetCause(err))
 		return
 	}
+	dryRun, err := this.GetBool("dry_run", false)
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
 
 	if !(scale >= common.ReplicasMin && scale <= common.ReplicasMax) {
 		err = fmt.Errorf(
@@ -270,9 +306,24 @@ func (this *AppController) Scale() {
 		this.ServeError(common.NewInternalServerError().SetCause(err))
 		return
 	}
+	if dryRun {
+		result, err := ar.DryRunScaleApp(namespace, appname, scale)
+		if err != nil {
+			beego.Error("dry run of scaling application failed for: "+err.Error(), "cluster: "+clusterId+",", "namespace: "+namespace+",", "name: "+appname, "!")
+			this.ServeError(err)
+			return
+		}
+		this.ServeResult(NewResult(true, result, ""))
+		return
+	}
 	if err := ar.ScaleApp(namespace, appname, scale); err != nil {
 		beego.Error("scale application failed for: "+err.Error(), "cluster: "+clusterId+",", "namespace: "+namespace+",", "name: "+appname, "!")
-		this.ServeError(common.NewInternalServerError().SetCause(err))
+		switch err.(type) {
+		case *common.Error:
+			this.ServeError(err)
+		default:
+			this.ServeError(common.NewInternalServerError().SetCause(err))
+		}
 		return
 	}
 	beego.Info("scale application succefully,", "cluster: "+clusterId+",", "namespace: "+namespace+",", "name: "+appname, "!")
@@ -280,12 +331,79 @@ func (this *AppController) Scale() {
 	this.ServeJSON()
 }
 
+func (this *AppController) GetAutoscaler() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	appname := this.GetStringFromPath(":app")
+
+	ar, err := resource.NewAppRes(clusterId, nil)
+	if err != nil {
+		this.ServeError(common.NewInternalServerError().SetCause(err))
+		return
+	}
+	result, err := ar.GetAutoscaler(namespace, appname)
+	if err != nil {
+		beego.Error(fmt.Sprintf("get autoscaler of application(%s/%s/%s) failed: %v", clusterId, namespace, appname, err))
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *AppController) SetAutoscaler() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	appname := this.GetStringFromPath(":app")
+	var param resource.AutoscalerParam
+
+	this.DecodeJSONReq(&param)
+	ar, err := resource.NewAppRes(clusterId, nil)
+	if err != nil {
+		beego.Error(fmt.Sprintf("set autoscaler of application(%s/%s/%s) failed: %v", clusterId, namespace, appname, err))
+		this.ServeError(common.NewInternalServerError().SetCause(err))
+		return
+	}
+	result, err := ar.SetAutoscaler(namespace, appname, param)
+	if err != nil {
+		beego.Error(fmt.Sprintf("set autoscaler of application(%s/%s/%s) failed: %v", clusterId, namespace, appname, err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("set autoscaler of application(%s/%s/%s) successfully!", clusterId, namespace, appname))
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *AppController) DeleteAutoscaler() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	appname := this.GetStringFromPath(":app")
+
+	ar, err := resource.NewAppRes(clusterId, nil)
+	if err != nil {
+		beego.Error(fmt.Sprintf("delete autoscaler of application(%s/%s/%s) failed: %v", clusterId, namespace, appname, err))
+		this.ServeError(common.NewInternalServerError().SetCause(err))
+		return
+	}
+	if err := ar.DeleteAutoscaler(namespace, appname); err != nil {
+		beego.Error(fmt.Sprintf("delete autoscaler of application(%s/%s/%s) failed: %v", clusterId, namespace, appname, err))
+		this.ServeError(common.NewInternalServerError().SetCause(err))
+		return
+	}
+	beego.Info(fmt.Sprintf("delete autoscaler of application(%s/%s/%s) successfully!", clusterId, namespace, appname))
+	this.ServeResult(NewResult(true, nil, ""))
+}
+
 func (this *AppController) RollingUpdate() {
 	clusterId := this.GetStringFromPath(":cluster")
 	appname := this.Ctx.Input.Param(":app")
 	namespace := this.Ctx.Input.Param(":namespace")
 	var param []resource.ContainerParam
 
+	dryRun, err := this.GetBool("dry_run", false)
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
 	this.DecodeJSONReq(&param)
 	if err := resource.CheckImageValidate(param); err != nil {
 		this.ServeError(common.NewBadRequest().SetCause(err))
@@ -299,6 +417,16 @@ func (this *AppController) RollingUpdate() {
 		this.ServeError(err)
 		return
 	}
+	if dryRun {
+		result, err := ar.DryRunRollingUpdateApp(namespace, appname, param)
+		if err != nil {
+			beego.Error("dry run of rolling update application failed for: "+err.Error(), "cluster: "+clusterId+",", "namespace: "+namespace+",", "name: "+appname, "!")
+			this.ServeError(err)
+			return
+		}
+		this.ServeResult(NewResult(true, result, ""))
+		return
+	}
 	if err := ar.RollingUpdateApp(namespace, appname, param); err != nil {
 		beego.Error("rolling update application failed for: "+err.Error(), "cluster: "+clusterId+",", "namespace: "+namespace+",", "name: "+appname, "!")
 		this.ServeError(err)
@@ -332,6 +460,19 @@ func (this *AppController) BatchRollingUpdate() {
 	this.ServeJSON()
 }
 
+func (this *AppController) Bulk() {
+	var req resource.BulkRequest
+	this.DecodeJSONReq(&req)
+
+	result, err := resource.BulkOperate(req)
+	if err != nil {
+		beego.Error("bulk operate applications failed:", err.Error())
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
 func (this *AppController) PodInspect() {
 	clusterId := this.GetStringFromPath(":cluster")
 	namespace := this.Ctx.Input.Param(":namespace")
@@ -193,6 +193,7 @@ func (c *BaseController) ServeError(err error) {
 			}
 			statusCode = srcErr.Status()
 			result = NewErrorResult(srcErr.Code(), srcErr.Message(), errDetail)
+			result.Data = srcErr.Details()
 		}
 	// k8s error
 	case apierrors.APIStatus:
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"kubecloud/backend/resource"
+	"kubecloud/common"
+)
+
+type CertificateController struct {
+	BaseController
+}
+
+// List returns the certificates of the tls secrets of all clusters, or the cluster and namespace by the query
+func (cc *CertificateController) List() {
+	clusterId := cc.GetString("cluster", "")
+	namespace := cc.GetString("namespace", common.AllNamespace)
+	cc.list(clusterId, namespace)
+}
+
+// NamespaceList returns the certificates of the tls secrets of the namespace
+func (cc *CertificateController) NamespaceList() {
+	clusterId := cc.GetStringFromPath(":cluster")
+	namespace := cc.GetStringFromPath(":namespace")
+	cc.list(clusterId, namespace)
+}
+
+func (cc *CertificateController) list(clusterId, namespace string) {
+	expireWithin, err := cc.GetInt("expire_within", 0)
+	if err != nil {
+		cc.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	var nslist []string
+	if namespace != common.AllNamespace {
+		nslist = []string{namespace}
+	}
+	result, err := resource.ListCertificates(clusterId, nslist, expireWithin)
+	if err != nil {
+		cc.ServeError(common.NewInternalServerError().SetCause(err))
+		return
+	}
+	cc.ServeResult(NewResult(true, result, ""))
+}
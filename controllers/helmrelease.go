@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"fmt"
+
+	"kubecloud/backend/resource"
+	"kubecloud/common"
+
+	"github.com/astaxie/beego"
+)
+
+type HelmReleaseController struct {
+	BaseController
+}
+
+func (this *HelmReleaseController) List() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+
+	result, err := resource.NewHelmReleaseRes(clusterId).ReleaseList(namespace)
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *HelmReleaseController) History() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":release")
+
+	result, err := resource.NewHelmReleaseRes(clusterId).ReleaseHistory(namespace, name)
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *HelmReleaseController) Install() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	prjid, eparam, err := this.releaseDeployParam()
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	tpl := resource.NewHelmTemplate()
+
+	this.DecodeJSONReq(tpl)
+	if err := resource.NewHelmReleaseRes(clusterId).InstallRelease(prjid, namespace, tpl, eparam); err != nil {
+		beego.Error(fmt.Sprintf("install release(%s/%s/%s) failed: %v", clusterId, namespace, tpl.ReleaseName(), err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("install release(%s/%s/%s) successfully!", clusterId, namespace, tpl.ReleaseName()))
+	this.ServeResult(NewResult(true, nil, ""))
+}
+
+func (this *HelmReleaseController) Rollback() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":release")
+	revision, err := this.GetInt("revision", 0)
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	prjid, eparam, err := this.releaseDeployParam()
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+
+	if err := resource.NewHelmReleaseRes(clusterId).RollbackRelease(prjid, namespace, name, revision, eparam); err != nil {
+		beego.Error(fmt.Sprintf("rollback release(%s/%s/%s) failed: %v", clusterId, namespace, name, err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("rollback release(%s/%s/%s) successfully!", clusterId, namespace, name))
+	this.ServeResult(NewResult(true, nil, ""))
+}
+
+func (this *HelmReleaseController) Uninstall() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":release")
+
+	if err := resource.NewHelmReleaseRes(clusterId).UninstallRelease(namespace, name); err != nil {
+		beego.Error(fmt.Sprintf("uninstall release(%s/%s/%s) failed: %v", clusterId, namespace, name, err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("uninstall release(%s/%s/%s) successfully!", clusterId, namespace, name))
+	this.ServeResult(NewResult(true, nil, ""))
+}
+
+func (this *HelmReleaseController) releaseDeployParam() (int64, *resource.ExtensionParam, error) {
+	force, err := this.GetBool("force", false)
+	if err != nil {
+		return 0, nil, err
+	}
+	prjid, err := this.GetInt64("projectId", resource.DEFAULT_PROJECT_ID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return prjid, &resource.ExtensionParam{Force: force}, nil
+}
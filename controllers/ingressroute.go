@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"kubecloud/backend/resource"
+	"kubecloud/common"
+)
+
+type IngressRouteController struct {
+	BaseController
+}
+
+// Conflicts returns the routes registered by several ingresses on the load balancer of the cluster,
+// or on all load balancers if the cluster is not given
+func (rc *IngressRouteController) Conflicts() {
+	clusterId := rc.GetString("cluster", "")
+	result, err := resource.ListIngressRouteConflicts(clusterId)
+	if err != nil {
+		rc.ServeError(common.NewInternalServerError().SetCause(err))
+		return
+	}
+	rc.ServeResult(NewResult(true, result, ""))
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"kubecloud/backend/resource"
+	"kubecloud/common"
+)
+
+type PortExposureController struct {
+	BaseController
+}
+
+func (pc *PortExposureController) List() {
+	clusterId := pc.GetStringFromPath(":cluster")
+	namespace := pc.GetStringFromPath(":namespace")
+	appname := pc.GetString("appname")
+	result, err := resource.PortExposureList(clusterId, namespace, appname)
+	if err != nil {
+		pc.ServeError(err)
+		return
+	}
+	pc.Data["json"] = NewResult(true, result, "")
+	pc.ServeJSON()
+}
+
+func (pc *PortExposureController) Create() {
+	clusterId := pc.GetStringFromPath(":cluster")
+	namespace := pc.GetStringFromPath(":namespace")
+
+	var param resource.PortExposureParam
+	pc.DecodeJSONReq(&param)
+	result, err := resource.PortExposureCreate(clusterId, namespace, param)
+	if err != nil {
+		pc.ServeError(err)
+		return
+	}
+	pc.Data["json"] = NewResult(true, result, "")
+	pc.ServeJSON()
+}
+
+func (pc *PortExposureController) Delete() {
+	clusterId := pc.GetStringFromPath(":cluster")
+	namespace := pc.GetStringFromPath(":namespace")
+	id, err := pc.GetInt64FromPath(":id")
+	if err != nil {
+		pc.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	if err := resource.PortExposureDelete(clusterId, namespace, id); err != nil {
+		pc.ServeError(err)
+		return
+	}
+	pc.Data["json"] = NewResult(true, nil, "")
+	pc.ServeJSON()
+}
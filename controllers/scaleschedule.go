@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"kubecloud/backend/resource"
+	"kubecloud/common"
+)
+
+type ScaleScheduleController struct {
+	BaseController
+}
+
+func (sc *ScaleScheduleController) List() {
+	clusterId := sc.GetStringFromPath(":cluster")
+	namespace := sc.GetStringFromPath(":namespace")
+	result, err := resource.ScaleScheduleList(clusterId, namespace)
+	if err != nil {
+		sc.ServeError(err)
+		return
+	}
+	sc.Data["json"] = NewResult(true, result, "")
+	sc.ServeJSON()
+}
+
+func (sc *ScaleScheduleController) Create() {
+	clusterId := sc.GetStringFromPath(":cluster")
+	namespace := sc.GetStringFromPath(":namespace")
+
+	var param resource.ScaleScheduleParam
+	sc.DecodeJSONReq(&param)
+	result, err := resource.ScaleScheduleCreate(clusterId, namespace, param)
+	if err != nil {
+		sc.ServeError(err)
+		return
+	}
+	sc.Data["json"] = NewResult(true, result, "")
+	sc.ServeJSON()
+}
+
+func (sc *ScaleScheduleController) Update() {
+	clusterId := sc.GetStringFromPath(":cluster")
+	namespace := sc.GetStringFromPath(":namespace")
+	id, err := sc.GetInt64FromPath(":id")
+	if err != nil {
+		sc.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+
+	var param resource.ScaleScheduleParam
+	sc.DecodeJSONReq(&param)
+	result, err := resource.ScaleScheduleUpdate(clusterId, namespace, id, param)
+	if err != nil {
+		sc.ServeError(err)
+		return
+	}
+	sc.Data["json"] = NewResult(true, result, "")
+	sc.ServeJSON()
+}
+
+func (sc *ScaleScheduleController) Delete() {
+	clusterId := sc.GetStringFromPath(":cluster")
+	namespace := sc.GetStringFromPath(":namespace")
+	id, err := sc.GetInt64FromPath(":id")
+	if err != nil {
+		sc.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	if err := resource.ScaleScheduleDelete(clusterId, namespace, id); err != nil {
+		sc.ServeError(err)
+		return
+	}
+	sc.Data["json"] = NewResult(true, nil, "")
+	sc.ServeJSON()
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"fmt"
+
+	"kubecloud/backend/resource"
+	"kubecloud/common"
+
+	"github.com/astaxie/beego"
+)
+
+type TemplateController struct {
+	BaseController
+}
+
+func (this *TemplateController) List() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	filterQuery := this.GetFilterQuery()
+
+	result, err := resource.NewTemplateRes(NamespaceListFunc(clusterId, namespace)).GetTemplateList(namespace, filterQuery)
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) Create() {
+	namespace := this.GetStringFromPath(":namespace")
+	var param resource.TemplateParam
+
+	this.DecodeJSONReq(&param)
+	result, err := resource.NewTemplateRes(nil).CreateTemplate(namespace, param)
+	if err != nil {
+		beego.Error(fmt.Sprintf("create template(%s/%s) failed: %v", namespace, param.Name, err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("create template(%s/%s) successfully!", namespace, param.Name))
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) Inspect() {
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+
+	result, err := resource.NewTemplateRes(nil).GetTemplateInfo(namespace, name)
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) Update() {
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+	var param resource.TemplateParam
+
+	this.DecodeJSONReq(&param)
+	result, err := resource.NewTemplateRes(nil).UpdateTemplate(namespace, name, param)
+	if err != nil {
+		beego.Error(fmt.Sprintf("update template(%s/%s) failed: %v", namespace, name, err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("update template(%s/%s) to version %v successfully!", namespace, name, result.LatestVersion))
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) Delete() {
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+
+	if err := resource.NewTemplateRes(nil).DeleteTemplate(namespace, name); err != nil {
+		beego.Error(fmt.Sprintf("delete template(%s/%s) failed: %v", namespace, name, err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("delete template(%s/%s) successfully!", namespace, name))
+	this.ServeResult(NewResult(true, nil, ""))
+}
+
+func (this *TemplateController) VersionList() {
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+
+	result, err := resource.NewTemplateRes(nil).GetTemplateVersionList(namespace, name)
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) VersionInspect() {
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+	version, err := this.GetInt64FromPath(":version")
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+
+	result, err := resource.NewTemplateRes(nil).GetTemplateVersion(namespace, name, int(version))
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) RenderTemplate() {
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+	var param resource.TemplateDeployParam
+
+	this.DecodeJSONReq(&param)
+	result, err := resource.NewTemplateRes(nil).RenderTemplate(namespace, name, param.Version, param.Values)
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) Preview() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+	var param resource.TemplateDeployParam
+
+	this.DecodeJSONReq(&param)
+	result, err := resource.NewTemplateRes(nil).PreviewTemplate(clusterId, namespace, name, param)
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) DependencyGraph() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+	var param resource.TemplateDeployParam
+
+	this.DecodeJSONReq(&param)
+	result, err := resource.NewTemplateRes(nil).TemplateDependencyGraph(clusterId, namespace, name, param)
+	if err != nil {
+		this.ServeError(err)
+		return
+	}
+	this.ServeResult(NewResult(true, result, ""))
+}
+
+func (this *TemplateController) Deploy() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	name := this.GetStringFromPath(":template")
+	force, err := this.GetBool("force", false)
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	prjid, err := this.GetInt64("projectId", resource.DEFAULT_PROJECT_ID)
+	if err != nil {
+		this.ServeError(common.NewBadRequest().SetCause(err))
+		return
+	}
+	var param resource.TemplateDeployParam
+
+	this.DecodeJSONReq(&param)
+	eparam := resource.ExtensionParam{
+		Force: force,
+	}
+	if err := resource.NewTemplateRes(nil).DeployTemplate(clusterId, namespace, name, prjid, param, &eparam); err != nil {
+		beego.Error(fmt.Sprintf("deploy template(%s/%s/%s) failed: %v", clusterId, namespace, name, err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("deploy template(%s/%s/%s) successfully!", clusterId, namespace, name))
+	this.ServeResult(NewResult(true, nil, ""))
+}
+
+func (this *TemplateController) Capture() {
+	clusterId := this.GetStringFromPath(":cluster")
+	namespace := this.GetStringFromPath(":namespace")
+	var param resource.TemplateCaptureParam
+
+	this.DecodeJSONReq(&param)
+	result, err := resource.NewTemplateRes(nil).CaptureTemplate(clusterId, namespace, param)
+	if err != nil {
+		beego.Error(fmt.Sprintf("capture template(%s/%s) from cluster %s failed: %v", namespace, param.Name, clusterId, err))
+		this.ServeError(err)
+		return
+	}
+	beego.Info(fmt.Sprintf("capture template(%s/%s) from cluster %s successfully, adopted applications: %v", namespace, param.Name, clusterId, result.Applications))
+	this.ServeResult(NewResult(true, result, ""))
+}
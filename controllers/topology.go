@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"kubecloud/backend/resource"
+)
+
+type TopologyController struct {
+	BaseController
+}
+
+func (tc *TopologyController) Inspect() {
+	clusterId := tc.GetStringFromPath(":cluster")
+	namespace := tc.GetStringFromPath(":namespace")
+	format := tc.GetString("format", resource.TopologyFormatVis)
+	result, err := resource.NewTopologyRes(clusterId).GetTopology(namespace, format)
+	if err != nil {
+		tc.ServeError(err)
+		return
+	}
+	tc.ServeResult(NewResult(true, result, ""))
+}
+
+func (tc *TopologyController) Simulate() {
+	clusterId := tc.GetStringFromPath(":cluster")
+	namespace := tc.GetStringFromPath(":namespace")
+
+	var param resource.TrafficSimulationParam
+	tc.DecodeJSONReq(&param)
+	result, err := resource.NewTopologyRes(clusterId).SimulateTraffic(namespace, param)
+	if err != nil {
+		tc.ServeError(err)
+		return
+	}
+	tc.ServeResult(NewResult(true, result, ""))
+}
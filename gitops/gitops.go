@@ -3,6 +3,7 @@ package gitops
 import (
 	"fmt"
 	"github.com/astaxie/beego"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,11 +12,16 @@ import (
 
 	"github.com/ghodss/yaml"
 	"github.com/golang/glog"
-	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"kubecloud/backend/dao"
+	"kubecloud/backend/service"
+	"kubecloud/backend/util/kubeutil"
 )
 
 const (
@@ -64,98 +70,19 @@ func CommitK8sResource(clusterId string, resList []interface{}) error {
 		return err
 	}
 
+	versions := clusterAPIVersions(clusterId)
 	var files []string
 	dir := filepath.Join(configRepoDir, clusterId)
 	for _, res := range resList {
-		var isDeployment bool
-		var subDir string
-		var fileName string
-		switch t := res.(type) {
-		case *corev1.Namespace:
-			subDir = "namespaces"
-			fileName = fmt.Sprintf("%s.yaml", t.Name)
-			res.(*corev1.Namespace).TypeMeta = metav1.TypeMeta{
-				Kind:       "Namespace",
-				APIVersion: "v1",
-			}
-			res.(*corev1.Namespace).ObjectMeta.ResourceVersion = ""
-		case *corev1.ResourceQuota:
-			subDir = "resourcequotas"
-			fileName = fmt.Sprintf("%s.yaml", t.Name)
-			res.(*corev1.ResourceQuota).TypeMeta = metav1.TypeMeta{
-				Kind:       "ResourceQuota",
-				APIVersion: "v1",
-			}
-			res.(*corev1.ResourceQuota).ObjectMeta.ResourceVersion = ""
-		case *corev1.ConfigMap:
-			subDir = "configmaps"
-			fileName = fmt.Sprintf("%s.yaml", t.Name)
-			res.(*corev1.ConfigMap).TypeMeta = metav1.TypeMeta{
-				Kind:       "ConfigMap",
-				APIVersion: "v1",
-			}
-			res.(*corev1.ConfigMap).ObjectMeta.ResourceVersion = ""
-		case *appsv1beta1.Deployment:
-			isDeployment = true
-			ownerName, ok := t.Annotations["owner_name"]
-			if !ok {
-				err := fmt.Errorf("not owner_name annotation")
-				return err
-			}
-			subDir = fmt.Sprintf("apps/%s/%s", ownerName, t.Namespace)
-			fileName = fmt.Sprintf("%s-dept.yaml", t.Name)
-			res.(*appsv1beta1.Deployment).TypeMeta = metav1.TypeMeta{
-				Kind:       "Deployment",
-				APIVersion: "apps/v1beta1",
-			}
-			res.(*appsv1beta1.Deployment).ObjectMeta.ResourceVersion = ""
-		case *corev1.Service:
-			ownerName, ok := t.Annotations["owner_name"]
-			if !ok {
-				err := fmt.Errorf("not owner_name annotation")
-				return err
-			}
-			version, ok := t.ObjectMeta.Labels["version"]
-			if !ok {
-				err := fmt.Errorf("not version label")
-				return err
-			}
-			subDir = fmt.Sprintf("apps/%s/%s", ownerName, t.Namespace)
-			fileName = fmt.Sprintf("%s-%s-svc.yaml", t.Name, version)
-			res.(*corev1.Service).TypeMeta = metav1.TypeMeta{
-				Kind:       "Service",
-				APIVersion: "v1",
-			}
-			res.(*corev1.Service).ObjectMeta.ResourceVersion = ""
-		case *extensionsv1beta1.Ingress:
-			ownerName, ok := t.Annotations["owner_name"]
-			if !ok {
-				err := fmt.Errorf("not owner_name annotation")
-				return err
-			}
-			version, ok := t.ObjectMeta.Labels["version"]
-			if !ok {
-				err := fmt.Errorf("not version label")
-				return err
-			}
-			subDir = fmt.Sprintf("apps/%s/%s", ownerName, t.Namespace)
-			fileName = fmt.Sprintf("%s-%s-ing.yaml", t.Name, version)
-			res.(*extensionsv1beta1.Ingress).TypeMeta = metav1.TypeMeta{
-				Kind:       "Ingress",
-				APIVersion: "extensions/v1beta1",
-			}
-			res.(*extensionsv1beta1.Ingress).ObjectMeta.ResourceVersion = ""
-		default:
-			err := fmt.Errorf("unsupported k8s resource type: %v", res)
-			glog.Error(err.Error())
+		subDir, fileName, out, isDeployment, err := resourceFile(res, versions)
+		if err != nil {
 			return err
 		}
-		yamlData, err := yaml.Marshal(res)
+		yamlData, err := marshalResource(out, isDeployment)
 		if err != nil {
-			glog.Errorf("marshal yaml error: %s", err.Error())
 			return err
 		}
-		if err := writeYamlFile(yamlData, dir, subDir, fileName, isDeployment); err != nil {
+		if err := writeYamlFile(yamlData, dir, subDir, fileName); err != nil {
 			return err
 		}
 		files = append(files, filepath.Join(subDir, fileName))
@@ -164,6 +91,185 @@ func CommitK8sResource(clusterId string, resList []interface{}) error {
 	return err
 }
 
+// K8sResourceFile returns the file of the resource in the config repo of cluster and the yaml which
+// would be committed to it, the config repo is not changed
+func K8sResourceFile(clusterId string, res interface{}) (string, []byte, error) {
+	subDir, fileName, out, isDeployment, err := resourceFile(res, clusterAPIVersions(clusterId))
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := marshalResource(out, isDeployment)
+	if err != nil {
+		return "", nil, err
+	}
+	return filepath.Join(subDir, fileName), data, nil
+}
+
+// ReadK8sResourceFile returns the yaml of the file in the config repo of cluster, it is nil if the file does not exist
+func ReadK8sResourceFile(clusterId, file string) ([]byte, error) {
+	mux.Lock()
+	defer mux.Unlock()
+	if _, ok := ConfigRepos[clusterId]; !ok {
+		return nil, fmt.Errorf("cluster %v not have config repo in git", clusterId)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(configRepoDir, clusterId, file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// the resources are written in the versions served by the cluster
+func clusterAPIVersions(clusterId string) kubeutil.APIVersions {
+	if client, err := service.GetClientset(clusterId); err == nil {
+		return kubeutil.GetAPIVersions(clusterId, client.Discovery())
+	}
+	return kubeutil.GAAPIVersions
+}
+
+// resourceFile returns the file of the resource in config repo and the object which is written to it
+func resourceFile(res interface{}, versions kubeutil.APIVersions) (subDir, fileName string, out interface{}, isDeployment bool, err error) {
+	// the objects of applications are written to the dir of their owner application
+	switch res.(type) {
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.DaemonSet, *batchv1.Job, *batchv1.CronJob,
+		*autoscalingv2.HorizontalPodAutoscaler, *corev1.Service, *networkingv1.Ingress, *unstructured.Unstructured:
+		obj := res.(metav1.Object)
+		ownerName, ok := obj.GetAnnotations()["owner_name"]
+		if !ok {
+			err = fmt.Errorf("not owner_name annotation")
+			return
+		}
+		subDir = fmt.Sprintf("apps/%s/%s", ownerName, obj.GetNamespace())
+	}
+	switch t := res.(type) {
+	case *corev1.Namespace:
+		subDir = "namespaces"
+		fileName = fmt.Sprintf("%s.yaml", t.Name)
+		res.(*corev1.Namespace).TypeMeta = metav1.TypeMeta{
+			Kind:       "Namespace",
+			APIVersion: "v1",
+		}
+		res.(*corev1.Namespace).ObjectMeta.ResourceVersion = ""
+	case *corev1.ResourceQuota:
+		subDir = "resourcequotas"
+		fileName = fmt.Sprintf("%s.yaml", t.Name)
+		res.(*corev1.ResourceQuota).TypeMeta = metav1.TypeMeta{
+			Kind:       "ResourceQuota",
+			APIVersion: "v1",
+		}
+		res.(*corev1.ResourceQuota).ObjectMeta.ResourceVersion = ""
+	case *corev1.ConfigMap:
+		subDir = "configmaps"
+		fileName = fmt.Sprintf("%s.yaml", t.Name)
+		res.(*corev1.ConfigMap).TypeMeta = metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		}
+		res.(*corev1.ConfigMap).ObjectMeta.ResourceVersion = ""
+	case *appsv1.Deployment:
+		isDeployment = true
+		fileName = fmt.Sprintf("%s-dept.yaml", t.Name)
+		res.(*appsv1.Deployment).TypeMeta = metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: versions.Deployment,
+		}
+		res.(*appsv1.Deployment).ObjectMeta.ResourceVersion = ""
+	case *appsv1.StatefulSet:
+		fileName = fmt.Sprintf("%s-sts.yaml", t.Name)
+		res.(*appsv1.StatefulSet).TypeMeta = metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: versions.StatefulSet,
+		}
+		res.(*appsv1.StatefulSet).ObjectMeta.ResourceVersion = ""
+	case *appsv1.DaemonSet:
+		fileName = fmt.Sprintf("%s-ds.yaml", t.Name)
+		res.(*appsv1.DaemonSet).TypeMeta = metav1.TypeMeta{
+			Kind:       "DaemonSet",
+			APIVersion: versions.DaemonSet,
+		}
+		res.(*appsv1.DaemonSet).ObjectMeta.ResourceVersion = ""
+	case *batchv1.Job:
+		fileName = fmt.Sprintf("%s-job.yaml", t.Name)
+		res.(*batchv1.Job).TypeMeta = metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		}
+		res.(*batchv1.Job).ObjectMeta.ResourceVersion = ""
+	case *batchv1.CronJob:
+		fileName = fmt.Sprintf("%s-cronjob.yaml", t.Name)
+		res.(*batchv1.CronJob).TypeMeta = metav1.TypeMeta{
+			Kind:       "CronJob",
+			APIVersion: versions.CronJob,
+		}
+		res.(*batchv1.CronJob).ObjectMeta.ResourceVersion = ""
+	case *autoscalingv2.HorizontalPodAutoscaler:
+		fileName = fmt.Sprintf("%s-hpa.yaml", t.Name)
+		apiVersion := versions.HorizontalPodAutoscaler
+		if apiVersion == "" {
+			apiVersion = kubeutil.AutoscalingV2
+		}
+		res.(*autoscalingv2.HorizontalPodAutoscaler).TypeMeta = metav1.TypeMeta{
+			Kind:       "HorizontalPodAutoscaler",
+			APIVersion: apiVersion,
+		}
+		res.(*autoscalingv2.HorizontalPodAutoscaler).ObjectMeta.ResourceVersion = ""
+	case *corev1.Service:
+		version, ok := t.ObjectMeta.Labels["version"]
+		if !ok {
+			err = fmt.Errorf("not version label")
+			return
+		}
+		fileName = fmt.Sprintf("%s-%s-svc.yaml", t.Name, version)
+		res.(*corev1.Service).TypeMeta = metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		}
+		res.(*corev1.Service).ObjectMeta.ResourceVersion = ""
+	case *networkingv1.Ingress:
+		version, ok := t.ObjectMeta.Labels["version"]
+		if !ok {
+			err = fmt.Errorf("not version label")
+			return
+		}
+		fileName = fmt.Sprintf("%s-%s-ing.yaml", t.Name, version)
+		res.(*networkingv1.Ingress).TypeMeta = metav1.TypeMeta{
+			Kind:       "Ingress",
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+		}
+		res.(*networkingv1.Ingress).ObjectMeta.ResourceVersion = ""
+		if versions.Ingress == kubeutil.ExtensionsV1beta1 {
+			// the backend of the legacy ingress has the different schema
+			res = kubeutil.IngressToLegacy(t)
+		}
+	case *unstructured.Unstructured:
+		fileName = fmt.Sprintf("%s-%s.yaml", t.GetName(), strings.ToLower(t.GetKind()))
+		t.SetResourceVersion("")
+	default:
+		err = fmt.Errorf("unsupported k8s resource type: %v", res)
+		glog.Error(err.Error())
+		return
+	}
+	return subDir, fileName, res, isDeployment, nil
+}
+
+func marshalResource(res interface{}, isDeployment bool) ([]byte, error) {
+	data, err := yaml.Marshal(res)
+	if err != nil {
+		glog.Errorf("marshal yaml error: %s", err.Error())
+		return nil, err
+	}
+	if isDeployment {
+		recreate := strings.Contains(string(data), `type: Recreate`)
+		if recreate {
+			data = []byte(strings.ReplaceAll(string(data), `type: Recreate`, "type: Recreate\n    rollingUpdate: null"))
+		}
+	}
+	return data, nil
+}
+
 func PushCommits(g *Git, files []string) error {
 	beego.Info("commit files: ", files)
 	msg := fmt.Sprintf("Update %v files", files)
@@ -181,7 +287,7 @@ func PushCommits(g *Git, files []string) error {
 	return nil
 }
 
-func writeYamlFile(data []byte, dir, subDir, fileName string, isDeployment bool) error {
+func writeYamlFile(data []byte, dir, subDir, fileName string) error {
 	exist, err := pathExists(filepath.Join(dir, subDir))
 	if err != nil {
 		glog.Errorf("get dir error: %s", err.Error())
@@ -200,13 +306,6 @@ func writeYamlFile(data []byte, dir, subDir, fileName string, isDeployment bool)
 	}
 	defer yamlFile.Close()
 
-	if isDeployment {
-		recreate := strings.Contains(string(data), `type: Recreate`)
-		if recreate {
-			data = []byte(strings.ReplaceAll(string(data), `type: Recreate`, "type: Recreate\n    rollingUpdate: null"))
-		}
-	}
-
 	if _, err := yamlFile.Write(data); err != nil {
 		glog.Errorf("Failed to write the file", err.Error())
 		return err
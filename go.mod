@@ -1,21 +1,27 @@
 module kubecloud
 
-go 1.12
+go 1.16
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/astaxie/beego v1.12.0
 	github.com/ghodss/yaml v1.0.0
-	github.com/go-sql-driver/mysql v1.4.1
-	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
-	github.com/gorilla/websocket v1.4.1 // indirect
-	github.com/prometheus/client_golang v1.0.0
+	github.com/go-sql-driver/mysql v1.5.0
+	github.com/golang/glog v1.0.0
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/prometheus/client_golang v1.11.0
+	github.com/prometheus/common v0.30.0
 	github.com/shiena/ansicolor v0.0.0-20151119151921-a422bbe96644 // indirect
-	github.com/stretchr/testify v1.4.0
+	github.com/stretchr/testify v1.8.0
+	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
 	gopkg.in/igm/sockjs-go.v2 v2.0.1
-	gopkg.in/yaml.v2 v2.2.4
-	k8s.io/api v0.17.0
-	k8s.io/apimachinery v0.17.0
-	k8s.io/apiserver v0.17.0
-	k8s.io/client-go v0.17.0
-	k8s.io/component-base v0.17.0
+	gopkg.in/yaml.v2 v2.4.0
+	helm.sh/helm/v3 v3.8.2
+	k8s.io/api v0.23.17
+	k8s.io/apimachinery v0.23.17
+	k8s.io/apiserver v0.23.17
+	k8s.io/client-go v0.23.17
+	k8s.io/component-base v0.23.17
+	sigs.k8s.io/kustomize/api v0.10.1
+	sigs.k8s.io/kustomize/kyaml v0.13.0
 )
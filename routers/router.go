@@ -18,6 +18,7 @@ func Init() {
 			beego.NSNamespace("/v1",
 				beego.NSRouter("/events", &controllers.EventsController{}, "get:Get"),
 				beego.NSRouter("/version", &controllers.VersionController{}, "get:Get"),
+				beego.NSRouter("/apps/bulk", &controllers.AppController{}, "post:Bulk"),
 
 				// cluster
 				beego.NSRouter("/clusters", &controllers.ClusterController{}, "post:CreateCluster"),
@@ -46,25 +47,60 @@ func Init() {
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/reconfigure", &controllers.AppController{}, "post:Reconfigure"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/rollingupdate", &controllers.AppController{}, "post:RollingUpdate"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/scale", &controllers.AppController{}, "post:Scale"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/autoscaler", &controllers.AppController{}, "get:GetAutoscaler;put:SetAutoscaler;delete:DeleteAutoscaler"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/log", &controllers.AppController{}, "get:Log"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/scaleschedules", &controllers.ScaleScheduleController{}, "get:List;post:Create"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/scaleschedules/:id", &controllers.ScaleScheduleController{}, "put:Update;delete:Delete"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/event", &controllers.AppController{}, "get:Event"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/pods/:podname/status", &controllers.AppController{}, "get:PodInspect"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/pods/:podname/containernames/:containername/terminal", &controllers.TermController{}, "get:PodTerminal"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/apps/:app/pods/:podname/containernames/:containername/exec", &controllers.ExecController{}, "get:PodTerminalExec"),
 
+				// template
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/templates", &controllers.TemplateController{}, "get:List;post:Create"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/templates/:template", &controllers.TemplateController{}, "get:Inspect;put:Update;delete:Delete"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/templates/:template/versions", &controllers.TemplateController{}, "get:VersionList"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/templates/:template/versions/:version", &controllers.TemplateController{}, "get:VersionInspect"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/templates/:template/render", &controllers.TemplateController{}, "post:RenderTemplate"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/templates/:template/preview", &controllers.TemplateController{}, "post:Preview"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/templates/:template/graph", &controllers.TemplateController{}, "post:DependencyGraph"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/templates/:template/deploy", &controllers.TemplateController{}, "post:Deploy"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/capture", &controllers.TemplateController{}, "post:Capture"),
+
+				// admission policy
+				beego.NSRouter("/clusters/:cluster/admissionpolicies", &controllers.AdmissionPolicyController{}, "get:List;post:Create"),
+				beego.NSRouter("/clusters/:cluster/admissionpolicies/:id", &controllers.AdmissionPolicyController{}, "get:Inspect;put:Update;delete:Delete"),
+				// app config set
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/configsets", &controllers.AppConfigSetController{}, "get:List;post:Create"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/configsets/:name", &controllers.AppConfigSetController{}, "get:Inspect;put:Update;delete:Delete"),
+				// topology
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/topology", &controllers.TopologyController{}, "get:Inspect"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/topology/simulation", &controllers.TopologyController{}, "post:Simulate"),
+
+				// helm release
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/releases", &controllers.HelmReleaseController{}, "get:List;post:Install"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/releases/:release", &controllers.HelmReleaseController{}, "get:History;delete:Uninstall"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/releases/:release/rollback", &controllers.HelmReleaseController{}, "post:Rollback"),
+
 				// secret
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/secrets", &controllers.SecretController{}, "post:Create"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/secrets/list", &controllers.SecretController{}, "post:List"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/secrets/:secret", &controllers.SecretController{}, "delete:Delete;put:Update"),
+				// certificate
+				beego.NSRouter("/certificates", &controllers.CertificateController{}, "get:List"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/certificates", &controllers.CertificateController{}, "get:NamespaceList"),
 
 				// ingress
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/ingresses", &controllers.IngressController{}, "post:Create"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/ingresses/list", &controllers.IngressController{}, "post:List"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/ingresses/:ingressID", &controllers.IngressController{}, "delete:Delete;put:Update;get:Inspect"),
+				beego.NSRouter("/ingressroutes/conflicts", &controllers.IngressRouteController{}, "get:Conflicts"),
 
 				// service
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/services", &controllers.ServiceController{}, "get:List"),
 				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/services/:service", &controllers.ServiceController{}, "get:Inspect"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/portexposures", &controllers.PortExposureController{}, "get:List;post:Create"),
+				beego.NSRouter("/clusters/:cluster/namespaces/:namespace/portexposures/:id", &controllers.PortExposureController{}, "delete:Delete"),
 
 				// harbor
 				beego.NSRouter("/harbors", &controllers.HarborController{}, "post:HarborCreate"),
@@ -94,6 +130,8 @@ func Init() {
 	})
 
 	beego.Handler("/api/v3/socket/:info(.*)", controllers.CreateAttachHandler("/socket"))
+	// the http-01 challenges of acme certificates
+	beego.Router("/.well-known/acme-challenge/:token", &controllers.AcmeController{}, "get:Challenge")
 
 	beego.ErrorController(&controllers.ErrorController{})
 